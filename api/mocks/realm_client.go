@@ -111,6 +111,20 @@ func (mr *MockRealmClientMockRecorder) CreateEmptyApp(groupID, appName, location
 	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "CreateEmptyApp", reflect.TypeOf((*MockRealmClient)(nil).CreateEmptyApp), groupID, appName, location, deploymentModel)
 }
 
+// DeleteApp mocks base method
+func (m *MockRealmClient) DeleteApp(groupID, appID string) error {
+	m.ctrl.T.Helper()
+	ret := m.ctrl.Call(m, "DeleteApp", groupID, appID)
+	ret0, _ := ret[0].(error)
+	return ret0
+}
+
+// DeleteApp indicates an expected call of DeleteApp
+func (mr *MockRealmClientMockRecorder) DeleteApp(groupID, appID interface{}) *gomock.Call {
+	mr.mock.ctrl.T.Helper()
+	return mr.mock.ctrl.RecordCallWithMethodType(mr.mock, "DeleteApp", reflect.TypeOf((*MockRealmClient)(nil).DeleteApp), groupID, appID)
+}
+
 // DeleteAsset mocks base method
 func (m *MockRealmClient) DeleteAsset(groupID, appID, path string) error {
 	m.ctrl.T.Helper()
@@ -24,9 +24,6 @@ import (
 type ExportStrategy string
 
 const (
-	// default to the current realm config version
-	configVersion = "20200603"
-
 	// ExportStrategyNone will result in no extra configuration into the call to Export
 	ExportStrategyNone ExportStrategy = "none"
 	// ExportStrategyTemplate will result in the `template` querystring parameter getting added to the call to Export
@@ -38,6 +35,7 @@ const (
 	authProviderLoginRoute = adminBaseURL + "/auth/providers/%s/login"
 
 	appsByGroupIDRoute      = adminBaseURL + "/groups/%s/apps"
+	appByIDRoute            = adminBaseURL + "/groups/%s/apps/%s"
 	atlasAppsByGroupIDRoute = appsByGroupIDRoute + "?product=atlas"
 	appImportRoute          = adminBaseURL + "/groups/%s/apps/%s/import"
 	appExportRoute          = adminBaseURL + "/groups/%s/apps/%s/export?%s"
@@ -97,6 +95,7 @@ type RealmClient interface {
 	CopyAsset(groupID, appID, fromPath, toPath string) error
 	CreateDraft(groupID, appID string) (*models.AppDraft, error)
 	CreateEmptyApp(groupID, appName, location, deploymentModel string) (*models.App, error)
+	DeleteApp(groupID, appID string) error
 	DeleteAsset(groupID, appID, path string) error
 	DeployDraft(groupID, appID, draftID string) (*models.Deployment, error)
 	Diff(groupID, appID string, appData []byte, strategy string) ([]string, error)
@@ -168,7 +167,7 @@ func (sc *basicRealmClient) Authenticate(authProvider auth.AuthenticationProvide
 
 // Export will download a Realm app as a .zip
 func (sc *basicRealmClient) Export(groupID, appID string, strategy ExportStrategy) (string, io.ReadCloser, error) {
-	queryParams := []string{fmt.Sprintf("version=%s", configVersion)}
+	queryParams := []string{fmt.Sprintf("version=%d", models.ConfigVersion)}
 	if strategy == ExportStrategyTemplate {
 		queryParams = append(queryParams, "template=true")
 	} else if strategy == ExportStrategySourceControl {
@@ -636,6 +635,12 @@ func (sc *basicRealmClient) CreateEmptyApp(groupID, appName, location, deploymen
 	return &app, nil
 }
 
+// DeleteApp deletes the app with the given ID
+func (sc *basicRealmClient) DeleteApp(groupID, appID string) error {
+	res, err := sc.ExecuteRequest(http.MethodDelete, fmt.Sprintf(appByIDRoute, groupID, appID), RequestOptions{})
+	return checkStatusNoContent(res, err, "failed to delete app")
+}
+
 func (sc *basicRealmClient) ListAssetsForAppID(groupID, appID string) ([]hosting.AssetMetadata, error) {
 	res, err := sc.ExecuteRequest(
 		http.MethodGet,
@@ -849,7 +854,7 @@ func checkStatusNoContent(res *http.Response, requestErr error, errMessage strin
 		return requestErr
 	}
 	if res.StatusCode != http.StatusNoContent {
-		return fmt.Errorf("%s: %s: %s", res.Status, errMessage, UnmarshalRealmError(res))
+		return fmt.Errorf("%s: %s: %w", res.Status, errMessage, UnmarshalRealmError(res))
 	}
 	return nil
 }
@@ -18,7 +18,8 @@ func (eanf ErrAppNotFound) Error() string {
 
 // ErrRealmResponse represents a response from a Realm API call
 type ErrRealmResponse struct {
-	data errRealmResponseData
+	data       errRealmResponseData
+	statusCode int
 }
 
 // Error returns a stringified error message
@@ -31,6 +32,11 @@ func (esr ErrRealmResponse) ErrorCode() string {
 	return esr.data.ErrorCode
 }
 
+// StatusCode returns the HTTP status code of the response, or 0 if it is unknown
+func (esr ErrRealmResponse) StatusCode() int {
+	return esr.statusCode
+}
+
 // UnmarshalJSON unmarshals JSON data into an ErrRealmResponse
 func (esr *ErrRealmResponse) UnmarshalJSON(data []byte) error {
 	return json.Unmarshal(data, &esr.data)
@@ -55,10 +61,11 @@ func UnmarshalRealmError(res *http.Response) error {
 			data: errRealmResponseData{
 				Error: res.Status,
 			},
+			statusCode: res.StatusCode,
 		}
 	}
 
-	var realmResponse ErrRealmResponse
+	realmResponse := ErrRealmResponse{statusCode: res.StatusCode}
 	if err := json.NewDecoder(&buf).Decode(&realmResponse); err != nil {
 		realmResponse.data.Error = str
 	}
@@ -51,6 +51,16 @@ func TestErrRealmResponse(t *testing.T) {
 		})
 		u.So(t, err, gc.ShouldBeError, "error: something went horribly, horribly wrong")
 	})
+
+	t.Run("should keep the status code of the response", func(t *testing.T) {
+		err := api.UnmarshalRealmError(&http.Response{
+			StatusCode: http.StatusTooManyRequests,
+			Body:       u.NewResponseBody(strings.NewReader(`{ "error": "slow down" }`)),
+		})
+		realmErr, ok := err.(api.ErrRealmResponse)
+		u.So(t, ok, gc.ShouldBeTrue)
+		u.So(t, realmErr.StatusCode(), gc.ShouldEqual, http.StatusTooManyRequests)
+	})
 }
 
 // md5Sum returns the md5 hash sum of the input string
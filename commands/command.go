@@ -212,6 +212,23 @@ func (c *BaseCommand) run(args []string) error {
 	return nil
 }
 
+// ColorUI returns a cli.Ui that prints informational messages in the given color,
+// or the command's cli.Ui as is when its output is not colored
+func (c *BaseCommand) ColorUI(color cli.UiColor) cli.Ui {
+	coloredUI, ok := c.UI.(*cli.ColoredUi)
+	if !ok {
+		return c.UI
+	}
+
+	return &cli.ColoredUi{
+		OutputColor: color,
+		InfoColor:   color,
+		ErrorColor:  coloredUI.ErrorColor,
+		WarnColor:   coloredUI.WarnColor,
+		Ui:          coloredUI.Ui,
+	}
+}
+
 // AskYesNo is used to prompt the user for yes/no input
 func (c *BaseCommand) AskYesNo(query string) (bool, error) {
 	if c.flagYes {
@@ -0,0 +1,150 @@
+package commands
+
+import (
+	"fmt"
+	"time"
+
+	"github.com/10gen/realm-cli/dependency/transpiler"
+
+	"github.com/mitchellh/cli"
+)
+
+const (
+	dependenciesCacheFlagAll    = "all"
+	dependenciesCacheFlagMaxAge = "max-age"
+
+	defaultTranspileCacheMaxAgeDays = 30
+)
+
+// NewDependenciesCacheCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewDependenciesCacheCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		return &DependenciesCacheCommand{
+			BaseCommand: &BaseCommand{
+				Name: "cache",
+				UI:   ui,
+			},
+		}, nil
+	}
+}
+
+// DependenciesCacheCommand is used to manage the local cache of transpiled dependencies
+type DependenciesCacheCommand struct {
+	*BaseCommand
+}
+
+// Synopsis returns a one-liner description for this command
+func (dcc *DependenciesCacheCommand) Synopsis() string {
+	return "Manage the local cache of transpiled dependencies."
+}
+
+// Help returns long-form help information for this command
+func (dcc *DependenciesCacheCommand) Help() string {
+	return dcc.Synopsis()
+}
+
+// Run executes the command
+func (dcc *DependenciesCacheCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// NewDependenciesCachePruneCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewDependenciesCachePruneCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		return &DependenciesCachePruneCommand{
+			BaseCommand: &BaseCommand{
+				Name: "prune",
+				UI:   ui,
+			},
+			now: time.Now,
+		}, nil
+	}
+}
+
+// DependenciesCachePruneCommand is used to remove the cached results of transpiling dependencies
+// that are no longer used
+type DependenciesCachePruneCommand struct {
+	*BaseCommand
+
+	now func() time.Time
+
+	flagAll    bool
+	flagMaxAge int
+}
+
+// Synopsis returns a one-liner description for this command
+func (dcpc *DependenciesCachePruneCommand) Synopsis() string {
+	return "Remove the unused entries of the local cache of transpiled dependencies."
+}
+
+// Help returns long-form help information for this command
+func (dcpc *DependenciesCachePruneCommand) Help() string {
+	return `Remove the entries of the local cache of transpiled dependencies, which 'import --include-dependencies'
+uses to avoid transpiling unchanged sources again, that were made by another version of a transpiler
+or that have not been used recently.
+
+Usage: realm-cli dependencies cache prune [options]
+
+OPTIONS:
+  --max-age [int]
+	The number of days after which an entry that has not been used is removed. Defaults to 30,
+	or only removing the entries of other transpiler versions if 0.
+
+  --all
+	Remove every entry of the cache.
+` +
+		dcpc.BaseCommand.Help()
+}
+
+// Run executes the command
+func (dcpc *DependenciesCachePruneCommand) Run(args []string) int {
+	flags := dcpc.NewFlagSet()
+
+	flags.BoolVar(&dcpc.flagAll, dependenciesCacheFlagAll, false, "")
+	flags.IntVar(&dcpc.flagMaxAge, dependenciesCacheFlagMaxAge, defaultTranspileCacheMaxAgeDays, "")
+
+	if err := dcpc.BaseCommand.run(args); err != nil {
+		dcpc.UI.Error(err.Error())
+		return 1
+	}
+
+	if dcpc.flagMaxAge < 0 {
+		dcpc.UI.Error("--max-age must not be negative")
+		return 1
+	}
+
+	if err := dcpc.prune(); err != nil {
+		dcpc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (dcpc *DependenciesCachePruneCommand) prune() error {
+	cacheDir, err := getTranspileCacheDir(dcpc.flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	maxAge := time.Duration(dcpc.flagMaxAge) * 24 * time.Hour
+	if dcpc.flagAll {
+		maxAge = -1
+	}
+
+	// the entries of older versions of the transpilers, or of a transpiler that is not installed, are removed
+	var versions []string
+	for _, name := range transpiler.TranspilerNames {
+		if version, versionErr := transpiler.Version(name); versionErr == nil {
+			versions = append(versions, version)
+		}
+	}
+
+	result, err := transpiler.PruneCache(cacheDir, versions, maxAge, dcpc.now())
+	if err != nil {
+		return fmt.Errorf("failed to prune the transpile cache %s: %s", cacheDir, err)
+	}
+
+	dcpc.UI.Info(fmt.Sprintf("Removed %d cached file(s) (%d bytes) from the transpile cache %s", result.Entries, result.Bytes, cacheDir))
+	return nil
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/10gen/realm-cli/dependency/transpiler"
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDependenciesCachePruneCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-dependencies-cache")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	configPathArg := "--config-path=" + filepath.Join(dir, "config.json")
+	cacheDir := filepath.Join(dir, utils.TranspileCacheDirName)
+	now := time.Now()
+
+	prune := func(args ...string) (int, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewDependenciesCachePruneCommandFactory(mockUI)()
+		u.So(t, err, gc.ShouldBeNil)
+
+		pruneCommand := cmd.(*DependenciesCachePruneCommand)
+		pruneCommand.storage = u.NewEmptyStorage()
+		pruneCommand.now = func() time.Time { return now }
+		return pruneCommand.Run(append(args, configPathArg)), mockUI
+	}
+
+	cache := func(version string, codes ...string) {
+		tr := transpiler.NewCachingTranspiler(transpiler.NewNativeTranspiler(), cacheDir, version)
+		_, err := tr.Transpile(context.Background(), codes...)
+		u.So(t, err, gc.ShouldBeNil)
+	}
+
+	t.Run("should remove nothing when there is no cache", func(t *testing.T) {
+		exitCode, mockUI := prune()
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, "Removed 0 cached file(s) (0 bytes) from the transpile cache "+cacheDir)
+	})
+
+	t.Run("should remove the entries of other transpiler versions", func(t *testing.T) {
+		cache(transpiler.NativeTranspilerVersion, "let a = 1;")
+		cache("native-0", "let a = 1;", "let b = 2;")
+
+		exitCode, mockUI := prune()
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, "Removed 2 cached file(s)")
+	})
+
+	t.Run("should remove the entries not used within the max age", func(t *testing.T) {
+		now = now.Add(2 * 24 * time.Hour)
+
+		exitCode, mockUI := prune("--max-age=3")
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, "Removed 0 cached file(s)")
+
+		exitCode, mockUI = prune("--max-age=1")
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, "Removed 1 cached file(s)")
+	})
+
+	t.Run("should remove every entry with --all", func(t *testing.T) {
+		cache(transpiler.NativeTranspilerVersion, "let a = 1;", "let b = 2;")
+
+		exitCode, mockUI := prune("--all")
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, "Removed 2 cached file(s)")
+
+		entries, err := ioutil.ReadDir(cacheDir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, entries, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should fail with a negative max age", func(t *testing.T) {
+		exitCode, mockUI := prune("--max-age=-1")
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "--max-age must not be negative")
+	})
+}
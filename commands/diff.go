@@ -1,12 +1,26 @@
 package commands
 
 import (
+	"encoding/json"
+	"fmt"
 	"io"
 	"os"
+	"path/filepath"
+	"strings"
 
 	"github.com/10gen/realm-cli/models"
 	"github.com/10gen/realm-cli/utils"
 	"github.com/mitchellh/cli"
+	"github.com/mitchellh/go-homedir"
+)
+
+const (
+	diffFlagAgainst = "against"
+	diffFlagOutput  = "output"
+
+	diffOutputText  = "text"
+	diffOutputJSON  = "json"
+	diffOutputPatch = "patch"
 )
 
 // NewDiffCommandFactory returns a new cli.CommandFactory given a cli.Ui
@@ -24,6 +38,7 @@ func NewDiffCommandFactory(ui cli.Ui) cli.CommandFactory {
 			},
 			workingDirectory: workingDirectory,
 			writeToDirectory: utils.WriteZipToDir,
+			flagVars:         utils.Variables{},
 			writeAppConfigToFile: func(dest string, app models.AppInstanceData) error {
 				return app.MarshalFile(dest)
 			},
@@ -39,12 +54,21 @@ type DiffCommand struct {
 	writeAppConfigToFile func(dest string, app models.AppInstanceData) error
 	workingDirectory     string
 
-	flagAppID          string
-	flagAppPath        string
-	flagAppName        string
-	flagGroupID        string
-	flagStrategy       string
-	flagIncludeHosting bool
+	flagAppID               string
+	flagAppPath             string
+	flagAppName             string
+	flagGroupID             string
+	flagStrategy            string
+	flagIncludeHosting      bool
+	flagIncludeDependencies bool
+	flagVars                utils.Variables
+	flagVarsFile            string
+	flagAgainst             string
+	flagOutput              string
+	flagExcludes            utils.StringSliceFlag
+	flagCompress            string
+	flagVerify              bool
+	flagHostingAssets       hostingAssetOptions
 }
 
 // Help returns long-form help information for this command
@@ -62,8 +86,50 @@ OPTIONS:
   --project-id [string]
 	The Atlas Project ID.
 
+  --strategy [merge|replace|replace-by-name] (default: merge)
+	Compare your app the way 'import --strategy' would import it, so that with the default merge strategy
+	the entities and static assets that only exist in the deployed app are not shown as deleted.
+	A plan written with --output=json can only be imported by 'import --plan-file' with the same strategy.
+
   --include-hosting
 	Upload static assets from "/hosting" directory.
+
+  --include-dependencies
+	Include the node_modules archive or directory within the "/functions" directory in the plan written
+	with --output=json, as 'import --include-dependencies --plan-file' expects.
+
+  --exclude [string]
+	A gitignore-style pattern of files in "/hosting/files" not to upload, in addition to those listed in
+	"/hosting/files/.realmignore". May be provided multiple times.
+
+  --compress [gzip|br]
+	Compare text-like static assets as they would be uploaded by 'import --compress'.
+
+  --verify
+	Rehash every static asset rather than trusting the hashes cached for unchanged files.
+
+  --follow-symlinks
+	Compare the files in symlinked directories of "/hosting/files", which are otherwise skipped.
+
+  --max-asset-size [int]
+	The largest size in megabytes of a static asset, checked like 'import --max-asset-size'. Defaults to 25, or unlimited if 0.
+
+  --var [NAME=VALUE]
+	Set the value of a ${NAME} template variable used in the app's configuration files.
+	May be provided multiple times, and takes precedence over --vars-file and environment variables.
+
+  --vars-file [string]
+	A path to a JSON file of template variable names to values.
+	Variables not set with --var or --vars-file are resolved from environment variables.
+
+  --against [string]
+	A path to another local app directory or exported app zip to compare your app against, instead of the
+	deployed app. The comparison is made offline and does not require you to be logged in.
+
+  --output [string]
+	The format of the diff. Must be one of "text", "json" or "patch", where "patch" requires --against.
+	Defaults to "text". Without --against, "json" writes the plan of changes an import would make,
+	which can be reviewed and then imported with 'import --plan-file'.
 	` +
 		dc.BaseCommand.Help()
 }
@@ -80,13 +146,53 @@ func (dc *DiffCommand) Run(args []string) int {
 	flags.StringVar(&dc.flagAppID, flagAppIDName, "", "")
 	flags.StringVar(&dc.flagAppPath, importFlagPath, "", "")
 	flags.StringVar(&dc.flagGroupID, flagProjectIDName, "", "")
+	flags.StringVar(&dc.flagStrategy, importFlagStrategy, importStrategyMerge, "")
 	flags.BoolVar(&dc.flagIncludeHosting, importFlagIncludeHosting, false, "")
+	flags.BoolVar(&dc.flagIncludeDependencies, importFlagIncludeDependencies, false, "")
+	flags.Var(dc.flagVars, importFlagVar, "")
+	flags.StringVar(&dc.flagVarsFile, importFlagVarsFile, "", "")
+	flags.StringVar(&dc.flagAgainst, diffFlagAgainst, "", "")
+	flags.StringVar(&dc.flagOutput, diffFlagOutput, diffOutputText, "")
+	flags.Var(&dc.flagExcludes, importFlagExclude, "")
+	flags.StringVar(&dc.flagCompress, importFlagCompress, "", "")
+	flags.BoolVar(&dc.flagVerify, importFlagVerify, false, "")
+	dc.flagHostingAssets.registerFlags(flags)
 
 	if err := dc.BaseCommand.run(args); err != nil {
 		dc.UI.Error(err.Error())
 		return 1
 	}
 
+	if dc.flagAgainst != "" {
+		if err := dc.diffAgainst(); err != nil {
+			dc.UI.Error(err.Error())
+			return 1
+		}
+		return 0
+	}
+
+	switch dc.flagOutput {
+	case diffOutputText, diffOutputJSON:
+	case diffOutputPatch:
+		dc.UI.Error(fmt.Sprintf("--%s=%s is only supported with --%s", diffFlagOutput, diffOutputPatch, diffFlagAgainst))
+		return 1
+	default:
+		dc.UI.Error(fmt.Sprintf("--%s must be one of %q, %q or %q", diffFlagOutput, diffOutputText, diffOutputJSON, diffOutputPatch))
+		return 1
+	}
+
+	switch dc.flagStrategy {
+	case importStrategyMerge, importStrategyReplace, importStrategyReplaceByName:
+	default:
+		dc.UI.Error(fmt.Sprintf("unknown diff strategy %q; accepted values are [%s|%s|%s]", dc.flagStrategy, importStrategyMerge, importStrategyReplace, importStrategyReplaceByName))
+		return 1
+	}
+
+	if err := dc.flagHostingAssets.validate(); err != nil {
+		dc.UI.Error(err.Error())
+		return 1
+	}
+
 	ic := &ImportCommand{
 		BaseCommand: dc.BaseCommand,
 
@@ -94,12 +200,20 @@ func (dc *DiffCommand) Run(args []string) int {
 		writeAppConfigToFile: dc.writeAppConfigToFile,
 		workingDirectory:     dc.workingDirectory,
 
-		flagAppID:          dc.flagAppID,
-		flagAppPath:        dc.flagAppPath,
-		flagAppName:        dc.flagAppName,
-		flagGroupID:        dc.flagGroupID,
-		flagStrategy:       dc.flagStrategy,
-		flagIncludeHosting: dc.flagIncludeHosting,
+		flagAppID:               dc.flagAppID,
+		flagAppPath:             dc.flagAppPath,
+		flagAppName:             dc.flagAppName,
+		flagGroupID:             dc.flagGroupID,
+		flagStrategy:            dc.flagStrategy,
+		flagIncludeHosting:      dc.flagIncludeHosting,
+		flagIncludeDependencies: dc.flagIncludeDependencies,
+		flagVars:                dc.flagVars,
+		flagVarsFile:            dc.flagVarsFile,
+		flagOutput:              dc.flagOutput,
+		flagExcludes:            dc.flagExcludes,
+		flagCompress:            dc.flagCompress,
+		flagVerify:              dc.flagVerify,
+		flagHostingAssets:       dc.flagHostingAssets,
 	}
 
 	dryRun := true
@@ -109,3 +223,130 @@ func (dc *DiffCommand) Run(args []string) int {
 	}
 	return 0
 }
+
+// diffAgainst compares the local app with another app directory or exported app zip without contacting the server
+func (dc *DiffCommand) diffAgainst() error {
+	switch dc.flagOutput {
+	case diffOutputText, diffOutputJSON, diffOutputPatch:
+	default:
+		return fmt.Errorf("--%s must be one of %q, %q or %q", diffFlagOutput, diffOutputText, diffOutputJSON, diffOutputPatch)
+	}
+
+	appPath, err := utils.ResolveAppDirectory(dc.flagAppPath, dc.workingDirectory)
+	if err != nil {
+		return err
+	}
+
+	localApp, err := utils.UnmarshalFromDir(appPath)
+	if err != nil {
+		return err
+	}
+
+	if err := utils.ValidateHostingConfig(appPath); err != nil {
+		return err
+	}
+
+	againstApp, err := dc.loadAgainstApp()
+	if err != nil {
+		return err
+	}
+
+	diffs, err := utils.DiffApps(againstApp, localApp)
+	if err != nil {
+		return err
+	}
+
+	switch dc.flagOutput {
+	case diffOutputJSON:
+		data, err := json.MarshalIndent(diffs, "", "    ")
+		if err != nil {
+			return err
+		}
+		dc.UI.Output(string(data))
+	case diffOutputPatch:
+		dc.UI.Output(strings.TrimSuffix(diffs.Patch(), "\n"))
+	default:
+		if len(diffs) == 0 {
+			dc.UI.Info("Apps are identical, nothing to do.")
+			return nil
+		}
+		dc.printAppEntityDiffs(diffs)
+	}
+
+	return nil
+}
+
+// loadAgainstApp loads the app to compare against from a directory or an exported app zip
+func (dc *DiffCommand) loadAgainstApp() (map[string]interface{}, error) {
+	againstPath, err := homedir.Expand(dc.flagAgainst)
+	if err != nil {
+		return nil, err
+	}
+	if !filepath.IsAbs(againstPath) {
+		againstPath = filepath.Join(dc.workingDirectory, againstPath)
+	}
+
+	info, err := os.Stat(againstPath)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find app to diff against: %s", err)
+	}
+
+	if info.IsDir() {
+		return utils.UnmarshalFromDir(againstPath)
+	}
+
+	zipFile, err := os.Open(againstPath)
+	if err != nil {
+		return nil, err
+	}
+	defer zipFile.Close()
+
+	app, err := utils.UnmarshalFromZip(zipFile)
+	if err != nil {
+		return nil, fmt.Errorf("failed to extract %s: %s", dc.flagAgainst, err)
+	}
+
+	return app, nil
+}
+
+// printAppEntityDiffs prints the entity diffs grouped by type with summary counts at the top,
+// along with colorized unified diffs of function and incoming webhook source changes
+func (c *BaseCommand) printAppEntityDiffs(diffs utils.AppEntityDiffs) {
+	c.UI.Info("Summary:")
+	for _, line := range diffs.Summary() {
+		c.UI.Info(line)
+	}
+
+	hunkUI := c.ColorUI(cli.UiColorCyan)
+	insertUI := c.ColorUI(cli.UiColorGreen)
+	deleteUI := c.ColorUI(cli.UiColorRed)
+
+	for i, d := range diffs {
+		if i == 0 || diffs[i-1].Type != d.Type {
+			c.UI.Info(d.Type.Title() + ":")
+		}
+
+		switch d.Change {
+		case utils.AppEntityAdded:
+			insertUI.Info("\t" + d.String())
+		case utils.AppEntityDeleted:
+			deleteUI.Info("\t" + d.String())
+		default:
+			c.UI.Info("\t" + d.String())
+		}
+
+		for _, hunk := range d.SourceHunks(3) {
+			hunkUI.Info("\t\t" + hunk.Header())
+			for _, line := range hunk.Lines {
+				switch line.Op {
+				case utils.DiffInsert:
+					insertUI.Info("\t\t" + line.String())
+				case utils.DiffDelete:
+					deleteUI.Info("\t\t" + line.String())
+				default:
+					c.UI.Info("\t\t" + line.String())
+				}
+			}
+		}
+	}
+}
@@ -1,13 +1,24 @@
 package commands
 
 import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"errors"
 	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
 	"testing"
 
+	"github.com/10gen/realm-cli/api"
 	"github.com/10gen/realm-cli/models"
 	"github.com/10gen/realm-cli/user"
+	"github.com/10gen/realm-cli/utils"
 	u "github.com/10gen/realm-cli/utils/test"
 	"github.com/mitchellh/cli"
+	"github.com/mitchellh/go-homedir"
 	gc "github.com/smartystreets/goconvey/convey"
 )
 
@@ -42,6 +53,40 @@ func setUpBasicDiffCommand() (*DiffCommand, *cli.MockUi) {
 	return diffCommand, mockUI
 }
 
+// zipTestApp zips the test app at src, placing its files under prefix
+func zipTestApp(t *testing.T, src, prefix string) []byte {
+	t.Helper()
+
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	u.So(t, filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil || relPath == "." {
+			return err
+		}
+		if info.IsDir() {
+			_, err = zw.Create(prefix + filepath.ToSlash(relPath) + "/")
+			return err
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		f, err := zw.Create(prefix + filepath.ToSlash(relPath))
+		if err != nil {
+			return err
+		}
+		_, err = f.Write(data)
+		return err
+	}), gc.ShouldBeNil)
+	u.So(t, zw.Close(), gc.ShouldBeNil)
+
+	return buf.Bytes()
+}
+
 func TestDiffCommand(t *testing.T) {
 	validArgs := []string{"--app-id=my-app-abcdef"}
 
@@ -97,6 +142,36 @@ func TestDiffCommand(t *testing.T) {
 					},
 				},
 			},
+			{
+				Description:      "it fails before calling the API if a template variable cannot be resolved",
+				Args:             append([]string{"--path=../testdata/templated_app", "--var=WEBHOOK_HOST=example.com"}, validArgs...),
+				ExpectedExitCode: 1,
+				ExpectedError:    "unresolved template variable(s): CLUSTER_NAME_MONGODB_ATLAS",
+				RealmClient: u.MockRealmClient{
+					FetchAppByClientAppIDFn: func(clientAppID string) (*models.App, error) {
+						panic("the API should not be called")
+					},
+				},
+			},
+			{
+				Description:      "it succeeds if every template variable is resolved",
+				Args:             append([]string{"--path=../testdata/templated_app", "--vars-file=../testdata/templated_app/variables.json"}, validArgs...),
+				ExpectedExitCode: 0,
+				RealmClient: u.MockRealmClient{
+					DiffFn: func(groupID, appID string, appData []byte, strategy string) ([]string, error) {
+						if !strings.Contains(string(appData), `"clusterName":"Cluster0"`) {
+							return nil, errors.New("template variables were not substituted")
+						}
+						return []string{"sample-diff-contents"}, nil
+					},
+					FetchAppByClientAppIDFn: func(clientAppID string) (*models.App, error) {
+						return &models.App{
+							GroupID: "group-id",
+							ID:      "app-id",
+						}, nil
+					},
+				},
+			},
 		} {
 			t.Run(tc.Description, func(t *testing.T) {
 				diffCommand, mockUI := setup()
@@ -112,4 +187,295 @@ func TestDiffCommand(t *testing.T) {
 
 	})
 
+	t.Run("when diffing against another app", func(t *testing.T) {
+		dir := copyTestApp(t, "../testdata/full_app")
+		defer os.RemoveAll(dir)
+
+		u.So(t, ioutil.WriteFile(
+			filepath.Join(dir, "functions", "function_a", "source.js"),
+			[]byte("exports = function(x) {\n  return x + 2;\n};\n"),
+			0600,
+		), gc.ShouldBeNil)
+		u.So(t, os.Remove(filepath.Join(dir, "values", "value_b.json")), gc.ShouldBeNil)
+
+		againstZip := zipTestApp(t, "../testdata/full_app", "full_app/")
+
+		zipPath := filepath.Join(dir, "against.zip")
+		u.So(t, ioutil.WriteFile(zipPath, againstZip, 0600), gc.ShouldBeNil)
+
+		for _, tc := range []struct {
+			description      string
+			args             []string
+			expectedExitCode int
+			expectedOutput   []string
+			expectedError    string
+		}{
+			{
+				description:      "it reports identical apps",
+				args:             []string{"--path=../testdata/full_app", "--against=../testdata/full_app"},
+				expectedExitCode: 0,
+				expectedOutput:   []string{"Apps are identical, nothing to do."},
+			},
+			{
+				description:      "it describes the changed entities without requiring a login",
+				args:             []string{"--path=" + dir, "--against=../testdata/full_app"},
+				expectedExitCode: 0,
+				expectedOutput:   []string{"Functions:\n\t* function_a (source)\n", "Values:\n\t- b\n"},
+			},
+			{
+				description:      "it compares against an exported app zip",
+				args:             []string{"--path=" + dir, "--against=" + zipPath},
+				expectedExitCode: 0,
+				expectedOutput:   []string{"Functions:\n\t* function_a (source)\n"},
+			},
+			{
+				description:      "it outputs the diff as json",
+				args:             []string{"--path=" + dir, "--against=../testdata/full_app", "--output=json"},
+				expectedExitCode: 0,
+				expectedOutput:   []string{`"type": "function",`, `"name": "function_a",`, `"change": "modified",`, `"change": "deleted"`},
+			},
+			{
+				description:      "it outputs the diff as a unified patch",
+				args:             []string{"--path=" + dir, "--against=../testdata/full_app", "--output=patch"},
+				expectedExitCode: 0,
+				expectedOutput: []string{
+					"--- a/functions/function_a/source.js\n+++ b/functions/function_a/source.js\n@@ -1,3 +1,3 @@\n exports = function(x) {\n-  return x + 1;\n+  return x + 2;\n };\n",
+					"--- a/values/b.json\n+++ /dev/null\n",
+				},
+			},
+			{
+				description:      "it fails with an unknown output format",
+				args:             []string{"--path=" + dir, "--against=../testdata/full_app", "--output=yaml"},
+				expectedExitCode: 1,
+				expectedError:    `--output must be one of "text", "json" or "patch"`,
+			},
+			{
+				description:      "it fails if the app to diff against does not exist",
+				args:             []string{"--path=" + dir, "--against=../testdata/bogus_app"},
+				expectedExitCode: 1,
+				expectedError:    "failed to find app to diff against",
+			},
+		} {
+			t.Run(tc.description, func(t *testing.T) {
+				diffCommand, mockUI := setUpBasicDiffCommand()
+				diffCommand.realmClient = &u.MockRealmClient{}
+
+				exitCode := diffCommand.Run(tc.args)
+				u.So(t, exitCode, gc.ShouldEqual, tc.expectedExitCode)
+				u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, tc.expectedError)
+				for _, expected := range tc.expectedOutput {
+					u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, expected)
+				}
+			})
+		}
+	})
+
+	t.Run("should expand the home directory of the app to diff against", func(t *testing.T) {
+		homeDir, err := ioutil.TempDir("", "realm-cli-home")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(homeDir)
+
+		u.So(t, ioutil.WriteFile(filepath.Join(homeDir, "against.zip"), zipTestApp(t, "../testdata/full_app", "full_app/"), 0600), gc.ShouldBeNil)
+
+		oldHome, oldDisableCache := os.Getenv("HOME"), homedir.DisableCache
+		os.Setenv("HOME", homeDir)
+		homedir.DisableCache = true
+		defer func() {
+			os.Setenv("HOME", oldHome)
+			homedir.DisableCache = oldDisableCache
+		}()
+
+		diffCommand, mockUI := setUpBasicDiffCommand()
+		diffCommand.realmClient = &u.MockRealmClient{}
+
+		exitCode := diffCommand.Run([]string{"--path=../testdata/full_app", "--against=~/against.zip"})
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+	})
+
+	t.Run("should output the import plan as json", func(t *testing.T) {
+		diffCommand, mockUI := setUpBasicDiffCommand()
+		diffCommand.user = &user.User{
+			APIKey:      "my-api-key",
+			AccessToken: u.GenerateValidAccessToken(),
+		}
+
+		exitCode := diffCommand.Run(append([]string{"--path=../testdata/full_app", "--output=json"}, validArgs...))
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+
+		var plan ImportPlan
+		u.So(t, json.Unmarshal(mockUI.OutputWriter.Bytes(), &plan), gc.ShouldBeNil)
+		u.So(t, plan, gc.ShouldResemble, ImportPlan{
+			Strategy: importStrategyMerge,
+			Diffs:    []string{"sample-diff-contents"},
+		})
+	})
+
+	t.Run("should diff with the given strategy", func(t *testing.T) {
+		diffCommand, mockUI := setUpBasicDiffCommand()
+		diffCommand.user = &user.User{
+			APIKey:      "my-api-key",
+			AccessToken: u.GenerateValidAccessToken(),
+		}
+		var strategies []string
+		realmClient := diffCommand.realmClient.(*u.MockRealmClient)
+		realmClient.DiffFn = func(groupID, appID string, appData []byte, strategy string) ([]string, error) {
+			strategies = append(strategies, strategy)
+			return []string{"sample-diff-contents"}, nil
+		}
+
+		exitCode := diffCommand.Run(append([]string{"--path=../testdata/full_app", "--output=json", "--strategy=replace"}, validArgs...))
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, strategies, gc.ShouldResemble, []string{importStrategyReplace})
+
+		var plan ImportPlan
+		u.So(t, json.Unmarshal(mockUI.OutputWriter.Bytes(), &plan), gc.ShouldBeNil)
+		u.So(t, plan.Strategy, gc.ShouldEqual, importStrategyReplace)
+	})
+
+	t.Run("should fail for an unknown strategy", func(t *testing.T) {
+		diffCommand, mockUI := setUpBasicDiffCommand()
+
+		exitCode := diffCommand.Run(append([]string{"--path=../testdata/full_app", "--strategy=overwrite"}, validArgs...))
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, `unknown diff strategy "overwrite"; accepted values are [merge|replace|replace-by-name]`)
+	})
+
+	t.Run("should write a plan that import accepts with the same flags", func(t *testing.T) {
+		planDir, err := ioutil.TempDir("", "realm-cli-plan")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(planDir)
+
+		testUser := &user.User{
+			APIKey:      "my-api-key",
+			AccessToken: u.GenerateValidAccessToken(),
+		}
+		newRealmClient := func() *u.MockRealmClient {
+			return &u.MockRealmClient{
+				ExportFn: func(groupID, appID string, strategy api.ExportStrategy) (string, io.ReadCloser, error) {
+					return "", u.NewResponseBody(bytes.NewReader([]byte{})), nil
+				},
+				ImportFn: func(groupID, appID string, appData []byte, strategy string) error {
+					return nil
+				},
+				DiffFn: func(groupID, appID string, appData []byte, strategy string) ([]string, error) {
+					return []string{"sample-diff-contents"}, nil
+				},
+				FetchAppByClientAppIDFn: func(clientAppID string) (*models.App, error) {
+					return &models.App{
+						GroupID:     "group-id",
+						ID:          "app-id",
+						ClientAppID: clientAppID,
+					}, nil
+				},
+			}
+		}
+		args := append([]string{"--path=../testdata/full_app", "--include-dependencies"}, validArgs...)
+
+		diffCommand, diffUI := setUpBasicDiffCommand()
+		diffCommand.user = testUser
+		diffCommand.realmClient = newRealmClient()
+
+		exitCode := diffCommand.Run(append([]string{"--output=json"}, args...))
+		u.So(t, diffUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+
+		var plan ImportPlan
+		u.So(t, json.Unmarshal(diffUI.OutputWriter.Bytes(), &plan), gc.ShouldBeNil)
+		u.So(t, plan.Dependencies, gc.ShouldNotBeNil)
+
+		planFile := filepath.Join(planDir, "plan.json")
+		u.So(t, ioutil.WriteFile(planFile, diffUI.OutputWriter.Bytes(), 0600), gc.ShouldBeNil)
+
+		importCommand, importUI := setUpBasicCommand()
+		importCommand.user = testUser
+		realmClient := newRealmClient()
+		importCommand.realmClient = realmClient
+
+		exitCode = importCommand.Run(append([]string{
+			"--plan-file=" + planFile,
+			"--transpiler=native",
+			"--config-path=" + filepath.Join(planDir, "config.json"),
+		}, args...))
+		u.So(t, importUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, len(realmClient.ImportFnCalls), gc.ShouldEqual, 1)
+	})
+
+	t.Run("should show source changes against the deployed app", func(t *testing.T) {
+		dir := copyTestApp(t, "../testdata/full_app")
+		defer os.RemoveAll(dir)
+
+		u.So(t, ioutil.WriteFile(
+			filepath.Join(dir, "functions", "function_a", "source.js"),
+			[]byte("exports = function(x) {\n  return x + 2;\n};\n"),
+			0600,
+		), gc.ShouldBeNil)
+
+		deployedZip := zipTestApp(t, "../testdata/full_app", "")
+
+		diffCommand, mockUI := setUpBasicDiffCommand()
+		diffCommand.user = &user.User{
+			APIKey:      "my-api-key",
+			AccessToken: u.GenerateValidAccessToken(),
+		}
+		realmClient := diffCommand.realmClient.(*u.MockRealmClient)
+		realmClient.ExportFn = func(groupID, appID string, strategy api.ExportStrategy) (string, io.ReadCloser, error) {
+			return "", u.NewResponseBody(bytes.NewReader(deployedZip)), nil
+		}
+
+		exitCode := diffCommand.Run(append([]string{"--path=" + dir}, validArgs...))
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, strings.Join([]string{
+			"Summary:",
+			"\tFunctions: 1 modified",
+			"Functions:",
+			"\t* function_a (source)",
+			"\t\t@@ -1,3 +1,3 @@",
+			"\t\t exports = function(x) {",
+			"\t\t-  return x + 1;",
+			"\t\t+  return x + 2;",
+			"\t\t };",
+			"Changes to deploy:",
+			"sample-diff-contents",
+		}, "\n"))
+	})
+
+	t.Run("should colorize source changes when the output is colored", func(t *testing.T) {
+		diffCommand, mockUI := setUpBasicDiffCommand()
+		diffCommand.UI = &cli.ColoredUi{
+			OutputColor: cli.UiColorNone,
+			InfoColor:   cli.UiColorNone,
+			ErrorColor:  cli.UiColorRed,
+			WarnColor:   cli.UiColorYellow,
+			Ui:          mockUI,
+		}
+
+		diffCommand.printAppEntityDiffs(utils.AppEntityDiffs{
+			{
+				Type:   utils.AppEntityTypeFunction,
+				Name:   "greet",
+				Change: utils.AppEntityModified,
+				Fields: []string{"source"},
+				From:   utils.AppFiles{"functions/greet/source.js": []byte("a\nb\n")},
+				To:     utils.AppFiles{"functions/greet/source.js": []byte("a\nc\n")},
+			},
+		})
+
+		output := mockUI.OutputWriter.String()
+		u.So(t, output, gc.ShouldContainSubstring, "\t* greet (source)\n")
+		u.So(t, output, gc.ShouldContainSubstring, "\033[0;36m\t\t@@ -1,2 +1,2 @@\033[0m")
+		u.So(t, output, gc.ShouldContainSubstring, "\t\t a\n")
+		u.So(t, output, gc.ShouldContainSubstring, "\033[0;31m\t\t-b\033[0m")
+		u.So(t, output, gc.ShouldContainSubstring, "\033[0;32m\t\t+c\033[0m")
+	})
+
+	t.Run("should require --against for patch output", func(t *testing.T) {
+		diffCommand, mockUI := setUpBasicDiffCommand()
+		exitCode := diffCommand.Run(append([]string{"--output=patch"}, validArgs...))
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "--output=patch is only supported with --against")
+	})
 }
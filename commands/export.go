@@ -1,6 +1,8 @@
 package commands
 
 import (
+	"bytes"
+	"encoding/json"
 	"fmt"
 	"io"
 	"os"
@@ -16,7 +18,7 @@ import (
 	"github.com/mitchellh/go-homedir"
 )
 
-const numWorkers = 4
+var errIncrementalRequiresHosting = fmt.Errorf("--incremental can only be used with --include-hosting")
 
 // NewExportCommandFactory returns a new cli.CommandFactory given a cli.Ui
 func NewExportCommandFactory(ui cli.Ui) cli.CommandFactory {
@@ -55,6 +57,9 @@ type ExportCommand struct {
 	flagIncludeHosting      bool
 	flagIncludeDependencies bool
 	flagForSourceControl    bool
+	flagTemplatize          bool
+	flagIncremental         bool
+	flagHostingLimits       HostingLimits
 }
 
 // Help returns long-form help information for this command
@@ -82,7 +87,22 @@ OPTIONS:
 	Download dependencies associated with this project
 
   --include-hosting
-	Download static assets associated with this project` +
+	Download static assets associated with this project
+
+  --incremental
+	Export into an existing directory, such as a previous export, and only download the static assets
+	whose local copy does not have the same hash. Requires --include-hosting.
+
+  --concurrency [int]
+	The number of static assets downloaded at once with --include-hosting. Defaults to 4.
+
+  --max-rps [float]
+	The most requests per second made to download static assets with --include-hosting,
+	shared by all concurrent downloads. Defaults to unlimited.
+
+  --templatize
+	Replace environment-specific fields (such as cluster names, value contents and the hosting custom domain)
+	with ${NAME} template variables, and write their current values to "` + utils.VariablesFileName + `"` +
 		ec.BaseCommand.Help()
 }
 
@@ -103,12 +123,20 @@ func (ec *ExportCommand) Run(args []string) int {
 	set.BoolVar(&ec.flagForSourceControl, "for-source-control", false, "")
 	set.BoolVar(&ec.flagIncludeDependencies, "include-dependencies", false, "")
 	set.BoolVar(&ec.flagIncludeHosting, "include-hosting", false, "")
+	set.BoolVar(&ec.flagTemplatize, "templatize", false, "")
+	set.BoolVar(&ec.flagIncremental, "incremental", false, "")
+	ec.flagHostingLimits.registerFlags(set)
 
 	if err := ec.BaseCommand.run(args); err != nil {
 		ec.UI.Error(err.Error())
 		return 1
 	}
 
+	if err := ec.flagHostingLimits.validate(); err != nil {
+		ec.UI.Error(err.Error())
+		return 1
+	}
+
 	if err := ec.run(); err != nil {
 		ec.UI.Error(err.Error())
 		return 1
@@ -122,6 +150,10 @@ func (ec *ExportCommand) run() error {
 		return errAppIDRequired
 	}
 
+	if ec.flagIncremental && !ec.flagIncludeHosting {
+		return errIncrementalRequiresHosting
+	}
+
 	user, err := ec.User()
 	if err != nil {
 		return err
@@ -175,10 +207,21 @@ func (ec *ExportCommand) run() error {
 		filename = filename[:lastUnderscoreIdx]
 	}
 
-	if err := ec.exportToDirectory(filename, body, false); err != nil {
+	// an incremental export refreshes the previous export in place
+	if err := ec.exportToDirectory(filename, body, ec.flagIncremental); err != nil {
+		return err
+	}
+
+	if err := utils.SplitHostingConfig(filename); err != nil {
 		return err
 	}
 
+	if ec.flagTemplatize {
+		if err := ec.templatize(filename); err != nil {
+			return err
+		}
+	}
+
 	if ec.flagIncludeDependencies {
 		depArchive, depBody, err := realmClient.ExportDependencies(app.GroupID, app.ID)
 		if err != nil {
@@ -200,3 +243,26 @@ func (ec *ExportCommand) run() error {
 	}
 	return nil
 }
+
+func (ec *ExportCommand) templatize(appPath string) error {
+	vars, err := utils.TemplatizeDir(appPath)
+	if err != nil {
+		return err
+	}
+
+	if len(vars) == 0 {
+		return nil
+	}
+
+	data, err := json.MarshalIndent(vars, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	if err := ec.writeFileToDirectory(filepath.Join(appPath, utils.VariablesFileName), bytes.NewReader(data)); err != nil {
+		return err
+	}
+
+	ec.UI.Info(fmt.Sprintf("Replaced %d environment-specific field(s) with template variables, see %s", len(vars), utils.VariablesFileName))
+	return nil
+}
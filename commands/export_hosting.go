@@ -2,6 +2,8 @@ package commands
 
 import (
 	"bytes"
+	"crypto/md5"
+	"encoding/hex"
 	"encoding/json"
 	"fmt"
 	"io"
@@ -9,6 +11,7 @@ import (
 	"os"
 	"path"
 	"sync"
+	"time"
 
 	"github.com/10gen/realm-cli/api"
 	"github.com/10gen/realm-cli/hosting"
@@ -16,8 +19,29 @@ import (
 	"github.com/10gen/realm-cli/utils"
 )
 
+// errAssetHashMismatch is returned when a downloaded asset does not have the hash it is listed with
+type errAssetHashMismatch struct {
+	filePath string
+	expected string
+	actual   string
+}
+
+func (err errAssetHashMismatch) Error() string {
+	return fmt.Sprintf("downloaded '%s' has hash %s, but %s was expected", err.filePath, err.actual, err.expected)
+}
+
+// assetHTTPClient downloads hosted assets as they are stored, without asking for or undoing a
+// Content-Encoding, so that their hash can be checked
+var assetHTTPClient = &http.Client{
+	Transport: func() http.RoundTripper {
+		transport := http.DefaultTransport.(*http.Transport).Clone()
+		transport.DisableCompression = true
+		return transport
+	}(),
+}
+
 func getAssetAtURL(url string) (io.ReadCloser, error) {
-	resp, err := http.Get(url)
+	resp, err := assetHTTPClient.Get(url)
 	if err != nil {
 		return nil, err
 	}
@@ -45,73 +69,103 @@ func exportStaticHostingAssets(realmClient api.RealmClient, ec *ExportCommand, a
 		return fmt.Errorf("failed to write static hosting asset attributes file at: %s", path.Join(appPath, utils.HostingAttributes))
 	}
 
-	// Variables for the parallelization below
-	var wg sync.WaitGroup
-	jobs := make(chan hosting.AssetMetadata)
-	errs := make(chan error)
-	errorsHandlerDone := make(chan struct{})
-
-	var errors []error
-	// function for the error checker to run
-	errChecker := func(errs <-chan error, errsDone chan<- struct{}) {
-		for err := range errs {
-			errors = append(errors, err)
+	// directories are created up front so that only files are downloaded by the workers
+	var files []hosting.AssetMetadata
+	var totalBytes int64
+	var unchanged int
+	for _, amd := range assetMetadatas {
+		if amd.IsDir() {
+			assetDir := path.Join(appPath, utils.HostingFilesDirectory, amd.FilePath)
+			if mkdirErr := os.MkdirAll(assetDir, os.ModePerm); mkdirErr != nil {
+				return fmt.Errorf("failed to create directory %q: %s", assetDir, mkdirErr)
+			}
+			continue
+		}
+
+		if ec.flagIncremental && localAssetMatches(appPath, amd) {
+			unchanged++
+			continue
 		}
-		errsDone <- struct{}{}
+
+		files = append(files, amd)
+		totalBytes += amd.FileSize
+	}
+
+	if unchanged > 0 {
+		ec.UI.Info(fmt.Sprintf("Skipping %d hosted asset(s) whose local copy is unchanged", unchanged))
 	}
 
-	// run the error handler
-	go errChecker(errs, errorsHandlerDone)
+	progress := newHostingProgress(ec.UI, "Exporting hosting assets", len(files), totalBytes)
+	progress.start()
+
+	// Variables for the parallelization below
+	var wg sync.WaitGroup
+	jobs := make(chan hosting.AssetMetadata)
 
 	// Spawn the workers
-	for n := 0; n < numWorkers; n++ {
+	limiter := ec.flagHostingLimits.limiter()
+	for n := 0; n < ec.flagHostingLimits.Concurrency; n++ {
 		wg.Add(1)
-		go assetDownloadWorker(jobs, &wg, errs, ec, appPath)
+		go assetDownloadWorker(jobs, &wg, limiter, progress, ec, appPath)
 	}
 
 	// Pass in the information
-	for _, amd := range assetMetadatas {
+	for _, amd := range files {
 		jobs <- amd
 	}
 
 	close(jobs)
 	wg.Wait()
-	close(errs)
-	<-errorsHandlerDone
+	progress.finish()
 
-	if len(errors) > 0 {
-		return fmt.Errorf("exporting hosted assets failed, %d downloads were unsuccessful with error: %s", len(errors), errors[0])
+	if len(progress.errors) > 0 {
+		return fmt.Errorf("exporting hosted assets failed, %d downloads were unsuccessful with error: %s", len(progress.errors), progress.errors[0])
 	}
 
 	return nil
 }
 
 // function for workers to run
-func assetDownloadWorker(jobs <-chan hosting.AssetMetadata, wg *sync.WaitGroup, errs chan<- error, ec *ExportCommand, appPath string) {
+func assetDownloadWorker(jobs <-chan hosting.AssetMetadata, wg *sync.WaitGroup, limiter *hostingLimiter, progress *hostingProgress, ec *ExportCommand, appPath string) {
 	defer wg.Done()
 
 	for job := range jobs {
-		if job.IsDir() {
-			assetDir := path.Join(appPath, utils.HostingFilesDirectory, job.FilePath)
-			if mkdirErr := os.MkdirAll(assetDir, os.ModePerm); mkdirErr != nil {
-				errs <- fmt.Errorf("failed to create directory %q: %s", assetDir, mkdirErr)
-			}
-			continue
-		}
+		job := job
 
-		// Go get the asset at the given URL
-		reader, err := ec.getAssetAtURL(job.URL)
-		if err != nil {
-			errs <- err
-			continue
-		}
+		start := time.Now()
+		err := doWithRetries(func() error { return downloadAsset(job, ec, appPath) }, limiter)
+		progress.record(hostingOpDownload, job.FileSize, time.Since(start), err)
+	}
+}
 
-		// Now store the asset at the proper filePath
-		err = ec.writeFileToDirectory(path.Join(appPath, utils.HostingFilesDirectory, job.FilePath), reader)
-		reader.Close()
-		if err != nil {
-			errs <- err
-			continue
-		}
+// downloadAsset stores the asset at the given URL at its path in the hosting files directory,
+// and checks that what was stored has the asset's hash
+func downloadAsset(job hosting.AssetMetadata, ec *ExportCommand, appPath string) error {
+	reader, err := ec.getAssetAtURL(job.URL)
+	if err != nil {
+		return err
 	}
+	defer reader.Close()
+
+	hash := md5.New()
+	if err := ec.writeFileToDirectory(path.Join(appPath, utils.HostingFilesDirectory, job.FilePath), io.TeeReader(reader, hash)); err != nil {
+		return err
+	}
+
+	if actual := hex.EncodeToString(hash.Sum(nil)); job.FileHash != "" && actual != job.FileHash {
+		return errAssetHashMismatch{job.FilePath, job.FileHash, actual}
+	}
+
+	return nil
+}
+
+// localAssetMatches reports whether the asset was already exported to the app at appPath,
+// which is the case when the local file has the asset's hash
+func localAssetMatches(appPath string, amd hosting.AssetMetadata) bool {
+	if amd.FileHash == "" {
+		return false
+	}
+
+	localHash, err := utils.GenerateFileHashStr(path.Join(appPath, utils.HostingFilesDirectory, amd.FilePath))
+	return err == nil && localHash == amd.FileHash
 }
@@ -0,0 +1,137 @@
+package commands
+
+import (
+	"crypto/md5"
+	"encoding/hex"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/models"
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func md5Hex(contents string) string {
+	sum := md5.Sum([]byte(contents))
+	return hex.EncodeToString(sum[:])
+}
+
+func TestExportStaticHostingAssets(t *testing.T) {
+	defer func(backoff time.Duration) { hostingRetryBackoff = backoff }(hostingRetryBackoff)
+	hostingRetryBackoff = time.Millisecond
+
+	app := &models.App{GroupID: "group-id", ID: "app-id"}
+
+	assets := map[string]string{
+		"/index.html":   "<html></html>",
+		"/css/main.css": "body { color: red; }",
+	}
+
+	realmClient := &u.MockRealmClient{
+		ListAssetsForAppIDFn: func(groupID, appID string) ([]hosting.AssetMetadata, error) {
+			return []hosting.AssetMetadata{
+				{FilePath: "/index.html", URL: "/index.html", FileHash: md5Hex(assets["/index.html"]), FileSize: 13},
+				{FilePath: "/css/"},
+				{FilePath: "/css/main.css", URL: "/css/main.css", FileHash: md5Hex(assets["/css/main.css"]), FileSize: 20},
+			}, nil
+		},
+	}
+
+	setup := func(t *testing.T, getAssetAtURL func(url string) (io.ReadCloser, error)) (*ExportCommand, *cli.MockUi, string) {
+		appPath, err := ioutil.TempDir("", "realm-cli-export-hosting")
+		u.So(t, err, gc.ShouldBeNil)
+
+		mockUI := cli.NewMockUi()
+		return &ExportCommand{
+			BaseCommand:          &BaseCommand{UI: mockUI},
+			writeFileToDirectory: utils.WriteFileToDir,
+			getAssetAtURL:        getAssetAtURL,
+			flagHostingLimits:    DefaultHostingLimits,
+		}, mockUI, appPath
+	}
+
+	var mu sync.Mutex
+	var downloads []string
+	serveAssets := func(url string) (io.ReadCloser, error) {
+		mu.Lock()
+		defer mu.Unlock()
+
+		downloads = append(downloads, url)
+		return ioutil.NopCloser(strings.NewReader(assets[url])), nil
+	}
+
+	readAsset := func(t *testing.T, appPath, filePath string) string {
+		data, err := ioutil.ReadFile(filepath.Join(appPath, utils.HostingFilesDirectory, filePath))
+		u.So(t, err, gc.ShouldBeNil)
+		return string(data)
+	}
+
+	t.Run("should download and verify every asset", func(t *testing.T) {
+		downloads = nil
+		exportCommand, _, appPath := setup(t, serveAssets)
+		defer os.RemoveAll(appPath)
+
+		u.So(t, exportStaticHostingAssets(realmClient, exportCommand, appPath, app), gc.ShouldBeNil)
+		u.So(t, readAsset(t, appPath, "/index.html"), gc.ShouldEqual, assets["/index.html"])
+		u.So(t, readAsset(t, appPath, "/css/main.css"), gc.ShouldEqual, assets["/css/main.css"])
+
+		t.Run("and only download changed assets when incremental", func(t *testing.T) {
+			downloads = nil
+			u.So(t, ioutil.WriteFile(filepath.Join(appPath, utils.HostingFilesDirectory, "/css/main.css"), []byte("stale"), 0600), gc.ShouldBeNil)
+
+			exportCommand, mockUI, _ := setup(t, serveAssets)
+			exportCommand.flagIncremental = true
+
+			u.So(t, exportStaticHostingAssets(realmClient, exportCommand, appPath, app), gc.ShouldBeNil)
+			u.So(t, downloads, gc.ShouldResemble, []string{"/css/main.css"})
+			u.So(t, readAsset(t, appPath, "/css/main.css"), gc.ShouldEqual, assets["/css/main.css"])
+			u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "Skipping 1 hosted asset(s) whose local copy is unchanged")
+		})
+	})
+
+	t.Run("should retry a download with the wrong hash", func(t *testing.T) {
+		downloads = nil
+		corrupted := map[string]bool{}
+		exportCommand, _, appPath := setup(t, func(url string) (io.ReadCloser, error) {
+			mu.Lock()
+			first := !corrupted[url]
+			corrupted[url] = true
+			mu.Unlock()
+
+			if first {
+				return ioutil.NopCloser(strings.NewReader("truncated")), nil
+			}
+			return serveAssets(url)
+		})
+		defer os.RemoveAll(appPath)
+
+		u.So(t, exportStaticHostingAssets(realmClient, exportCommand, appPath, app), gc.ShouldBeNil)
+		u.So(t, readAsset(t, appPath, "/index.html"), gc.ShouldEqual, assets["/index.html"])
+
+		sort.Strings(downloads)
+		u.So(t, downloads, gc.ShouldResemble, []string{"/css/main.css", "/index.html"})
+	})
+
+	t.Run("should fail when a download keeps having the wrong hash", func(t *testing.T) {
+		exportCommand, _, appPath := setup(t, func(url string) (io.ReadCloser, error) {
+			return ioutil.NopCloser(strings.NewReader("truncated")), nil
+		})
+		defer os.RemoveAll(appPath)
+
+		err := exportStaticHostingAssets(realmClient, exportCommand, appPath, app)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldContainSubstring, "exporting hosted assets failed, 2 downloads were unsuccessful")
+		u.So(t, err.Error(), gc.ShouldContainSubstring, "has hash "+md5Hex("truncated"))
+	})
+}
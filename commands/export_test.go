@@ -50,6 +50,14 @@ func TestExportCommand(t *testing.T) {
 		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, errAppIDRequired.Error())
 	})
 
+	t.Run("should require --include-hosting with --incremental", func(t *testing.T) {
+		exportCommand, mockUI := setup()
+		exitCode := exportCommand.Run([]string{`--app-id=my-cool-app`, `--incremental`})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, errIncrementalRequiresHosting.Error())
+	})
+
 	t.Run("should require the user to be logged in", func(t *testing.T) {
 		exportCommand, mockUI := setup()
 		exitCode := exportCommand.Run([]string{`--app-id=my-cool-app`})
@@ -0,0 +1,1096 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/models"
+	u "github.com/10gen/realm-cli/user"
+	"github.com/10gen/realm-cli/utils"
+	"github.com/mitchellh/cli"
+)
+
+// NewHostingCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		return &HostingCommand{
+			BaseCommand: &BaseCommand{
+				Name: "hosting",
+				UI:   ui,
+			},
+		}, nil
+	}
+}
+
+// HostingCommand is used to manage a Realm App's hosted assets
+type HostingCommand struct {
+	*BaseCommand
+}
+
+// Synopsis returns a one-liner description for this command
+func (hc *HostingCommand) Synopsis() string {
+	return "Manage the hosted assets of your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (hc *HostingCommand) Help() string {
+	return hc.Synopsis()
+}
+
+// Run executes the command
+func (hc *HostingCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+const (
+	flagHostingPath  = "path"
+	flagHostingLong  = "long"
+	flagHostingFrom  = "from"
+	flagHostingTo    = "to"
+	flagHostingAttr  = "attr"
+	flagHostingUnset = "unset"
+	flagHostingFile  = "file"
+)
+
+var (
+	errHostingPathRequired = fmt.Errorf("a path (--%s=[string]) is required", flagHostingPath)
+	errHostingFromRequired = fmt.Errorf("a source path (--%s=[string]) is required", flagHostingFrom)
+	errHostingToRequired   = fmt.Errorf("a destination path (--%s=[string]) is required", flagHostingTo)
+	errHostingFileRequired = fmt.Errorf("a local file (--%s=[string]) is required", flagHostingFile)
+)
+
+// NewHostingBaseCommand returns a new *HostingBaseCommand
+func NewHostingBaseCommand(name, workingDirectory string, ui cli.Ui) *HostingBaseCommand {
+	return &HostingBaseCommand{
+		ProjectCommand:   NewProjectCommand(name, ui),
+		workingDirectory: workingDirectory,
+	}
+}
+
+// HostingBaseCommand represents a common Atlas project-based hosting command
+type HostingBaseCommand struct {
+	*ProjectCommand
+
+	workingDirectory string
+
+	flagAppID string
+}
+
+// Help returns long-form help information for the HostingBaseCommand command
+func (hbc *HostingBaseCommand) Help() string {
+	return `
+OPTIONAL:
+  --app-id [string]
+	The App ID for your app (i.e. the name of your app followed by a unique suffix, like "my-app-nysja").
+	Required if not being run from within a realm project directory.` +
+		hbc.ProjectCommand.Help()
+}
+
+func (hbc *HostingBaseCommand) run(args []string) error {
+	if hbc.FlagSet == nil {
+		hbc.NewFlagSet()
+	}
+
+	hbc.FlagSet.StringVar(&hbc.flagAppID, flagAppIDName, "", "")
+
+	if err := hbc.ProjectCommand.run(args); err != nil {
+		return err
+	}
+
+	user, err := hbc.User()
+	if err != nil {
+		return err
+	}
+
+	if !user.LoggedIn() {
+		return u.ErrNotLoggedIn
+	}
+
+	return nil
+}
+
+func (hbc *HostingBaseCommand) resolveApp() (*models.App, error) {
+	appID := hbc.flagAppID
+	if hbc.flagAppID == "" {
+		appPath, err := utils.ResolveAppDirectory("", hbc.workingDirectory)
+		if err != nil {
+			return nil, err
+		}
+
+		appInstanceData, err := utils.ResolveAppInstanceData(hbc.flagAppID, appPath)
+		if err != nil {
+			return nil, err
+		}
+		appID = appInstanceData.AppID()
+	}
+
+	realmClient, err := hbc.RealmClient()
+	if err != nil {
+		return nil, err
+	}
+
+	if hbc.flagProjectID == "" {
+		return realmClient.FetchAppByClientAppID(appID)
+	}
+	return realmClient.FetchAppByGroupIDAndClientAppID(hbc.flagProjectID, appID)
+}
+
+// NewHostingInvalidateCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingInvalidateCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingInvalidateCommand{
+			HostingBaseCommand: NewHostingBaseCommand("invalidate", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingInvalidateCommand is used to invalidate the CDN cache of a Realm app's hosted assets
+type HostingInvalidateCommand struct {
+	*HostingBaseCommand
+
+	flagPaths utils.StringSliceFlag
+}
+
+// Synopsis returns a one-liner description for this command
+func (hic *HostingInvalidateCommand) Synopsis() string {
+	return "Invalidate the CDN cache of your Realm App's hosted assets."
+}
+
+// Help returns long-form help information for this command
+func (hic *HostingInvalidateCommand) Help() string {
+	return `Invalidate the CDN cache of your Realm Application's hosted assets.
+
+Usage: realm-cli hosting invalidate [--path [string]...] [options]
+
+OPTIONS:
+  --path [string]
+	The path of an asset to invalidate, which may end in a wildcard (e.g. "/images/*") to
+	invalidate every asset under a directory. May be provided multiple times. Defaults to "/*".
+` +
+		hic.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hic *HostingInvalidateCommand) Run(args []string) int {
+	hic.NewFlagSet()
+
+	hic.FlagSet.Var(&hic.flagPaths, flagHostingPath, "")
+
+	if err := hic.HostingBaseCommand.run(args); err != nil {
+		hic.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hic.invalidate(); err != nil {
+		hic.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hic *HostingInvalidateCommand) invalidate() error {
+	paths := []string(hic.flagPaths)
+	if len(paths) == 0 {
+		paths = []string{"/*"}
+	}
+
+	if err := validateAssetPaths(paths...); err != nil {
+		return err
+	}
+
+	app, err := hic.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hic.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	for _, path := range paths {
+		if err := realmClient.InvalidateCache(app.GroupID, app.ID, path); err != nil {
+			return err
+		}
+		hic.UI.Info(fmt.Sprintf("Invalidated CDN cache for %s", path))
+	}
+
+	return nil
+}
+
+// validateAssetPaths checks that every path is an absolute hosted asset path
+func validateAssetPaths(paths ...string) error {
+	for _, path := range paths {
+		if !strings.HasPrefix(path, "/") {
+			return fmt.Errorf("path %q must start with '/'", path)
+		}
+	}
+	return nil
+}
+
+// matchAssets returns the assets whose paths match each of the given paths or glob patterns,
+// erroring if a pattern does not match any asset
+func matchAssets(assets []hosting.AssetMetadata, patterns []string) ([]hosting.AssetMetadata, error) {
+	var matched []hosting.AssetMetadata
+	seen := map[string]bool{}
+
+	for _, pattern := range patterns {
+		found := false
+		for _, asset := range assets {
+			if asset.IsDir() {
+				continue
+			}
+
+			ok, err := path.Match(pattern, asset.FilePath)
+			if err != nil {
+				return nil, fmt.Errorf("invalid path %q: %s", pattern, err)
+			}
+			if !ok {
+				continue
+			}
+
+			found = true
+			if !seen[asset.FilePath] {
+				seen[asset.FilePath] = true
+				matched = append(matched, asset)
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no hosted assets found matching %q", pattern)
+		}
+	}
+
+	return matched, nil
+}
+
+// mergeAssetAttributes returns attrs with the set attributes replacing any of the same name
+// and the unset attribute names removed, sorted by name
+func mergeAssetAttributes(attrs, set []hosting.AssetAttribute, unset []string) []hosting.AssetAttribute {
+	byName := map[string]string{}
+	for _, attr := range attrs {
+		byName[attr.Name] = attr.Value
+	}
+	for _, attr := range set {
+		byName[attr.Name] = attr.Value
+	}
+	for _, name := range unset {
+		delete(byName, name)
+	}
+
+	merged := make([]hosting.AssetAttribute, 0, len(byName))
+	for name, value := range byName {
+		merged = append(merged, hosting.AssetAttribute{Name: name, Value: value})
+	}
+	sort.Slice(merged, func(i, j int) bool { return merged[i].Name < merged[j].Name })
+	return merged
+}
+
+// parseAssetAttributes parses the Name=Value pairs of --attr and the names of --unset
+func parseAssetAttributes(pairs, names []string) ([]hosting.AssetAttribute, []string, error) {
+	set := make([]hosting.AssetAttribute, 0, len(pairs))
+	for _, pair := range pairs {
+		attr, err := hosting.ParseAssetAttribute(pair)
+		if err != nil {
+			return nil, nil, err
+		}
+		set = append(set, attr)
+	}
+
+	unset := make([]string, 0, len(names))
+	for _, name := range names {
+		canonical, err := hosting.CanonicalAttributeName(name)
+		if err != nil {
+			return nil, nil, err
+		}
+		unset = append(unset, canonical)
+	}
+
+	return set, unset, nil
+}
+
+func formatAssetAttributes(attrs []hosting.AssetAttribute) string {
+	pairs := make([]string, 0, len(attrs))
+	for _, attr := range attrs {
+		pairs = append(pairs, attr.Name+"="+attr.Value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ", ")
+}
+
+// NewHostingListCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingListCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingListCommand{
+			HostingBaseCommand: NewHostingBaseCommand("ls", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingListCommand is used to list the hosted assets of a Realm app
+type HostingListCommand struct {
+	*HostingBaseCommand
+
+	flagPath string
+	flagLong bool
+}
+
+// Synopsis returns a one-liner description for this command
+func (hlc *HostingListCommand) Synopsis() string {
+	return "List the hosted assets of your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (hlc *HostingListCommand) Help() string {
+	return `List the hosted assets of your Realm Application as a tree.
+
+Usage: realm-cli hosting ls [--path [string]] [--long] [options]
+
+OPTIONS:
+  --path [string]
+	Only list the assets under this directory (e.g. "/images").
+
+  --long
+	List one asset per line along with its size, hash and attributes.
+` +
+		hlc.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hlc *HostingListCommand) Run(args []string) int {
+	hlc.NewFlagSet()
+
+	hlc.FlagSet.StringVar(&hlc.flagPath, flagHostingPath, "/", "")
+	hlc.FlagSet.BoolVar(&hlc.flagLong, flagHostingLong, false, "")
+
+	if err := hlc.HostingBaseCommand.run(args); err != nil {
+		hlc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hlc.list(); err != nil {
+		hlc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hlc *HostingListCommand) list() error {
+	if err := validateAssetPaths(hlc.flagPath); err != nil {
+		return err
+	}
+	root := strings.TrimSuffix(hlc.flagPath, "/") + "/"
+
+	app, err := hlc.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hlc.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	assets, err := realmClient.ListAssetsForAppID(app.GroupID, app.ID)
+	if err != nil {
+		return err
+	}
+
+	var listed []hosting.AssetMetadata
+	for _, asset := range assets {
+		if !asset.IsDir() && strings.HasPrefix(asset.FilePath, root) {
+			listed = append(listed, asset)
+		}
+	}
+	sort.Slice(listed, func(i, j int) bool { return listed[i].FilePath < listed[j].FilePath })
+
+	if len(listed) == 0 {
+		hlc.UI.Info("No hosted assets found for this app")
+		return nil
+	}
+
+	if hlc.flagLong {
+		var buf bytes.Buffer
+		w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+		fmt.Fprintln(w, "SIZE\tHASH\tPATH\tATTRIBUTES")
+		for _, asset := range listed {
+			fmt.Fprintf(w, "%d\t%s\t%s\t%s\n", asset.FileSize, asset.FileHash, asset.FilePath, formatAssetAttributes(asset.Attrs))
+		}
+		w.Flush()
+
+		for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+			hlc.UI.Info(strings.TrimRight(line, " "))
+		}
+		return nil
+	}
+
+	paths := make([]string, 0, len(listed))
+	for _, asset := range listed {
+		paths = append(paths, strings.TrimPrefix(asset.FilePath, root))
+	}
+
+	hlc.UI.Info(root)
+	for _, line := range assetTree(paths) {
+		hlc.UI.Info(line)
+	}
+
+	return nil
+}
+
+// assetTreeNode is a directory or file in the tree printed by assetTree
+type assetTreeNode struct {
+	name     string
+	children []*assetTreeNode
+}
+
+func (n *assetTreeNode) child(name string) *assetTreeNode {
+	for _, c := range n.children {
+		if c.name == name {
+			return c
+		}
+	}
+	c := &assetTreeNode{name: name}
+	n.children = append(n.children, c)
+	return c
+}
+
+// assetTree returns the lines of a tree drawing of the sorted slash-separated paths
+func assetTree(paths []string) []string {
+	root := &assetTreeNode{}
+	for _, p := range paths {
+		node := root
+		parts := strings.Split(p, "/")
+		for i, part := range parts {
+			name := part
+			if i < len(parts)-1 {
+				name += "/"
+			}
+			node = node.child(name)
+		}
+	}
+
+	var lines []string
+	var walk func(node *assetTreeNode, indent string)
+	walk = func(node *assetTreeNode, indent string) {
+		for i, c := range node.children {
+			branch, nextIndent := "├── ", "│   "
+			if i == len(node.children)-1 {
+				branch, nextIndent = "└── ", "    "
+			}
+			lines = append(lines, indent+branch+c.name)
+			walk(c, indent+nextIndent)
+		}
+	}
+	walk(root, "")
+
+	return lines
+}
+
+// NewHostingCopyCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingCopyCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingCopyCommand{
+			HostingBaseCommand: NewHostingBaseCommand("cp", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingCopyCommand is used to copy a hosted asset of a Realm app
+type HostingCopyCommand struct {
+	*HostingBaseCommand
+
+	flagFrom string
+	flagTo   string
+}
+
+// Synopsis returns a one-liner description for this command
+func (hcc *HostingCopyCommand) Synopsis() string {
+	return "Copy a hosted asset of your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (hcc *HostingCopyCommand) Help() string {
+	return `Copy a hosted asset of your Realm Application to a new path.
+
+Usage: realm-cli hosting cp --from [string] --to [string] [options]
+
+REQUIRED:
+  --from [string]
+	The path of the asset to copy (e.g. "/index.html").
+
+  --to [string]
+	The path to copy the asset to.
+` +
+		hcc.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hcc *HostingCopyCommand) Run(args []string) int {
+	hcc.NewFlagSet()
+
+	hcc.FlagSet.StringVar(&hcc.flagFrom, flagHostingFrom, "", "")
+	hcc.FlagSet.StringVar(&hcc.flagTo, flagHostingTo, "", "")
+
+	if err := hcc.HostingBaseCommand.run(args); err != nil {
+		hcc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hcc.copy(); err != nil {
+		hcc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hcc *HostingCopyCommand) copy() error {
+	if err := validateFromTo(hcc.flagFrom, hcc.flagTo); err != nil {
+		return err
+	}
+
+	app, err := hcc.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hcc.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	if err := realmClient.CopyAsset(app.GroupID, app.ID, hcc.flagFrom, hcc.flagTo); err != nil {
+		return err
+	}
+
+	hcc.UI.Info(fmt.Sprintf("Copied %s to %s", hcc.flagFrom, hcc.flagTo))
+	return nil
+}
+
+func validateFromTo(from, to string) error {
+	if from == "" {
+		return errHostingFromRequired
+	}
+	if to == "" {
+		return errHostingToRequired
+	}
+	return validateAssetPaths(from, to)
+}
+
+// NewHostingMoveCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingMoveCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingMoveCommand{
+			HostingBaseCommand: NewHostingBaseCommand("mv", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingMoveCommand is used to move a hosted asset of a Realm app
+type HostingMoveCommand struct {
+	*HostingBaseCommand
+
+	flagFrom string
+	flagTo   string
+}
+
+// Synopsis returns a one-liner description for this command
+func (hmc *HostingMoveCommand) Synopsis() string {
+	return "Move a hosted asset of your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (hmc *HostingMoveCommand) Help() string {
+	return `Move a hosted asset of your Realm Application to a new path.
+
+Usage: realm-cli hosting mv --from [string] --to [string] [options]
+
+REQUIRED:
+  --from [string]
+	The path of the asset to move (e.g. "/index.html").
+
+  --to [string]
+	The path to move the asset to.
+` +
+		hmc.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hmc *HostingMoveCommand) Run(args []string) int {
+	hmc.NewFlagSet()
+
+	hmc.FlagSet.StringVar(&hmc.flagFrom, flagHostingFrom, "", "")
+	hmc.FlagSet.StringVar(&hmc.flagTo, flagHostingTo, "", "")
+
+	if err := hmc.HostingBaseCommand.run(args); err != nil {
+		hmc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hmc.move(); err != nil {
+		hmc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hmc *HostingMoveCommand) move() error {
+	if err := validateFromTo(hmc.flagFrom, hmc.flagTo); err != nil {
+		return err
+	}
+
+	app, err := hmc.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hmc.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	if err := realmClient.MoveAsset(app.GroupID, app.ID, hmc.flagFrom, hmc.flagTo); err != nil {
+		return err
+	}
+
+	hmc.UI.Info(fmt.Sprintf("Moved %s to %s", hmc.flagFrom, hmc.flagTo))
+	return nil
+}
+
+// NewHostingRemoveCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingRemoveCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingRemoveCommand{
+			HostingBaseCommand: NewHostingBaseCommand("rm", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingRemoveCommand is used to remove hosted assets from a Realm app
+type HostingRemoveCommand struct {
+	*HostingBaseCommand
+
+	flagPaths utils.StringSliceFlag
+}
+
+// Synopsis returns a one-liner description for this command
+func (hrc *HostingRemoveCommand) Synopsis() string {
+	return "Remove hosted assets from your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (hrc *HostingRemoveCommand) Help() string {
+	return `Remove hosted assets from your Realm Application.
+
+Usage: realm-cli hosting rm --path [string]... [options]
+
+REQUIRED:
+  --path [string]
+	The path of an asset to remove, which may be a glob (e.g. "/images/*.png").
+	May be provided multiple times. The matching assets are listed and removed once you confirm,
+	or right away with -y.
+` +
+		hrc.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hrc *HostingRemoveCommand) Run(args []string) int {
+	hrc.NewFlagSet()
+
+	hrc.FlagSet.Var(&hrc.flagPaths, flagHostingPath, "")
+
+	if err := hrc.HostingBaseCommand.run(args); err != nil {
+		hrc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hrc.remove(); err != nil {
+		hrc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hrc *HostingRemoveCommand) remove() error {
+	if len(hrc.flagPaths) == 0 {
+		return errHostingPathRequired
+	}
+	if err := validateAssetPaths(hrc.flagPaths...); err != nil {
+		return err
+	}
+
+	app, err := hrc.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hrc.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	assets, err := realmClient.ListAssetsForAppID(app.GroupID, app.ID)
+	if err != nil {
+		return err
+	}
+
+	matched, err := matchAssets(assets, hrc.flagPaths)
+	if err != nil {
+		return err
+	}
+
+	hrc.UI.Info("The following hosted assets will be removed:")
+	for _, asset := range matched {
+		hrc.UI.Info(fmt.Sprintf("\t%s", asset.FilePath))
+	}
+
+	confirm, err := hrc.AskYesNo("Please confirm the changes shown above:")
+	if err != nil {
+		return err
+	}
+	if !confirm {
+		return nil
+	}
+
+	for _, asset := range matched {
+		if err := realmClient.DeleteAsset(app.GroupID, app.ID, asset.FilePath); err != nil {
+			return fmt.Errorf("deleting '%s' failed => %s", asset.FilePath, err)
+		}
+		hrc.UI.Info(fmt.Sprintf("Removed %s", asset.FilePath))
+	}
+
+	return nil
+}
+
+// NewHostingSetAttrCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingSetAttrCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingSetAttrCommand{
+			HostingBaseCommand: NewHostingBaseCommand("set-attr", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingSetAttrCommand is used to set the attributes of hosted assets of a Realm app
+type HostingSetAttrCommand struct {
+	*HostingBaseCommand
+
+	flagPaths utils.StringSliceFlag
+	flagAttrs utils.StringSliceFlag
+	flagUnset utils.StringSliceFlag
+}
+
+// Synopsis returns a one-liner description for this command
+func (hsc *HostingSetAttrCommand) Synopsis() string {
+	return "Set the attributes of hosted assets of your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (hsc *HostingSetAttrCommand) Help() string {
+	return `Set the attributes of hosted assets of your Realm Application, keeping any attributes not mentioned.
+
+Usage: realm-cli hosting set-attr --path [string]... [--attr [string]...] [--unset [string]...] [options]
+
+REQUIRED:
+  --path [string]
+	The path of an asset to update, which may be a glob (e.g. "/images/*.png").
+	May be provided multiple times.
+
+OPTIONS:
+  --attr [string]
+	An attribute to set, as Name=Value (e.g. "Cache-Control=max-age=3600"). May be provided multiple times.
+
+  --unset [string]
+	The name of an attribute to remove. May be provided multiple times.
+` +
+		hsc.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hsc *HostingSetAttrCommand) Run(args []string) int {
+	hsc.NewFlagSet()
+
+	hsc.FlagSet.Var(&hsc.flagPaths, flagHostingPath, "")
+	hsc.FlagSet.Var(&hsc.flagAttrs, flagHostingAttr, "")
+	hsc.FlagSet.Var(&hsc.flagUnset, flagHostingUnset, "")
+
+	if err := hsc.HostingBaseCommand.run(args); err != nil {
+		hsc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hsc.setAttributes(); err != nil {
+		hsc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hsc *HostingSetAttrCommand) setAttributes() error {
+	if len(hsc.flagPaths) == 0 {
+		return errHostingPathRequired
+	}
+	if err := validateAssetPaths(hsc.flagPaths...); err != nil {
+		return err
+	}
+
+	set, unset, err := parseAssetAttributes(hsc.flagAttrs, hsc.flagUnset)
+	if err != nil {
+		return err
+	}
+	if len(set) == 0 && len(unset) == 0 {
+		return fmt.Errorf("an attribute to set (--%s=[string]) or unset (--%s=[string]) is required", flagHostingAttr, flagHostingUnset)
+	}
+
+	app, err := hsc.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hsc.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	assets, err := realmClient.ListAssetsForAppID(app.GroupID, app.ID)
+	if err != nil {
+		return err
+	}
+
+	matched, err := matchAssets(assets, hsc.flagPaths)
+	if err != nil {
+		return err
+	}
+
+	for _, asset := range matched {
+		attrs := mergeAssetAttributes(asset.Attrs, set, unset)
+		if err := realmClient.SetAssetAttributes(app.GroupID, app.ID, asset.FilePath, attrs...); err != nil {
+			return fmt.Errorf("%s => %s", asset.FilePath, err)
+		}
+		hsc.UI.Info(fmt.Sprintf("Updated attributes of %s: %s", asset.FilePath, formatAssetAttributes(attrs)))
+	}
+
+	return nil
+}
+
+// NewHostingUploadCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingUploadCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingUploadCommand{
+			HostingBaseCommand: NewHostingBaseCommand("upload", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingUploadCommand is used to upload local files as hosted assets of a Realm app
+type HostingUploadCommand struct {
+	*HostingBaseCommand
+
+	flagFiles utils.StringSliceFlag
+	flagPath  string
+	flagAttrs utils.StringSliceFlag
+}
+
+// Synopsis returns a one-liner description for this command
+func (huc *HostingUploadCommand) Synopsis() string {
+	return "Upload files as hosted assets of your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (huc *HostingUploadCommand) Help() string {
+	return `Upload local files as hosted assets of your Realm Application, replacing any existing assets at the same paths.
+
+Usage: realm-cli hosting upload --file [string]... [--path [string]] [options]
+
+REQUIRED:
+  --file [string]
+	A local file to upload, which may be a glob (e.g. "dist/*.js"). May be provided multiple times.
+
+OPTIONS:
+  --path [string]
+	The path to upload the file to. When it ends in "/" or more than one file is uploaded, each
+	file is uploaded into this directory under its own name. Defaults to "/".
+
+  --attr [string]
+	An attribute to set on every uploaded asset, as Name=Value (e.g. "Cache-Control=no-cache").
+	May be provided multiple times. Content-Type is otherwise derived from the file extension.
+` +
+		huc.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (huc *HostingUploadCommand) Run(args []string) int {
+	huc.NewFlagSet()
+
+	huc.FlagSet.Var(&huc.flagFiles, flagHostingFile, "")
+	huc.FlagSet.StringVar(&huc.flagPath, flagHostingPath, "/", "")
+	huc.FlagSet.Var(&huc.flagAttrs, flagHostingAttr, "")
+
+	if err := huc.HostingBaseCommand.run(args); err != nil {
+		huc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := huc.upload(); err != nil {
+		huc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (huc *HostingUploadCommand) upload() error {
+	if len(huc.flagFiles) == 0 {
+		return errHostingFileRequired
+	}
+	if err := validateAssetPaths(huc.flagPath); err != nil {
+		return err
+	}
+
+	set, _, err := parseAssetAttributes(huc.flagAttrs, nil)
+	if err != nil {
+		return err
+	}
+
+	files, err := huc.resolveFiles()
+	if err != nil {
+		return err
+	}
+
+	// files with the same name would be uploaded to the same asset, the last overwriting the others
+	intoDir := strings.HasSuffix(huc.flagPath, "/") || len(files) > 1
+	assetPaths := make([]string, len(files))
+	filesByAssetPath := map[string]string{}
+	for i, file := range files {
+		assetPaths[i] = huc.flagPath
+		if intoDir {
+			assetPaths[i] = path.Join(huc.flagPath, filepath.Base(file))
+		}
+		if other, ok := filesByAssetPath[assetPaths[i]]; ok {
+			return fmt.Errorf("%s and %s would both be uploaded to %s, please upload them separately", other, file, assetPaths[i])
+		}
+		filesByAssetPath[assetPaths[i]] = file
+	}
+
+	app, err := huc.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := huc.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	assetCache := hosting.NewAssetCache()
+
+	for i, file := range files {
+		assetPath := assetPaths[i]
+
+		info, err := os.Stat(file)
+		if err != nil {
+			return err
+		}
+
+		assetMetadata, err := hosting.FileToAssetMetadata(app.ID, file, assetPath, info, nil, nil, assetCache, hosting.CompressionNone)
+		if err != nil {
+			return err
+		}
+		assetMetadata.Attrs = mergeAssetAttributes(assetMetadata.Attrs, set, nil)
+
+		if err := uploadFile(app.GroupID, app.ID, file, realmClient, *assetMetadata); err != nil {
+			return err
+		}
+		huc.UI.Info(fmt.Sprintf("Uploaded %s to %s", file, assetPath))
+	}
+
+	return nil
+}
+
+// resolveFiles expands the --file globs relative to the working directory into a sorted list of regular files
+func (huc *HostingUploadCommand) resolveFiles() ([]string, error) {
+	seen := map[string]bool{}
+	var files []string
+
+	for _, pattern := range huc.flagFiles {
+		if !filepath.IsAbs(pattern) {
+			pattern = filepath.Join(huc.workingDirectory, pattern)
+		}
+
+		matches, err := filepath.Glob(pattern)
+		if err != nil {
+			return nil, fmt.Errorf("invalid file %q: %s", pattern, err)
+		}
+
+		found := false
+		for _, match := range matches {
+			info, err := os.Stat(match)
+			if err != nil {
+				return nil, err
+			}
+			if info.IsDir() {
+				continue
+			}
+
+			found = true
+			if !seen[match] {
+				seen[match] = true
+				files = append(files, match)
+			}
+		}
+
+		if !found {
+			return nil, fmt.Errorf("no files found matching %q", pattern)
+		}
+	}
+
+	sort.Strings(files)
+	return files, nil
+}
@@ -0,0 +1,64 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"strings"
+
+	"github.com/10gen/realm-cli/hosting"
+)
+
+const (
+	flagHostingFollowSymlinks = "follow-symlinks"
+	flagHostingMaxAssetSize   = "max-asset-size"
+
+	// defaultHostingMaxAssetSize is the largest size in megabytes of a file that can be hosted
+	defaultHostingMaxAssetSize = 25
+)
+
+// hostingAssetOptions controls how the local hosted assets are listed and the checks
+// they must all pass before any of them is uploaded
+type hostingAssetOptions struct {
+	// followSymlinks walks symlinked directories, which are otherwise skipped
+	followSymlinks bool
+
+	// maxAssetSize is the largest size in megabytes of an asset, or unlimited if 0
+	maxAssetSize int
+}
+
+func (hao *hostingAssetOptions) registerFlags(flags *flag.FlagSet) {
+	flags.BoolVar(&hao.followSymlinks, flagHostingFollowSymlinks, false, "")
+	flags.IntVar(&hao.maxAssetSize, flagHostingMaxAssetSize, defaultHostingMaxAssetSize, "")
+}
+
+func (hao hostingAssetOptions) validate() error {
+	if hao.maxAssetSize < 0 {
+		return fmt.Errorf("--%s must not be negative, but was %d", flagHostingMaxAssetSize, hao.maxAssetSize)
+	}
+	return nil
+}
+
+// check reports every asset that is larger than maxAssetSize,
+// and every group of assets whose paths differ only by case
+func (hao hostingAssetOptions) check(assetMetadata []hosting.AssetMetadata) error {
+	var problems []string
+
+	if hao.maxAssetSize > 0 {
+		maxBytes := int64(hao.maxAssetSize) * 1024 * 1024
+		for _, am := range assetMetadata {
+			if am.FileSize > maxBytes {
+				problems = append(problems, fmt.Sprintf("%s is %s, larger than the maximum of %s (--%s)", am.FilePath, formatBytes(am.FileSize), formatBytes(maxBytes), flagHostingMaxAssetSize))
+			}
+		}
+	}
+
+	for _, paths := range hosting.CaseCollisions(assetMetadata) {
+		problems = append(problems, fmt.Sprintf("%s differ only by case, so they would clash once uploaded", strings.Join(paths, ", ")))
+	}
+
+	if len(problems) == 0 {
+		return nil
+	}
+
+	return fmt.Errorf("%d problem(s) found with the local hosted assets, none were uploaded:\n\t%s", len(problems), strings.Join(problems, "\n\t"))
+}
@@ -0,0 +1,38 @@
+package commands
+
+import (
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostingAssetOptions(t *testing.T) {
+	assetMetadata := []hosting.AssetMetadata{
+		{FilePath: "/index.html", FileSize: 1024},
+		{FilePath: "/video.mp4", FileSize: 40 * 1024 * 1024},
+		{FilePath: "/img/Logo.png", FileSize: 2048},
+		{FilePath: "/img/logo.png", FileSize: 2048},
+	}
+
+	t.Run("should reject a negative maximum asset size", func(t *testing.T) {
+		err := hostingAssetOptions{maxAssetSize: -1}.validate()
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEqual, "--max-asset-size must not be negative, but was -1")
+	})
+
+	t.Run("should report every oversized asset and case collision at once", func(t *testing.T) {
+		err := hostingAssetOptions{maxAssetSize: defaultHostingMaxAssetSize}.check(assetMetadata)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEqual, `2 problem(s) found with the local hosted assets, none were uploaded:
+	/video.mp4 is 40.0 MB, larger than the maximum of 25.0 MB (--max-asset-size)
+	/img/Logo.png, /img/logo.png differ only by case, so they would clash once uploaded`)
+	})
+
+	t.Run("should not limit the size of assets if the maximum is 0", func(t *testing.T) {
+		err := hostingAssetOptions{}.check(assetMetadata[:2])
+		u.So(t, err, gc.ShouldBeNil)
+	})
+}
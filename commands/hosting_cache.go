@@ -0,0 +1,249 @@
+package commands
+
+import (
+	"bytes"
+	"fmt"
+	"os"
+	"sort"
+	"strings"
+	"text/tabwriter"
+
+	"github.com/10gen/realm-cli/hosting"
+
+	"github.com/mitchellh/cli"
+)
+
+// NewHostingCacheCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingCacheCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		return &HostingCacheCommand{
+			BaseCommand: &BaseCommand{
+				Name: "cache",
+				UI:   ui,
+			},
+		}, nil
+	}
+}
+
+// HostingCacheCommand is used to manage the local cache of hosted asset hashes
+type HostingCacheCommand struct {
+	*BaseCommand
+}
+
+// Synopsis returns a one-liner description for this command
+func (hcc *HostingCacheCommand) Synopsis() string {
+	return "Show or clear the local cache of hosted asset hashes."
+}
+
+// Help returns long-form help information for this command
+func (hcc *HostingCacheCommand) Help() string {
+	return hcc.Synopsis()
+}
+
+// Run executes the command
+func (hcc *HostingCacheCommand) Run(args []string) int {
+	return cli.RunResultHelp
+}
+
+// hostingCacheHelp documents the --app-id option of the hosting cache commands
+const hostingCacheHelp = `
+OPTIONS:
+  --app-id [string]
+	The App ID for your app (i.e. the name of your app followed by a unique suffix, like "my-app-nysja").
+	Defaults to every app with cached hashes.`
+
+// NewHostingCacheShowCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingCacheShowCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		return &HostingCacheShowCommand{
+			BaseCommand: &BaseCommand{
+				Name: "show",
+				UI:   ui,
+			},
+		}, nil
+	}
+}
+
+// HostingCacheShowCommand is used to show the cached hashes of hosted assets
+type HostingCacheShowCommand struct {
+	*BaseCommand
+
+	flagAppID string
+}
+
+// Synopsis returns a one-liner description for this command
+func (hcsc *HostingCacheShowCommand) Synopsis() string {
+	return "Show the local cache of hosted asset hashes."
+}
+
+// Help returns long-form help information for this command
+func (hcsc *HostingCacheShowCommand) Help() string {
+	return `Show the local cache of hosted asset hashes, which 'import --include-hosting' uses to avoid
+rehashing files whose size and modification time are unchanged.
+
+Usage: realm-cli hosting cache show [options]
+` + hostingCacheHelp +
+		hcsc.BaseCommand.Help()
+}
+
+// Run executes the command
+func (hcsc *HostingCacheShowCommand) Run(args []string) int {
+	flags := hcsc.NewFlagSet()
+
+	flags.StringVar(&hcsc.flagAppID, flagAppIDName, "", "")
+
+	if err := hcsc.BaseCommand.run(args); err != nil {
+		hcsc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hcsc.show(); err != nil {
+		hcsc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hcsc *HostingCacheShowCommand) show() error {
+	cachePath, err := getAssetCachePath(hcsc.flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	assetCache, err := hosting.CacheFileToAssetCache(cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return fmt.Errorf("failed to read the hosting asset cache %s: %s", cachePath, err)
+		}
+		assetCache = hosting.NewAssetCache()
+	}
+
+	entries := assetCache.Entries()
+
+	if hcsc.flagAppID == "" {
+		if len(entries) == 0 {
+			hcsc.UI.Info(fmt.Sprintf("The hosting asset cache %s is empty", cachePath))
+			return nil
+		}
+
+		appIDs := make([]string, 0, len(entries))
+		for appID := range entries {
+			appIDs = append(appIDs, appID)
+		}
+		sort.Strings(appIDs)
+
+		hcsc.UI.Info(fmt.Sprintf("Hosting asset cache %s:", cachePath))
+		for _, appID := range appIDs {
+			hcsc.UI.Info(fmt.Sprintf("%s: %d file(s)", appID, len(entries[appID])))
+		}
+		return nil
+	}
+
+	aces := entries[hcsc.flagAppID]
+	if len(aces) == 0 {
+		hcsc.UI.Info(fmt.Sprintf("No hosted asset hashes are cached for %s", hcsc.flagAppID))
+		return nil
+	}
+
+	paths := make([]string, 0, len(aces))
+	for path := range aces {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+
+	var buf bytes.Buffer
+	w := tabwriter.NewWriter(&buf, 0, 4, 2, ' ', 0)
+	fmt.Fprintln(w, "PATH\tSIZE\tHASH\tCOMPRESSION")
+	for _, path := range paths {
+		ace := aces[path]
+		compression := "-"
+		if ace.Compression != hosting.CompressionNone {
+			compression = fmt.Sprintf("%s (%d, %s)", ace.Compression, ace.CompressedSize, ace.CompressedHash)
+		}
+		fmt.Fprintf(w, "%s\t%d\t%s\t%s\n", path, ace.FileSize, ace.FileHash, compression)
+	}
+	if err := w.Flush(); err != nil {
+		return err
+	}
+
+	for _, line := range strings.Split(strings.TrimSuffix(buf.String(), "\n"), "\n") {
+		hcsc.UI.Info(strings.TrimRight(line, " "))
+	}
+	return nil
+}
+
+// NewHostingCacheClearCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingCacheClearCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		return &HostingCacheClearCommand{
+			BaseCommand: &BaseCommand{
+				Name: "clear",
+				UI:   ui,
+			},
+		}, nil
+	}
+}
+
+// HostingCacheClearCommand is used to clear the cached hashes of hosted assets
+type HostingCacheClearCommand struct {
+	*BaseCommand
+
+	flagAppID string
+}
+
+// Synopsis returns a one-liner description for this command
+func (hccc *HostingCacheClearCommand) Synopsis() string {
+	return "Clear the local cache of hosted asset hashes."
+}
+
+// Help returns long-form help information for this command
+func (hccc *HostingCacheClearCommand) Help() string {
+	return `Clear the local cache of hosted asset hashes, so that the next 'import --include-hosting' rehashes every file.
+
+Usage: realm-cli hosting cache clear [options]
+` + hostingCacheHelp +
+		hccc.BaseCommand.Help()
+}
+
+// Run executes the command
+func (hccc *HostingCacheClearCommand) Run(args []string) int {
+	flags := hccc.NewFlagSet()
+
+	flags.StringVar(&hccc.flagAppID, flagAppIDName, "", "")
+
+	if err := hccc.BaseCommand.run(args); err != nil {
+		hccc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hccc.clear(); err != nil {
+		hccc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hccc *HostingCacheClearCommand) clear() error {
+	cachePath, err := getAssetCachePath(hccc.flagConfigPath)
+	if err != nil {
+		return err
+	}
+
+	if _, err := os.Stat(cachePath); os.IsNotExist(err) {
+		hccc.UI.Info(fmt.Sprintf("The hosting asset cache %s is empty", cachePath))
+		return nil
+	}
+
+	if err := hosting.ClearCacheFile(cachePath, hccc.flagAppID); err != nil {
+		return fmt.Errorf("failed to clear the hosting asset cache %s: %s", cachePath, err)
+	}
+
+	if hccc.flagAppID == "" {
+		hccc.UI.Info(fmt.Sprintf("Cleared the hosting asset cache %s", cachePath))
+	} else {
+		hccc.UI.Info(fmt.Sprintf("Cleared the cached hosted asset hashes of %s", hccc.flagAppID))
+	}
+	return nil
+}
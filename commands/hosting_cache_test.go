@@ -0,0 +1,99 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostingCacheCommands(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-hosting-cache")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	configPathArg := "--config-path=" + filepath.Join(dir, "config.json")
+	cachePath := filepath.Join(dir, utils.HostingCacheFileName)
+
+	show := func(args ...string) (int, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingCacheShowCommandFactory(mockUI)()
+		u.So(t, err, gc.ShouldBeNil)
+
+		showCommand := cmd.(*HostingCacheShowCommand)
+		showCommand.storage = u.NewEmptyStorage()
+		return showCommand.Run(append(args, configPathArg)), mockUI
+	}
+
+	clear := func(args ...string) (int, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingCacheClearCommandFactory(mockUI)()
+		u.So(t, err, gc.ShouldBeNil)
+
+		clearCommand := cmd.(*HostingCacheClearCommand)
+		clearCommand.storage = u.NewEmptyStorage()
+		return clearCommand.Run(append(args, configPathArg)), mockUI
+	}
+
+	t.Run("should show an empty cache when there is no cache file", func(t *testing.T) {
+		exitCode, mockUI := show()
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "The hosting asset cache "+cachePath+" is empty")
+	})
+
+	assetCache := hosting.NewAssetCache()
+	assetCache.Set("app-a", hosting.AssetCacheEntry{FilePath: "/index.html", FileSize: 120, FileHash: "h1"})
+	assetCache.Set("app-a", hosting.AssetCacheEntry{
+		FilePath:       "/app.js",
+		FileSize:       4096,
+		FileHash:       "h2",
+		Compression:    hosting.CompressionGzip,
+		CompressedSize: 1024,
+		CompressedHash: "h2gz",
+	})
+	assetCache.Set("app-b", hosting.AssetCacheEntry{FilePath: "/index.html", FileSize: 80, FileHash: "h3"})
+	u.So(t, hosting.UpdateCacheFile(cachePath, assetCache), gc.ShouldBeNil)
+
+	t.Run("should show the number of cached files of every app", func(t *testing.T) {
+		exitCode, mockUI := show()
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldEqual, "Hosting asset cache "+cachePath+":\napp-a: 2 file(s)\napp-b: 1 file(s)\n")
+	})
+
+	t.Run("should show the cached hashes of an app", func(t *testing.T) {
+		exitCode, mockUI := show("--app-id=app-a")
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldEqual, `PATH         SIZE  HASH  COMPRESSION
+/app.js      4096  h2    gzip (1024, h2gz)
+/index.html  120   h1    -
+`)
+	})
+
+	t.Run("should clear the cached hashes of an app", func(t *testing.T) {
+		exitCode, mockUI := clear("--app-id=app-a")
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "Cleared the cached hosted asset hashes of app-a")
+
+		exitCode, mockUI = show()
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldEqual, "Hosting asset cache "+cachePath+":\napp-b: 1 file(s)\n")
+	})
+
+	t.Run("should clear the whole cache", func(t *testing.T) {
+		exitCode, mockUI := clear()
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "Cleared the hosting asset cache "+cachePath)
+
+		exitCode, mockUI = show()
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "is empty")
+	})
+}
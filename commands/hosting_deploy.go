@@ -0,0 +1,217 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/utils"
+
+	"github.com/mitchellh/cli"
+)
+
+var errHostingDeployPathRequired = fmt.Errorf("a local directory (--%s=[string]) is required", flagHostingPath)
+
+// NewHostingDeployCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingDeployCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingDeployCommand{
+			HostingBaseCommand: NewHostingBaseCommand("deploy", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingDeployCommand is used to sync the hosted assets of a Realm app with a local directory,
+// without importing the rest of the app
+type HostingDeployCommand struct {
+	*HostingBaseCommand
+
+	flagPath              string
+	flagStrategy          string
+	flagResetCDNCache     bool
+	flagResetCDNThreshold int
+	flagExcludes          utils.StringSliceFlag
+	flagCompress          string
+	flagVerify            bool
+	flagHostingLimits     HostingLimits
+	flagHostingAssets     hostingAssetOptions
+}
+
+// Synopsis returns a one-liner description for this command
+func (hdc *HostingDeployCommand) Synopsis() string {
+	return "Deploy a local directory as the hosted assets of your Realm App."
+}
+
+// Help returns long-form help information for this command
+func (hdc *HostingDeployCommand) Help() string {
+	return `Deploy a local directory as the hosted assets of your Realm App, uploading only the files that changed.
+Unlike 'import --include-hosting', the rest of the app is neither imported nor deployed.
+
+Usage: realm-cli hosting deploy --path [string] [options]
+
+REQUIRED:
+  --path [string]
+	The local directory of the assets to deploy (e.g. "dist"). The attributes of the assets are read
+	from a "metadata.json" file next to this directory, if there is one.
+
+OPTIONS:
+  --strategy [merge|replace] (default: merge)
+	How the assets should be deployed.
+	merge - upload added and modified files while preserving hosted assets missing from the local directory.
+	replace - like merge but also removes hosted assets missing from the local directory.
+	A file with the same contents as a hosted asset is copied from that asset rather than uploaded, or moved
+	from it with replace if the asset is missing from the local directory. With merge, a renamed file is
+	therefore copied and its old path is kept.
+
+  --exclude [string]
+	A gitignore-style pattern of files in the local directory not to upload, in addition to those listed
+	in its ".realmignore" file. May be provided multiple times.
+
+  --compress [gzip|br]
+	Compress text-like static assets (such as HTML, CSS and JavaScript) before uploading them,
+	setting their Content-Encoding accordingly. Assets that already declare a Content-Encoding are uploaded as is.
+
+  --verify
+	Rehash every static asset rather than trusting the hashes cached for files whose size and
+	modification time are unchanged, and update the cache with the new hashes.
+
+  --follow-symlinks
+	Upload the files in symlinked directories of the local directory, which are otherwise skipped. A symlink
+	to a directory containing it is reported as a cycle. Symlinked files are uploaded with the contents of their targets.
+
+  --max-asset-size [int]
+	The largest size in megabytes of a static asset. Every larger asset, along with every set of assets whose
+	paths differ only by case, is reported before anything is uploaded. Defaults to 25, or unlimited if 0.
+
+  --reset-cdn-cache
+	Invalidate cdn cache for added, modified and deleted files.
+
+  --reset-cdn-cache-threshold [int]
+	The number of changed paths above which they are collapsed into directory wildcards
+	when invalidating the cdn cache. Defaults to 20.
+
+  --concurrency [int]
+	The number of hosted assets uploaded, removed or updated at once. Defaults to 4.
+
+  --max-rps [float]
+	The most requests per second made to deploy the assets, shared by all concurrent requests.
+	Defaults to unlimited.
+` +
+		hdc.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hdc *HostingDeployCommand) Run(args []string) int {
+	hdc.NewFlagSet()
+
+	hdc.FlagSet.StringVar(&hdc.flagPath, flagHostingPath, "", "")
+	hdc.FlagSet.StringVar(&hdc.flagStrategy, importFlagStrategy, importStrategyMerge, "")
+	hdc.FlagSet.BoolVar(&hdc.flagResetCDNCache, importFlagResetCDNCache, false, "")
+	hdc.FlagSet.IntVar(&hdc.flagResetCDNThreshold, importFlagResetCDNThreshold, hosting.DefaultInvalidationThreshold, "")
+	hdc.FlagSet.Var(&hdc.flagExcludes, importFlagExclude, "")
+	hdc.FlagSet.StringVar(&hdc.flagCompress, importFlagCompress, "", "")
+	hdc.FlagSet.BoolVar(&hdc.flagVerify, importFlagVerify, false, "")
+	hdc.flagHostingLimits.registerFlags(hdc.FlagSet)
+	hdc.flagHostingAssets.registerFlags(hdc.FlagSet)
+
+	if err := hdc.HostingBaseCommand.run(args); err != nil {
+		hdc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hdc.deploy(); err != nil {
+		hdc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hdc *HostingDeployCommand) deploy() error {
+	if hdc.flagPath == "" {
+		return errHostingDeployPathRequired
+	}
+
+	if hdc.flagStrategy != importStrategyMerge && hdc.flagStrategy != importStrategyReplace {
+		return fmt.Errorf("unknown deploy strategy %q; accepted values are [%s|%s]", hdc.flagStrategy, importStrategyMerge, importStrategyReplace)
+	}
+
+	if err := hdc.flagHostingLimits.validate(); err != nil {
+		return err
+	}
+
+	if err := hdc.flagHostingAssets.validate(); err != nil {
+		return err
+	}
+
+	compression, err := hosting.ParseCompression(hdc.flagCompress)
+	if err != nil {
+		return err
+	}
+
+	rootDir := hdc.flagPath
+	if !filepath.IsAbs(rootDir) {
+		rootDir = filepath.Join(hdc.workingDirectory, rootDir)
+	}
+
+	info, err := os.Stat(rootDir)
+	if err != nil {
+		return err
+	}
+	if !info.IsDir() {
+		return fmt.Errorf("%s is not a directory", hdc.flagPath)
+	}
+
+	app, err := hdc.resolveApp()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hdc.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	localAssetMetadata, err := listLocalHostingAssets(app.ClientAppID, rootDir, localHostingMetadataPath(rootDir), hdc.flagConfigPath, hdc.flagExcludes, compression, hdc.flagVerify, hdc.flagHostingAssets, hdc.UI)
+	if err != nil {
+		return err
+	}
+
+	remoteAssetMetadata, err := realmClient.ListAssetsForAppID(app.GroupID, app.ID)
+	if err != nil {
+		return fmt.Errorf("error retrieving remote assets: %s", err)
+	}
+
+	assetMetadataDiffs := hosting.DiffAssetMetadata(localAssetMetadata, remoteAssetMetadata, hdc.flagStrategy == importStrategyMerge)
+
+	diff := assetMetadataDiffs.Diff()
+	if len(diff) == 0 {
+		hdc.UI.Info("Hosted assets are identical to the local directory, nothing to do.")
+		return nil
+	}
+
+	for _, line := range diff {
+		hdc.UI.Info(line)
+	}
+
+	confirm, err := hdc.AskYesNo("Please confirm the changes shown above:")
+	if err != nil {
+		return err
+	}
+	if !confirm {
+		return nil
+	}
+
+	if err := ImportHosting(app.GroupID, app.ID, rootDir, assetMetadataDiffs, hdc.flagResetCDNCache, hdc.flagResetCDNThreshold, hdc.flagHostingLimits, realmClient, hdc.UI); err != nil {
+		return err
+	}
+
+	hdc.UI.Info(fmt.Sprintf("Successfully deployed the hosted assets of '%s'", app.ClientAppID))
+	return nil
+}
@@ -0,0 +1,158 @@
+package commands
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/user"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostingDeployCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-hosting-deploy")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	distDir := filepath.Join(dir, "dist")
+	u.So(t, os.MkdirAll(distDir, 0755), gc.ShouldBeNil)
+	u.So(t, ioutil.WriteFile(filepath.Join(distDir, "index.html"), []byte("<html></html>"), 0644), gc.ShouldBeNil)
+	u.So(t, ioutil.WriteFile(filepath.Join(distDir, "app.css"), []byte("body {}"), 0644), gc.ShouldBeNil)
+
+	configPathArg := "--config-path=" + filepath.Join(dir, "config.json")
+
+	remoteAssets := []hosting.AssetMetadata{
+		{FilePath: "/app.css", FileHash: "stale", FileSize: 4},
+		{FilePath: "/old.html", FileHash: "old", FileSize: 3},
+	}
+
+	setup := func(realmClient *u.MockRealmClient) (*HostingDeployCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingDeployCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		deployCommand := cmd.(*HostingDeployCommand)
+		deployCommand.workingDirectory = dir
+		setUpBasicHostingCommand(deployCommand.HostingBaseCommand, realmClient)
+		return deployCommand, mockUI
+	}
+
+	recordingClient := func(requests *[]string) *u.MockRealmClient {
+		var mu sync.Mutex
+		record := func(request string) {
+			mu.Lock()
+			defer mu.Unlock()
+			*requests = append(*requests, request)
+		}
+
+		return &u.MockRealmClient{
+			ListAssetsForAppIDFn: func(groupID, appID string) ([]hosting.AssetMetadata, error) {
+				u.So(t, groupID, gc.ShouldEqual, "group-id")
+				u.So(t, appID, gc.ShouldEqual, "app-id")
+				return remoteAssets, nil
+			},
+			UploadAssetFn: func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error {
+				record("upload " + path)
+				return nil
+			},
+			DeleteAssetFn: func(groupID, appID, path string) error {
+				record("delete " + path)
+				return nil
+			},
+			InvalidateCacheFn: func(groupID, appID, path string) error {
+				record("invalidate " + path)
+				return nil
+			},
+		}
+	}
+
+	t.Run("should require the user to be logged in", func(t *testing.T) {
+		deployCommand, mockUI := setup(nil)
+
+		exitCode := deployCommand.Run([]string{"--path=dist"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, user.ErrNotLoggedIn.Error())
+	})
+
+	t.Run("should require a path", func(t *testing.T) {
+		deployCommand, mockUI := setup(nil)
+		logInHostingCommand(deployCommand.HostingBaseCommand)
+
+		exitCode := deployCommand.Run([]string{})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, errHostingDeployPathRequired.Error())
+	})
+
+	t.Run("should reject an unknown strategy", func(t *testing.T) {
+		deployCommand, mockUI := setup(nil)
+		logInHostingCommand(deployCommand.HostingBaseCommand)
+
+		exitCode := deployCommand.Run([]string{"--path=dist", "--strategy=replace-by-name"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, `unknown deploy strategy "replace-by-name"`)
+	})
+
+	t.Run("should upload added and modified files while preserving the other hosted assets", func(t *testing.T) {
+		var requests []string
+		deployCommand, mockUI := setup(recordingClient(&requests))
+		logInHostingCommand(deployCommand.HostingBaseCommand)
+
+		exitCode := deployCommand.Run([]string{"--path=dist", "-y", "--app-id=my-app-abcde", configPathArg})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+
+		sort.Strings(requests)
+		u.So(t, requests, gc.ShouldResemble, []string{"upload /app.css", "upload /index.html"})
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "+ /index.html")
+	})
+
+	t.Run("should also remove missing hosted assets and invalidate the cdn cache with the replace strategy", func(t *testing.T) {
+		var requests []string
+		deployCommand, mockUI := setup(recordingClient(&requests))
+		logInHostingCommand(deployCommand.HostingBaseCommand)
+
+		exitCode := deployCommand.Run([]string{"--path=" + distDir, "--strategy=replace", "--reset-cdn-cache", "-y", "--app-id=my-app-abcde", configPathArg})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+
+		sort.Strings(requests)
+		u.So(t, requests, gc.ShouldResemble, []string{
+			"delete /old.html",
+			"invalidate /app.css",
+			"invalidate /index.html",
+			"invalidate /old.html",
+			"upload /app.css",
+			"upload /index.html",
+		})
+	})
+
+	t.Run("should do nothing when the hosted assets are up to date", func(t *testing.T) {
+		localAssets, err := hosting.ListLocalAssetMetadata("", distDir, nil, nil, hosting.NewAssetCache(), nil, hosting.CompressionNone, false)
+		u.So(t, err, gc.ShouldBeNil)
+
+		var requests []string
+		realmClient := recordingClient(&requests)
+		realmClient.ListAssetsForAppIDFn = func(groupID, appID string) ([]hosting.AssetMetadata, error) {
+			return localAssets, nil
+		}
+
+		deployCommand, mockUI := setup(realmClient)
+		logInHostingCommand(deployCommand.HostingBaseCommand)
+
+		exitCode := deployCommand.Run([]string{"--path=dist", "--strategy=replace", "-y", "--app-id=my-app-abcde", configPathArg})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, requests, gc.ShouldBeEmpty)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "nothing to do")
+	})
+}
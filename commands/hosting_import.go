@@ -0,0 +1,133 @@
+package commands
+
+import (
+	"fmt"
+	"os"
+
+	"github.com/10gen/realm-cli/hosting"
+
+	"github.com/mitchellh/cli"
+)
+
+const flagHostingResume = "resume"
+
+var errHostingResumeRequired = fmt.Errorf("a manifest of failed operations (--%s=[string]) is required", flagHostingResume)
+
+// NewHostingImportCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingImportCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingImportCommand{
+			HostingBaseCommand: NewHostingBaseCommand("import", workingDirectory, ui),
+		}, nil
+	}
+}
+
+// HostingImportCommand is used to retry the hosting operations that failed during an import
+type HostingImportCommand struct {
+	*HostingBaseCommand
+
+	flagResume            string
+	flagResetCDNCache     bool
+	flagResetCDNThreshold int
+	flagHostingLimits     HostingLimits
+}
+
+// Synopsis returns a one-liner description for this command
+func (hic *HostingImportCommand) Synopsis() string {
+	return "Retry the hosted asset operations that failed during an import."
+}
+
+// Help returns long-form help information for this command
+func (hic *HostingImportCommand) Help() string {
+	return `Retry the hosted asset operations that failed during an import.
+
+Usage: realm-cli hosting import --resume [string] [options]
+
+REQUIRED:
+  --resume [string]
+	A path to the manifest of failed operations written by 'import --include-hosting' when some
+	assets could not be uploaded, deleted or updated. Only those operations are retried, and the
+	manifest is removed once they all succeed. The files changed since the failed import are
+	uploaded with their current contents.
+
+OPTIONS:
+  --reset-cdn-cache
+	Invalidate cdn cache for the retried files.
+
+  --reset-cdn-cache-threshold [int]
+	The number of changed paths above which they are collapsed into directory wildcards
+	when invalidating the cdn cache. Defaults to 20.
+
+  --concurrency [int]
+	The number of operations retried at once. Defaults to 4.
+
+  --max-rps [float]
+	The most requests per second made to retry the operations, shared by all concurrent requests.
+	Defaults to unlimited.
+` +
+		hic.HostingBaseCommand.Help()
+}
+
+// Run executes the command
+func (hic *HostingImportCommand) Run(args []string) int {
+	hic.NewFlagSet()
+
+	hic.FlagSet.StringVar(&hic.flagResume, flagHostingResume, "", "")
+	hic.FlagSet.BoolVar(&hic.flagResetCDNCache, importFlagResetCDNCache, false, "")
+	hic.FlagSet.IntVar(&hic.flagResetCDNThreshold, importFlagResetCDNThreshold, hosting.DefaultInvalidationThreshold, "")
+	hic.flagHostingLimits.registerFlags(hic.FlagSet)
+
+	if err := hic.HostingBaseCommand.run(args); err != nil {
+		hic.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := hic.resume(); err != nil {
+		hic.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (hic *HostingImportCommand) resume() error {
+	if hic.flagResume == "" {
+		return errHostingResumeRequired
+	}
+
+	if err := hic.flagHostingLimits.validate(); err != nil {
+		return err
+	}
+
+	manifest, err := readHostingManifest(hic.flagResume)
+	if err != nil {
+		return err
+	}
+
+	diffs, err := manifest.diffs()
+	if err != nil {
+		return err
+	}
+
+	realmClient, err := hic.RealmClient()
+	if err != nil {
+		return err
+	}
+
+	if err := ImportHosting(manifest.GroupID, manifest.AppID, manifest.RootDir, diffs, hic.flagResetCDNCache, hic.flagResetCDNThreshold, hic.flagHostingLimits, realmClient, hic.UI); err != nil {
+		return err
+	}
+
+	// ImportHosting removes the manifest at its default path, but it may have been moved
+	if err := os.Remove(hic.flagResume); err != nil && !os.IsNotExist(err) {
+		return err
+	}
+
+	hic.UI.Info("Done.")
+	return nil
+}
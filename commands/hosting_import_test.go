@@ -0,0 +1,242 @@
+package commands
+
+import (
+	"errors"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"sort"
+	"sync"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/user"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostingImportCommand(t *testing.T) {
+	rootDir, err := filepath.Abs("../testdata/full_app/hosting/files")
+	u.So(t, err, gc.ShouldBeNil)
+
+	setup := func(realmClient *u.MockRealmClient) (*HostingImportCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingImportCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		importCommand := cmd.(*HostingImportCommand)
+		setUpBasicHostingCommand(importCommand.HostingBaseCommand, realmClient)
+		return importCommand, mockUI
+	}
+
+	writeManifest := func(t *testing.T, manifest *hostingManifest) string {
+		dir, err := ioutil.TempDir("", "realm-cli-hosting-import")
+		u.So(t, err, gc.ShouldBeNil)
+
+		manifestPath := filepath.Join(dir, "failures.json")
+		u.So(t, writeHostingManifest(manifestPath, manifest), gc.ShouldBeNil)
+		return manifestPath
+	}
+
+	t.Run("should require the user to be logged in", func(t *testing.T) {
+		importCommand, mockUI := setup(nil)
+
+		exitCode := importCommand.Run([]string{"--resume=failures.json"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, user.ErrNotLoggedIn.Error())
+	})
+
+	t.Run("should require a manifest", func(t *testing.T) {
+		importCommand, mockUI := setup(nil)
+		logInHostingCommand(importCommand.HostingBaseCommand)
+
+		exitCode := importCommand.Run([]string{})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, errHostingResumeRequired.Error())
+	})
+
+	t.Run("should reject a manifest without an app", func(t *testing.T) {
+		manifestPath := writeManifest(t, &hostingManifest{RootDir: rootDir})
+		defer os.RemoveAll(filepath.Dir(manifestPath))
+
+		importCommand, mockUI := setup(nil)
+		logInHostingCommand(importCommand.HostingBaseCommand)
+
+		exitCode := importCommand.Run([]string{"--resume=" + manifestPath})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "is missing its group_id, app_id or root_dir")
+	})
+
+	t.Run("should retry only the operations in the manifest", func(t *testing.T) {
+		errFailed := errors.New("500 Internal Server Error")
+
+		hash0, size0, err := hosting.CompressionNone.HashFile(filepath.Join(rootDir, "asset_file0.json"))
+		u.So(t, err, gc.ShouldBeNil)
+		hash1, size1, err := hosting.CompressionNone.HashFile(filepath.Join(rootDir, "ships", "nostromo.json"))
+		u.So(t, err, gc.ShouldBeNil)
+
+		manifest := &hostingManifest{GroupID: "group-id", AppID: "app-id", RootDir: rootDir}
+		manifest.addAdded(hosting.AssetMetadata{FilePath: "/asset_file0.json", FileHash: hash0, FileSize: size0}, errFailed)
+		manifest.addDeleted(hosting.AssetMetadata{FilePath: "/gone.html"}, errFailed)
+		manifest.addModified(hosting.ModifiedAssetMetadata{
+			AssetMetadata: hosting.AssetMetadata{
+				FilePath: "/ships/nostromo.json",
+				FileHash: hash1,
+				FileSize: size1,
+				Attrs:    []hosting.AssetAttribute{{Name: "Cache-Control", Value: "no-cache"}},
+			},
+			AttrModified: true,
+		}, errFailed)
+
+		manifestPath := writeManifest(t, manifest)
+		defer os.RemoveAll(filepath.Dir(manifestPath))
+
+		var mu sync.Mutex
+		var requests []string
+		record := func(request string) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, request)
+		}
+
+		importCommand, mockUI := setup(&u.MockRealmClient{
+			UploadAssetFn: func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error {
+				u.So(t, groupID, gc.ShouldEqual, "group-id")
+				u.So(t, appID, gc.ShouldEqual, "app-id")
+				u.So(t, hash, gc.ShouldEqual, hash0)
+				record("upload " + path)
+				return nil
+			},
+			DeleteAssetFn: func(groupID, appID, path string) error {
+				record("delete " + path)
+				return nil
+			},
+			SetAssetAttributesFn: func(groupID, appID, path string, attributes ...hosting.AssetAttribute) error {
+				u.So(t, attributes, gc.ShouldResemble, []hosting.AssetAttribute{{Name: "Cache-Control", Value: "no-cache"}})
+				record("set-attr " + path)
+				return nil
+			},
+		})
+		logInHostingCommand(importCommand.HostingBaseCommand)
+
+		exitCode := importCommand.Run([]string{"--resume=" + manifestPath})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+
+		sort.Strings(requests)
+		u.So(t, requests, gc.ShouldResemble, []string{
+			"delete /gone.html",
+			"set-attr /ships/nostromo.json",
+			"upload /asset_file0.json",
+		})
+
+		_, err = os.Stat(manifestPath)
+		u.So(t, os.IsNotExist(err), gc.ShouldBeTrue)
+	})
+
+	t.Run("should replan the operations on files changed since the failed import", func(t *testing.T) {
+		errFailed := errors.New("500 Internal Server Error")
+
+		dir, err := ioutil.TempDir("", "realm-cli-hosting-import")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		for _, name := range []string{"added.txt", "renamed.txt", "copied.txt", "moved.txt"} {
+			u.So(t, ioutil.WriteFile(filepath.Join(dir, name), []byte("changed "+name), 0600), gc.ShouldBeNil)
+		}
+		movedHash, movedSize, err := hosting.CompressionNone.HashFile(filepath.Join(dir, "moved.txt"))
+		u.So(t, err, gc.ShouldBeNil)
+
+		manifest := &hostingManifest{GroupID: "group-id", AppID: "app-id", RootDir: dir}
+		manifest.addAdded(hosting.AssetMetadata{FilePath: "/added.txt", FileHash: "stale", FileSize: 1}, errFailed)
+		manifest.addRenamed(hosting.RenamedAssetMetadata{
+			AssetMetadata: hosting.AssetMetadata{FilePath: "/renamed.txt", FileHash: "stale", FileSize: 1},
+			FromPath:      "/old.txt",
+		}, false, errFailed)
+		manifest.addRenamed(hosting.RenamedAssetMetadata{
+			AssetMetadata: hosting.AssetMetadata{FilePath: "/copied.txt", FileHash: "stale", FileSize: 1},
+			FromPath:      "/original.txt",
+			Copy:          true,
+		}, false, errFailed)
+		manifest.addRenamed(hosting.RenamedAssetMetadata{
+			AssetMetadata: hosting.AssetMetadata{
+				FilePath: "/moved.txt",
+				FileHash: movedHash,
+				FileSize: movedSize,
+				Attrs:    []hosting.AssetAttribute{{Name: "Cache-Control", Value: "no-cache"}},
+			},
+			FromPath:     "/before.txt",
+			AttrModified: true,
+		}, true, errFailed)
+
+		manifestPath := writeManifest(t, manifest)
+		defer os.RemoveAll(filepath.Dir(manifestPath))
+
+		var mu sync.Mutex
+		var requests []string
+		record := func(request string) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, request)
+		}
+
+		importCommand, mockUI := setup(&u.MockRealmClient{
+			UploadAssetFn: func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error {
+				u.So(t, hash, gc.ShouldNotEqual, "stale")
+				u.So(t, size, gc.ShouldEqual, len("changed ")+len(path)-1)
+				record("upload " + path)
+				return nil
+			},
+			DeleteAssetFn: func(groupID, appID, path string) error {
+				record("delete " + path)
+				return nil
+			},
+			MoveAssetFn: func(groupID, appID, fromPath, toPath string) error {
+				record("move " + fromPath)
+				return nil
+			},
+			CopyAssetFn: func(groupID, appID, fromPath, toPath string) error {
+				record("copy " + fromPath)
+				return nil
+			},
+			SetAssetAttributesFn: func(groupID, appID, path string, attributes ...hosting.AssetAttribute) error {
+				record("set-attr " + path)
+				return nil
+			},
+		})
+		logInHostingCommand(importCommand.HostingBaseCommand)
+
+		exitCode := importCommand.Run([]string{"--resume=" + manifestPath})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+
+		sort.Strings(requests)
+		u.So(t, requests, gc.ShouldResemble, []string{
+			"delete /old.txt",
+			"set-attr /moved.txt",
+			"upload /added.txt",
+			"upload /copied.txt",
+			"upload /renamed.txt",
+		})
+	})
+
+	t.Run("should fail if a file to upload no longer exists", func(t *testing.T) {
+		manifest := &hostingManifest{GroupID: "group-id", AppID: "app-id", RootDir: rootDir}
+		manifest.addAdded(hosting.AssetMetadata{FilePath: "/missing.txt"}, errors.New("500 Internal Server Error"))
+
+		manifestPath := writeManifest(t, manifest)
+		defer os.RemoveAll(filepath.Dir(manifestPath))
+
+		importCommand, mockUI := setup(&u.MockRealmClient{})
+		logInHostingCommand(importCommand.HostingBaseCommand)
+
+		exitCode := importCommand.Run([]string{"--resume=" + manifestPath})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "failed to read '/missing.txt'")
+	})
+}
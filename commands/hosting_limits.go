@@ -0,0 +1,76 @@
+package commands
+
+import (
+	"flag"
+	"fmt"
+	"sync"
+	"time"
+)
+
+const (
+	flagHostingConcurrency = "concurrency"
+	flagHostingMaxRPS      = "max-rps"
+
+	defaultHostingConcurrency = 4
+)
+
+// HostingLimits bounds the requests made by the workers transferring hosted assets
+type HostingLimits struct {
+	// Concurrency is the number of workers making requests at once
+	Concurrency int
+
+	// MaxRPS is the most requests made per second across all workers, or unlimited if 0
+	MaxRPS float64
+}
+
+// DefaultHostingLimits are the HostingLimits used unless --concurrency or --max-rps is provided
+var DefaultHostingLimits = HostingLimits{Concurrency: defaultHostingConcurrency}
+
+func (hl *HostingLimits) registerFlags(flags *flag.FlagSet) {
+	flags.IntVar(&hl.Concurrency, flagHostingConcurrency, defaultHostingConcurrency, "")
+	flags.Float64Var(&hl.MaxRPS, flagHostingMaxRPS, 0, "")
+}
+
+func (hl HostingLimits) validate() error {
+	if hl.Concurrency < 1 {
+		return fmt.Errorf("--%s must be at least 1, but was %d", flagHostingConcurrency, hl.Concurrency)
+	}
+	if hl.MaxRPS < 0 {
+		return fmt.Errorf("--%s must not be negative, but was %v", flagHostingMaxRPS, hl.MaxRPS)
+	}
+	return nil
+}
+
+// limiter returns the hostingLimiter shared by the workers, which is nil when the requests are unlimited
+func (hl HostingLimits) limiter() *hostingLimiter {
+	if hl.MaxRPS <= 0 {
+		return nil
+	}
+	return &hostingLimiter{interval: time.Duration(float64(time.Second) / hl.MaxRPS)}
+}
+
+// hostingLimiter spaces out the requests made by concurrent workers so that at most one starts per interval
+type hostingLimiter struct {
+	interval time.Duration
+
+	mu   sync.Mutex
+	next time.Time
+}
+
+// wait blocks until the next request may be made. A nil hostingLimiter never blocks
+func (hl *hostingLimiter) wait() {
+	if hl == nil {
+		return
+	}
+
+	hl.mu.Lock()
+	now := time.Now()
+	if hl.next.Before(now) {
+		hl.next = now
+	}
+	delay := hl.next.Sub(now)
+	hl.next = hl.next.Add(hl.interval)
+	hl.mu.Unlock()
+
+	time.Sleep(delay)
+}
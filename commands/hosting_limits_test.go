@@ -0,0 +1,81 @@
+package commands
+
+import (
+	"sync"
+	"testing"
+	"time"
+
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostingLimits(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		limits      HostingLimits
+		expectedErr string
+	}{
+		{
+			description: "should accept the defaults",
+			limits:      DefaultHostingLimits,
+		},
+		{
+			description: "should accept a fractional rate",
+			limits:      HostingLimits{Concurrency: 16, MaxRPS: 0.5},
+		},
+		{
+			description: "should reject a concurrency below 1",
+			limits:      HostingLimits{Concurrency: 0},
+			expectedErr: "--concurrency must be at least 1, but was 0",
+		},
+		{
+			description: "should reject a negative rate",
+			limits:      HostingLimits{Concurrency: 4, MaxRPS: -1},
+			expectedErr: "--max-rps must not be negative, but was -1",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			err := tc.limits.validate()
+			if tc.expectedErr == "" {
+				u.So(t, err, gc.ShouldBeNil)
+			} else {
+				u.So(t, err, gc.ShouldNotBeNil)
+				u.So(t, err.Error(), gc.ShouldEqual, tc.expectedErr)
+			}
+		})
+	}
+
+	t.Run("should not limit the requests without a rate", func(t *testing.T) {
+		limiter := DefaultHostingLimits.limiter()
+		u.So(t, limiter, gc.ShouldBeNil)
+
+		start := time.Now()
+		for i := 0; i < 100; i++ {
+			limiter.wait()
+		}
+		u.So(t, time.Since(start), gc.ShouldBeLessThan, 10*time.Millisecond)
+	})
+
+	t.Run("should space out the requests of concurrent workers", func(t *testing.T) {
+		limiter := HostingLimits{Concurrency: 4, MaxRPS: 100}.limiter()
+		u.So(t, limiter.interval, gc.ShouldEqual, 10*time.Millisecond)
+
+		start := time.Now()
+
+		var wg sync.WaitGroup
+		for n := 0; n < 4; n++ {
+			wg.Add(1)
+			go func() {
+				defer wg.Done()
+				for i := 0; i < 5; i++ {
+					limiter.wait()
+				}
+			}()
+		}
+		wg.Wait()
+
+		// the first of the 20 requests is made immediately, and the rest 10ms apart
+		u.So(t, time.Since(start), gc.ShouldBeGreaterThanOrEqualTo, 190*time.Millisecond)
+	})
+}
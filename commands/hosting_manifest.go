@@ -0,0 +1,204 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sync"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/utils"
+)
+
+// hostingManifest records the operations of a hosting import that failed, so that
+// only those can be retried with 'hosting import --resume'
+type hostingManifest struct {
+	GroupID  string                 `json:"group_id"`
+	AppID    string                 `json:"app_id"`
+	RootDir  string                 `json:"root_dir"`
+	Added    []hostingManifestEntry `json:"added,omitempty"`
+	Deleted  []hostingManifestEntry `json:"deleted,omitempty"`
+	Modified []hostingManifestEntry `json:"modified,omitempty"`
+	Renamed  []hostingManifestEntry `json:"renamed,omitempty"`
+
+	mu sync.Mutex
+}
+
+// hostingManifestEntry is a failed operation on an asset along with the error it last failed with
+type hostingManifestEntry struct {
+	Asset        hosting.AssetMetadata `json:"asset"`
+	Compression  hosting.Compression   `json:"compression,omitempty"`
+	BodyModified bool                  `json:"body_modified,omitempty"`
+	AttrModified bool                  `json:"attr_modified,omitempty"`
+	FromPath     string                `json:"from_path,omitempty"`
+	Copy         bool                  `json:"copy,omitempty"`
+	Moved        bool                  `json:"moved,omitempty"`
+	Error        string                `json:"error"`
+}
+
+func newHostingManifestEntry(am hosting.AssetMetadata, err error) hostingManifestEntry {
+	return hostingManifestEntry{
+		Asset:       am,
+		Compression: am.Compression,
+		Error:       err.Error(),
+	}
+}
+
+// hostingManifestPath returns the path of the manifest written for an import of the assets in rootDir
+func hostingManifestPath(rootDir string) string {
+	return filepath.Join(filepath.Dir(rootDir), utils.HostingImportManifestFileName)
+}
+
+func (m *hostingManifest) addAdded(am hosting.AssetMetadata, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Added = append(m.Added, newHostingManifestEntry(am, err))
+}
+
+func (m *hostingManifest) addDeleted(am hosting.AssetMetadata, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	m.Deleted = append(m.Deleted, newHostingManifestEntry(am, err))
+}
+
+func (m *hostingManifest) addModified(mAM hosting.ModifiedAssetMetadata, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := newHostingManifestEntry(mAM.AssetMetadata, err)
+	entry.BodyModified = mAM.BodyModified
+	entry.AttrModified = mAM.AttrModified
+	m.Modified = append(m.Modified, entry)
+}
+
+// addRenamed records a failed rename, which has already moved or copied the asset to its path if moved is set
+func (m *hostingManifest) addRenamed(rAM hosting.RenamedAssetMetadata, moved bool, err error) {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	entry := newHostingManifestEntry(rAM.AssetMetadata, err)
+	entry.AttrModified = rAM.AttrModified
+	entry.FromPath = rAM.FromPath
+	entry.Copy = rAM.Copy
+	entry.Moved = moved
+	m.Renamed = append(m.Renamed, entry)
+}
+
+// diffs returns the AssetMetadataDiffs that retry the recorded operations. The local files of the assets
+// are hashed again, so that those changed since the failed import are uploaded with their current contents
+// rather than moved, copied or left with the body recorded in the manifest
+func (m *hostingManifest) diffs() (*hosting.AssetMetadataDiffs, error) {
+	// rehash returns the asset of entry with the hash and size of its local file, and whether they changed
+	rehash := func(entry hostingManifestEntry) (hosting.AssetMetadata, bool, error) {
+		am := entry.Asset
+		am.Compression = entry.Compression
+
+		hash, size, err := am.Compression.HashFile(filepath.Join(m.RootDir, am.FilePath))
+		if err != nil {
+			return am, false, fmt.Errorf("failed to read '%s', import the app's hosting assets again if it was removed: %s", am.FilePath, err)
+		}
+
+		changed := hash != am.FileHash || size != am.FileSize
+		am.FileHash = hash
+		am.FileSize = size
+		return am, changed, nil
+	}
+
+	added := make([]hosting.AssetMetadata, 0, len(m.Added))
+	for _, entry := range m.Added {
+		am, _, err := rehash(entry)
+		if err != nil {
+			return nil, err
+		}
+		added = append(added, am)
+	}
+
+	deleted := make([]hosting.AssetMetadata, 0, len(m.Deleted))
+	for _, entry := range m.Deleted {
+		am := entry.Asset
+		am.Compression = entry.Compression
+		deleted = append(deleted, am)
+	}
+
+	modified := make([]hosting.ModifiedAssetMetadata, 0, len(m.Modified))
+	for _, entry := range m.Modified {
+		am, changed, err := rehash(entry)
+		if err != nil {
+			return nil, err
+		}
+		modified = append(modified, hosting.ModifiedAssetMetadata{
+			AssetMetadata: am,
+			BodyModified:  entry.BodyModified || changed,
+			AttrModified:  entry.AttrModified,
+		})
+	}
+
+	var renamed []hosting.RenamedAssetMetadata
+	for _, entry := range m.Renamed {
+		am, changed, err := rehash(entry)
+		if err != nil {
+			return nil, err
+		}
+
+		switch {
+		case entry.Moved && (changed || entry.AttrModified):
+			// the asset is already at its path, and is left to be updated
+			modified = append(modified, hosting.ModifiedAssetMetadata{
+				AssetMetadata: am,
+				BodyModified:  changed,
+				AttrModified:  entry.AttrModified,
+			})
+		case entry.Moved:
+		case changed:
+			// the file no longer matches the asset it was to be moved or copied from
+			added = append(added, am)
+			if !entry.Copy {
+				deleted = append(deleted, hosting.AssetMetadata{FilePath: entry.FromPath})
+			}
+		default:
+			renamed = append(renamed, hosting.RenamedAssetMetadata{
+				AssetMetadata: am,
+				FromPath:      entry.FromPath,
+				Copy:          entry.Copy,
+				AttrModified:  entry.AttrModified,
+			})
+		}
+	}
+
+	diffs := hosting.NewAssetMetadataDiffs(added, deleted, modified)
+	diffs.RenamedLocally = renamed
+	return diffs, nil
+}
+
+func writeHostingManifest(path string, m *hostingManifest) error {
+	m.mu.Lock()
+	defer m.mu.Unlock()
+
+	data, err := json.MarshalIndent(m, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, 0600)
+}
+
+func readHostingManifest(path string) (*hostingManifest, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	var m hostingManifest
+	if err := json.Unmarshal(data, &m); err != nil {
+		return nil, fmt.Errorf("failed to parse hosting import manifest %s: %s", path, err)
+	}
+
+	if m.GroupID == "" || m.AppID == "" || m.RootDir == "" {
+		return nil, fmt.Errorf("hosting import manifest %s is missing its group_id, app_id or root_dir", path)
+	}
+
+	return &m, nil
+}
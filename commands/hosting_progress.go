@@ -0,0 +1,275 @@
+package commands
+
+import (
+	"fmt"
+	"io"
+	"os"
+	"sync"
+	"time"
+
+	"github.com/mattn/go-isatty"
+	"github.com/mitchellh/cli"
+)
+
+const (
+	hostingProgressTTYInterval = 200 * time.Millisecond
+	hostingProgressLogInterval = 5 * time.Second
+)
+
+// hostingOpKind is the kind of request made for a hosted asset
+type hostingOpKind int
+
+const (
+	hostingOpUpload hostingOpKind = iota
+	hostingOpDelete
+	hostingOpSetAttributes
+	hostingOpDownload
+	hostingOpMove
+	hostingOpCopy
+)
+
+var hostingOpKindLabels = map[hostingOpKind]string{
+	hostingOpUpload:        "uploaded",
+	hostingOpDelete:        "deleted",
+	hostingOpSetAttributes: "attributes updated",
+	hostingOpDownload:      "downloaded",
+	hostingOpMove:          "moved",
+	hostingOpCopy:          "copied",
+}
+
+// hostingOpStats are the totals of the completed requests of a hostingOpKind
+type hostingOpStats struct {
+	count int
+	bytes int64
+
+	// duration is the time spent on the requests summed across workers, so it can exceed the time elapsed
+	duration time.Duration
+}
+
+// hostingProgress reports the progress of a set of hosted asset requests made by concurrent workers.
+// On a terminal the progress is redrawn in place on stderr, otherwise it is logged periodically
+type hostingProgress struct {
+	ui     cli.Ui
+	action string
+
+	// out and tty are where the in-place progress line is drawn and whether to draw it at all
+	out         io.Writer
+	tty         bool
+	logInterval time.Duration
+
+	totalFiles int
+	totalBytes int64
+
+	mu        sync.Mutex
+	started   time.Time
+	doneFiles int
+	doneBytes int64
+	errors    []error
+	stats     map[hostingOpKind]*hostingOpStats
+	drawn     bool
+
+	stop     chan struct{}
+	finished chan struct{}
+}
+
+// newHostingProgress returns a hostingProgress for totalFiles requests transferring totalBytes,
+// where action describes them (e.g. "Uploading hosting assets")
+func newHostingProgress(ui cli.Ui, action string, totalFiles int, totalBytes int64) *hostingProgress {
+	return &hostingProgress{
+		ui:          ui,
+		action:      action,
+		out:         os.Stderr,
+		tty:         isatty.IsTerminal(os.Stderr.Fd()),
+		logInterval: hostingProgressLogInterval,
+		totalFiles:  totalFiles,
+		totalBytes:  totalBytes,
+		stats:       map[hostingOpKind]*hostingOpStats{},
+	}
+}
+
+// start begins reporting the progress until finish is called
+func (hp *hostingProgress) start() {
+	hp.started = time.Now()
+	hp.stop = make(chan struct{})
+	hp.finished = make(chan struct{})
+
+	interval := hp.logInterval
+	if hp.tty {
+		interval = hostingProgressTTYInterval
+	}
+
+	go func() {
+		defer close(hp.finished)
+
+		ticker := time.NewTicker(interval)
+		defer ticker.Stop()
+
+		for {
+			select {
+			case <-hp.stop:
+				return
+			case <-ticker.C:
+				hp.report()
+			}
+		}
+	}()
+}
+
+// record accounts for a completed request of the given kind, printing its error if it failed
+func (hp *hostingProgress) record(kind hostingOpKind, bytes int64, duration time.Duration, err error) {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	hp.doneFiles++
+
+	if err != nil {
+		hp.errors = append(hp.errors, err)
+		hp.clearLine()
+		hp.ui.Error(err.Error())
+		return
+	}
+
+	hp.doneBytes += bytes
+
+	stats, ok := hp.stats[kind]
+	if !ok {
+		stats = &hostingOpStats{}
+		hp.stats[kind] = stats
+	}
+	stats.count++
+	stats.bytes += bytes
+	stats.duration += duration
+}
+
+// finish stops reporting the progress and prints a summary of the completed requests
+func (hp *hostingProgress) finish() {
+	close(hp.stop)
+	<-hp.finished
+
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	hp.clearLine()
+
+	if hp.totalFiles == 0 {
+		return
+	}
+
+	for _, line := range hp.summary(time.Since(hp.started)) {
+		hp.ui.Info(line)
+	}
+}
+
+func (hp *hostingProgress) report() {
+	hp.mu.Lock()
+	defer hp.mu.Unlock()
+
+	line := hp.status(time.Since(hp.started))
+	if hp.tty {
+		fmt.Fprintf(hp.out, "\r\033[K%s", line)
+		hp.drawn = true
+		return
+	}
+	hp.ui.Info(line)
+}
+
+// clearLine erases the in-place progress line so that other output is not appended to it
+func (hp *hostingProgress) clearLine() {
+	if hp.drawn {
+		fmt.Fprint(hp.out, "\r\033[K")
+		hp.drawn = false
+	}
+}
+
+// status returns the progress line after elapsed, e.g.
+// "Uploading hosting assets: 12/40 files, 1.2 MB/3.4 MB (350.0 KB/s), ETA 6s"
+func (hp *hostingProgress) status(elapsed time.Duration) string {
+	line := fmt.Sprintf("%s: %d/%d files", hp.action, hp.doneFiles, hp.totalFiles)
+
+	if hp.totalBytes > 0 {
+		line += fmt.Sprintf(", %s/%s", formatBytes(hp.doneBytes), formatBytes(hp.totalBytes))
+		if elapsed > 0 {
+			line += fmt.Sprintf(" (%s/s)", formatBytes(int64(float64(hp.doneBytes)/elapsed.Seconds())))
+		}
+	}
+
+	if eta, ok := hp.eta(elapsed); ok {
+		line += fmt.Sprintf(", ETA %s", eta)
+	}
+
+	return line
+}
+
+// eta estimates the time remaining from the bytes transferred so far or, when there are none, the files done
+func (hp *hostingProgress) eta(elapsed time.Duration) (time.Duration, bool) {
+	var done, total float64
+	if hp.totalBytes > 0 && hp.doneBytes > 0 {
+		done, total = float64(hp.doneBytes), float64(hp.totalBytes)
+	} else if hp.doneFiles > 0 {
+		done, total = float64(hp.doneFiles), float64(hp.totalFiles)
+	} else {
+		return 0, false
+	}
+
+	remaining := time.Duration(float64(elapsed) * (total - done) / done)
+	if remaining < 0 {
+		remaining = 0
+	}
+	return remaining.Round(time.Second), true
+}
+
+// summary returns the lines describing the completed requests, grouped by kind. The time of each kind
+// is the worker time spent on its requests, which are made concurrently, rather than the time elapsed
+func (hp *hostingProgress) summary(elapsed time.Duration) []string {
+	header := fmt.Sprintf("%s finished in %s", hp.action, formatDuration(elapsed))
+	if hp.doneBytes > 0 {
+		header += fmt.Sprintf(" (%s transferred)", formatBytes(hp.doneBytes))
+	}
+
+	lines := []string{header + ":"}
+	for _, kind := range []hostingOpKind{hostingOpUpload, hostingOpDownload, hostingOpMove, hostingOpCopy, hostingOpDelete, hostingOpSetAttributes} {
+		stats, ok := hp.stats[kind]
+		if !ok {
+			continue
+		}
+
+		line := fmt.Sprintf("\t%s: %d", hostingOpKindLabels[kind], stats.count)
+		if stats.bytes > 0 {
+			line += fmt.Sprintf(" (%s)", formatBytes(stats.bytes))
+		}
+		lines = append(lines, line+fmt.Sprintf(" in %s of worker time", formatDuration(stats.duration)))
+	}
+
+	if len(hp.errors) > 0 {
+		lines = append(lines, fmt.Sprintf("\tfailed: %d", len(hp.errors)))
+	}
+
+	return lines
+}
+
+// formatBytes returns n as a human readable size, e.g. "1.5 MB"
+func formatBytes(n int64) string {
+	const unit = 1024
+	if n < unit {
+		return fmt.Sprintf("%d B", n)
+	}
+
+	div, exp := int64(unit), 0
+	for m := n / unit; m >= unit; m /= unit {
+		div *= unit
+		exp++
+	}
+	return fmt.Sprintf("%.1f %cB", float64(n)/float64(div), "KMGTPE"[exp])
+}
+
+// formatDuration returns d rounded to a readable precision, e.g. "1.25s" or "350ms"
+func formatDuration(d time.Duration) string {
+	switch {
+	case d >= time.Minute:
+		return d.Round(time.Second).String()
+	case d >= time.Second:
+		return d.Round(10 * time.Millisecond).String()
+	default:
+		return d.Round(time.Millisecond).String()
+	}
+}
@@ -0,0 +1,118 @@
+package commands
+
+import (
+	"bytes"
+	"errors"
+	"strings"
+	"testing"
+	"time"
+
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostingProgress(t *testing.T) {
+	t.Run("should describe the progress so far", func(t *testing.T) {
+		progress := newHostingProgress(cli.NewMockUi(), "Importing hosting assets", 4, 4096)
+		u.So(t, progress.status(0), gc.ShouldEqual, "Importing hosting assets: 0/4 files, 0 B/4.0 KB")
+
+		progress.record(hostingOpUpload, 1024, time.Second, nil)
+		u.So(t, progress.status(2*time.Second), gc.ShouldEqual, "Importing hosting assets: 1/4 files, 1.0 KB/4.0 KB (512 B/s), ETA 6s")
+	})
+
+	t.Run("should estimate the time remaining from files when there are no bytes", func(t *testing.T) {
+		progress := newHostingProgress(cli.NewMockUi(), "Importing hosting assets", 3, 0)
+		progress.record(hostingOpDelete, 0, time.Second, nil)
+		u.So(t, progress.status(time.Second), gc.ShouldEqual, "Importing hosting assets: 1/3 files, ETA 2s")
+	})
+
+	t.Run("should summarize the completed requests by kind", func(t *testing.T) {
+		progress := newHostingProgress(cli.NewMockUi(), "Importing hosting assets", 5, 3072)
+		progress.record(hostingOpUpload, 1024, 250*time.Millisecond, nil)
+		progress.record(hostingOpUpload, 2048, 1500*time.Millisecond, nil)
+		progress.record(hostingOpDelete, 0, 100*time.Millisecond, nil)
+		progress.record(hostingOpSetAttributes, 0, 50*time.Millisecond, nil)
+		progress.record(hostingOpUpload, 512, time.Second, errors.New("something went wrong"))
+
+		u.So(t, progress.summary(90*time.Second), gc.ShouldResemble, []string{
+			"Importing hosting assets finished in 1m30s (3.0 KB transferred):",
+			"\tuploaded: 2 (3.0 KB) in 1.75s of worker time",
+			"\tdeleted: 1 in 100ms of worker time",
+			"\tattributes updated: 1 in 50ms of worker time",
+			"\tfailed: 1",
+		})
+	})
+
+	t.Run("should print errors as they occur and the summary when finished", func(t *testing.T) {
+		mockUI := cli.NewMockUi()
+		progress := newHostingProgress(mockUI, "Exporting hosting assets", 2, 10)
+		progress.tty = false
+		progress.start()
+
+		progress.record(hostingOpDownload, 10, time.Millisecond, nil)
+		progress.record(hostingOpDownload, 0, time.Millisecond, errors.New("download failed"))
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldEqual, "download failed\n")
+
+		progress.finish()
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, "Exporting hosting assets finished in ")
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "\tdownloaded: 1 (10 B) in 1ms of worker time\n\tfailed: 1\n")
+	})
+
+	t.Run("should log the progress periodically when not on a terminal", func(t *testing.T) {
+		mockUI := cli.NewMockUi()
+		progress := newHostingProgress(mockUI, "Importing hosting assets", 1, 0)
+		progress.tty = false
+		progress.logInterval = 10 * time.Millisecond
+		progress.start()
+
+		time.Sleep(50 * time.Millisecond)
+		progress.finish()
+
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldStartWith, "Importing hosting assets: 0/1 files\n")
+	})
+
+	t.Run("should redraw the progress in place on a terminal", func(t *testing.T) {
+		mockUI := cli.NewMockUi()
+		out := new(bytes.Buffer)
+
+		progress := newHostingProgress(mockUI, "Importing hosting assets", 1, 0)
+		progress.tty = true
+		progress.out = out
+		progress.start()
+
+		time.Sleep(2 * hostingProgressTTYInterval)
+		progress.finish()
+
+		u.So(t, out.String(), gc.ShouldStartWith, "\r\033[KImporting hosting assets: 0/1 files")
+		u.So(t, out.String(), gc.ShouldEndWith, "\r\033[K")
+		u.So(t, strings.Contains(mockUI.OutputWriter.String(), "0/1 files"), gc.ShouldBeFalse)
+	})
+
+	t.Run("should not print a summary when there was nothing to do", func(t *testing.T) {
+		mockUI := cli.NewMockUi()
+		progress := newHostingProgress(mockUI, "Importing hosting assets", 0, 0)
+		progress.tty = false
+		progress.start()
+		progress.finish()
+
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldBeEmpty)
+	})
+}
+
+func TestFormatBytes(t *testing.T) {
+	for _, tc := range []struct {
+		n        int64
+		expected string
+	}{
+		{0, "0 B"},
+		{1023, "1023 B"},
+		{1024, "1.0 KB"},
+		{1536, "1.5 KB"},
+		{5 * 1024 * 1024, "5.0 MB"},
+		{3 * 1024 * 1024 * 1024, "3.0 GB"},
+	} {
+		u.So(t, formatBytes(tc.n), gc.ShouldEqual, tc.expected)
+	}
+}
@@ -0,0 +1,178 @@
+package commands
+
+import (
+	"fmt"
+	"net/http"
+	"os"
+	"path/filepath"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/utils"
+
+	"github.com/mitchellh/cli"
+)
+
+const (
+	flagHostingPort = "port"
+
+	defaultHostingServePort = 8080
+)
+
+// NewHostingServeCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewHostingServeCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &HostingServeCommand{
+			BaseCommand: &BaseCommand{
+				Name: "serve",
+				UI:   ui,
+			},
+			workingDirectory: workingDirectory,
+			listenAndServe:   http.ListenAndServe,
+		}, nil
+	}
+}
+
+// HostingServeCommand is used to preview the hosted assets of a Realm app over a local HTTP server
+type HostingServeCommand struct {
+	*BaseCommand
+
+	workingDirectory string
+	listenAndServe   func(addr string, handler http.Handler) error
+
+	flagPath     string
+	flagPort     int
+	flagExcludes utils.StringSliceFlag
+}
+
+// Synopsis returns a one-liner description for this command
+func (hsc *HostingServeCommand) Synopsis() string {
+	return "Preview the hosted assets of your Realm App over a local HTTP server."
+}
+
+// Help returns long-form help information for this command
+func (hsc *HostingServeCommand) Help() string {
+	return `Preview the hosted assets of your Realm App over a local HTTP server, before deploying them.
+Every file is served with the attributes it would be hosted with as headers, such as its Content-Type
+and Cache-Control, and a file with a Website-Redirect-Location redirects to that location.
+A path without a file is answered with the "default_error_path" of "hosting/config.json", if it is set,
+with its "default_response_code". Changes to "metadata.json" and "config.json" are picked up when the
+server is restarted.
+
+Usage: realm-cli hosting serve [options]
+
+OPTIONS:
+  --path [string]
+	The local directory of the assets to serve. The attributes of the assets are read from a
+	"metadata.json" file next to this directory, if there is one. Defaults to the "hosting/files"
+	directory of the realm project directory the command is run from.
+
+  --port [int]
+	The port on localhost to serve the assets on. Defaults to 8080.
+
+  --exclude [string]
+	A gitignore-style pattern of files in the local directory not to serve, in addition to those listed
+	in its ".realmignore" file. May be provided multiple times.
+` +
+		hsc.BaseCommand.Help()
+}
+
+// Run executes the command
+func (hsc *HostingServeCommand) Run(args []string) int {
+	flags := hsc.NewFlagSet()
+
+	flags.StringVar(&hsc.flagPath, flagHostingPath, "", "")
+	flags.IntVar(&hsc.flagPort, flagHostingPort, defaultHostingServePort, "")
+	flags.Var(&hsc.flagExcludes, importFlagExclude, "")
+
+	if err := hsc.BaseCommand.run(args); err != nil {
+		hsc.UI.Error(err.Error())
+		return 1
+	}
+
+	handler, rootDir, err := hsc.handler()
+	if err != nil {
+		hsc.UI.Error(err.Error())
+		return 1
+	}
+
+	addr := fmt.Sprintf("localhost:%d", hsc.flagPort)
+	hsc.UI.Info(fmt.Sprintf("Serving %s on http://%s, press Ctrl+C to stop", rootDir, addr))
+
+	if err := hsc.listenAndServe(addr, handler); err != nil {
+		hsc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+// handler returns the http.Handler serving the hosting files directory, along with the path of that directory
+func (hsc *HostingServeCommand) handler() (http.Handler, string, error) {
+	if hsc.flagPort < 1 || hsc.flagPort > 65535 {
+		return nil, "", fmt.Errorf("--%s must be between 1 and 65535, but was %d", flagHostingPort, hsc.flagPort)
+	}
+
+	rootDir := hsc.flagPath
+	if rootDir == "" {
+		appPath, err := utils.ResolveAppDirectory("", hsc.workingDirectory)
+		if err != nil {
+			return nil, "", err
+		}
+		rootDir = filepath.Join(appPath, utils.HostingFilesDirectory)
+	} else if !filepath.IsAbs(rootDir) {
+		rootDir = filepath.Join(hsc.workingDirectory, rootDir)
+	}
+
+	info, err := os.Stat(rootDir)
+	if err != nil {
+		return nil, "", err
+	}
+	if !info.IsDir() {
+		return nil, "", fmt.Errorf("%s is not a directory", rootDir)
+	}
+
+	assetDescs, attributeRules, err := loadLocalHostingMetadata(localHostingMetadataPath(rootDir))
+	if err != nil {
+		return nil, "", err
+	}
+
+	ignoreRules, err := hosting.LoadIgnoreRules(rootDir, hsc.flagExcludes)
+	if err != nil {
+		return nil, "", fmt.Errorf("error loading %s file: %v", hosting.IgnoreFileName, err)
+	}
+
+	// like the metadata, the hosting config is next to the hosting files directory of an app
+	var hostingConfig *utils.HostingConfig
+	if hostingDir := filepath.Dir(rootDir); filepath.Base(hostingDir) == utils.HostingRoot {
+		if hostingConfig, err = utils.ReadHostingConfig(filepath.Dir(hostingDir)); err != nil {
+			return nil, "", err
+		}
+	}
+
+	return hsc.logRequests(hosting.NewPreviewHandler(rootDir, assetDescs, attributeRules, ignoreRules, hostingConfig)), rootDir, nil
+}
+
+// logRequests wraps handler to print the method, path and status of every request it serves
+func (hsc *HostingServeCommand) logRequests(handler http.Handler) http.Handler {
+	return http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		sw := &statusResponseWriter{ResponseWriter: w, status: http.StatusOK}
+		handler.ServeHTTP(sw, r)
+		hsc.UI.Info(fmt.Sprintf("%s %s %d", r.Method, r.URL.Path, sw.status))
+	})
+}
+
+// statusResponseWriter is an http.ResponseWriter that records the status of the response
+type statusResponseWriter struct {
+	http.ResponseWriter
+	status int
+}
+
+func (sw *statusResponseWriter) WriteHeader(status int) {
+	sw.status = status
+	sw.ResponseWriter.WriteHeader(status)
+}
@@ -0,0 +1,91 @@
+package commands
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestHostingServeCommand(t *testing.T) {
+	appDir, err := filepath.Abs("../testdata/full_app")
+	u.So(t, err, gc.ShouldBeNil)
+
+	// serve runs the command, returning the handler it would have served on the address it would have listened on
+	serve := func(args ...string) (int, string, http.Handler, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingServeCommandFactory(mockUI)()
+		u.So(t, err, gc.ShouldBeNil)
+
+		var servedAddr string
+		var servedHandler http.Handler
+
+		serveCommand := cmd.(*HostingServeCommand)
+		serveCommand.workingDirectory = appDir
+		serveCommand.storage = u.NewEmptyStorage()
+		serveCommand.listenAndServe = func(addr string, handler http.Handler) error {
+			servedAddr, servedHandler = addr, handler
+			return nil
+		}
+
+		exitCode := serveCommand.Run(args)
+		return exitCode, servedAddr, servedHandler, mockUI
+	}
+
+	t.Run("should reject an invalid port", func(t *testing.T) {
+		exitCode, _, _, mockUI := serve("--port=0")
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "--port must be between 1 and 65535, but was 0")
+	})
+
+	t.Run("should reject a path that is not a directory", func(t *testing.T) {
+		exitCode, _, _, mockUI := serve("--path=hosting/metadata.json")
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "is not a directory")
+	})
+
+	t.Run("should serve the hosting files of the app directory and log the requests", func(t *testing.T) {
+		exitCode, addr, handler, mockUI := serve("--port=9090")
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, addr, gc.ShouldEqual, "localhost:9090")
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "Serving "+filepath.Join(appDir, "hosting", "files")+" on http://localhost:9090")
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/asset_file0.json", nil))
+		u.So(t, w.Code, gc.ShouldEqual, http.StatusOK)
+		u.So(t, w.Header().Get("Content-Type"), gc.ShouldEqual, "application/json")
+
+		w = httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/missing.html", nil))
+		u.So(t, w.Code, gc.ShouldEqual, http.StatusNotFound)
+
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldEndWith, "GET /asset_file0.json 200\nGET /missing.html 404\n")
+	})
+
+	t.Run("should serve the default error path of the hosting config for missing paths", func(t *testing.T) {
+		dir := copyTestApp(t, "../testdata/full_app")
+		defer os.RemoveAll(dir)
+		u.So(t, ioutil.WriteFile(filepath.Join(dir, utils.HostingConfigPath), []byte(`{"default_error_path": "/asset_file1.html", "default_response_code": 200}`), 0600), gc.ShouldBeNil)
+
+		exitCode, _, handler, mockUI := serve("--path=" + filepath.Join(dir, utils.HostingFilesDirectory))
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+
+		expected, err := ioutil.ReadFile(filepath.Join(dir, utils.HostingFilesDirectory, "asset_file1.html"))
+		u.So(t, err, gc.ShouldBeNil)
+
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/app/settings", nil))
+		u.So(t, w.Code, gc.ShouldEqual, http.StatusOK)
+		u.So(t, w.Body.String(), gc.ShouldEqual, string(expected))
+	})
+}
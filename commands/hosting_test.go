@@ -0,0 +1,442 @@
+package commands
+
+import (
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/models"
+	"github.com/10gen/realm-cli/user"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/mitchellh/cli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func setUpBasicHostingCommand(baseCommand *HostingBaseCommand, realmClient *u.MockRealmClient) {
+	baseCommand.storage = u.NewEmptyStorage()
+
+	if realmClient == nil {
+		realmClient = &u.MockRealmClient{}
+	}
+	realmClient.FetchAppByClientAppIDFn = func(clientAppID string) (*models.App, error) {
+		return &models.App{
+			GroupID: "group-id",
+			ID:      "app-id",
+		}, nil
+	}
+	baseCommand.realmClient = realmClient
+}
+
+func logInHostingCommand(baseCommand *HostingBaseCommand) {
+	baseCommand.user = &user.User{
+		APIKey:      "my-api-key",
+		AccessToken: u.GenerateValidAccessToken(),
+	}
+}
+
+func TestHostingInvalidateCommand(t *testing.T) {
+	setup := func(realmClient *u.MockRealmClient) (*HostingInvalidateCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingInvalidateCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		invalidateCommand := cmd.(*HostingInvalidateCommand)
+		setUpBasicHostingCommand(invalidateCommand.HostingBaseCommand, realmClient)
+		return invalidateCommand, mockUI
+	}
+
+	t.Run("should require the user to be logged in", func(t *testing.T) {
+		invalidateCommand, mockUI := setup(nil)
+
+		exitCode := invalidateCommand.Run([]string{"--app-id=my-app-abcdef"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, user.ErrNotLoggedIn.Error())
+	})
+
+	for _, tc := range []struct {
+		description string
+		args        []string
+		expected    []string
+	}{
+		{
+			description: "should invalidate every asset by default",
+			args:        []string{"--app-id=my-app-abcdef"},
+			expected:    []string{"/*"},
+		},
+		{
+			description: "should invalidate each of the given paths",
+			args:        []string{"--app-id=my-app-abcdef", "--path=/index.html", "--path=/images/*"},
+			expected:    []string{"/index.html", "/images/*"},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			var invalidated []string
+			invalidateCommand, mockUI := setup(&u.MockRealmClient{
+				InvalidateCacheFn: func(groupID, appID, path string) error {
+					u.So(t, groupID, gc.ShouldEqual, "group-id")
+					u.So(t, appID, gc.ShouldEqual, "app-id")
+					invalidated = append(invalidated, path)
+					return nil
+				},
+			})
+			logInHostingCommand(invalidateCommand.HostingBaseCommand)
+
+			exitCode := invalidateCommand.Run(tc.args)
+			u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+			u.So(t, exitCode, gc.ShouldEqual, 0)
+			u.So(t, invalidated, gc.ShouldResemble, tc.expected)
+			for _, path := range tc.expected {
+				u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "Invalidated CDN cache for "+path)
+			}
+		})
+	}
+
+	t.Run("should reject paths not starting with a slash", func(t *testing.T) {
+		invalidateCommand, mockUI := setup(&u.MockRealmClient{
+			InvalidateCacheFn: func(groupID, appID, path string) error {
+				t.Errorf("unexpected invalidation of %s", path)
+				return nil
+			},
+		})
+		logInHostingCommand(invalidateCommand.HostingBaseCommand)
+
+		exitCode := invalidateCommand.Run([]string{"--app-id=my-app-abcdef", "--path=index.html"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, `path "index.html" must start with '/'`)
+	})
+}
+
+var testHostedAssets = []hosting.AssetMetadata{
+	{FilePath: "/index.html", FileHash: "h1", FileSize: 10, Attrs: []hosting.AssetAttribute{{Name: "Content-Type", Value: "text/html"}}},
+	{FilePath: "/images/"},
+	{FilePath: "/images/a.png", FileHash: "h2", FileSize: 20},
+	{FilePath: "/images/b.png", FileHash: "h3", FileSize: 30, Attrs: []hosting.AssetAttribute{
+		{Name: "Content-Type", Value: "image/png"},
+		{Name: "Cache-Control", Value: "no-cache"},
+	}},
+	{FilePath: "/js/app.js", FileHash: "h4", FileSize: 40},
+}
+
+func listTestHostedAssets(groupID, appID string) ([]hosting.AssetMetadata, error) {
+	return testHostedAssets, nil
+}
+
+func TestHostingListCommand(t *testing.T) {
+	setup := func() (*HostingListCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingListCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		listCommand := cmd.(*HostingListCommand)
+		setUpBasicHostingCommand(listCommand.HostingBaseCommand, &u.MockRealmClient{
+			ListAssetsForAppIDFn: listTestHostedAssets,
+		})
+		logInHostingCommand(listCommand.HostingBaseCommand)
+		return listCommand, mockUI
+	}
+
+	t.Run("should list the assets as a tree", func(t *testing.T) {
+		listCommand, mockUI := setup()
+
+		exitCode := listCommand.Run([]string{"--app-id=my-app-abcdef"})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldEqual, `/
+├── images/
+│   ├── a.png
+│   └── b.png
+├── index.html
+└── js/
+    └── app.js
+`)
+	})
+
+	t.Run("should list the assets under a path with their size, hash and attributes", func(t *testing.T) {
+		listCommand, mockUI := setup()
+
+		exitCode := listCommand.Run([]string{"--app-id=my-app-abcdef", "--path=/images", "--long"})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldEqual, `SIZE  HASH  PATH           ATTRIBUTES
+20    h2    /images/a.png
+30    h3    /images/b.png  Cache-Control=no-cache, Content-Type=image/png
+`)
+	})
+}
+
+func TestHostingCopyAndMoveCommands(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		factory     func(ui cli.Ui) cli.CommandFactory
+		expected    string
+	}{
+		{"cp should copy the asset", NewHostingCopyCommandFactory, "Copied /index.html to /home.html"},
+		{"mv should move the asset", NewHostingMoveCommandFactory, "Moved /index.html to /home.html"},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			var calls [][]string
+			record := func(kind string) func(groupID, appID, fromPath, toPath string) error {
+				return func(groupID, appID, fromPath, toPath string) error {
+					calls = append(calls, []string{kind, fromPath, toPath})
+					return nil
+				}
+			}
+			realmClient := &u.MockRealmClient{CopyAssetFn: record("copy"), MoveAssetFn: record("move")}
+
+			mockUI := cli.NewMockUi()
+			cmd, err := tc.factory(mockUI)()
+			u.So(t, err, gc.ShouldBeNil)
+
+			var baseCommand *HostingBaseCommand
+			switch c := cmd.(type) {
+			case *HostingCopyCommand:
+				baseCommand = c.HostingBaseCommand
+			case *HostingMoveCommand:
+				baseCommand = c.HostingBaseCommand
+			}
+			setUpBasicHostingCommand(baseCommand, realmClient)
+			logInHostingCommand(baseCommand)
+
+			exitCode := cmd.Run([]string{"--app-id=my-app-abcdef", "--from=/index.html"})
+			u.So(t, exitCode, gc.ShouldEqual, 1)
+			u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, errHostingToRequired.Error())
+			u.So(t, calls, gc.ShouldBeEmpty)
+
+			mockUI.ErrorWriter.Reset()
+			exitCode = cmd.Run([]string{"--app-id=my-app-abcdef", "--from=/index.html", "--to=/home.html"})
+			u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+			u.So(t, exitCode, gc.ShouldEqual, 0)
+			u.So(t, calls, gc.ShouldHaveLength, 1)
+			u.So(t, calls[0][1:], gc.ShouldResemble, []string{"/index.html", "/home.html"})
+			u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, tc.expected)
+		})
+	}
+}
+
+func TestHostingRemoveCommand(t *testing.T) {
+	setup := func(deleted *[]string) (*HostingRemoveCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingRemoveCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		removeCommand := cmd.(*HostingRemoveCommand)
+		setUpBasicHostingCommand(removeCommand.HostingBaseCommand, &u.MockRealmClient{
+			ListAssetsForAppIDFn: listTestHostedAssets,
+			DeleteAssetFn: func(groupID, appID, path string) error {
+				*deleted = append(*deleted, path)
+				return nil
+			},
+		})
+		logInHostingCommand(removeCommand.HostingBaseCommand)
+		return removeCommand, mockUI
+	}
+
+	t.Run("should remove the assets matching each path", func(t *testing.T) {
+		var deleted []string
+		removeCommand, mockUI := setup(&deleted)
+
+		exitCode := removeCommand.Run([]string{"--app-id=my-app-abcdef", "--path=/images/*.png", "--path=/js/app.js", "-y"})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, deleted, gc.ShouldResemble, []string{"/images/a.png", "/images/b.png", "/js/app.js"})
+	})
+
+	t.Run("should list the matching assets and remove them once confirmed", func(t *testing.T) {
+		var deleted []string
+		removeCommand, mockUI := setup(&deleted)
+		mockUI.InputReader = strings.NewReader("y\n")
+
+		exitCode := removeCommand.Run([]string{"--app-id=my-app-abcdef", "--path=/images/*.png"})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "The following hosted assets will be removed:\n\t/images/a.png\n\t/images/b.png\n")
+		u.So(t, deleted, gc.ShouldResemble, []string{"/images/a.png", "/images/b.png"})
+	})
+
+	t.Run("should not remove anything if not confirmed", func(t *testing.T) {
+		var deleted []string
+		removeCommand, mockUI := setup(&deleted)
+		mockUI.InputReader = strings.NewReader("n\n")
+
+		exitCode := removeCommand.Run([]string{"--app-id=my-app-abcdef", "--path=/images/*.png"})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "/images/a.png")
+		u.So(t, deleted, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should fail without removing anything if a path matches no assets", func(t *testing.T) {
+		var deleted []string
+		removeCommand, mockUI := setup(&deleted)
+
+		exitCode := removeCommand.Run([]string{"--app-id=my-app-abcdef", "--path=/js/app.js", "--path=/css/*"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, `no hosted assets found matching "/css/*"`)
+		u.So(t, deleted, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should require a path", func(t *testing.T) {
+		var deleted []string
+		removeCommand, mockUI := setup(&deleted)
+
+		exitCode := removeCommand.Run([]string{"--app-id=my-app-abcdef"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, errHostingPathRequired.Error())
+	})
+}
+
+func TestHostingSetAttrCommand(t *testing.T) {
+	setup := func(set map[string][]hosting.AssetAttribute) (*HostingSetAttrCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingSetAttrCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		setAttrCommand := cmd.(*HostingSetAttrCommand)
+		setUpBasicHostingCommand(setAttrCommand.HostingBaseCommand, &u.MockRealmClient{
+			ListAssetsForAppIDFn: listTestHostedAssets,
+			SetAssetAttributesFn: func(groupID, appID, path string, attributes ...hosting.AssetAttribute) error {
+				set[path] = attributes
+				return nil
+			},
+		})
+		logInHostingCommand(setAttrCommand.HostingBaseCommand)
+		return setAttrCommand, mockUI
+	}
+
+	t.Run("should merge the attributes with the existing ones", func(t *testing.T) {
+		set := map[string][]hosting.AssetAttribute{}
+		setAttrCommand, mockUI := setup(set)
+
+		exitCode := setAttrCommand.Run([]string{
+			"--app-id=my-app-abcdef",
+			"--path=/images/b.png",
+			"--attr=cache-control=max-age=60",
+			"--attr=Content-Language=fr",
+			"--unset=Content-Type",
+		})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, set, gc.ShouldResemble, map[string][]hosting.AssetAttribute{
+			"/images/b.png": {
+				{Name: "Cache-Control", Value: "max-age=60"},
+				{Name: "Content-Language", Value: "fr"},
+			},
+		})
+	})
+
+	t.Run("should reject unsupported attributes", func(t *testing.T) {
+		set := map[string][]hosting.AssetAttribute{}
+		setAttrCommand, mockUI := setup(set)
+
+		exitCode := setAttrCommand.Run([]string{"--app-id=my-app-abcdef", "--path=/index.html", "--attr=X-Custom=1"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, `unsupported attribute "X-Custom"`)
+		u.So(t, set, gc.ShouldBeEmpty)
+	})
+}
+
+func TestHostingUploadCommand(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-hosting-upload")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	for name, contents := range map[string]string{
+		"index.html": "<html></html>",
+		"a.js":       "var a;",
+		"b.js":       "var b;",
+		"lib/a.js":   "var libA;",
+	} {
+		u.So(t, os.MkdirAll(filepath.Dir(filepath.Join(dir, name)), 0755), gc.ShouldBeNil)
+		u.So(t, ioutil.WriteFile(filepath.Join(dir, name), []byte(contents), 0600), gc.ShouldBeNil)
+	}
+
+	type upload struct {
+		path  string
+		body  string
+		attrs []hosting.AssetAttribute
+	}
+
+	setup := func(uploads *[]upload) (*HostingUploadCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewHostingUploadCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		uploadCommand := cmd.(*HostingUploadCommand)
+		uploadCommand.workingDirectory = dir
+		setUpBasicHostingCommand(uploadCommand.HostingBaseCommand, &u.MockRealmClient{
+			UploadAssetFn: func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error {
+				data, err := ioutil.ReadAll(body)
+				if err != nil {
+					return err
+				}
+				*uploads = append(*uploads, upload{path, string(data), attributes})
+				return nil
+			},
+		})
+		logInHostingCommand(uploadCommand.HostingBaseCommand)
+		return uploadCommand, mockUI
+	}
+
+	t.Run("should upload a single file to the given path", func(t *testing.T) {
+		var uploads []upload
+		uploadCommand, mockUI := setup(&uploads)
+
+		exitCode := uploadCommand.Run([]string{"--app-id=my-app-abcdef", "--file=index.html", "--path=/home.html", "--attr=Cache-Control=no-cache"})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, uploads, gc.ShouldResemble, []upload{
+			{"/home.html", "<html></html>", []hosting.AssetAttribute{
+				{Name: "Cache-Control", Value: "no-cache"},
+				{Name: "Content-Type", Value: "text/html"},
+			}},
+		})
+	})
+
+	t.Run("should upload the files matching a glob into a directory", func(t *testing.T) {
+		var uploads []upload
+		uploadCommand, mockUI := setup(&uploads)
+
+		exitCode := uploadCommand.Run([]string{"--app-id=my-app-abcdef", "--file=*.js", "--path=/js"})
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, uploads, gc.ShouldHaveLength, 2)
+		u.So(t, uploads[0].path, gc.ShouldEqual, "/js/a.js")
+		u.So(t, uploads[0].body, gc.ShouldEqual, "var a;")
+		u.So(t, uploads[1].path, gc.ShouldEqual, "/js/b.js")
+	})
+
+	t.Run("should fail before uploading anything if files would be uploaded to the same path", func(t *testing.T) {
+		var uploads []upload
+		uploadCommand, mockUI := setup(&uploads)
+
+		exitCode := uploadCommand.Run([]string{"--app-id=my-app-abcdef", "--file=*.js", "--file=lib/*.js", "--path=/js"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, filepath.Join(dir, "a.js")+" and "+filepath.Join(dir, "lib", "a.js")+" would both be uploaded to /js/a.js")
+		u.So(t, uploads, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should fail if no files match", func(t *testing.T) {
+		var uploads []upload
+		uploadCommand, mockUI := setup(&uploads)
+
+		exitCode := uploadCommand.Run([]string{"--app-id=my-app-abcdef", "--file=*.css"})
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "no files found matching")
+		u.So(t, uploads, gc.ShouldBeEmpty)
+	})
+}
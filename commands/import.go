@@ -11,12 +11,14 @@ import (
 	"time"
 
 	"github.com/10gen/realm-cli/api"
+	"github.com/10gen/realm-cli/dependency/transpiler"
 	"github.com/10gen/realm-cli/hosting"
 	"github.com/10gen/realm-cli/models"
 	u "github.com/10gen/realm-cli/user"
 	"github.com/10gen/realm-cli/utils"
 
 	"github.com/mitchellh/cli"
+	"github.com/mitchellh/go-homedir"
 )
 
 const (
@@ -25,10 +27,18 @@ const (
 	importFlagAppName             = "app-name"
 	importFlagIncludeHosting      = "include-hosting"
 	importFlagResetCDNCache       = "reset-cdn-cache"
+	importFlagResetCDNThreshold   = "reset-cdn-cache-threshold"
 	importStrategyMerge           = "merge"
 	importStrategyReplace         = "replace"
 	importStrategyReplaceByName   = "replace-by-name"
 	importFlagIncludeDependencies = "include-dependencies"
+	importFlagTranspiler          = "transpiler"
+	importFlagVar                 = "var"
+	importFlagVarsFile            = "vars-file"
+	importFlagPlanFile            = "plan-file"
+	importFlagExclude             = "exclude"
+	importFlagCompress            = "compress"
+	importFlagVerify              = "verify"
 )
 
 // Set of location and deployment model options supported by Realm backend
@@ -64,6 +74,7 @@ func NewImportCommandFactory(ui cli.Ui) cli.CommandFactory {
 			},
 			workingDirectory: workingDirectory,
 			writeToDirectory: utils.WriteZipToDir,
+			flagVars:         utils.Variables{},
 			writeAppConfigToFile: func(dest string, app models.AppInstanceData) error {
 				return app.MarshalFile(dest)
 			},
@@ -86,13 +97,29 @@ type ImportCommand struct {
 	flagStrategy            string
 	flagIncludeHosting      bool
 	flagResetCDNCache       bool
+	flagResetCDNThreshold   int
 	flagIncludeDependencies bool
+	flagTranspiler          string
+	flagVars                utils.Variables
+	flagVarsFile            string
+	flagPlanFile            string
+	flagOutput              string
+	flagExcludes            utils.StringSliceFlag
+	flagCompress            string
+	flagVerify              bool
+	flagHostingLimits       HostingLimits
+	flagHostingAssets       hostingAssetOptions
+	flagTranspileOptions    DependencyTranspileOptions
 }
 
 // Help returns long-form help information for this command
 func (ic *ImportCommand) Help() string {
 	return `Import and deploy a realm application from a local directory.
 
+The file served by hosting for paths without a file is set by the "default_error_path" of "/hosting/config.json",
+which must be a file in "/hosting/files", such as "/index.html" for a single-page app or "/404.html" for a custom
+not found page. Its "default_response_code" is either 200 or 404 (the default).
+
 REQUIRED:
   --app-id [string]
 	The App ID for your app (i.e. the name of your app followed by a unique suffix, like "my-app-nysja").
@@ -114,15 +141,82 @@ OPTIONS:
 	replace-by-name - like replace, but uses resource names instead of _id's for identity resolution
 
   --include-hosting
-	Upload static assets from "/hosting" directory.
+	Upload static assets from "/hosting" directory. Entries of "/hosting/metadata.json" whose path is a glob
+	(e.g. "/static/**/*.js") set the attributes of every matching file that has no entry of its own.
+	Operations that still fail after being retried are recorded in "/hosting/.import-failures.json",
+	which 'hosting import --resume' retries. A file with the same contents as a hosted asset is copied
+	from that asset rather than uploaded. With --strategy=replace the asset is moved instead if it is
+	missing from the local directory, whereas with the default merge strategy a renamed file is copied
+	and its old path is kept.
+
+  --exclude [string]
+	A gitignore-style pattern of files in "/hosting/files" not to upload, in addition to those listed in
+	"/hosting/files/.realmignore". May be provided multiple times.
+
+  --compress [gzip|br]
+	Compress text-like static assets (such as HTML, CSS and JavaScript) before uploading them,
+	setting their Content-Encoding accordingly. Assets that already declare a Content-Encoding are uploaded as is.
+
+  --verify
+	Rehash every static asset rather than trusting the hashes cached for files whose size and
+	modification time are unchanged, and update the cache with the new hashes.
+
+  --follow-symlinks
+	Upload the files in symlinked directories of "/hosting/files", which are otherwise skipped. A symlink
+	to a directory containing it is reported as a cycle. Symlinked files are uploaded with the contents of their targets.
+
+  --max-asset-size [int]
+	The largest size in megabytes of a static asset. Every larger asset, along with every set of assets whose
+	paths differ only by case, is reported before anything is uploaded. Defaults to 25, or unlimited if 0.
+	Imports with --include-hosting were previously not limited, so pass 0 to keep uploading larger assets.
 
   --reset-cdn-cache
-	Invalidate cdn cache for modified files.
+	Invalidate cdn cache for added, modified and deleted files.
+
+  --reset-cdn-cache-threshold [int]
+	The number of changed paths above which they are collapsed into directory wildcards
+	when invalidating the cdn cache. Defaults to 20.
+
+  --concurrency [int]
+	The number of static assets uploaded or deleted at once with --include-hosting. Defaults to 4.
+
+  --max-rps [float]
+	The most requests per second made to import static assets with --include-hosting,
+	shared by all concurrent requests. Use it when the API throttles the import. Defaults to unlimited.
 
 
   --include-dependencies
 	Upload the node_modules archive within the "/functions" directory.
 	The supported formats are: TAR, GZIP, and ZIP
+	Without an archive, the "/functions/node_modules" directory is uploaded instead, leaving out the
+	devDependencies according to the package-lock.json, npm-shrinkwrap.json or yarn.lock next to it.
+	Symlinked packages are uploaded with the contents of their targets, and node_modules/.bin is left out.
+
+  --transpiler [native|external] (default: external)
+	The transpiler used to compile the dependencies to ES5.
+	native - the transpiler built into the CLI, which does not need the "transpiler" binary but does not
+	support generators, async functions, new.target, import.meta, private class members or BigInt literals yet.
+	external - the "transpiler" binary found on the PATH.
+	The results are cached next to the user configuration file, so that unchanged sources are not transpiled
+	again. Use 'dependencies cache prune' to remove the cached results that are no longer used.
+
+  --transpile-concurrency [int]
+	The number of batches of dependencies transpiled at once, each by its own transpiler process. Defaults to 4.
+
+  --skip-untranspilable
+	Upload the dependencies that fail to transpile as they are, with a warning, rather than failing the import.
+
+  --var [NAME=VALUE]
+	Set the value of a ${NAME} template variable used in the app's configuration files.
+	May be provided multiple times, and takes precedence over --vars-file and environment variables.
+
+  --vars-file [string]
+	A path to a JSON file of template variable names to values.
+	Variables not set with --var or --vars-file are resolved from environment variables.
+
+  --plan-file [string]
+	A path to a plan of changes written by 'diff --output json'. The import is only made, without asking for
+	confirmation, if the changes it would make still match the reviewed plan.
 	` +
 		ic.BaseCommand.Help()
 }
@@ -143,13 +237,50 @@ func (ic *ImportCommand) Run(args []string) int {
 	flags.StringVar(&ic.flagStrategy, importFlagStrategy, importStrategyMerge, "")
 	flags.BoolVar(&ic.flagIncludeHosting, importFlagIncludeHosting, false, "")
 	flags.BoolVar(&ic.flagResetCDNCache, importFlagResetCDNCache, false, "")
+	flags.IntVar(&ic.flagResetCDNThreshold, importFlagResetCDNThreshold, hosting.DefaultInvalidationThreshold, "")
 	flags.BoolVar(&ic.flagIncludeDependencies, importFlagIncludeDependencies, false, "")
+	flags.StringVar(&ic.flagTranspiler, importFlagTranspiler, transpiler.ExternalTranspilerName, "")
+	flags.Var(ic.flagVars, importFlagVar, "")
+	flags.StringVar(&ic.flagVarsFile, importFlagVarsFile, "", "")
+	flags.StringVar(&ic.flagPlanFile, importFlagPlanFile, "", "")
+	flags.Var(&ic.flagExcludes, importFlagExclude, "")
+	flags.StringVar(&ic.flagCompress, importFlagCompress, "", "")
+	flags.BoolVar(&ic.flagVerify, importFlagVerify, false, "")
+	ic.flagHostingLimits.registerFlags(flags)
+	ic.flagHostingAssets.registerFlags(flags)
+	ic.flagTranspileOptions = DefaultDependencyTranspileOptions
+	ic.flagTranspileOptions.registerFlags(flags)
 
 	if err := ic.BaseCommand.run(args); err != nil {
 		ic.UI.Error(err.Error())
 		return 1
 	}
 
+	if _, err := hosting.ParseCompression(ic.flagCompress); err != nil {
+		ic.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := ic.flagHostingLimits.validate(); err != nil {
+		ic.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := ic.flagHostingAssets.validate(); err != nil {
+		ic.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := ic.flagTranspileOptions.validate(); err != nil {
+		ic.UI.Error(err.Error())
+		return 1
+	}
+
+	if _, err := transpiler.NewTranspiler(ic.flagTranspiler); err != nil {
+		ic.UI.Error(err.Error())
+		return 1
+	}
+
 	switch ic.flagStrategy {
 	case importStrategyMerge, importStrategyReplace, importStrategyReplaceByName:
 	default:
@@ -191,6 +322,20 @@ func (ic *ImportCommand) importApp(dryRun bool) error {
 		return err
 	}
 
+	if err := utils.ValidateHostingConfig(appPath); err != nil {
+		return err
+	}
+
+	vars, err := ic.resolveVariables()
+	if err != nil {
+		return err
+	}
+
+	substituted, err := utils.SubstituteVariables(loadedApp, vars)
+	if err != nil {
+		return err
+	}
+
 	appData, err := json.Marshal(loadedApp)
 	if err != nil {
 		return err
@@ -219,6 +364,9 @@ func (ic *ImportCommand) importApp(dryRun bool) error {
 
 	if appNotFound {
 		if dryRun {
+			if ic.flagOutput == diffOutputJSON {
+				return err
+			}
 			ic.UI.Info(fmt.Sprintf("%s. To create a new app, use the 'import' command", err.Error()))
 			return nil
 		}
@@ -249,35 +397,14 @@ func (ic *ImportCommand) importApp(dryRun bool) error {
 		return dirErr
 	}
 	if ic.flagIncludeHosting {
-		assetDescs, fileErr := hosting.MetadataFileToAssetDescriptions(filepath.Join(appPath, utils.HostingAttributes))
-		if fileErr != nil {
-			return errIncludeHosting(fmt.Errorf("error loading metadata.json file: %v", fileErr))
-		}
-
-		cachePath, cPErr := getAssetCachePath(ic.flagConfigPath)
-		if cPErr != nil {
-			return cPErr
+		compression, cmpErr := hosting.ParseCompression(ic.flagCompress)
+		if cmpErr != nil {
+			return errIncludeHosting(cmpErr)
 		}
 
-		assetCache, cErr := hosting.CacheFileToAssetCache(cachePath)
-		if cErr != nil {
-			if !os.IsNotExist(cErr) {
-				return cErr
-			}
-			assetCache = hosting.NewAssetCache()
-		}
-
-		localAssetMetadata, aMErr :=
-			hosting.ListLocalAssetMetadata(appInstanceData.AppID(), rootDir, assetDescs, assetCache)
-
+		localAssetMetadata, aMErr := listLocalHostingAssets(appInstanceData.AppID(), rootDir, filepath.Join(appPath, utils.HostingAttributes), ic.flagConfigPath, ic.flagExcludes, compression, ic.flagVerify, ic.flagHostingAssets, ic.UI)
 		if aMErr != nil {
-			return errIncludeHosting(fmt.Errorf("error processing local assets %s: %s", rootDir, aMErr))
-		}
-
-		if assetCache.Dirty() {
-			if uError := hosting.UpdateCacheFile(cachePath, assetCache); uError != nil {
-				ic.UI.Error(uError.Error())
-			}
+			return errIncludeHosting(aMErr)
 		}
 
 		remoteAssetMetadata, rAMErr := realmClient.ListAssetsForAppID(app.GroupID, app.ID)
@@ -288,28 +415,61 @@ func (ic *ImportCommand) importApp(dryRun bool) error {
 		assetMetadataDiffs = hosting.DiffAssetMetadata(localAssetMetadata, remoteAssetMetadata, ic.flagStrategy == importStrategyMerge)
 	}
 
-	// Diff changes unless -y flag has been provided or if this is a new app
-	if !ic.flagYes && !skipDiff {
-		diffs, diffErr := realmClient.Diff(app.GroupID, app.ID, appData, ic.flagStrategy)
-		if diffErr != nil {
-			return fmt.Errorf("failed to diff app with currently deployed instance: %s", diffErr)
+	if skipDiff && ic.flagPlanFile != "" {
+		return fmt.Errorf("cannot import a reviewed plan with --%s since the app does not exist yet", importFlagPlanFile)
+	}
+
+	// Diff changes unless -y flag has been provided or if this is a new app,
+	// though a reviewed plan is always checked against the current changes
+	if (!ic.flagYes || ic.flagPlanFile != "") && !skipDiff {
+		plan, planErr := ic.buildImportPlan(app, appPath, appData, assetMetadataDiffs, realmClient)
+		if planErr != nil {
+			return planErr
 		}
 
-		if ic.flagIncludeHosting && assetMetadataDiffs != nil {
-			hostingDiff := assetMetadataDiffs.Diff()
-			diffs = append(diffs, hostingDiff...)
+		if ic.flagOutput == diffOutputJSON {
+			data, marshalErr := json.MarshalIndent(plan, "", "    ")
+			if marshalErr != nil {
+				return marshalErr
+			}
+			ic.UI.Output(string(data))
+			return nil
 		}
 
-		if ic.flagIncludeDependencies {
-			diffs = append(diffs, "Import dependencies")
+		if ic.flagPlanFile != "" {
+			planFile, expandErr := homedir.Expand(ic.flagPlanFile)
+			if expandErr != nil {
+				return expandErr
+			}
+
+			reviewedPlan, readErr := ImportPlanFromFile(planFile)
+			if readErr != nil {
+				return readErr
+			}
+
+			if reviewedPlan.Strategy != plan.Strategy {
+				return fmt.Errorf("the reviewed plan in %s was made for the %q strategy rather than %q, please review the changes again with 'diff --strategy=%s --output=json'", ic.flagPlanFile, reviewedPlan.Strategy, plan.Strategy, plan.Strategy)
+			}
+
+			if !plan.Equal(reviewedPlan) {
+				ic.UI.Info("The changes to import are now:")
+				for _, diff := range plan.Diff() {
+					ic.UI.Info(diff)
+				}
+				return fmt.Errorf("the changes to import no longer match the reviewed plan in %s, please review them again", ic.flagPlanFile)
+			}
 		}
 
-		if len(diffs) == 0 {
+		if !plan.HasChanges() {
 			ic.UI.Info("Deployed app is identical to proposed version, nothing to do.")
 			return nil
 		}
 
-		for _, diff := range diffs {
+		if dryRun {
+			ic.printDeployedAppEntityDiffs(app, loadedApp, realmClient)
+		}
+
+		for _, diff := range plan.Diff() {
 			ic.UI.Info(diff)
 		}
 
@@ -317,13 +477,16 @@ func (ic *ImportCommand) importApp(dryRun bool) error {
 			return nil
 		}
 
-		confirm, confirmErr := ic.AskYesNo("Please confirm the changes shown above:")
-		if confirmErr != nil {
-			return confirmErr
-		}
+		// the plan file is the confirmation of the changes it describes
+		if ic.flagPlanFile == "" {
+			confirm, confirmErr := ic.AskYesNo("Please confirm the changes shown above:")
+			if confirmErr != nil {
+				return confirmErr
+			}
 
-		if !confirm {
-			return nil
+			if !confirm {
+				return nil
+			}
 		}
 	}
 
@@ -411,7 +574,7 @@ func (ic *ImportCommand) importApp(dryRun bool) error {
 
 	if ic.flagIncludeHosting && assetMetadataDiffs != nil {
 		ic.UI.Info("Importing hosting assets...")
-		if hostingImportErr := ImportHosting(app.GroupID, app.ID, rootDir, assetMetadataDiffs, ic.flagResetCDNCache, realmClient, ic.UI); hostingImportErr != nil {
+		if hostingImportErr := ImportHosting(app.GroupID, app.ID, rootDir, assetMetadataDiffs, ic.flagResetCDNCache, ic.flagResetCDNThreshold, ic.flagHostingLimits, realmClient, ic.UI); hostingImportErr != nil {
 			return fmt.Errorf("failed to import hosting assets %s", hostingImportErr)
 		}
 		ic.UI.Info("Done.")
@@ -423,32 +586,134 @@ func (ic *ImportCommand) importApp(dryRun bool) error {
 			return dirErr
 		}
 
-		importErr := ImportDependencies(ic.UI, app.GroupID, app.ID, functionsDir, realmClient)
+		tr, trErr := newCachingTranspiler(ic.flagTranspiler, ic.flagConfigPath)
+		if trErr != nil {
+			return trErr
+		}
+
+		importErr := ImportDependencies(ic.UI, app.GroupID, app.ID, functionsDir, realmClient, tr, ic.flagTranspileOptions)
 		if importErr != nil {
 			return importErr
 		}
+		reportTranspileCacheStats(ic.UI, tr.Stats())
 		ic.UI.Info("Done.")
 	}
 
-	exportStrategy := api.ExportStrategyNone
-	if ic.flagStrategy == importStrategyReplaceByName {
-		exportStrategy = api.ExportStrategySourceControl
+	// syncing would overwrite the template variables with their resolved values
+	if substituted > 0 {
+		ic.UI.Info("Skipping sync of local directory since the app uses template variables")
+	} else {
+		exportStrategy := api.ExportStrategyNone
+		if ic.flagStrategy == importStrategyReplaceByName {
+			exportStrategy = api.ExportStrategySourceControl
+		}
+
+		_, body, err := realmClient.Export(app.GroupID, app.ID, exportStrategy)
+		if err != nil {
+			return errImportAppSyncFailure(err)
+		}
+
+		defer body.Close()
+
+		if err := ic.writeToDirectory(appPath, body, true); err != nil {
+			return errImportAppSyncFailure(err)
+		}
 	}
 
-	_, body, err := realmClient.Export(app.GroupID, app.ID, exportStrategy)
+	ic.UI.Info(fmt.Sprintf("Successfully imported '%s'", app.ClientAppID))
+
+	return nil
+}
+
+// buildImportPlan describes the changes importing the app would make to the deployed app
+func (ic *ImportCommand) buildImportPlan(app *models.App, appPath string, appData []byte, assetMetadataDiffs *hosting.AssetMetadataDiffs, realmClient api.RealmClient) (*ImportPlan, error) {
+	diffs, err := realmClient.Diff(app.GroupID, app.ID, appData, ic.flagStrategy)
 	if err != nil {
-		return errImportAppSyncFailure(err)
+		return nil, fmt.Errorf("failed to diff app with currently deployed instance: %s", err)
+	}
+
+	plan := &ImportPlan{
+		AppID:    app.ClientAppID,
+		Strategy: ic.flagStrategy,
+		Diffs:    diffs,
+	}
+	if plan.Diffs == nil {
+		plan.Diffs = []string{}
 	}
 
+	if ic.flagIncludeHosting && assetMetadataDiffs != nil {
+		plan.Hosting = newImportPlanHosting(assetMetadataDiffs)
+	}
+
+	if ic.flagIncludeDependencies {
+		plan.Dependencies, err = newImportPlanDependencies(appPath)
+		if err != nil {
+			return nil, err
+		}
+	}
+
+	return plan, nil
+}
+
+// printDeployedAppEntityDiffs exports the deployed app to print how each of its entities would change,
+// including the changed lines of function and incoming webhook source that the server diff does not show
+func (ic *ImportCommand) printDeployedAppEntityDiffs(app *models.App, loadedApp map[string]interface{}, realmClient api.RealmClient) {
+	_, body, err := realmClient.Export(app.GroupID, app.ID, api.ExportStrategyNone)
+	if err == nil && body == nil {
+		err = errors.New("no app data returned")
+	}
+	if err != nil {
+		ic.UI.Warn(fmt.Sprintf("Unable to show source changes, failed to export deployed app: %s", err))
+		return
+	}
 	defer body.Close()
 
-	if err := ic.writeToDirectory(appPath, body, true); err != nil {
-		return errImportAppSyncFailure(err)
+	deployedApp, err := utils.UnmarshalFromZip(body)
+	if err != nil {
+		ic.UI.Warn(fmt.Sprintf("Unable to show source changes, failed to read deployed app: %s", err))
+		return
 	}
 
-	ic.UI.Info(fmt.Sprintf("Successfully imported '%s'", app.ClientAppID))
+	diffs, err := utils.DiffApps(deployedApp, loadedApp)
+	if err != nil {
+		ic.UI.Warn(fmt.Sprintf("Unable to show source changes: %s", err))
+		return
+	}
 
-	return nil
+	if len(diffs) == 0 {
+		return
+	}
+
+	ic.printAppEntityDiffs(diffs)
+	ic.UI.Info("Changes to deploy:")
+}
+
+// resolveVariables merges the variables from --vars-file with those set by --var,
+// with the latter taking precedence
+func (ic *ImportCommand) resolveVariables() (utils.Variables, error) {
+	vars := utils.Variables{}
+
+	if ic.flagVarsFile != "" {
+		path, err := homedir.Expand(ic.flagVarsFile)
+		if err != nil {
+			return nil, err
+		}
+
+		fileVars, err := utils.LoadVariablesFile(path)
+		if err != nil {
+			return nil, fmt.Errorf("failed to load variables file: %s", err)
+		}
+
+		for name, value := range fileVars {
+			vars[name] = value
+		}
+	}
+
+	for name, value := range ic.flagVars {
+		vars[name] = value
+	}
+
+	return vars, nil
 }
 
 func (ic *ImportCommand) fetchAppByClientAppID(clientAppID string) (*models.App, error) {
@@ -3,42 +3,104 @@ package commands
 import (
 	"archive/zip"
 	"context"
+	"crypto/md5"
 	"errors"
+	"flag"
 	"fmt"
 	"io"
 	"io/ioutil"
 	"os"
 	"path/filepath"
+	"sort"
+	"strings"
+	"sync"
+	"time"
 
 	"github.com/10gen/realm-cli/api"
+	"github.com/10gen/realm-cli/dependency/lockfile"
 	"github.com/10gen/realm-cli/dependency/transpiler"
 	"github.com/10gen/realm-cli/utils"
 	"github.com/mitchellh/cli"
 )
 
-func ImportDependencies(ui cli.Ui, groupID, appID, dir string, client api.RealmClient) error {
-	fullPath, err := findDependenciesLocation(dir)
-	if err != nil {
-		return err
+const (
+	flagTranspileConcurrency = "transpile-concurrency"
+	flagSkipUntranspilable   = "skip-untranspilable"
+
+	defaultTranspileConcurrency = 4
+	defaultTranspileBatchFiles  = 200
+	defaultTranspileBatchBytes  = 4 << 20
+
+	transpileProgressInterval = 5 * time.Second
+)
+
+// DependencyTranspileOptions bounds the batches of sources sent to the transpiler at once,
+// and sets what happens to the sources that fail to transpile
+type DependencyTranspileOptions struct {
+	// Concurrency is the number of batches transpiled at once, each by its own transpiler process
+	Concurrency int
+
+	// BatchFiles and BatchBytes are the most sources and the largest total size of the sources of a batch,
+	// which always holds at least one source
+	BatchFiles int
+	BatchBytes int
+
+	// SkipUntranspilable uploads the sources that fail to transpile as they are, rather than failing the import
+	SkipUntranspilable bool
+}
+
+// DefaultDependencyTranspileOptions are the DependencyTranspileOptions used unless --transpile-concurrency
+// or --skip-untranspilable is provided
+var DefaultDependencyTranspileOptions = DependencyTranspileOptions{
+	Concurrency: defaultTranspileConcurrency,
+	BatchFiles:  defaultTranspileBatchFiles,
+	BatchBytes:  defaultTranspileBatchBytes,
+}
+
+func (dto *DependencyTranspileOptions) registerFlags(flags *flag.FlagSet) {
+	flags.IntVar(&dto.Concurrency, flagTranspileConcurrency, defaultTranspileConcurrency, "")
+	flags.BoolVar(&dto.SkipUntranspilable, flagSkipUntranspilable, false, "")
+}
+
+func (dto DependencyTranspileOptions) validate() error {
+	if dto.Concurrency < 1 {
+		return fmt.Errorf("--%s must be at least 1, but was %d", flagTranspileConcurrency, dto.Concurrency)
 	}
+	return nil
+}
 
-	file, err := os.Open(fullPath)
+// transpileBatch is a set of consecutive JavaScript sources of the archive, the first of which is
+// the source at offset among all of them
+type transpileBatch struct {
+	offset  int
+	names   []string
+	sources []string
+	bytes   int
+}
+
+// ImportDependencies transpiles the JavaScript sources of the node_modules archive or directory in dir with tr,
+// and uploads them along with the other files of the archive. The sources are transpiled in batches
+// bounded by options while the archive is read, and every file is written to the uploaded archive
+// as soon as it is ready
+func ImportDependencies(ui cli.Ui, groupID, appID, dir string, client api.RealmClient, tr transpiler.Transpiler, options DependencyTranspileOptions) error {
+	deps, err := loadDependencies(dir)
 	if err != nil {
-		return fmt.Errorf("failed to open the dependencies file '%s': %s", fullPath, err)
+		return err
 	}
 
-	defer file.Close()
-	fileInfo, err := file.Stat()
-	if err != nil {
-		return errors.New("failed to read dependencies from " + fullPath)
+	if deps.isDir {
+		if deps.lockfilePath == "" {
+			ui.Info(fmt.Sprintf("uploading every package of %s, as no lockfile was found to tell its devDependencies apart", deps.path))
+		} else {
+			ui.Info(fmt.Sprintf("leaving out %d devDependencies package(s) of %s according to %s", len(deps.devDirs), deps.path, filepath.Base(deps.lockfilePath)))
+		}
 	}
 
-	archive, err := utils.NewArchiveReader(file, fullPath, fileInfo.Size())
+	archive, closeArchive, err := deps.open()
 	if err != nil {
 		return err
 	}
-
-	tr := transpiler.NewExternalTranspiler(transpiler.DefaultTranspilerCommand)
+	defer closeArchive()
 
 	outFile, err := os.Create(filepath.Join(os.TempDir(), "node_modules.zip"))
 	if err != nil {
@@ -46,69 +108,142 @@ func ImportDependencies(ui cli.Ui, groupID, appID, dir string, client api.RealmC
 	}
 	defer outFile.Close()
 
+	// zip.Writer is shared by the workers, and only writes one file at a time
 	w := zip.NewWriter(outFile)
+	var wMu sync.Mutex
+	writeFile := func(name string, data []byte) error {
+		wMu.Lock()
+		defer wMu.Unlock()
 
-	fullNames := make([]string, 0)
-	sources := make([]string, 0)
-	for {
-		header, err := archive.Next()
-		if err == io.EOF {
-			break
-		}
+		f, err := w.Create(name)
 		if err != nil {
-			return fmt.Errorf("failed to advance to the next entry in the archive: %s", err)
+			return err
 		}
+		_, err = f.Write(data)
+		return err
+	}
 
-		if header.FileInfo().IsDir() {
-			continue
-		}
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
 
-		fullpath, err := filepath.Rel(dir, header.FullPath)
-		if err != nil {
-			// header.FullPath is the relative path already
-			fullpath = header.FullPath
+	ui.Info("transpiling dependencies started.")
+	progress := newTranspileProgress(ui)
+
+	var errMu sync.Mutex
+	var untranspilable dependencyTranspileErrors
+	var workerErr error
+	fail := func(err error) {
+		errMu.Lock()
+		defer errMu.Unlock()
+
+		if workerErr == nil {
+			workerErr = err
 		}
+		cancel()
+	}
+	skip := func(errs []dependencyTranspileError) {
+		errMu.Lock()
+		defer errMu.Unlock()
 
-		fileContents, err := ioutil.ReadAll(archive)
-		if err != nil {
-			return fmt.Errorf("failed to read file '%s' in the archive: %s", fullpath, err)
+		untranspilable = append(untranspilable, errs...)
+	}
+
+	var wg sync.WaitGroup
+	batches := make(chan *transpileBatch)
+	for n := 0; n < options.Concurrency; n++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for batch := range batches {
+				errs, err := transpileBatchToZip(ctx, tr, batch, options.SkipUntranspilable, writeFile)
+				if err != nil {
+					fail(err)
+					continue
+				}
+				skip(errs)
+				progress.record(len(batch.sources), batch.bytes)
+			}
+		}()
+	}
+
+	readErr := func() error {
+		defer close(batches)
+
+		batch := &transpileBatch{}
+		dispatch := func() error {
+			if len(batch.sources) == 0 {
+				return nil
+			}
+			select {
+			case batches <- batch:
+			case <-ctx.Done():
+				return ctx.Err()
+			}
+			batch = &transpileBatch{offset: batch.offset + len(batch.sources)}
+			return nil
 		}
 
-		ext := filepath.Ext(fullpath)
-		if ext != ".js" {
-			f, err := w.Create(fullpath)
+		for {
+			header, err := archive.Next()
+			if err == io.EOF {
+				break
+			}
+			if err != nil {
+				return fmt.Errorf("failed to advance to the next entry in the archive: %s", err)
+			}
+
+			if header.FileInfo().IsDir() {
+				continue
+			}
+
+			fullpath, err := filepath.Rel(filepath.Dir(deps.path), header.FullPath)
 			if err != nil {
-				return err
+				// header.FullPath is the relative path already
+				fullpath = header.FullPath
 			}
-			_, err = f.Write(fileContents)
+			fullpath = filepath.ToSlash(fullpath)
+
+			fileContents, err := ioutil.ReadAll(archive)
 			if err != nil {
-				return err
+				return fmt.Errorf("failed to read file '%s' in the archive: %s", fullpath, err)
+			}
+
+			ext := filepath.Ext(fullpath)
+			if ext != ".js" {
+				if err := writeFile(fullpath, fileContents); err != nil {
+					return err
+				}
+				continue
 			}
-			continue
+
+			if len(batch.sources) > 0 && (len(batch.sources) >= options.BatchFiles || batch.bytes+len(fileContents) > options.BatchBytes) {
+				if err := dispatch(); err != nil {
+					return err
+				}
+			}
+			batch.names = append(batch.names, fullpath)
+			batch.sources = append(batch.sources, string(fileContents))
+			batch.bytes += len(fileContents)
 		}
+		return dispatch()
+	}()
 
-		sources = append(sources, string(fileContents))
-		fullNames = append(fullNames, fullpath)
+	wg.Wait()
+	if workerErr != nil {
+		return workerErr
+	}
+	if readErr != nil {
+		return readErr
 	}
-	ctx, cancel := context.WithCancel(context.Background())
-	defer cancel()
 
-	ui.Info("transpiling dependencies started.")
-	transpiled, err := tr.Transpile(ctx, sources...)
-	if err != nil {
-		return err
+	sort.SliceStable(untranspilable, func(i, j int) bool { return untranspilable[i].Index < untranspilable[j].Index })
+	if len(untranspilable) != 0 && !options.SkipUntranspilable {
+		return untranspilable
 	}
-	for i, t := range transpiled {
-		f, err := w.Create(fullNames[i])
-		if err != nil {
-			return err
-		}
-		_, err = f.Write([]byte(t.Code))
-		if err != nil {
-			return err
-		}
+	progress.finish()
+	for _, e := range untranspilable {
+		ui.Warn(fmt.Sprintf("%s\n%s will be uploaded without being transpiled", e.Error(), e.Path))
 	}
-	ui.Info("transpiling dependencies finished.")
 
 	err = w.Close()
 	if err != nil {
@@ -131,17 +266,351 @@ func ImportDependencies(ui cli.Ui, groupID, appID, dir string, client api.RealmC
 	return nil
 }
 
+// dependencyTranspileError is the failure to transpile the JavaScript source of the node_modules archive at Path,
+// whose Index among all the sources of the archive is that of the TranspileError
+type dependencyTranspileError struct {
+	*transpiler.TranspileError
+	Path    string
+	Excerpt string
+}
+
+// Error returns the position of the error in the source as path:line:column, where the line and column start at 1,
+// followed by the reason and the excerpt of the source
+func (dte dependencyTranspileError) Error() string {
+	location := dte.Path
+	if dte.Line > 0 {
+		location = fmt.Sprintf("%s:%d:%d", dte.Path, dte.Line, dte.Column+1)
+	}
+
+	message := location + ": " + dte.Reason()
+	if dte.Excerpt != "" {
+		message += "\n" + dte.Excerpt
+	}
+	return message
+}
+
+// dependencyTranspileErrors are the failures to transpile the sources of the node_modules archive
+type dependencyTranspileErrors []dependencyTranspileError
+
+func (dtes dependencyTranspileErrors) Error() string {
+	messages := make([]string, len(dtes))
+	for i, dte := range dtes {
+		messages[i] = dte.Error()
+	}
+	return fmt.Sprintf("failed to transpile %d dependency file(s), use --%s to upload them without transpiling them:\n%s",
+		len(dtes), flagSkipUntranspilable, strings.Join(messages, "\n"))
+}
+
+// transpileBatchToZip transpiles the sources of batch with tr and writes the results with writeFile, returning
+// the sources that failed to transpile. Unless skip is set, nothing is written if any source fails. Otherwise,
+// the other sources are transpiled again without them, and they are written as they are
+func transpileBatchToZip(ctx context.Context, tr transpiler.Transpiler, batch *transpileBatch, skip bool, writeFile func(name string, data []byte) error) ([]dependencyTranspileError, error) {
+	var failed []dependencyTranspileError
+	remaining := make([]int, len(batch.sources))
+	for i := range remaining {
+		remaining[i] = i
+	}
+
+	for len(remaining) != 0 {
+		sources := make([]string, len(remaining))
+		for i, n := range remaining {
+			sources[i] = batch.sources[n]
+		}
+
+		transpiled, err := tr.Transpile(ctx, sources...)
+		if err == nil {
+			for i, t := range transpiled {
+				if err := writeFile(batch.names[remaining[i]], []byte(t.Code)); err != nil {
+					return nil, err
+				}
+			}
+			break
+		}
+
+		errs, ok := err.(transpiler.TranspileErrors)
+		if !ok || len(errs) == 0 {
+			return nil, err
+		}
+
+		failedIndexes := map[int]bool{}
+		for _, e := range errs {
+			if e.Index < 0 || e.Index >= len(remaining) {
+				return nil, fmt.Errorf("the transpiler failed with an error for an unknown source: %s", e.Error())
+			}
+			n := remaining[e.Index]
+			failedIndexes[n] = true
+
+			e.Index = batch.offset + n
+			failed = append(failed, dependencyTranspileError{
+				TranspileError: e,
+				Path:           batch.names[n],
+				Excerpt:        e.Excerpt(batch.sources[n]),
+			})
+		}
+
+		if !skip {
+			return failed, nil
+		}
+
+		var next []int
+		for _, n := range remaining {
+			if !failedIndexes[n] {
+				next = append(next, n)
+			}
+		}
+		remaining = next
+	}
+
+	for _, f := range failed {
+		if err := writeFile(f.Path, []byte(batch.sources[f.Index-batch.offset])); err != nil {
+			return nil, err
+		}
+	}
+	return failed, nil
+}
+
+// transpileProgress reports the number of sources transpiled by concurrent workers, at most once per interval
+type transpileProgress struct {
+	ui       cli.Ui
+	interval time.Duration
+
+	mu       sync.Mutex
+	started  time.Time
+	reported time.Time
+	files    int
+	bytes    int64
+}
+
+func newTranspileProgress(ui cli.Ui) *transpileProgress {
+	now := time.Now()
+	return &transpileProgress{
+		ui:       ui,
+		interval: transpileProgressInterval,
+		started:  now,
+		reported: now,
+	}
+}
+
+// record accounts for a transpiled batch of files holding bytes of sources
+func (tp *transpileProgress) record(files, bytes int) {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	tp.files += files
+	tp.bytes += int64(bytes)
+
+	if time.Since(tp.reported) < tp.interval {
+		return
+	}
+	tp.reported = time.Now()
+	tp.ui.Info(fmt.Sprintf("transpiling dependencies: %d file(s) (%s) transpiled", tp.files, formatBytes(tp.bytes)))
+}
+
+// finish prints the totals of the transpiled files
+func (tp *transpileProgress) finish() {
+	tp.mu.Lock()
+	defer tp.mu.Unlock()
+
+	tp.ui.Info(fmt.Sprintf("transpiling dependencies finished: %d file(s) (%s) transpiled in %s.", tp.files, formatBytes(tp.bytes), formatDuration(time.Since(tp.started))))
+}
+
+// newCachingTranspiler returns the transpiler with the given name, which stores its results in the
+// transpile cache next to the user configuration file at configPath
+func newCachingTranspiler(name, configPath string) (*transpiler.CachingTranspiler, error) {
+	tr, err := transpiler.NewTranspiler(name)
+	if err != nil {
+		return nil, err
+	}
+
+	version, err := transpiler.Version(name)
+	if err != nil {
+		return nil, fmt.Errorf("failed to find the version of the %s transpiler: %s", name, err)
+	}
+
+	cacheDir, err := getTranspileCacheDir(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	return transpiler.NewCachingTranspiler(tr, cacheDir, version), nil
+}
+
+func getTranspileCacheDir(configPath string) (string, error) {
+	configDir, err := getConfigDir(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, utils.TranspileCacheDirName), nil
+}
+
+func reportTranspileCacheStats(ui cli.Ui, stats transpiler.CacheStats) {
+	total := stats.Hits + stats.Misses
+	if total == 0 {
+		return
+	}
+	ui.Info(fmt.Sprintf("transpile cache: %d of %d file(s) found in the cache (%.0f%% hit ratio)", stats.Hits, total, stats.HitRatio()*100))
+}
+
+// findDependenciesLocation returns the path of the node_modules archive in dir or, if there is none,
+// of the node_modules directory
 func findDependenciesLocation(dir string) (string, error) {
-	archFile := filepath.Join(dir, "node_modules*")
+	archFile := filepath.Join(dir, lockfile.NodeModulesDirName+"*")
 
 	matches, err := filepath.Glob(archFile)
 
 	if err != nil {
 		return "", fmt.Errorf("failed to find a node_modules archive in the '%s' directory: %s", dir, err)
 	}
-	if len(matches) == 0 {
-		return "", fmt.Errorf("node_modules archive not found in the '%s' directory", dir)
+
+	var archives []string
+	var nodeModulesDir string
+	for _, match := range matches {
+		info, err := os.Stat(match)
+		if err != nil {
+			return "", err
+		}
+		if !info.IsDir() {
+			archives = append(archives, match)
+		} else if filepath.Base(match) == lockfile.NodeModulesDirName {
+			nodeModulesDir = match
+		}
+	}
+
+	switch {
+	case len(archives) > 1:
+		names := make([]string, len(archives))
+		for i, archive := range archives {
+			names[i] = filepath.Base(archive)
+		}
+		return "", fmt.Errorf("found more than one node_modules archive in the '%s' directory, keep only one of: %s", dir, strings.Join(names, ", "))
+	case len(archives) == 1:
+		return filepath.Abs(archives[0])
+	case nodeModulesDir != "":
+		return filepath.Abs(nodeModulesDir)
+	}
+
+	if _, err := os.Stat(filepath.Join(dir, lockfile.PackageJSONFileName)); err == nil {
+		return "", fmt.Errorf("node_modules directory not found in the '%s' directory, install the dependencies of its %s first", dir, lockfile.PackageJSONFileName)
+	}
+	return "", fmt.Errorf("node_modules archive or directory not found in the '%s' directory", dir)
+}
+
+// dependencies are the node_modules archive or directory whose files are uploaded
+type dependencies struct {
+	path  string
+	isDir bool
+
+	// lockfilePath is the lockfile next to a node_modules directory, if there is one, according to which
+	// the packages in devDirs are only needed for development and are left out
+	lockfilePath string
+	devDirs      []string
+}
+
+// loadDependencies finds the dependencies in dir and, for a node_modules directory, the packages
+// that are only needed for development
+func loadDependencies(dir string) (*dependencies, error) {
+	fullPath, err := findDependenciesLocation(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	info, err := os.Stat(fullPath)
+	if err != nil {
+		return nil, err
+	}
+
+	deps := &dependencies{path: fullPath, isDir: info.IsDir()}
+	if !deps.isDir {
+		return deps, nil
+	}
+
+	deps.lockfilePath, err = lockfile.Find(filepath.Dir(fullPath))
+	if err != nil {
+		return nil, err
+	}
+	if deps.lockfilePath != "" {
+		deps.devDirs, err = lockfile.DevPackages(filepath.Dir(fullPath), deps.lockfilePath)
+		if err != nil {
+			return nil, err
+		}
+	}
+	return deps, nil
+}
+
+// open returns an ArchiveReader of the files of the dependencies, along with the function that closes it
+func (d *dependencies) open() (utils.ArchiveReader, func(), error) {
+	if d.isDir {
+		devDirs := make(map[string]bool, len(d.devDirs))
+		for _, devDir := range d.devDirs {
+			devDirs[devDir] = true
+		}
+
+		// the symlinks of node_modules/.bin are the executables of the packages, which functions cannot run
+		root := filepath.Dir(d.path)
+		archive, err := utils.NewResolvedDirReader(d.path, func(path string) bool {
+			if filepath.Base(path) == ".bin" && filepath.Base(filepath.Dir(path)) == lockfile.NodeModulesDirName {
+				return true
+			}
+			dir, err := filepath.Rel(root, path)
+			return err == nil && devDirs[filepath.ToSlash(dir)]
+		})
+		if err != nil {
+			return nil, nil, fmt.Errorf("failed to read the dependencies directory '%s': %s", d.path, err)
+		}
+		return archive, func() {}, nil
+	}
+
+	file, err := os.Open(d.path)
+	if err != nil {
+		return nil, nil, fmt.Errorf("failed to open the dependencies file '%s': %s", d.path, err)
 	}
 
-	return filepath.Abs(matches[0])
+	fileInfo, err := file.Stat()
+	if err != nil {
+		file.Close()
+		return nil, nil, errors.New("failed to read dependencies from " + d.path)
+	}
+
+	archive, err := utils.NewArchiveReader(file, d.path, fileInfo.Size())
+	if err != nil {
+		file.Close()
+		return nil, nil, err
+	}
+	return archive, func() { file.Close() }, nil
+}
+
+// hash returns the hash of the dependencies, which for a directory covers the path and contents
+// of every file that is uploaded
+func (d *dependencies) hash() (string, error) {
+	if !d.isDir {
+		return utils.GenerateFileHashStr(d.path)
+	}
+
+	archive, closeArchive, err := d.open()
+	if err != nil {
+		return "", err
+	}
+	defer closeArchive()
+
+	h := md5.New()
+	for {
+		header, err := archive.Next()
+		if err == io.EOF {
+			break
+		}
+		if err != nil {
+			return "", err
+		}
+
+		fullpath, err := filepath.Rel(filepath.Dir(d.path), header.FullPath)
+		if err != nil {
+			return "", err
+		}
+		fmt.Fprintf(h, "%s\x00%d\x00", filepath.ToSlash(fullpath), header.FileInfo().Size())
+		if _, err := io.Copy(h, archive); err != nil {
+			return "", err
+		}
+	}
+	return fmt.Sprintf("%x", h.Sum(nil)), nil
 }
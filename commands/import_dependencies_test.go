@@ -1,35 +1,323 @@
 package commands
 
 import (
+	"archive/zip"
+	"context"
+	"errors"
 	"fmt"
+	"io/ioutil"
+	"os"
 	"path/filepath"
+	"strings"
+	"sync"
 	"testing"
 
+	"github.com/10gen/realm-cli/dependency/transpiler"
 	u "github.com/10gen/realm-cli/utils/test"
 	"github.com/mitchellh/cli"
 	gc "github.com/smartystreets/goconvey/convey"
 )
 
 func TestImportDependencies(t *testing.T) {
-	t.Run("should be successful", func(t *testing.T) {
-		expectedGroupID := "group-id"
-		expectedAppID := "app-id"
-		dir := "../testdata/app_with_dependencies/functions"
+	expectedGroupID := "group-id"
+	expectedAppID := "app-id"
+	dir := "../testdata/app_with_dependencies/functions"
 
-		realmClient := &u.MockRealmClient{
+	// uploadedFiles returns the mock client that reads the names and contents of the files of the uploaded archive into files
+	uploadedFiles := func(t *testing.T, files map[string]string) *u.MockRealmClient {
+		return &u.MockRealmClient{
 			UploadDependenciesFn: func(groupID, appID, fullPath string) error {
 				u.So(t, groupID, gc.ShouldEqual, expectedGroupID)
 				u.So(t, appID, gc.ShouldEqual, expectedAppID)
 				u.So(t, fullPath, gc.ShouldContainSubstring, "node_modules.zip")
+
+				r, err := zip.OpenReader(fullPath)
+				u.So(t, err, gc.ShouldBeNil)
+				defer r.Close()
+
+				for _, f := range r.File {
+					rc, err := f.Open()
+					u.So(t, err, gc.ShouldBeNil)
+					data, err := ioutil.ReadAll(rc)
+					u.So(t, err, gc.ShouldBeNil)
+					rc.Close()
+					files[f.Name] = string(data)
+				}
 				return nil
 			},
 		}
+	}
+
+	t.Run("should be successful", func(t *testing.T) {
+		files := map[string]string{}
 
 		mockUI := cli.NewMockUi()
-		err := ImportDependencies(mockUI, expectedGroupID, expectedAppID, dir, realmClient)
+		err := ImportDependencies(mockUI, expectedGroupID, expectedAppID, dir, uploadedFiles(t, files), transpiler.NewNativeTranspiler(), DefaultDependencyTranspileOptions)
 		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "transpiling dependencies finished: 40 file(s)")
+
+		u.So(t, len(files), gc.ShouldEqual, 71)
+		u.So(t, files["node_modules/debug/README.md"], gc.ShouldNotBeEmpty)
+		u.So(t, files["node_modules/debug/src/index.js"], gc.ShouldStartWith, "\"use strict\";")
+	})
+
+	t.Run("should write the same files when transpiling small batches concurrently", func(t *testing.T) {
+		expected := map[string]string{}
+		err := ImportDependencies(cli.NewMockUi(), expectedGroupID, expectedAppID, dir, uploadedFiles(t, expected), transpiler.NewNativeTranspiler(), DependencyTranspileOptions{
+			Concurrency: 1,
+			BatchFiles:  1000,
+			BatchBytes:  1 << 30,
+		})
+		u.So(t, err, gc.ShouldBeNil)
+
+		tr := &batchRecordingTranspiler{Transpiler: transpiler.NewNativeTranspiler()}
+		files := map[string]string{}
+		err = ImportDependencies(cli.NewMockUi(), expectedGroupID, expectedAppID, dir, uploadedFiles(t, files), tr, DependencyTranspileOptions{
+			Concurrency: 3,
+			BatchFiles:  4,
+			BatchBytes:  16 << 10,
+		})
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, files, gc.ShouldResemble, expected)
+
+		u.So(t, len(tr.batches), gc.ShouldBeGreaterThan, 10)
+		for _, batch := range tr.batches {
+			u.So(t, len(batch), gc.ShouldBeBetweenOrEqual, 1, 4)
+			if len(batch) > 1 {
+				size := 0
+				for _, source := range batch {
+					size += len(source)
+				}
+				u.So(t, size, gc.ShouldBeLessThanOrEqualTo, 16<<10)
+			}
+		}
+	})
+
+	t.Run("should return the errors of every batch with the indexes of the sources in the archive", func(t *testing.T) {
+		tr := &failingTranspiler{fail: func(code string) bool { return strings.Contains(code, "module.exports") }}
+		err := ImportDependencies(cli.NewMockUi(), expectedGroupID, expectedAppID, dir, &u.MockRealmClient{}, tr, DependencyTranspileOptions{
+			Concurrency: 2,
+			BatchFiles:  3,
+			BatchBytes:  1 << 20,
+		})
+		u.So(t, err, gc.ShouldNotBeNil)
+
+		errs, ok := err.(dependencyTranspileErrors)
+		u.So(t, ok, gc.ShouldBeTrue)
+		u.So(t, len(errs), gc.ShouldBeGreaterThan, 3)
+		for i, e := range errs {
+			u.So(t, e.Index, gc.ShouldBeBetweenOrEqual, 0, 39)
+			u.So(t, strings.HasSuffix(e.Path, ".js"), gc.ShouldBeTrue)
+			if i > 0 {
+				u.So(t, e.Index, gc.ShouldBeGreaterThan, errs[i-1].Index)
+			}
+		}
+	})
+
+	brokenDir, err := ioutil.TempDir("", "realm-cli-dependencies")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(brokenDir)
+
+	writeZip(t, filepath.Join(brokenDir, "node_modules.zip"), []zipFile{
+		{"node_modules/a/index.js", "module.exports = () => 1;\n"},
+		{"node_modules/b/index.js", "var a = 1;\nfunction f() {\n  let x = ;\n}\n"},
+		{"node_modules/b/package.json", "{}"},
+		{"node_modules/c/index.js", "let c = 3;\n"},
+		{"node_modules/d/index.js", "(\n"},
+	})
+
+	t.Run("should report every source that fails to transpile with its path, position and excerpt", func(t *testing.T) {
+		err := ImportDependencies(cli.NewMockUi(), expectedGroupID, expectedAppID, brokenDir, &u.MockRealmClient{}, transpiler.NewNativeTranspiler(), DefaultDependencyTranspileOptions)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEqual, `failed to transpile 2 dependency file(s), use --skip-untranspilable to upload them without transpiling them:
+node_modules/b/index.js:3:11: Unexpected token
+  1 | var a = 1;
+  2 | function f() {
+> 3 |   let x = ;
+    |           ^
+  4 | }
+  5 |
+node_modules/d/index.js:2:1: Unexpected token
+  1 | (
+> 2 |
+    | ^`)
+
+		errs := err.(dependencyTranspileErrors)
+		u.So(t, errs[0].Index, gc.ShouldEqual, 1)
+		u.So(t, errs[1].Index, gc.ShouldEqual, 3)
+	})
+
+	t.Run("should upload the sources that fail to transpile as they are with --skip-untranspilable", func(t *testing.T) {
+		files := map[string]string{}
+		mockUI := cli.NewMockUi()
+		err := ImportDependencies(mockUI, expectedGroupID, expectedAppID, brokenDir, uploadedFiles(t, files), transpiler.NewNativeTranspiler(), DependencyTranspileOptions{
+			Concurrency:        2,
+			BatchFiles:         3,
+			BatchBytes:         1 << 20,
+			SkipUntranspilable: true,
+		})
+		u.So(t, err, gc.ShouldBeNil)
+
+		u.So(t, files, gc.ShouldResemble, map[string]string{
+			"node_modules/a/index.js":     "\"use strict\";\n\nmodule.exports = function () {\n  return 1;\n};",
+			"node_modules/b/index.js":     "var a = 1;\nfunction f() {\n  let x = ;\n}\n",
+			"node_modules/b/package.json": "{}",
+			"node_modules/c/index.js":     "\"use strict\";\n\nvar c = 3;",
+			"node_modules/d/index.js":     "(\n",
+		})
+
+		errOutput := mockUI.ErrorWriter.String()
+		u.So(t, errOutput, gc.ShouldContainSubstring, "node_modules/b/index.js:3:11: Unexpected token\n")
+		u.So(t, errOutput, gc.ShouldContainSubstring, "node_modules/b/index.js will be uploaded without being transpiled")
+		u.So(t, errOutput, gc.ShouldContainSubstring, "node_modules/d/index.js will be uploaded without being transpiled")
+		u.So(t, strings.Index(errOutput, "node_modules/b/"), gc.ShouldBeLessThan, strings.Index(errOutput, "node_modules/d/"))
+	})
+
+	t.Run("should upload a node_modules directory without its devDependencies", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "realm-cli-dependencies")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		writeFiles(t, dir, map[string]string{
+			"package.json": `{"dependencies": {"a": "1.0.0"}, "devDependencies": {"b": "1.0.0"}}`,
+			"package-lock.json": `{
+  "lockfileVersion": 2,
+  "packages": {
+    "": {},
+    "node_modules/a": {"version": "1.0.0"},
+    "node_modules/b": {"version": "1.0.0", "dev": true},
+    "node_modules/@types/b": {"version": "1.0.0", "dev": true}
+  }
+}`,
+			"node_modules/a/package.json":        `{"name": "a"}`,
+			"node_modules/a/index.js":            "module.exports = () => 1;",
+			"node_modules/b/package.json":        `{"name": "b"}`,
+			"node_modules/b/index.js":            "module.exports = 2;",
+			"node_modules/@types/b/index.d.ts":   "",
+			"node_modules/.package-lock.json":    "{}",
+			"node_modules/ab/lib/index.js":       "let ab = 3;",
+			"node_modules/a/node_modules/c/c.js": "let c = 4;",
+		})
+		u.So(t, os.MkdirAll(filepath.Join(dir, "node_modules", ".bin"), 0755), gc.ShouldBeNil)
+		u.So(t, os.Symlink("../b/index.js", filepath.Join(dir, "node_modules", ".bin", "b")), gc.ShouldBeNil)
+		u.So(t, os.Symlink("../../missing.js", filepath.Join(dir, "node_modules", ".bin", "missing")), gc.ShouldBeNil)
+
+		// a "file:" dependency is a symlink to its directory
+		writeFiles(t, dir, map[string]string{"packages/linked/index.js": "let linked = 5;"})
+		u.So(t, os.Symlink("../packages/linked", filepath.Join(dir, "node_modules", "linked")), gc.ShouldBeNil)
+
+		files := map[string]string{}
+		mockUI := cli.NewMockUi()
+		err = ImportDependencies(mockUI, expectedGroupID, expectedAppID, dir, uploadedFiles(t, files), transpiler.NewNativeTranspiler(), DefaultDependencyTranspileOptions)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "leaving out 2 devDependencies package(s) of "+filepath.Join(dir, "node_modules")+" according to package-lock.json")
+
+		u.So(t, files, gc.ShouldResemble, map[string]string{
+			"node_modules/.package-lock.json":    "{}",
+			"node_modules/a/package.json":        `{"name": "a"}`,
+			"node_modules/a/index.js":            "\"use strict\";\n\nmodule.exports = function () {\n  return 1;\n};",
+			"node_modules/a/node_modules/c/c.js": "\"use strict\";\n\nvar c = 4;",
+			"node_modules/ab/lib/index.js":       "\"use strict\";\n\nvar ab = 3;",
+			"node_modules/linked/index.js":       "\"use strict\";\n\nvar linked = 5;",
+		})
+
+		t.Run("and fail on a broken symlink to a package", func(t *testing.T) {
+			u.So(t, os.Symlink("../packages/missing", filepath.Join(dir, "node_modules", "missing")), gc.ShouldBeNil)
+
+			err := ImportDependencies(cli.NewMockUi(), expectedGroupID, expectedAppID, dir, &u.MockRealmClient{}, transpiler.NewNativeTranspiler(), DefaultDependencyTranspileOptions)
+			u.So(t, err, gc.ShouldNotBeNil)
+			u.So(t, err.Error(), gc.ShouldContainSubstring, "failed to resolve symlink "+filepath.Join(dir, "node_modules", "missing"))
+		})
 	})
 
+	t.Run("should stop transpiling when a batch cannot be transpiled", func(t *testing.T) {
+		tr := &failingTranspiler{err: errors.New("transpiler crashed")}
+		err := ImportDependencies(cli.NewMockUi(), expectedGroupID, expectedAppID, dir, &u.MockRealmClient{}, tr, DependencyTranspileOptions{
+			Concurrency: 2,
+			BatchFiles:  1,
+			BatchBytes:  1 << 20,
+		})
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEqual, "transpiler crashed")
+		u.So(t, tr.calls, gc.ShouldBeLessThan, 40)
+	})
+}
+
+// writeFiles writes the files at the given slash-separated paths relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		u.So(t, os.MkdirAll(filepath.Dir(path), 0755), gc.ShouldBeNil)
+		u.So(t, ioutil.WriteFile(path, []byte(contents), 0644), gc.ShouldBeNil)
+	}
+}
+
+type zipFile struct {
+	name     string
+	contents string
+}
+
+func writeZip(t *testing.T, path string, files []zipFile) {
+	f, err := os.Create(path)
+	u.So(t, err, gc.ShouldBeNil)
+	defer f.Close()
+
+	w := zip.NewWriter(f)
+	for _, file := range files {
+		fw, err := w.Create(file.name)
+		u.So(t, err, gc.ShouldBeNil)
+		_, err = fw.Write([]byte(file.contents))
+		u.So(t, err, gc.ShouldBeNil)
+	}
+	u.So(t, w.Close(), gc.ShouldBeNil)
+}
+
+// batchRecordingTranspiler is a Transpiler that records the batches of sources it is asked to transpile
+type batchRecordingTranspiler struct {
+	transpiler.Transpiler
+
+	mu      sync.Mutex
+	batches [][]string
+}
+
+func (brt *batchRecordingTranspiler) Transpile(ctx context.Context, codes ...string) ([]transpiler.TranspileResult, error) {
+	brt.mu.Lock()
+	brt.batches = append(brt.batches, codes)
+	brt.mu.Unlock()
+	return brt.Transpiler.Transpile(ctx, codes...)
+}
+
+// failingTranspiler is a Transpiler that fails with err, or else with a TranspileError for every code matching fail
+type failingTranspiler struct {
+	err  error
+	fail func(code string) bool
+
+	mu    sync.Mutex
+	calls int
+}
+
+func (ft *failingTranspiler) Transpile(ctx context.Context, codes ...string) ([]transpiler.TranspileResult, error) {
+	ft.mu.Lock()
+	ft.calls++
+	ft.mu.Unlock()
+
+	if ft.err != nil {
+		return nil, ft.err
+	}
+
+	var errs transpiler.TranspileErrors
+	results := make([]transpiler.TranspileResult, len(codes))
+	for i, code := range codes {
+		if ft.fail(code) {
+			errs = append(errs, &transpiler.TranspileError{Index: i, Message: "unknown: Unexpected token (1:0)", Line: 1})
+			continue
+		}
+		results[i] = transpiler.TranspileResult{Code: code}
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return results, nil
 }
 
 func TestFindDependenciesLocation(t *testing.T) {
@@ -68,12 +356,12 @@ func TestFindDependenciesLocation(t *testing.T) {
 		{
 			desc: "should return an error with an app without a node modules archive",
 			dir:  "../testdata/app_without_dependencies/functions",
-			err:  "node_modules archive not found in the '%s' directory",
+			err:  "node_modules archive or directory not found in the '%s' directory",
 		},
 		{
 			desc: "should return an error with an app without a functions folder",
 			dir:  "../testdata/simple_app/functions",
-			err:  "node_modules archive not found in the '%s' directory",
+			err:  "node_modules archive or directory not found in the '%s' directory",
 		},
 	} {
 		t.Run(tc.desc, func(t *testing.T) {
@@ -84,4 +372,42 @@ func TestFindDependenciesLocation(t *testing.T) {
 			u.So(t, err.Error(), gc.ShouldEqual, fmt.Sprintf(tc.err, dir))
 		})
 	}
+
+	t.Run("with a node_modules directory", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "realm-cli-dependencies")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		writeFiles(t, dir, map[string]string{"package.json": "{}"})
+
+		t.Run("should return an error with a package.json but no node_modules", func(t *testing.T) {
+			_, err := findDependenciesLocation(dir)
+			u.So(t, err, gc.ShouldNotBeNil)
+			u.So(t, err.Error(), gc.ShouldEqual, fmt.Sprintf("node_modules directory not found in the '%s' directory, install the dependencies of its package.json first", dir))
+		})
+
+		writeFiles(t, dir, map[string]string{"node_modules/a/index.js": ""})
+
+		t.Run("should find the node_modules directory", func(t *testing.T) {
+			location, err := findDependenciesLocation(dir)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, location, gc.ShouldEqual, filepath.Join(dir, "node_modules"))
+		})
+
+		writeFiles(t, dir, map[string]string{"node_modules.zip": ""})
+
+		t.Run("should prefer the node_modules archive", func(t *testing.T) {
+			location, err := findDependenciesLocation(dir)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, location, gc.ShouldEqual, filepath.Join(dir, "node_modules.zip"))
+		})
+
+		writeFiles(t, dir, map[string]string{"node_modules.tgz": ""})
+
+		t.Run("should return an error with more than one node_modules archive", func(t *testing.T) {
+			_, err := findDependenciesLocation(dir)
+			u.So(t, err, gc.ShouldNotBeNil)
+			u.So(t, err.Error(), gc.ShouldEqual, fmt.Sprintf("found more than one node_modules archive in the '%s' directory, keep only one of: node_modules.tgz, node_modules.zip", dir))
+		})
+	})
 }
@@ -1,10 +1,16 @@
 package commands
 
 import (
+	"bytes"
+	"errors"
 	"fmt"
+	"io"
+	"net"
+	"net/http"
 	"os"
 	"path/filepath"
 	"sync"
+	"time"
 
 	"github.com/10gen/realm-cli/api"
 	"github.com/10gen/realm-cli/hosting"
@@ -14,73 +20,185 @@ import (
 	"github.com/mitchellh/go-homedir"
 )
 
-// checkErrs builds a list of errors from the error channel errChan and logs them
-func checkErrs(errChan <-chan error, errDoneChan chan<- struct{}, ui cli.Ui, errors *[]error) {
-	for err := range errChan {
-		*errors = append(*errors, err)
-		ui.Error(err.Error())
-	}
-	errDoneChan <- struct{}{}
-}
-
-// ImportHosting will push local Realm hosting assets to the server
-func ImportHosting(groupID, appID, rootDir string, assetMetadataDiffs *hosting.AssetMetadataDiffs, resetCache bool, client api.RealmClient, ui cli.Ui) error {
-	// build a channel of hosting operations
-	var opWG sync.WaitGroup
-	opChan := make(chan hostingOp)
-	errChan := make(chan error)
-	errDoneChan := make(chan struct{})
-
-	var errors []error
-	go checkErrs(errChan, errDoneChan, ui, &errors)
-
-	// create workers
-	for n := 0; n < numWorkers; n++ {
-		opWG.Add(1)
-		go hostingOpHandler(opChan, &opWG, errChan)
+const hostingMaxAttempts = 3
+
+// hostingRetryBackoff is the delay before the second attempt of a failed operation, doubled for each attempt after it
+var hostingRetryBackoff = time.Second
+
+// ImportHosting will push local Realm hosting assets to the server, and if resetCache is set invalidate
+// the CDN cache for the changed paths, collapsed into directory wildcards above invalidationThreshold,
+// including those of the operations that succeeded when others failed.
+// The requests are made by limits.Concurrency workers, at no more than limits.MaxRPS per second.
+// Operations that fail with a transient error are retried, and those that still fail are recorded in
+// a manifest next to rootDir which 'hosting import --resume' retries
+func ImportHosting(groupID, appID, rootDir string, assetMetadataDiffs *hosting.AssetMetadataDiffs, resetCache bool, invalidationThreshold int, limits HostingLimits, client api.RealmClient, ui cli.Ui) error {
+	rootDir, err := filepath.Abs(rootDir)
+	if err != nil {
+		return err
 	}
 
 	baseOp := baseHostingOp{groupID, appID, rootDir, client}
-	// create hosting Ops to be handled
+
+	var ops []hostingOp
 	for _, added := range assetMetadataDiffs.AddedLocally {
-		opChan <- &addOp{baseOp, added}
+		ops = append(ops, &addOp{baseOp, added})
 	}
 
 	for _, deleted := range assetMetadataDiffs.DeletedLocally {
-		opChan <- &deleteOp{baseOp, deleted}
+		ops = append(ops, &deleteOp{baseOp, deleted})
 	}
 
 	for _, modified := range assetMetadataDiffs.ModifiedLocally {
-		opChan <- &modifyOp{baseOp, modified}
+		ops = append(ops, &modifyOp{baseOp, modified})
+	}
+
+	for _, renamed := range assetMetadataDiffs.RenamedLocally {
+		ops = append(ops, &renameOp{baseHostingOp: baseOp, renamedAssetMetadata: renamed})
+	}
+
+	var totalBytes int64
+	for _, op := range ops {
+		totalBytes += op.size()
+	}
+
+	progress := newHostingProgress(ui, "Importing hosting assets", len(ops), totalBytes)
+	progress.start()
+
+	manifest := &hostingManifest{GroupID: groupID, AppID: appID, RootDir: rootDir}
+	changes := &hostingChanges{diffs: hosting.NewAssetMetadataDiffs(nil, nil, nil)}
+
+	// build a channel of hosting operations
+	var opWG sync.WaitGroup
+	opChan := make(chan hostingOp)
+
+	// create workers
+	limiter := limits.limiter()
+	for n := 0; n < limits.Concurrency; n++ {
+		opWG.Add(1)
+		go hostingOpHandler(opChan, &opWG, limiter, progress, manifest, changes)
+	}
+
+	for _, op := range ops {
+		opChan <- op
 	}
 
 	close(opChan)
 	opWG.Wait()
-	close(errChan)
-	<-errDoneChan
+	progress.finish()
 
-	if len(errors) > 0 {
-		return fmt.Errorf("%v error(s) occurred while importing hosting assets", len(errors))
+	// the paths changed by the operations that succeeded are invalidated even if others failed,
+	// since a resumed import only invalidates the paths it changes itself
+	var invalidateErr error
+	if resetCache {
+		for _, path := range changes.diffs.InvalidationPaths(invalidationThreshold) {
+			if invalidateErr = client.InvalidateCache(groupID, appID, path); invalidateErr != nil {
+				break
+			}
+		}
 	}
 
-	if resetCache {
-		if err := client.InvalidateCache(groupID, appID, "/*"); err != nil {
-			return err
+	manifestPath := hostingManifestPath(rootDir)
+	if len(progress.errors) > 0 {
+		if writeErr := writeHostingManifest(manifestPath, manifest); writeErr != nil {
+			return fmt.Errorf("%v error(s) occurred while importing hosting assets, and the failed operations could not be recorded: %s", len(progress.errors), writeErr)
+		}
+		if invalidateErr != nil {
+			return fmt.Errorf("%v error(s) occurred while importing hosting assets, and the cdn cache could not be invalidated for the imported ones: %s; retry the failed operations with 'realm-cli hosting import --resume %s'", len(progress.errors), invalidateErr, manifestPath)
 		}
+		return fmt.Errorf("%v error(s) occurred while importing hosting assets, retry the failed operations with 'realm-cli hosting import --resume %s'", len(progress.errors), manifestPath)
 	}
 
-	return nil
+	// the failures of a previous import have been made up for
+	if removeErr := os.Remove(manifestPath); removeErr != nil && !os.IsNotExist(removeErr) {
+		return removeErr
+	}
+
+	return invalidateErr
+}
+
+// hostingChanges collects the changes made to the remote assets, whose paths are invalidated in the cdn cache
+type hostingChanges struct {
+	diffs *hosting.AssetMetadataDiffs
+
+	mu sync.Mutex
+}
+
+func (c *hostingChanges) addAdded(am hosting.AssetMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.diffs.AddedLocally = append(c.diffs.AddedLocally, am)
+}
+
+func (c *hostingChanges) addDeleted(am hosting.AssetMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.diffs.DeletedLocally = append(c.diffs.DeletedLocally, am)
+}
+
+func (c *hostingChanges) addModified(mAM hosting.ModifiedAssetMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.diffs.ModifiedLocally = append(c.diffs.ModifiedLocally, mAM)
+}
+
+func (c *hostingChanges) addRenamed(rAM hosting.RenamedAssetMetadata) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.diffs.RenamedLocally = append(c.diffs.RenamedLocally, rAM)
 }
 
-func hostingOpHandler(opChan <-chan hostingOp, opWG *sync.WaitGroup, errChan chan<- error) {
+func hostingOpHandler(opChan <-chan hostingOp, opWG *sync.WaitGroup, limiter *hostingLimiter, progress *hostingProgress, manifest *hostingManifest, changes *hostingChanges) {
 	defer opWG.Done()
 
 	for op := range opChan {
-		if doErr := op.Do(); doErr != nil {
-			errChan <- doErr
-			continue
+		start := time.Now()
+		err := doWithRetries(op.Do, limiter)
+		progress.record(op.kind(), op.size(), time.Since(start), err)
+
+		op.recordChange(changes, err)
+		if err != nil {
+			op.recordFailure(manifest, err)
+		}
+	}
+}
+
+// doWithRetries calls do, retrying it with an exponential backoff for as long as it fails with a transient error
+func doWithRetries(do func() error, limiter *hostingLimiter) error {
+	backoff := hostingRetryBackoff
+
+	for attempt := 1; ; attempt++ {
+		limiter.wait()
+
+		err := do()
+		if err == nil || attempt == hostingMaxAttempts || !isTransientHostingError(err) {
+			return err
 		}
+
+		time.Sleep(backoff)
+		backoff *= 2
+	}
+}
+
+// isTransientHostingError reports whether a request failed in a way that may succeed when retried,
+// which is the case when it was throttled, met a server error, could not reach the server
+// or downloaded a corrupted asset
+func isTransientHostingError(err error) bool {
+	var mismatchErr errAssetHashMismatch
+	if errors.As(err, &mismatchErr) {
+		return true
 	}
+
+	var realmErr api.ErrRealmResponse
+	if errors.As(err, &realmErr) {
+		return realmErr.StatusCode() == http.StatusTooManyRequests || realmErr.StatusCode() >= http.StatusInternalServerError
+	}
+
+	var netErr net.Error
+	return errors.As(err, &netErr) || errors.Is(err, io.ErrUnexpectedEOF)
 }
 
 type baseHostingOp struct {
@@ -93,6 +211,16 @@ type baseHostingOp struct {
 // hostingOp represents an import operation done with hosting assets
 type hostingOp interface {
 	Do() error
+
+	// kind and size describe the request made by Do and the number of bytes it uploads
+	kind() hostingOpKind
+	size() int64
+
+	// recordChange adds the operation to the changes if it changed the remote asset, even though it failed with err
+	recordChange(changes *hostingChanges, err error)
+
+	// recordFailure adds the operation to the manifest of failed operations
+	recordFailure(manifest *hostingManifest, err error)
 }
 
 type addOp struct {
@@ -105,6 +233,24 @@ func (op *addOp) Do() error {
 	return doUpload(op.groupID, op.appID, op.rootDir, op.client, op.assetMetadata)
 }
 
+func (op *addOp) kind() hostingOpKind {
+	return hostingOpUpload
+}
+
+func (op *addOp) size() int64 {
+	return op.assetMetadata.FileSize
+}
+
+func (op *addOp) recordChange(changes *hostingChanges, err error) {
+	if err == nil {
+		changes.addAdded(op.assetMetadata)
+	}
+}
+
+func (op *addOp) recordFailure(manifest *hostingManifest, err error) {
+	manifest.addAdded(op.assetMetadata, err)
+}
+
 type deleteOp struct {
 	baseHostingOp
 	assetMetadata hosting.AssetMetadata
@@ -114,11 +260,29 @@ type deleteOp struct {
 func (op *deleteOp) Do() error {
 	fp := op.assetMetadata.FilePath
 	if err := op.client.DeleteAsset(op.groupID, op.appID, fp); err != nil {
-		return fmt.Errorf("deleting '%s' failed => %s", fp, err)
+		return fmt.Errorf("deleting '%s' failed => %w", fp, err)
 	}
 	return nil
 }
 
+func (op *deleteOp) kind() hostingOpKind {
+	return hostingOpDelete
+}
+
+func (op *deleteOp) size() int64 {
+	return 0
+}
+
+func (op *deleteOp) recordChange(changes *hostingChanges, err error) {
+	if err == nil {
+		changes.addDeleted(op.assetMetadata)
+	}
+}
+
+func (op *deleteOp) recordFailure(manifest *hostingManifest, err error) {
+	manifest.addDeleted(op.assetMetadata, err)
+}
+
 type modifyOp struct {
 	baseHostingOp
 	modifiedAssetMetadata hosting.ModifiedAssetMetadata
@@ -136,7 +300,7 @@ func (op *modifyOp) Do() error {
 				op.appID,
 				fp,
 				mAM.AssetMetadata.Attrs...); err != nil {
-			return fmt.Errorf("%s => %s", fp, err)
+			return fmt.Errorf("%s => %w", fp, err)
 		}
 
 		return nil
@@ -149,37 +313,220 @@ func (op *modifyOp) Do() error {
 	return nil
 }
 
+func (op *modifyOp) kind() hostingOpKind {
+	if op.modifiedAssetMetadata.AttrModified && !op.modifiedAssetMetadata.BodyModified {
+		return hostingOpSetAttributes
+	}
+	return hostingOpUpload
+}
+
+func (op *modifyOp) size() int64 {
+	if op.kind() == hostingOpSetAttributes {
+		return 0
+	}
+	return op.modifiedAssetMetadata.AssetMetadata.FileSize
+}
+
+func (op *modifyOp) recordChange(changes *hostingChanges, err error) {
+	if err == nil {
+		changes.addModified(op.modifiedAssetMetadata)
+	}
+}
+
+func (op *modifyOp) recordFailure(manifest *hostingManifest, err error) {
+	manifest.addModified(op.modifiedAssetMetadata, err)
+}
+
+type renameOp struct {
+	baseHostingOp
+	renamedAssetMetadata hosting.RenamedAssetMetadata
+
+	// moved is set once the asset has been moved or copied, so that a retry only sets its attributes
+	moved bool
+}
+
+// Do moves or copies the remote asset to its new path, then sets its attributes if they differ
+func (op *renameOp) Do() error {
+	rAM := op.renamedAssetMetadata
+	fp := rAM.AssetMetadata.FilePath
+
+	if !op.moved {
+		if rAM.Copy {
+			if err := op.client.CopyAsset(op.groupID, op.appID, rAM.FromPath, fp); err != nil {
+				return fmt.Errorf("copying '%s' to '%s' failed => %w", rAM.FromPath, fp, err)
+			}
+		} else {
+			if err := op.client.MoveAsset(op.groupID, op.appID, rAM.FromPath, fp); err != nil {
+				return fmt.Errorf("moving '%s' to '%s' failed => %w", rAM.FromPath, fp, err)
+			}
+		}
+		op.moved = true
+	}
+
+	if rAM.AttrModified {
+		if err := op.client.SetAssetAttributes(op.groupID, op.appID, fp, rAM.AssetMetadata.Attrs...); err != nil {
+			return fmt.Errorf("%s => %w", fp, err)
+		}
+	}
+
+	return nil
+}
+
+func (op *renameOp) kind() hostingOpKind {
+	if op.renamedAssetMetadata.Copy {
+		return hostingOpCopy
+	}
+	return hostingOpMove
+}
+
+func (op *renameOp) size() int64 {
+	return 0
+}
+
+func (op *renameOp) recordChange(changes *hostingChanges, err error) {
+	if err == nil || op.moved {
+		changes.addRenamed(op.renamedAssetMetadata)
+	}
+}
+
+func (op *renameOp) recordFailure(manifest *hostingManifest, err error) {
+	manifest.addRenamed(op.renamedAssetMetadata, op.moved, err)
+}
+
 func doUpload(groupID, appID, rootDir string, client api.RealmClient, am hosting.AssetMetadata) error {
+	return uploadFile(groupID, appID, filepath.Join(rootDir, am.FilePath), client, am)
+}
+
+// uploadFile uploads the local file at localPath as the asset described by am
+func uploadFile(groupID, appID, localPath string, client api.RealmClient, am hosting.AssetMetadata) error {
 	errStrF := "uploading '%s' failed => %s"
 
-	body, bodyErr := os.Open(filepath.Join(rootDir, am.FilePath))
+	file, bodyErr := os.Open(localPath)
 	if bodyErr != nil {
 		return fmt.Errorf(errStrF, am.FilePath, bodyErr)
 	}
-	defer body.Close()
+	defer file.Close()
+
+	var body io.Reader = file
+	if am.Compression != hosting.CompressionNone {
+		compressed, compressErr := am.Compression.Compress(file)
+		if compressErr != nil {
+			return fmt.Errorf(errStrF, am.FilePath, compressErr)
+		}
+		body = bytes.NewReader(compressed)
+	}
 
 	if uploadErr := client.UploadAsset(groupID, appID, am.FilePath, am.FileHash, am.FileSize, body, am.Attrs...); uploadErr != nil {
-		return fmt.Errorf(errStrF, am.FilePath, uploadErr)
+		return fmt.Errorf("uploading '%s' failed => %w", am.FilePath, uploadErr)
 	}
 
 	return nil
 }
 
 func getAssetCachePath(configPath string) (string, error) {
-	cachePath, eErr := homedir.Expand(configPath)
+	configDir, err := getConfigDir(configPath)
+	if err != nil {
+		return "", err
+	}
+
+	return filepath.Join(configDir, utils.HostingCacheFileName), nil
+}
+
+// getConfigDir returns the directory of the user configuration file at configPath,
+// which defaults to ~/.config/realm
+func getConfigDir(configPath string) (string, error) {
+	configDir, eErr := homedir.Expand(configPath)
 	if eErr != nil {
 		return "", eErr
 	}
 
-	if cachePath == "" {
+	if configDir == "" {
 		home, dirErr := homedir.Dir()
 		if dirErr != nil {
 			return "", dirErr
 		}
-		cachePath = filepath.Join(home, ".config", "realm")
-	} else {
-		cachePath = filepath.Dir(cachePath)
+		return filepath.Join(home, ".config", "realm"), nil
+	}
+
+	return filepath.Dir(configDir), nil
+}
+
+// listLocalHostingAssets builds the AssetMetadata of the files in rootDir for the app with the client appID,
+// described by the metadata file at metadataPath unless it is empty, and stores the hashes it generates
+// in the asset cache next to configPath. If verify is set, every file is rehashed. The assets must pass
+// the checks of options before any of them is uploaded
+func listLocalHostingAssets(appID, rootDir, metadataPath, configPath string, excludes []string, compression hosting.Compression, verify bool, options hostingAssetOptions, ui cli.Ui) ([]hosting.AssetMetadata, error) {
+	assetDescs, attributeRules, err := loadLocalHostingMetadata(metadataPath)
+	if err != nil {
+		return nil, err
+	}
+
+	cachePath, err := getAssetCachePath(configPath)
+	if err != nil {
+		return nil, err
+	}
+
+	assetCache, err := hosting.CacheFileToAssetCache(cachePath)
+	if err != nil {
+		if !os.IsNotExist(err) {
+			return nil, err
+		}
+		assetCache = hosting.NewAssetCache()
+	}
+
+	if verify {
+		assetCache = hosting.NewVerifyingAssetCache(assetCache)
+	}
+
+	ignoreRules, err := hosting.LoadIgnoreRules(rootDir, excludes)
+	if err != nil {
+		return nil, fmt.Errorf("error loading %s file: %v", hosting.IgnoreFileName, err)
+	}
+
+	assetMetadata, err := hosting.ListLocalAssetMetadata(appID, rootDir, assetDescs, attributeRules, assetCache, ignoreRules, compression, options.followSymlinks)
+	if err != nil {
+		return nil, fmt.Errorf("error processing local assets %s: %s", rootDir, err)
+	}
+
+	if assetCache.Dirty() {
+		if err := hosting.UpdateCacheFile(cachePath, assetCache); err != nil {
+			ui.Error(err.Error())
+		}
+	}
+
+	if err := options.check(assetMetadata); err != nil {
+		return nil, err
+	}
+
+	return assetMetadata, nil
+}
+
+// loadLocalHostingMetadata reads the AssetDescriptions and AttributeRules of the metadata file at metadataPath,
+// which are empty if metadataPath is
+func loadLocalHostingMetadata(metadataPath string) (map[string]hosting.AssetDescription, hosting.AttributeRules, error) {
+	if metadataPath == "" {
+		return map[string]hosting.AssetDescription{}, nil, nil
+	}
+
+	assetDescs, err := hosting.MetadataFileToAssetDescriptions(metadataPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading metadata.json file: %v", err)
 	}
 
-	return filepath.Join(cachePath, utils.HostingCacheFileName), nil
+	attributeRules, err := hosting.MetadataFileToAttributeRules(metadataPath)
+	if err != nil {
+		return nil, nil, fmt.Errorf("error loading metadata.json file: %v", err)
+	}
+
+	return assetDescs, attributeRules, nil
+}
+
+// localHostingMetadataPath returns the path of the metadata file next to the hosting files directory rootDir,
+// or an empty path if there is none
+func localHostingMetadataPath(rootDir string) string {
+	metadataPath := filepath.Join(filepath.Dir(rootDir), filepath.Base(utils.HostingAttributes))
+	if _, err := os.Stat(metadataPath); err != nil {
+		return ""
+	}
+	return metadataPath
 }
@@ -1,12 +1,23 @@
 package commands
 
 import (
+	"bytes"
+	"compress/gzip"
+	"errors"
 	"fmt"
+	"io"
+	"io/ioutil"
 	"net/http"
 	"net/http/httptest"
+	"net/url"
+	"os"
 	"path/filepath"
+	"sort"
 	"strings"
+	"sync"
+	"sync/atomic"
 	"testing"
+	"time"
 
 	"github.com/10gen/realm-cli/api"
 	"github.com/10gen/realm-cli/hosting"
@@ -30,7 +41,7 @@ func TestImportHosting(t *testing.T) {
 	u.So(t, relErr, gc.ShouldBeNil)
 
 	assetMetadataDiffs := &hosting.AssetMetadataDiffs{
-		[]hosting.AssetMetadata{
+		AddedLocally: []hosting.AssetMetadata{
 			{
 				FilePath: fmt.Sprintf("/%s", relPath0),
 			},
@@ -38,12 +49,12 @@ func TestImportHosting(t *testing.T) {
 				FilePath: fmt.Sprintf("/%s", relPath1),
 			},
 		},
-		[]hosting.AssetMetadata{
+		DeletedLocally: []hosting.AssetMetadata{
 			{
 				FilePath: "/deleteMe",
 			},
 		},
-		[]hosting.ModifiedAssetMetadata{},
+		ModifiedLocally: []hosting.ModifiedAssetMetadata{},
 	}
 
 	t.Run("should work with a client", func(t *testing.T) {
@@ -52,22 +63,240 @@ func TestImportHosting(t *testing.T) {
 		}
 		testServer := httptest.NewServer(http.HandlerFunc(testHandler))
 		testClient := api.NewRealmClient(api.NewClient(testServer.URL))
-		u.So(t, ImportHosting("groupID", "appID", rootDir, assetMetadataDiffs, false, testClient, cli.NewMockUi()), gc.ShouldBeNil)
+		u.So(t, ImportHosting("groupID", "appID", rootDir, assetMetadataDiffs, false, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, testClient, cli.NewMockUi()), gc.ShouldBeNil)
 	})
 
 	t.Run("should log errors correctly", func(t *testing.T) {
+		defer func(backoff time.Duration) { hostingRetryBackoff = backoff }(hostingRetryBackoff)
+		hostingRetryBackoff = time.Millisecond
+
+		manifestPath := hostingManifestPath(rootDir)
+		defer os.Remove(manifestPath)
+
+		var requests int32
 		testHandler := func(w http.ResponseWriter, r *http.Request) {
+			atomic.AddInt32(&requests, 1)
 			w.WriteHeader(http.StatusInternalServerError)
 		}
 		testServer := httptest.NewServer(http.HandlerFunc(testHandler))
 		testClient := api.NewRealmClient(api.NewClient(testServer.URL))
 
 		mockUI := cli.NewMockUi()
-		importErr := ImportHosting("groupID", "appID", rootDir, assetMetadataDiffs, false, testClient, mockUI)
+		importErr := ImportHosting("groupID", "appID", rootDir, assetMetadataDiffs, false, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, testClient, mockUI)
 		u.So(t, importErr, gc.ShouldNotBeNil)
-		u.So(t, importErr.Error(), gc.ShouldContainSubstring, "3")
+		u.So(t, importErr.Error(), gc.ShouldStartWith, "3 error(s) occurred while importing hosting assets")
+		u.So(t, importErr.Error(), gc.ShouldContainSubstring, "hosting import --resume "+manifestPath)
 		u.So(t, len(strings.Split(mockUI.ErrorWriter.String(), "\n"))-1, gc.ShouldEqual, 3)
+
+		t.Run("after retrying each operation", func(t *testing.T) {
+			u.So(t, atomic.LoadInt32(&requests), gc.ShouldEqual, 3*hostingMaxAttempts)
+		})
+
+		t.Run("and record the failed operations in a manifest", func(t *testing.T) {
+			manifest, err := readHostingManifest(manifestPath)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, manifest.GroupID, gc.ShouldEqual, "groupID")
+			u.So(t, manifest.AppID, gc.ShouldEqual, "appID")
+			u.So(t, manifest.RootDir, gc.ShouldEqual, rootDir)
+
+			diffs, err := manifest.diffs()
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, diffs.AddedLocally, gc.ShouldHaveLength, 2)
+			u.So(t, diffs.DeletedLocally, gc.ShouldResemble, []hosting.AssetMetadata{{FilePath: "/deleteMe"}})
+			u.So(t, diffs.ModifiedLocally, gc.ShouldBeEmpty)
+			u.So(t, manifest.Deleted[0].Error, gc.ShouldContainSubstring, "deleting '/deleteMe' failed")
+		})
+
+		t.Run("which is removed once an import succeeds", func(t *testing.T) {
+			u.So(t, ImportHosting("groupID", "appID", rootDir, hosting.NewAssetMetadataDiffs(nil, nil, nil), false, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, testClient, cli.NewMockUi()), gc.ShouldBeNil)
+
+			_, err := os.Stat(manifestPath)
+			u.So(t, os.IsNotExist(err), gc.ShouldBeTrue)
+		})
+	})
+
+	t.Run("should not retry operations that failed with a client error", func(t *testing.T) {
+		manifestPath := hostingManifestPath(rootDir)
+		defer os.Remove(manifestPath)
+
+		var requests int32
+		realmClient := &u.MockRealmClient{
+			DeleteAssetFn: func(groupID, appID, path string) error {
+				atomic.AddInt32(&requests, 1)
+				return api.UnmarshalRealmError(&http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       u.NewResponseBody(strings.NewReader(`{"error": "not found"}`)),
+				})
+			},
+		}
+
+		diffs := hosting.NewAssetMetadataDiffs(nil, []hosting.AssetMetadata{{FilePath: "/deleteMe"}}, nil)
+		u.So(t, ImportHosting("groupID", "appID", rootDir, diffs, false, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, realmClient, cli.NewMockUi()), gc.ShouldNotBeNil)
+		u.So(t, atomic.LoadInt32(&requests), gc.ShouldEqual, 1)
 	})
+
+	t.Run("should invalidate the paths of the operations that succeeded when others failed", func(t *testing.T) {
+		manifestPath := hostingManifestPath(rootDir)
+		defer os.Remove(manifestPath)
+
+		var mu sync.Mutex
+		var invalidated []string
+		realmClient := &u.MockRealmClient{
+			UploadAssetFn: func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error {
+				return nil
+			},
+			DeleteAssetFn: func(groupID, appID, path string) error {
+				return api.UnmarshalRealmError(&http.Response{
+					StatusCode: http.StatusNotFound,
+					Body:       u.NewResponseBody(strings.NewReader(`{"error": "not found"}`)),
+				})
+			},
+			InvalidateCacheFn: func(groupID, appID, path string) error {
+				mu.Lock()
+				defer mu.Unlock()
+				invalidated = append(invalidated, path)
+				return nil
+			},
+		}
+
+		importErr := ImportHosting("groupID", "appID", rootDir, assetMetadataDiffs, true, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, realmClient, cli.NewMockUi())
+		u.So(t, importErr, gc.ShouldNotBeNil)
+		u.So(t, importErr.Error(), gc.ShouldStartWith, "1 error(s) occurred while importing hosting assets")
+
+		sort.Strings(invalidated)
+		u.So(t, invalidated, gc.ShouldResemble, []string{"/" + relPath0, "/" + filepath.ToSlash(relPath1)})
+	})
+
+	t.Run("should upload the compressed body of compressed assets", func(t *testing.T) {
+		contents, err := ioutil.ReadFile(path0)
+		u.So(t, err, gc.ShouldBeNil)
+
+		var uploaded []byte
+		realmClient := &u.MockRealmClient{
+			UploadAssetFn: func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error {
+				data, err := ioutil.ReadAll(body)
+				uploaded = data
+				return err
+			},
+		}
+
+		compressedDiffs := hosting.NewAssetMetadataDiffs([]hosting.AssetMetadata{
+			{FilePath: fmt.Sprintf("/%s", relPath0), Compression: hosting.CompressionGzip},
+		}, nil, nil)
+		u.So(t, ImportHosting("groupID", "appID", rootDir, compressedDiffs, false, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, realmClient, cli.NewMockUi()), gc.ShouldBeNil)
+
+		gr, err := gzip.NewReader(bytes.NewReader(uploaded))
+		u.So(t, err, gc.ShouldBeNil)
+		decompressed, err := ioutil.ReadAll(gr)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, decompressed, gc.ShouldResemble, contents)
+	})
+
+	t.Run("should move and copy renamed assets instead of uploading them", func(t *testing.T) {
+		var requests []string
+		var mu sync.Mutex
+		record := func(request string) {
+			mu.Lock()
+			defer mu.Unlock()
+			requests = append(requests, request)
+		}
+
+		realmClient := &u.MockRealmClient{
+			MoveAssetFn: func(groupID, appID, fromPath, toPath string) error {
+				record("move " + fromPath + " " + toPath)
+				return nil
+			},
+			CopyAssetFn: func(groupID, appID, fromPath, toPath string) error {
+				record("copy " + fromPath + " " + toPath)
+				return nil
+			},
+			SetAssetAttributesFn: func(groupID, appID, path string, attrs ...hosting.AssetAttribute) error {
+				record("attributes " + path)
+				return nil
+			},
+			UploadAssetFn: func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error {
+				record("upload " + path)
+				return nil
+			},
+		}
+
+		renamedDiffs := hosting.NewAssetMetadataDiffs(nil, nil, nil)
+		renamedDiffs.RenamedLocally = []hosting.RenamedAssetMetadata{
+			{AssetMetadata: hosting.AssetMetadata{FilePath: "/new.json"}, FromPath: "/old.json", AttrModified: true},
+			{AssetMetadata: hosting.AssetMetadata{FilePath: "/copy.json"}, FromPath: "/kept.json", Copy: true},
+		}
+		u.So(t, ImportHosting("groupID", "appID", rootDir, renamedDiffs, false, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, realmClient, cli.NewMockUi()), gc.ShouldBeNil)
+
+		sort.Strings(requests)
+		u.So(t, requests, gc.ShouldResemble, []string{
+			"attributes /new.json",
+			"copy /kept.json /copy.json",
+			"move /old.json /new.json",
+		})
+	})
+
+	t.Run("should not move a renamed asset again when setting its attributes fails", func(t *testing.T) {
+		defer func(backoff time.Duration) { hostingRetryBackoff = backoff }(hostingRetryBackoff)
+		hostingRetryBackoff = time.Millisecond
+
+		manifestPath := hostingManifestPath(rootDir)
+		defer os.Remove(manifestPath)
+
+		var moves, attempts int32
+		realmClient := &u.MockRealmClient{
+			MoveAssetFn: func(groupID, appID, fromPath, toPath string) error {
+				atomic.AddInt32(&moves, 1)
+				return nil
+			},
+			SetAssetAttributesFn: func(groupID, appID, path string, attrs ...hosting.AssetAttribute) error {
+				atomic.AddInt32(&attempts, 1)
+				return api.UnmarshalRealmError(&http.Response{
+					StatusCode: http.StatusServiceUnavailable,
+					Body:       u.NewResponseBody(strings.NewReader("")),
+				})
+			},
+		}
+
+		renamedDiffs := hosting.NewAssetMetadataDiffs(nil, nil, nil)
+		renamedDiffs.RenamedLocally = []hosting.RenamedAssetMetadata{
+			{AssetMetadata: hosting.AssetMetadata{FilePath: "/new.json"}, FromPath: "/old.json", AttrModified: true},
+		}
+		u.So(t, ImportHosting("groupID", "appID", rootDir, renamedDiffs, false, hosting.DefaultInvalidationThreshold, DefaultHostingLimits, realmClient, cli.NewMockUi()), gc.ShouldNotBeNil)
+		u.So(t, atomic.LoadInt32(&moves), gc.ShouldEqual, 1)
+		u.So(t, atomic.LoadInt32(&attempts), gc.ShouldEqual, hostingMaxAttempts)
+
+		manifest, err := readHostingManifest(manifestPath)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, manifest.Renamed, gc.ShouldHaveLength, 1)
+		u.So(t, manifest.Renamed[0].Moved, gc.ShouldBeTrue)
+	})
+}
+
+func TestIsTransientHostingError(t *testing.T) {
+	realmError := func(statusCode int) error {
+		return api.UnmarshalRealmError(&http.Response{
+			StatusCode: statusCode,
+			Body:       u.NewResponseBody(strings.NewReader("")),
+		})
+	}
+
+	for _, tc := range []struct {
+		description string
+		err         error
+		expected    bool
+	}{
+		{"a throttled request", realmError(http.StatusTooManyRequests), true},
+		{"a server error", fmt.Errorf("uploading '/a' failed => %w", realmError(http.StatusBadGateway)), true},
+		{"a client error", realmError(http.StatusBadRequest), false},
+		{"a connection error", &url.Error{Op: "Put", URL: "http://localhost", Err: errors.New("connection reset by peer")}, true},
+		{"an interrupted response", fmt.Errorf("reading failed => %w", io.ErrUnexpectedEOF), true},
+		{"a corrupted download", errAssetHashMismatch{"/a", "abc", "def"}, true},
+		{"a local error", errors.New("open /a: no such file or directory"), false},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			u.So(t, isTransientHostingError(tc.err), gc.ShouldEqual, tc.expected)
+		})
+	}
 }
 
 func TestHostingOp(t *testing.T) {
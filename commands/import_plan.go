@@ -0,0 +1,192 @@
+package commands
+
+import (
+	"bytes"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"path/filepath"
+	"sort"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/utils"
+)
+
+// ImportPlan describes the changes an import would make to a deployed app
+type ImportPlan struct {
+	AppID        string                  `json:"app_id"`
+	Strategy     string                  `json:"strategy"`
+	Diffs        []string                `json:"diffs"`
+	Hosting      *ImportPlanHosting      `json:"hosting,omitempty"`
+	Dependencies *ImportPlanDependencies `json:"dependencies,omitempty"`
+}
+
+// ImportPlanHosting describes the changes an import would make to the app's hosted assets
+type ImportPlanHosting struct {
+	Added    []ImportPlanAsset `json:"added"`
+	Deleted  []ImportPlanAsset `json:"deleted"`
+	Modified []ImportPlanAsset `json:"modified"`
+	Renamed  []ImportPlanAsset `json:"renamed,omitempty"`
+}
+
+// ImportPlanAsset describes a single hosted asset that an import would change
+type ImportPlanAsset struct {
+	Path         string `json:"path"`
+	Hash         string `json:"hash,omitempty"`
+	BodyModified bool   `json:"body_modified,omitempty"`
+	AttrModified bool   `json:"attrs_modified,omitempty"`
+	FromPath     string `json:"from_path,omitempty"`
+	Copy         bool   `json:"copy,omitempty"`
+}
+
+// ImportPlanDependencies describes the dependencies archive an import would upload
+type ImportPlanDependencies struct {
+	Archive string `json:"archive"`
+	Hash    string `json:"hash"`
+}
+
+// newImportPlanHosting builds an ImportPlanHosting from the diffs of the local and remote assets
+func newImportPlanHosting(assetMetadataDiffs *hosting.AssetMetadataDiffs) *ImportPlanHosting {
+	planHosting := &ImportPlanHosting{
+		Added:    []ImportPlanAsset{},
+		Deleted:  []ImportPlanAsset{},
+		Modified: []ImportPlanAsset{},
+	}
+
+	for _, added := range assetMetadataDiffs.AddedLocally {
+		planHosting.Added = append(planHosting.Added, ImportPlanAsset{Path: added.FilePath, Hash: added.FileHash})
+	}
+	for _, deleted := range assetMetadataDiffs.DeletedLocally {
+		planHosting.Deleted = append(planHosting.Deleted, ImportPlanAsset{Path: deleted.FilePath, Hash: deleted.FileHash})
+	}
+	for _, modified := range assetMetadataDiffs.ModifiedLocally {
+		planHosting.Modified = append(planHosting.Modified, ImportPlanAsset{
+			Path:         modified.AssetMetadata.FilePath,
+			Hash:         modified.AssetMetadata.FileHash,
+			BodyModified: modified.BodyModified,
+			AttrModified: modified.AttrModified,
+		})
+	}
+
+	for _, renamed := range assetMetadataDiffs.RenamedLocally {
+		planHosting.Renamed = append(planHosting.Renamed, ImportPlanAsset{
+			Path:         renamed.AssetMetadata.FilePath,
+			Hash:         renamed.AssetMetadata.FileHash,
+			AttrModified: renamed.AttrModified,
+			FromPath:     renamed.FromPath,
+			Copy:         renamed.Copy,
+		})
+	}
+
+	for _, assets := range [][]ImportPlanAsset{planHosting.Added, planHosting.Deleted, planHosting.Modified, planHosting.Renamed} {
+		sort.Slice(assets, func(i, j int) bool { return assets[i].Path < assets[j].Path })
+	}
+
+	return planHosting
+}
+
+// newImportPlanDependencies builds an ImportPlanDependencies from the archive or directory of dependencies
+// found in the functions directory
+func newImportPlanDependencies(appPath string) (*ImportPlanDependencies, error) {
+	functionsDir := filepath.Join(appPath, utils.FunctionsRoot)
+
+	deps, err := loadDependencies(functionsDir)
+	if err != nil {
+		return nil, err
+	}
+
+	hash, err := deps.hash()
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the dependencies '%s': %s", deps.path, err)
+	}
+
+	return &ImportPlanDependencies{
+		Archive: filepath.ToSlash(filepath.Join(utils.FunctionsRoot, filepath.Base(deps.path))),
+		Hash:    hash,
+	}, nil
+}
+
+// HasChanges returns whether the ImportPlan contains any changes or not
+func (ip *ImportPlan) HasChanges() bool {
+	if len(ip.Diffs) != 0 || ip.Dependencies != nil {
+		return true
+	}
+
+	return ip.Hosting != nil &&
+		(len(ip.Hosting.Added) != 0 || len(ip.Hosting.Deleted) != 0 || len(ip.Hosting.Modified) != 0 || len(ip.Hosting.Renamed) != 0)
+}
+
+// Diff returns a list of strings representing the plan
+func (ip *ImportPlan) Diff() []string {
+	diff := append([]string{}, ip.Diffs...)
+
+	if ip.Hosting != nil {
+		if len(ip.Hosting.Added) > 0 {
+			diff = append(diff, "New Files:")
+		}
+		for _, added := range ip.Hosting.Added {
+			diff = append(diff, fmt.Sprintf("\t+ %s", added.Path))
+		}
+
+		if len(ip.Hosting.Deleted) > 0 {
+			diff = append(diff, "Removed Files:")
+		}
+		for _, deleted := range ip.Hosting.Deleted {
+			diff = append(diff, fmt.Sprintf("\t- %s", deleted.Path))
+		}
+
+		if len(ip.Hosting.Modified) > 0 {
+			diff = append(diff, "Modified Files:")
+		}
+		for _, modified := range ip.Hosting.Modified {
+			diff = append(diff, fmt.Sprintf("\t* %s", modified.Path))
+		}
+
+		if len(ip.Hosting.Renamed) > 0 {
+			diff = append(diff, "Renamed Files:")
+		}
+		for _, renamed := range ip.Hosting.Renamed {
+			line := fmt.Sprintf("\t> %s -> %s", renamed.FromPath, renamed.Path)
+			if renamed.Copy {
+				line += " (copy)"
+			}
+			diff = append(diff, line)
+		}
+	}
+
+	if ip.Dependencies != nil {
+		diff = append(diff, "Import dependencies")
+	}
+
+	return diff
+}
+
+// Equal returns whether the ImportPlan describes the same changes as other
+func (ip *ImportPlan) Equal(other *ImportPlan) bool {
+	a, aErr := ip.canonicalJSON()
+	b, bErr := other.canonicalJSON()
+	return aErr == nil && bErr == nil && bytes.Equal(a, b)
+}
+
+func (ip *ImportPlan) canonicalJSON() ([]byte, error) {
+	plan := *ip
+	if plan.Diffs == nil {
+		plan.Diffs = []string{}
+	}
+	return json.Marshal(plan)
+}
+
+// ImportPlanFromFile reads an ImportPlan previously written by `diff --output json`
+func ImportPlanFromFile(path string) (*ImportPlan, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read plan file: %s", err)
+	}
+
+	var plan ImportPlan
+	if err := json.Unmarshal(data, &plan); err != nil {
+		return nil, fmt.Errorf("failed to parse plan file %s: %s", path, err)
+	}
+
+	return &plan, nil
+}
@@ -0,0 +1,122 @@
+package commands
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestImportPlan(t *testing.T) {
+	assetMetadataDiffs := hosting.NewAssetMetadataDiffs(
+		[]hosting.AssetMetadata{
+			{FilePath: "/b.html", FileHash: "bb"},
+			{FilePath: "/a.html", FileHash: "aa"},
+		},
+		[]hosting.AssetMetadata{
+			{FilePath: "/old.css", FileHash: "cc"},
+		},
+		[]hosting.ModifiedAssetMetadata{
+			{
+				AssetMetadata: hosting.AssetMetadata{FilePath: "/index.html", FileHash: "dd"},
+				AttrModified:  true,
+			},
+		},
+	)
+	assetMetadataDiffs.RenamedLocally = []hosting.RenamedAssetMetadata{
+		{AssetMetadata: hosting.AssetMetadata{FilePath: "/new.png", FileHash: "ee"}, FromPath: "/old.png"},
+		{AssetMetadata: hosting.AssetMetadata{FilePath: "/copy.png", FileHash: "ff"}, FromPath: "/logo.png", Copy: true},
+	}
+
+	plan := &ImportPlan{
+		AppID:    "my-app-abcdef",
+		Strategy: importStrategyMerge,
+		Diffs:    []string{"sample-diff-contents"},
+		Hosting:  newImportPlanHosting(assetMetadataDiffs),
+	}
+
+	t.Run("should describe hosting changes sorted by path", func(t *testing.T) {
+		u.So(t, plan.Hosting, gc.ShouldResemble, &ImportPlanHosting{
+			Added:    []ImportPlanAsset{{Path: "/a.html", Hash: "aa"}, {Path: "/b.html", Hash: "bb"}},
+			Deleted:  []ImportPlanAsset{{Path: "/old.css", Hash: "cc"}},
+			Modified: []ImportPlanAsset{{Path: "/index.html", Hash: "dd", AttrModified: true}},
+			Renamed: []ImportPlanAsset{
+				{Path: "/copy.png", Hash: "ff", FromPath: "/logo.png", Copy: true},
+				{Path: "/new.png", Hash: "ee", FromPath: "/old.png"},
+			},
+		})
+	})
+
+	t.Run("should render the plan as diff lines", func(t *testing.T) {
+		u.So(t, plan.Diff(), gc.ShouldResemble, []string{
+			"sample-diff-contents",
+			"New Files:",
+			"\t+ /a.html",
+			"\t+ /b.html",
+			"Removed Files:",
+			"\t- /old.css",
+			"Modified Files:",
+			"\t* /index.html",
+			"Renamed Files:",
+			"\t> /logo.png -> /copy.png (copy)",
+			"\t> /old.png -> /new.png",
+		})
+	})
+
+	t.Run("should compare plans by the changes they describe", func(t *testing.T) {
+		same := *plan
+		u.So(t, plan.Equal(&same), gc.ShouldBeTrue)
+
+		otherStrategy := *plan
+		otherStrategy.Strategy = importStrategyReplace
+		u.So(t, plan.Equal(&otherStrategy), gc.ShouldBeFalse)
+
+		withDependencies := *plan
+		withDependencies.Dependencies = &ImportPlanDependencies{Archive: "functions/node_modules.zip", Hash: "ee"}
+		u.So(t, plan.Equal(&withDependencies), gc.ShouldBeFalse)
+
+		empty := &ImportPlan{}
+		u.So(t, empty.Equal(&ImportPlan{Diffs: []string{}}), gc.ShouldBeTrue)
+		u.So(t, empty.HasChanges(), gc.ShouldBeFalse)
+	})
+
+	t.Run("should describe the dependencies archive to upload", func(t *testing.T) {
+		dependencies, err := newImportPlanDependencies("../testdata/app_with_dependencies")
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, dependencies.Archive, gc.ShouldEqual, "functions/node_modules.tar")
+		u.So(t, dependencies.Hash, gc.ShouldNotBeEmpty)
+	})
+
+	t.Run("should describe the files of the dependencies directory to upload", func(t *testing.T) {
+		appDir, err := ioutil.TempDir("", "realm-cli-import-plan")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(appDir)
+
+		writeFiles(t, filepath.Join(appDir, "functions"), map[string]string{
+			"package.json":            `{"dependencies": {"a": "1.0.0"}}`,
+			"package-lock.json":       `{"lockfileVersion": 2, "packages": {"node_modules/b": {"dev": true}}}`,
+			"node_modules/a/index.js": "module.exports = 1;",
+			"node_modules/b/index.js": "module.exports = 2;",
+		})
+
+		dependencies, err := newImportPlanDependencies(appDir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, dependencies.Archive, gc.ShouldEqual, "functions/node_modules")
+		u.So(t, dependencies.Hash, gc.ShouldNotBeEmpty)
+
+		// the dev packages are not uploaded, so they do not change the plan
+		writeFiles(t, filepath.Join(appDir, "functions"), map[string]string{"node_modules/b/index.js": "module.exports = 3;"})
+		unchanged, err := newImportPlanDependencies(appDir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, unchanged.Hash, gc.ShouldEqual, dependencies.Hash)
+
+		writeFiles(t, filepath.Join(appDir, "functions"), map[string]string{"node_modules/a/index.js": "module.exports = 4;"})
+		changed, err := newImportPlanDependencies(appDir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, changed.Hash, gc.ShouldNotEqual, dependencies.Hash)
+	})
+}
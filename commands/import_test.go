@@ -511,6 +511,30 @@ func TestImportCommand(t *testing.T) {
 		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, user.ErrNotLoggedIn.Error())
 	})
 
+	t.Run("should reject an unsupported compression", func(t *testing.T) {
+		importCommand, mockUI := setUpBasicCommand()
+		importCommand.user = &user.User{
+			APIKey:      "my-api-key",
+			AccessToken: u.GenerateValidAccessToken(),
+		}
+
+		exitCode := importCommand.Run(append([]string{"--include-hosting", "--compress=zip"}, validArgs...))
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, `unsupported compression "zip"`)
+	})
+
+	t.Run("should reject a concurrency below 1", func(t *testing.T) {
+		importCommand, mockUI := setUpBasicCommand()
+		importCommand.user = &user.User{
+			APIKey:      "my-api-key",
+			AccessToken: u.GenerateValidAccessToken(),
+		}
+
+		exitCode := importCommand.Run(append([]string{"--include-hosting", "--concurrency=0"}, validArgs...))
+		u.So(t, exitCode, gc.ShouldEqual, 1)
+		u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "--concurrency must be at least 1, but was 0")
+	})
+
 	t.Run("when the user is logged in", func(t *testing.T) {
 		setup := func() (*ImportCommand, *cli.MockUi) {
 			importCommand, mockUI := setUpBasicCommand()
@@ -563,6 +587,78 @@ func TestImportCommand(t *testing.T) {
 			u.So(t, len(mockClient.ImportFnCalls), gc.ShouldEqual, 0)
 		})
 
+		t.Run("with a reviewed plan file", func(t *testing.T) {
+			planDir, err := ioutil.TempDir("", "realm-cli-plan")
+			u.So(t, err, gc.ShouldBeNil)
+			defer os.RemoveAll(planDir)
+
+			planFile := filepath.Join(planDir, "plan.json")
+			u.So(t, ioutil.WriteFile(planFile, []byte(`{
+				"app_id": "my-app-abcdef",
+				"strategy": "merge",
+				"diffs": ["sample-diff-contents"]
+			}`), 0600), gc.ShouldBeNil)
+
+			setupPlanClient := func(diffs []string) *u.MockRealmClient {
+				return &u.MockRealmClient{
+					ImportFn: func(groupID, appID string, appData []byte, strategy string) error {
+						return nil
+					},
+					DiffFn: func(groupID, appID string, appData []byte, strategy string) ([]string, error) {
+						return diffs, nil
+					},
+					ExportFn: func(groupID, appID string, strategy api.ExportStrategy) (string, io.ReadCloser, error) {
+						return "", u.NewResponseBody(bytes.NewReader([]byte{})), nil
+					},
+					FetchAppByClientAppIDFn: func(clientAppID string) (*models.App, error) {
+						return &models.App{
+							GroupID:     "group-id",
+							ID:          "app-id",
+							ClientAppID: clientAppID,
+						}, nil
+					},
+				}
+			}
+
+			t.Run("it imports without asking for confirmation if the plan still matches", func(t *testing.T) {
+				importCommand, mockUI := setup()
+				realmClient := setupPlanClient([]string{"sample-diff-contents"})
+				importCommand.realmClient = realmClient
+
+				exitCode := importCommand.Run(append([]string{"--path=../testdata/full_app", "--plan-file=" + planFile}, validArgs...))
+
+				u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+				u.So(t, exitCode, gc.ShouldEqual, 0)
+				u.So(t, mockUI.OutputWriter.String(), gc.ShouldNotContainSubstring, "Please confirm the changes shown above")
+				u.So(t, len(realmClient.ImportFnCalls), gc.ShouldEqual, 1)
+			})
+
+			t.Run("it checks the plan even when confirmation is skipped", func(t *testing.T) {
+				importCommand, mockUI := setup()
+				realmClient := setupPlanClient([]string{"sample-diff-contents", "another-change"})
+				importCommand.realmClient = realmClient
+
+				exitCode := importCommand.Run(append([]string{"--path=../testdata/full_app", "--plan-file=" + planFile, "-y"}, validArgs...))
+
+				u.So(t, exitCode, gc.ShouldEqual, 1)
+				u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "no longer match the reviewed plan")
+				u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "another-change")
+				u.So(t, len(realmClient.ImportFnCalls), gc.ShouldEqual, 0)
+			})
+
+			t.Run("it fails if the plan was made for another strategy", func(t *testing.T) {
+				importCommand, mockUI := setup()
+				realmClient := setupPlanClient([]string{"sample-diff-contents"})
+				importCommand.realmClient = realmClient
+
+				exitCode := importCommand.Run(append([]string{"--path=../testdata/full_app", "--plan-file=" + planFile, "--strategy=replace"}, validArgs...))
+
+				u.So(t, exitCode, gc.ShouldEqual, 1)
+				u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, `was made for the "merge" strategy rather than "replace", please review the changes again with 'diff --strategy=replace --output=json'`)
+				u.So(t, len(realmClient.ImportFnCalls), gc.ShouldEqual, 0)
+			})
+		})
+
 		t.Run("it asks the user to discard existing drafts", func(t *testing.T) {
 			ctrl := gomock.NewController(t)
 			realmClient := mock_api.NewMockRealmClient(ctrl)
@@ -684,6 +780,18 @@ func TestImportCommand(t *testing.T) {
 				ExpectedExitCode: 1,
 				ExpectedError:    "directory does not exist",
 			},
+			{
+				Description:      "it fails if given an unknown transpiler",
+				Args:             append([]string{"--path=../testdata/full_app", "--include-dependencies", "--transpiler=babel"}, validArgs...),
+				ExpectedExitCode: 1,
+				ExpectedError:    "unknown transpiler 'babel', must be one of: native, external",
+			},
+			{
+				Description:      "it fails if given a transpile concurrency below 1",
+				Args:             append([]string{"--path=../testdata/full_app", "--include-dependencies", "--transpile-concurrency=0"}, validArgs...),
+				ExpectedExitCode: 1,
+				ExpectedError:    "--transpile-concurrency must be at least 1, but was 0",
+			},
 			{
 				Description:      "it succeeds if given a valid flagAppPath",
 				Args:             append([]string{"--path=../testdata/full_app"}, validArgs...),
@@ -964,7 +1072,7 @@ func TestImportCommand(t *testing.T) {
 		for _, tc := range []testCase{
 			{
 				Description:      "it succeeds if given a valid flagAppPath and flagIncludeDependencies",
-				Args:             append([]string{"--path=../testdata/full_app", "--include-dependencies"}, validArgs...),
+				Args:             append([]string{"--path=../testdata/full_app", "--include-dependencies", "--transpiler=native"}, validArgs...),
 				ExpectedExitCode: 0,
 				RealmClient: u.MockRealmClient{
 					ExportFn: func(groupID, appID string, strategy api.ExportStrategy) (string, io.ReadCloser, error) {
@@ -996,6 +1104,13 @@ func TestImportCommand(t *testing.T) {
 				exitCode := importCommand.Run(append(tc.Args, "--config-path=../testdata/configs/tmp/config.json"))
 				u.So(t, exitCode, gc.ShouldEqual, tc.ExpectedExitCode)
 				u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, tc.ExpectedError)
+
+				cacheDir := filepath.Join(filepath.Dir(importCommand.flagConfigPath), utils.TranspileCacheDirName)
+				_, sErr := os.Stat(cacheDir)
+				u.So(t, sErr, gc.ShouldBeNil)
+				u.So(t, os.RemoveAll(cacheDir), gc.ShouldBeNil)
+
+				u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "transpile cache: 0 of ")
 			})
 		}
 
@@ -0,0 +1,239 @@
+package commands
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+
+	"github.com/10gen/realm-cli/api"
+	"github.com/10gen/realm-cli/models"
+	u "github.com/10gen/realm-cli/user"
+	"github.com/10gen/realm-cli/utils"
+
+	"github.com/mitchellh/cli"
+)
+
+const (
+	migrateFlagDryRun = "dry-run"
+)
+
+// NewMigrateCommandFactory returns a new cli.CommandFactory given a cli.Ui
+func NewMigrateCommandFactory(ui cli.Ui) cli.CommandFactory {
+	return func() (cli.Command, error) {
+		workingDirectory, err := os.Getwd()
+		if err != nil {
+			return nil, err
+		}
+
+		return &MigrateCommand{
+			BaseCommand: &BaseCommand{
+				Name: "migrate",
+				UI:   ui,
+			},
+			workingDirectory: workingDirectory,
+		}, nil
+	}
+}
+
+// MigrateCommand is used to rewrite a local app directory to the current config version
+type MigrateCommand struct {
+	*BaseCommand
+
+	workingDirectory string
+
+	flagAppPath string
+	flagGroupID string
+	flagDryRun  bool
+}
+
+// Help returns long-form help information for this command
+func (mc *MigrateCommand) Help() string {
+	return `Migrate a local realm application directory to the current config version.
+
+OPTIONS:
+  --path [string]
+	A path to the local directory containing your app.
+
+  --dry-run
+	Show the changes that would be made to the local directory without writing them.
+
+  --project-id [string]
+	The Atlas Project ID used to create a scratch app when the app cannot be migrated offline,
+	which is the case for apps at config version 20180301 ("stitch.json").
+	The app's configuration is imported into the scratch app and exported at the current config version,
+	after which the scratch app is deleted. Requires you to be logged in.` +
+		mc.BaseCommand.Help()
+}
+
+// Synopsis returns a one-liner description for this command
+func (mc *MigrateCommand) Synopsis() string {
+	return `Migrate a local realm application directory to the current config version.`
+}
+
+// Run executes the command
+func (mc *MigrateCommand) Run(args []string) int {
+	flags := mc.NewFlagSet()
+
+	flags.StringVar(&mc.flagAppPath, importFlagPath, "", "")
+	flags.StringVar(&mc.flagGroupID, flagProjectIDName, "", "")
+	flags.BoolVar(&mc.flagDryRun, migrateFlagDryRun, false, "")
+
+	if err := mc.BaseCommand.run(args); err != nil {
+		mc.UI.Error(err.Error())
+		return 1
+	}
+
+	if err := mc.migrate(); err != nil {
+		mc.UI.Error(err.Error())
+		return 1
+	}
+
+	return 0
+}
+
+func (mc *MigrateCommand) migrate() error {
+	appPath, err := mc.resolveAppDirectory()
+	if err != nil {
+		return err
+	}
+
+	files, err := utils.ReadAppFiles(appPath)
+	if err != nil {
+		return err
+	}
+
+	version, err := files.ConfigVersion()
+	if err != nil {
+		return err
+	}
+
+	if version == models.ConfigVersion {
+		mc.UI.Info(fmt.Sprintf("App is already at config version %d, nothing to do.", version))
+		return nil
+	}
+
+	migrated, err := utils.MigrateAppFiles(files)
+	if err == utils.ErrNoMigrationPath {
+		if mc.flagDryRun {
+			mc.UI.Info(fmt.Sprintf("No offline migration is available from config version %d; the app would be migrated through a scratch app.", version))
+			return nil
+		}
+
+		mc.UI.Info(fmt.Sprintf("No offline migration is available from config version %d, migrating through a scratch app...", version))
+		migrated, err = mc.roundTrip(appPath, files)
+	}
+	if err != nil {
+		return err
+	}
+
+	diff := utils.DiffAppFiles(files, migrated)
+	if diff == "" {
+		mc.UI.Info("Migrated app is identical to the local directory, nothing to do.")
+		return nil
+	}
+
+	mc.UI.Info(fmt.Sprintf("Migrating app from config version %d to %d:", version, models.ConfigVersion))
+	mc.UI.Info(diff)
+
+	if mc.flagDryRun {
+		return nil
+	}
+
+	confirm, err := mc.AskYesNo("Please confirm the changes shown above:")
+	if err != nil {
+		return err
+	}
+
+	if !confirm {
+		return nil
+	}
+
+	if err := utils.WriteAppFiles(appPath, files, migrated); err != nil {
+		return fmt.Errorf("failed to write migrated app: %s", err)
+	}
+
+	mc.UI.Info(fmt.Sprintf("Successfully migrated app to config version %d", models.ConfigVersion))
+	return nil
+}
+
+// resolveAppDirectory finds the app directory, which may still use the legacy config file name
+func (mc *MigrateCommand) resolveAppDirectory() (string, error) {
+	if mc.flagAppPath != "" {
+		return utils.ResolveAppDirectory(mc.flagAppPath, mc.workingDirectory)
+	}
+
+	if dir, err := utils.GetDirectoryContainingFile(mc.workingDirectory, models.AppConfigFileName); err == nil {
+		return dir, nil
+	}
+
+	return utils.GetDirectoryContainingFile(mc.workingDirectory, models.LegacyAppConfigFileName)
+}
+
+// roundTrip migrates the app by importing it into a scratch app and exporting it at the current config version
+func (mc *MigrateCommand) roundTrip(appPath string, files utils.AppFiles) (utils.AppFiles, error) {
+	user, err := mc.User()
+	if err != nil {
+		return nil, err
+	}
+
+	if !user.LoggedIn() {
+		return nil, u.ErrNotLoggedIn
+	}
+
+	if mc.flagGroupID == "" {
+		return nil, fmt.Errorf("a Project ID (--%s=[string]) must be supplied to migrate through a scratch app", flagProjectIDName)
+	}
+
+	appInstanceData, err := files.AppInstanceData()
+	if err != nil {
+		return nil, err
+	}
+
+	loadedApp, err := utils.UnmarshalFromDir(appPath)
+	if err != nil {
+		return nil, err
+	}
+
+	appData, err := json.Marshal(loadedApp)
+	if err != nil {
+		return nil, err
+	}
+
+	realmClient, err := mc.RealmClient()
+	if err != nil {
+		return nil, err
+	}
+
+	scratchName := "migrate-" + utils.RandomAlphaString(8)
+	app, err := realmClient.CreateEmptyApp(mc.flagGroupID, scratchName, appInstanceData.AppLocation(), appInstanceData.AppDeploymentModel())
+	if err != nil {
+		return nil, fmt.Errorf("failed to create scratch app: %s", err)
+	}
+	defer func() {
+		if deleteErr := realmClient.DeleteApp(app.GroupID, app.ID); deleteErr != nil {
+			mc.UI.Warn(fmt.Sprintf("We failed to delete the scratch app %q, please delete it manually: %s", app.ClientAppID, deleteErr))
+		}
+	}()
+
+	if err := realmClient.Import(app.GroupID, app.ID, appData, importStrategyReplace); err != nil {
+		return nil, fmt.Errorf("failed to import app into scratch app: %s", err)
+	}
+
+	_, body, err := realmClient.Export(app.GroupID, app.ID, api.ExportStrategyNone)
+	if err != nil {
+		return nil, fmt.Errorf("failed to export scratch app: %s", err)
+	}
+	defer body.Close()
+
+	migrated, err := utils.ZipToAppFiles(body)
+	if err != nil {
+		return nil, err
+	}
+
+	// the export describes the scratch app, so restore the identity of the original one
+	if err := migrated.SetAppInstanceData(appInstanceData); err != nil {
+		return nil, err
+	}
+
+	return migrated, nil
+}
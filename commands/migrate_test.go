@@ -0,0 +1,163 @@
+package commands
+
+import (
+	"archive/zip"
+	"bytes"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/api"
+	"github.com/10gen/realm-cli/models"
+	"github.com/10gen/realm-cli/user"
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+
+	"github.com/mitchellh/cli"
+)
+
+// copyTestApp copies the test app at src into a new temporary directory
+func copyTestApp(t *testing.T, src string) string {
+	t.Helper()
+
+	dir, err := ioutil.TempDir("", "realm-cli-test-app")
+	u.So(t, err, gc.ShouldBeNil)
+
+	err = filepath.Walk(src, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		relPath, err := filepath.Rel(src, path)
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return os.MkdirAll(filepath.Join(dir, relPath), os.ModePerm)
+		}
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		return ioutil.WriteFile(filepath.Join(dir, relPath), data, info.Mode())
+	})
+	u.So(t, err, gc.ShouldBeNil)
+
+	return dir
+}
+
+func TestMigrateCommand(t *testing.T) {
+	setup := func() (*MigrateCommand, *cli.MockUi) {
+		mockUI := cli.NewMockUi()
+		cmd, err := NewMigrateCommandFactory(mockUI)()
+		if err != nil {
+			panic(err)
+		}
+
+		migrateCommand := cmd.(*MigrateCommand)
+		migrateCommand.storage = u.NewEmptyStorage()
+		return migrateCommand, mockUI
+	}
+
+	t.Run("should do nothing for an app at the current config version", func(t *testing.T) {
+		migrateCommand, mockUI := setup()
+		exitCode := migrateCommand.Run([]string{"--path=../testdata/simple_app"})
+		u.So(t, exitCode, gc.ShouldEqual, 0)
+		u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "already at config version 20200603")
+	})
+
+	t.Run("when no offline migration is available", func(t *testing.T) {
+		t.Run("it only reports the migration with --dry-run", func(t *testing.T) {
+			dir := copyTestApp(t, "../testdata/legacy_app")
+			defer os.RemoveAll(dir)
+
+			migrateCommand, mockUI := setup()
+			exitCode := migrateCommand.Run([]string{"--path=" + dir, "--dry-run"})
+			u.So(t, exitCode, gc.ShouldEqual, 0)
+			u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+			u.So(t, mockUI.OutputWriter.String(), gc.ShouldContainSubstring, "No offline migration is available from config version 20180301; the app would be migrated through a scratch app.")
+
+			_, err := os.Stat(filepath.Join(dir, models.LegacyAppConfigFileName))
+			u.So(t, err, gc.ShouldBeNil)
+		})
+
+		t.Run("it requires a project id", func(t *testing.T) {
+			dir := copyTestApp(t, "../testdata/legacy_app")
+			defer os.RemoveAll(dir)
+
+			migrateCommand, mockUI := setup()
+			migrateCommand.user = &user.User{
+				APIKey:      "my-api-key",
+				AccessToken: u.GenerateValidAccessToken(),
+			}
+
+			exitCode := migrateCommand.Run([]string{"--path=" + dir, "-y"})
+			u.So(t, exitCode, gc.ShouldEqual, 1)
+			u.So(t, mockUI.ErrorWriter.String(), gc.ShouldContainSubstring, "--project-id")
+		})
+
+		t.Run("it migrates through a scratch app and deletes it", func(t *testing.T) {
+			dir := copyTestApp(t, "../testdata/legacy_app")
+			defer os.RemoveAll(dir)
+
+			var exportZip bytes.Buffer
+			zw := zip.NewWriter(&exportZip)
+			for name, contents := range map[string]string{
+				"config.json":                 `{"app_id": "migrate-scratch-abcde", "name": "migrate-scratch", "config_version": 20200603}`,
+				"functions/hello/config.json": `{"name": "hello", "private": false}`,
+				"functions/hello/source.js":   "exports = function() {\n  return \"hello\";\n};\n",
+			} {
+				f, err := zw.Create(name)
+				u.So(t, err, gc.ShouldBeNil)
+				_, err = f.Write([]byte(contents))
+				u.So(t, err, gc.ShouldBeNil)
+			}
+			u.So(t, zw.Close(), gc.ShouldBeNil)
+
+			var deletedAppID string
+			var importedApps [][]string
+			realmClient := &u.MockRealmClient{
+				CreateEmptyAppFn: func(groupID, appName, locationName, deploymentModelName string) (*models.App, error) {
+					return &models.App{ID: "scratch-id", GroupID: groupID, ClientAppID: appName + "-abcde", Name: appName}, nil
+				},
+				ImportFn: func(groupID, appID string, appData []byte, strategy string) error {
+					importedApps = append(importedApps, []string{groupID, appID, strategy})
+					return nil
+				},
+				ExportFn: func(groupID, appID string, strategy api.ExportStrategy) (string, io.ReadCloser, error) {
+					return "", u.NewResponseBody(bytes.NewReader(exportZip.Bytes())), nil
+				},
+				DeleteAppFn: func(groupID, appID string) error {
+					deletedAppID = appID
+					return nil
+				},
+			}
+
+			migrateCommand, mockUI := setup()
+			migrateCommand.realmClient = realmClient
+			migrateCommand.user = &user.User{
+				APIKey:      "my-api-key",
+				AccessToken: u.GenerateValidAccessToken(),
+			}
+
+			exitCode := migrateCommand.Run([]string{"--path=" + dir, "--project-id=group-id", "-y"})
+			u.So(t, mockUI.ErrorWriter.String(), gc.ShouldBeEmpty)
+			u.So(t, exitCode, gc.ShouldEqual, 0)
+
+			u.So(t, importedApps, gc.ShouldResemble, [][]string{{"group-id", "scratch-id", importStrategyReplace}})
+			u.So(t, deletedAppID, gc.ShouldEqual, "scratch-id")
+
+			appInstanceData := models.AppInstanceData{}
+			u.So(t, appInstanceData.UnmarshalFile(dir), gc.ShouldBeNil)
+			u.So(t, appInstanceData.AppID(), gc.ShouldEqual, "legacy-app-abcde")
+			u.So(t, appInstanceData.AppName(), gc.ShouldEqual, "legacy-app")
+
+			// files missing from the export are removed, the rest are untouched
+			_, err := os.Stat(filepath.Join(dir, "values", "greeting.json"))
+			u.So(t, os.IsNotExist(err), gc.ShouldBeTrue)
+			_, err = os.Stat(filepath.Join(dir, "functions", "hello", "source.js"))
+			u.So(t, err, gc.ShouldBeNil)
+		})
+	})
+}
@@ -0,0 +1,124 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// The files describing the dependencies of a package
+const (
+	PackageJSONFileName   = "package.json"
+	NodeModulesDirName    = "node_modules"
+	NpmShrinkwrapFileName = "npm-shrinkwrap.json"
+	NpmLockfileName       = "package-lock.json"
+	YarnLockfileName      = "yarn.lock"
+)
+
+// Names are the names of the supported lockfiles, in the order npm and yarn give them precedence
+var Names = []string{NpmShrinkwrapFileName, NpmLockfileName, YarnLockfileName}
+
+// Find returns the path of the lockfile in dir, or an empty string if there is none
+func Find(dir string) (string, error) {
+	for _, name := range Names {
+		lockfilePath := filepath.Join(dir, name)
+		if _, err := os.Stat(lockfilePath); err == nil {
+			return lockfilePath, nil
+		} else if !os.IsNotExist(err) {
+			return "", err
+		}
+	}
+	return "", nil
+}
+
+// DevPackages returns the directories of the packages installed in the node_modules directory of dir
+// that are only needed for development according to the lockfile at lockfilePath, as slash-separated
+// paths relative to dir (e.g. "node_modules/mocha" or "node_modules/a/node_modules/b").
+// The packages that the lockfile does not describe are assumed to be needed
+func DevPackages(dir, lockfilePath string) ([]string, error) {
+	data, err := ioutil.ReadFile(lockfilePath)
+	if err != nil {
+		return nil, err
+	}
+
+	var devDirs []string
+	switch filepath.Base(lockfilePath) {
+	case NpmShrinkwrapFileName, NpmLockfileName:
+		devDirs, err = npmDevPackages(data)
+	case YarnLockfileName:
+		devDirs, err = yarnDevPackages(dir, data)
+	default:
+		return nil, fmt.Errorf("unsupported lockfile '%s', must be one of: %s", lockfilePath, strings.Join(Names, ", "))
+	}
+	if err != nil {
+		return nil, fmt.Errorf("failed to read the lockfile '%s': %s", lockfilePath, err)
+	}
+
+	sort.Strings(devDirs)
+	return devDirs, nil
+}
+
+// packageJSON holds the fields of a package.json file that describe the dependencies of a package
+type packageJSON struct {
+	Name                 string            `json:"name"`
+	Version              string            `json:"version"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+func readPackageJSON(dir string) (packageJSON, error) {
+	var pkg packageJSON
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, PackageJSONFileName))
+	if err != nil {
+		return pkg, err
+	}
+
+	if err := json.Unmarshal(data, &pkg); err != nil {
+		return pkg, fmt.Errorf("failed to parse '%s': %s", filepath.Join(dir, PackageJSONFileName), err)
+	}
+	return pkg, nil
+}
+
+// packageDirs returns the directories of the packages installed in the node_modules directory of dir,
+// as slash-separated paths relative to root
+func packageDirs(root, dir string) ([]string, error) {
+	entries, err := ioutil.ReadDir(filepath.Join(root, filepath.FromSlash(dir), NodeModulesDirName))
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	var dirs []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() || strings.HasPrefix(name, ".") {
+			continue
+		}
+
+		pkgDir := path.Join(dir, NodeModulesDirName, name)
+		if !strings.HasPrefix(name, "@") {
+			dirs = append(dirs, pkgDir)
+			continue
+		}
+
+		scoped, err := ioutil.ReadDir(filepath.Join(root, filepath.FromSlash(pkgDir)))
+		if err != nil {
+			return nil, err
+		}
+		for _, entry := range scoped {
+			if entry.IsDir() {
+				dirs = append(dirs, path.Join(pkgDir, entry.Name()))
+			}
+		}
+	}
+	return dirs, nil
+}
@@ -0,0 +1,248 @@
+package lockfile
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+// writeFiles writes the files at the given slash-separated paths relative to dir
+func writeFiles(t *testing.T, dir string, files map[string]string) {
+	for name, contents := range files {
+		path := filepath.Join(dir, filepath.FromSlash(name))
+		u.So(t, os.MkdirAll(filepath.Dir(path), 0755), gc.ShouldBeNil)
+		u.So(t, ioutil.WriteFile(path, []byte(contents), 0644), gc.ShouldBeNil)
+	}
+}
+
+// nodeModules are the packages installed for an app depending on "axios" and "@scope/util",
+// with the dev dependencies "mocha" and "@types/node". Both axios and mocha depend on different
+// versions of "debug", and mocha depends on "ms" like debug does. axios bundles "follow-redirects",
+// which no lockfile describes
+var nodeModules = map[string]string{
+	"package.json": `{
+  "name": "functions",
+  "dependencies": {"axios": "^0.19.0", "@scope/util": "1.0.0"},
+  "devDependencies": {"mocha": "^8.0.0", "@types/node": "^14.0.0"}
+}`,
+	"node_modules/axios/package.json":                               `{"name": "axios", "version": "0.19.2"}`,
+	"node_modules/axios/index.js":                                   `module.exports = require("debug");`,
+	"node_modules/axios/node_modules/follow-redirects/package.json": `{"name": "follow-redirects", "version": "1.5.10"}`,
+	"node_modules/debug/package.json":                               `{"name": "debug", "version": "3.1.0"}`,
+	"node_modules/ms/package.json":                                  `{"name": "ms", "version": "2.0.0"}`,
+	"node_modules/@scope/util/package.json":                         `{"name": "@scope/util", "version": "1.0.0"}`,
+	"node_modules/mocha/package.json":                               `{"name": "mocha", "version": "8.1.0"}`,
+	"node_modules/mocha/node_modules/debug/package.json":            `{"name": "debug", "version": "4.1.1"}`,
+	"node_modules/@types/node/package.json":                         `{"name": "@types/node", "version": "14.0.1"}`,
+	"node_modules/.bin/mocha":                                       `#!/usr/bin/env node`,
+}
+
+var expectedDevPackages = []string{"node_modules/@types/node", "node_modules/mocha"}
+
+func TestDevPackages(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		lockfile    string
+		contents    string
+		expected    []string
+	}{
+		{
+			description: "should find the dev packages of a lockfile version 1",
+			lockfile:    NpmLockfileName,
+			contents: `{
+  "name": "functions",
+  "lockfileVersion": 1,
+  "dependencies": {
+    "axios": {"version": "0.19.2", "requires": {"debug": "=3.1.0"}},
+    "debug": {"version": "3.1.0", "requires": {"ms": "2.0.0"}},
+    "ms": {"version": "2.0.0"},
+    "@scope/util": {"version": "1.0.0"},
+    "mocha": {
+      "version": "8.1.0",
+      "dev": true,
+      "requires": {"debug": "4.1.1", "ms": "2.0.0"},
+      "dependencies": {"debug": {"version": "4.1.1", "dev": true}}
+    },
+    "@types/node": {"version": "14.0.1", "dev": true}
+  }
+}`,
+			expected: expectedDevPackages,
+		},
+		{
+			description: "should find the dev packages of a lockfile version 2",
+			lockfile:    NpmShrinkwrapFileName,
+			contents: `{
+  "name": "functions",
+  "lockfileVersion": 2,
+  "packages": {
+    "": {"name": "functions"},
+    "node_modules/axios": {"version": "0.19.2"},
+    "node_modules/debug": {"version": "3.1.0"},
+    "node_modules/ms": {"version": "2.0.0"},
+    "node_modules/@scope/util": {"version": "1.0.0"},
+    "node_modules/mocha": {"version": "8.1.0", "dev": true},
+    "node_modules/mocha/node_modules/debug": {"version": "4.1.1", "dev": true},
+    "node_modules/@types/node": {"version": "14.0.1", "dev": true}
+  },
+  "dependencies": {
+    "mocha": {"version": "8.1.0"}
+  }
+}`,
+			expected: []string{"node_modules/@types/node", "node_modules/mocha", "node_modules/mocha/node_modules/debug"},
+		},
+		{
+			description: "should find the dev packages of a yarn 1 lockfile",
+			lockfile:    YarnLockfileName,
+			contents: `# THIS IS AN AUTOGENERATED FILE. DO NOT EDIT THIS FILE DIRECTLY.
+# yarn lockfile v1
+
+
+"@scope/util@1.0.0":
+  version "1.0.0"
+  resolved "https://registry.yarnpkg.com/@scope/util/-/util-1.0.0.tgz"
+
+"@types/node@^14.0.0":
+  version "14.0.1"
+
+axios@^0.19.0:
+  version "0.19.2"
+  dependencies:
+    debug "=3.1.0"
+
+debug@4.1.1:
+  version "4.1.1"
+  dependencies:
+    ms "^2.1.1"
+
+debug@=3.1.0:
+  version "3.1.0"
+  dependencies:
+    ms "2.0.0"
+
+mocha@^8.0.0:
+  version "8.1.0"
+  dependencies:
+    debug "4.1.1"
+    ms "2.0.0"
+
+ms@2.0.0, ms@^2.1.1:
+  version "2.0.0"
+`,
+			expected: expectedDevPackages,
+		},
+		{
+			description: "should find the dev packages of a yarn 2 lockfile",
+			lockfile:    YarnLockfileName,
+			contents: `# This file is generated by running "yarn install" inside your project.
+
+__metadata:
+  version: 4
+  cacheKey: 7
+
+"@scope/util@npm:1.0.0":
+  version: 1.0.0
+  resolution: "@scope/util@npm:1.0.0"
+
+"@types/node@npm:^14.0.0":
+  version: 14.0.1
+
+"axios@npm:^0.19.0":
+  version: 0.19.2
+  dependencies:
+    debug: =3.1.0
+
+"debug@npm:4.1.1":
+  version: 4.1.1
+
+"debug@npm:=3.1.0":
+  version: 3.1.0
+  dependencies:
+    ms: 2.0.0
+
+"functions@workspace:.":
+  version: 0.0.0-use.local
+  dependencies:
+    axios: ^0.19.0
+    mocha: ^8.0.0
+
+"mocha@npm:^8.0.0":
+  version: 8.1.0
+  dependencies:
+    debug: 4.1.1
+
+"ms@npm:2.0.0":
+  version: 2.0.0
+`,
+			expected: expectedDevPackages,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			dir, err := ioutil.TempDir("", "realm-cli-lockfile")
+			u.So(t, err, gc.ShouldBeNil)
+			defer os.RemoveAll(dir)
+
+			writeFiles(t, dir, nodeModules)
+			writeFiles(t, dir, map[string]string{tc.lockfile: tc.contents})
+
+			lockfilePath, err := Find(dir)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, lockfilePath, gc.ShouldEqual, filepath.Join(dir, tc.lockfile))
+
+			devDirs, err := DevPackages(dir, lockfilePath)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, devDirs, gc.ShouldResemble, tc.expected)
+		})
+	}
+
+	t.Run("should fail to read an invalid lockfile", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "realm-cli-lockfile")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		writeFiles(t, dir, map[string]string{
+			NpmLockfileName:  `{"dependencies": [`,
+			YarnLockfileName: "  version \"1.0.0\"\n",
+		})
+
+		_, err = DevPackages(dir, filepath.Join(dir, NpmLockfileName))
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldStartWith, "failed to read the lockfile '"+filepath.Join(dir, NpmLockfileName)+"': ")
+
+		_, err = DevPackages(dir, filepath.Join(dir, YarnLockfileName))
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEndWith, "line 1: expected the descriptors of an entry")
+	})
+}
+
+func TestFind(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-lockfile")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	t.Run("should find no lockfile in a directory without one", func(t *testing.T) {
+		lockfilePath, err := Find(dir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, lockfilePath, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should prefer npm-shrinkwrap.json over package-lock.json over yarn.lock", func(t *testing.T) {
+		writeFiles(t, dir, map[string]string{YarnLockfileName: ""})
+		lockfilePath, err := Find(dir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, lockfilePath, gc.ShouldEqual, filepath.Join(dir, YarnLockfileName))
+
+		writeFiles(t, dir, map[string]string{NpmLockfileName: "{}"})
+		lockfilePath, err = Find(dir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, lockfilePath, gc.ShouldEqual, filepath.Join(dir, NpmLockfileName))
+
+		writeFiles(t, dir, map[string]string{NpmShrinkwrapFileName: "{}"})
+		lockfilePath, err = Find(dir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, lockfilePath, gc.ShouldEqual, filepath.Join(dir, NpmShrinkwrapFileName))
+	})
+}
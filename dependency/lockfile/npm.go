@@ -0,0 +1,52 @@
+package lockfile
+
+import (
+	"encoding/json"
+	"path"
+	"strings"
+)
+
+// npmLockfile holds the fields of a package-lock.json or npm-shrinkwrap.json file that describe which
+// packages are dev dependencies. Lockfile version 1 nests the dependencies of each package under it,
+// while later versions list every package by its directory
+type npmLockfile struct {
+	Packages     map[string]npmPackage `json:"packages"`
+	Dependencies map[string]npmPackage `json:"dependencies"`
+}
+
+type npmPackage struct {
+	Dev          bool                  `json:"dev"`
+	Dependencies map[string]npmPackage `json:"dependencies"`
+}
+
+func npmDevPackages(data []byte) ([]string, error) {
+	var lockfile npmLockfile
+	if err := json.Unmarshal(data, &lockfile); err != nil {
+		return nil, err
+	}
+
+	var devDirs []string
+	if lockfile.Packages != nil {
+		for dir, pkg := range lockfile.Packages {
+			// the packages outside of node_modules are the root package and its workspaces
+			if pkg.Dev && (strings.HasPrefix(dir, NodeModulesDirName+"/") || strings.Contains(dir, "/"+NodeModulesDirName+"/")) {
+				devDirs = append(devDirs, dir)
+			}
+		}
+		return devDirs, nil
+	}
+
+	var walk func(dir string, deps map[string]npmPackage)
+	walk = func(dir string, deps map[string]npmPackage) {
+		for name, pkg := range deps {
+			pkgDir := path.Join(dir, NodeModulesDirName, name)
+			if pkg.Dev {
+				devDirs = append(devDirs, pkgDir)
+				continue
+			}
+			walk(pkgDir, pkg.Dependencies)
+		}
+	}
+	walk("", lockfile.Dependencies)
+	return devDirs, nil
+}
@@ -0,0 +1,215 @@
+package lockfile
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"os"
+	"path/filepath"
+	"strconv"
+	"strings"
+
+	"gopkg.in/yaml.v2"
+)
+
+// yarnMetadataKey is the key of the metadata entry of the lockfiles written by yarn 2 and later,
+// which are YAML documents rather than the custom format of yarn 1
+const yarnMetadataKey = "__metadata"
+
+// yarnPackage is an entry of a yarn.lock file, which describes the package that a set of descriptors
+// (e.g. "lodash@^4.17.0") resolve to
+type yarnPackage struct {
+	Version              string            `yaml:"version"`
+	Dependencies         map[string]string `yaml:"dependencies"`
+	OptionalDependencies map[string]string `yaml:"optionalDependencies"`
+}
+
+// yarnDevPackages finds the packages of the node_modules directory of dir whose name and version are only
+// needed by the devDependencies of the package.json of dir, since yarn.lock does not mark dev dependencies.
+// The packages that yarn.lock does not describe, such as bundled dependencies, are kept
+func yarnDevPackages(dir string, data []byte) ([]string, error) {
+	var entries map[string]*yarnPackage
+	var err error
+	if bytes.Contains(data, []byte(yarnMetadataKey+":")) {
+		entries, err = parseYarnBerryLockfile(data)
+	} else {
+		entries, err = parseYarnLockfile(data)
+	}
+	if err != nil {
+		return nil, err
+	}
+
+	root, err := readPackageJSON(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	reachable := func(roots ...map[string]string) map[string]bool {
+		found := map[string]bool{}
+		var visit func(deps map[string]string)
+		visit = func(deps map[string]string) {
+			for name, versionRange := range deps {
+				entry, ok := entries[name+"@"+versionRange]
+				if !ok {
+					entry, ok = entries[name+"@npm:"+versionRange]
+				}
+				if !ok || found[name+"@"+entry.Version] {
+					continue
+				}
+				found[name+"@"+entry.Version] = true
+				visit(entry.Dependencies)
+				visit(entry.OptionalDependencies)
+			}
+		}
+		for _, deps := range roots {
+			visit(deps)
+		}
+		return found
+	}
+	needed := reachable(root.Dependencies, root.OptionalDependencies)
+	dev := reachable(root.DevDependencies)
+
+	var devDirs []string
+	var walk func(pkgDir string) error
+	walk = func(pkgDir string) error {
+		pkgDirs, err := packageDirs(dir, pkgDir)
+		if err != nil {
+			return err
+		}
+
+		for _, pkgDir := range pkgDirs {
+			pkg, err := readPackageJSON(filepath.Join(dir, filepath.FromSlash(pkgDir)))
+			if err != nil && !os.IsNotExist(err) {
+				return err
+			}
+
+			// a directory without a package.json is not a package that yarn installed
+			if err == nil {
+				name := pkg.Name
+				if name == "" {
+					name = packageName(pkgDir)
+				}
+				if key := name + "@" + pkg.Version; dev[key] && !needed[key] {
+					devDirs = append(devDirs, pkgDir)
+					continue
+				}
+			}
+
+			if err := walk(pkgDir); err != nil {
+				return err
+			}
+		}
+		return nil
+	}
+
+	if err := walk(""); err != nil {
+		return nil, err
+	}
+	return devDirs, nil
+}
+
+// parseYarnLockfile parses a yarn.lock file written by yarn 1, returning its entries by descriptor
+func parseYarnLockfile(data []byte) (map[string]*yarnPackage, error) {
+	entries := map[string]*yarnPackage{}
+
+	var entry *yarnPackage
+	var section map[string]string
+	scanner := bufio.NewScanner(bytes.NewReader(data))
+	scanner.Buffer(nil, len(data)+1)
+	for n := 1; scanner.Scan(); n++ {
+		line := strings.TrimRight(scanner.Text(), " \r")
+		trimmed := strings.TrimLeft(line, " ")
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+
+		switch indent := len(line) - len(trimmed); {
+		case indent == 0:
+			if !strings.HasSuffix(line, ":") {
+				return nil, fmt.Errorf("line %d: expected the descriptors of an entry", n)
+			}
+			entry = &yarnPackage{}
+			section = nil
+			for _, descriptor := range strings.Split(strings.TrimSuffix(line, ":"), ",") {
+				entries[unquoteYarnString(strings.TrimSpace(descriptor))] = entry
+			}
+
+		case entry == nil:
+			return nil, fmt.Errorf("line %d: expected the descriptors of an entry", n)
+
+		case indent == 2:
+			section = nil
+			switch {
+			case trimmed == "dependencies:":
+				entry.Dependencies = map[string]string{}
+				section = entry.Dependencies
+			case trimmed == "optionalDependencies:":
+				entry.OptionalDependencies = map[string]string{}
+				section = entry.OptionalDependencies
+			case strings.HasPrefix(trimmed, "version "):
+				entry.Version = unquoteYarnString(strings.TrimSpace(strings.TrimPrefix(trimmed, "version ")))
+			}
+
+		case section != nil:
+			name, versionRange, err := splitYarnField(trimmed)
+			if err != nil {
+				return nil, fmt.Errorf("line %d: %s", n, err)
+			}
+			section[name] = versionRange
+		}
+	}
+	if err := scanner.Err(); err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
+
+// parseYarnBerryLockfile parses a yarn.lock file written by yarn 2 or later, returning its entries by descriptor
+func parseYarnBerryLockfile(data []byte) (map[string]*yarnPackage, error) {
+	var doc map[string]*yarnPackage
+	if err := yaml.Unmarshal(data, &doc); err != nil {
+		return nil, err
+	}
+
+	entries := map[string]*yarnPackage{}
+	for key, entry := range doc {
+		if key == yarnMetadataKey || entry == nil {
+			continue
+		}
+		for _, descriptor := range strings.Split(key, ",") {
+			entries[strings.TrimSpace(descriptor)] = entry
+		}
+	}
+	return entries, nil
+}
+
+// splitYarnField splits a field of a yarn 1 lockfile, such as `"@babel/core" "^7.0.0"`, into its name and value
+func splitYarnField(field string) (string, string, error) {
+	var name string
+	if strings.HasPrefix(field, `"`) {
+		end := strings.Index(field[1:], `"`)
+		if end < 0 {
+			return "", "", fmt.Errorf("unterminated string %s", field)
+		}
+		name, field = field[1:end+1], field[end+2:]
+	} else {
+		i := strings.Index(field, " ")
+		if i < 0 {
+			return "", "", fmt.Errorf("expected a name and a value, but found %s", field)
+		}
+		name, field = field[:i], field[i:]
+	}
+	return name, unquoteYarnString(strings.TrimSpace(field)), nil
+}
+
+func unquoteYarnString(s string) string {
+	if unquoted, err := strconv.Unquote(s); err == nil {
+		return unquoted
+	}
+	return s
+}
+
+// packageName returns the name of the package in pkgDir, which is the path after its last node_modules
+func packageName(pkgDir string) string {
+	return strings.TrimPrefix(pkgDir[strings.LastIndex(pkgDir, NodeModulesDirName+"/"):], NodeModulesDirName+"/")
+}
@@ -0,0 +1,450 @@
+package transpiler
+
+// node is a node of the syntax tree, whose pos is the offset in the source where it starts
+type node interface {
+	start() int
+}
+
+type expr interface {
+	node
+}
+
+type stmt interface {
+	node
+}
+
+// pattern is the target of a binding or an assignment, which is an *ident, *member, *arrayPattern,
+// *objectPattern or *assignPattern
+type pattern interface {
+	node
+}
+
+type pos int
+
+func (p pos) start() int {
+	return int(p)
+}
+
+// Expressions
+
+type ident struct {
+	pos
+	name    string
+	binding *binding
+}
+
+type thisExpr struct{ pos }
+
+type superExpr struct{ pos }
+
+type literalKind int
+
+const (
+	litNumber literalKind = iota
+	litString
+	litRegexp
+	litBool
+	litNull
+)
+
+// literal is a number, string, regular expression, boolean or null literal, whose value is its ES5 spelling
+// or, for a string, its raw text and cooked value
+type literal struct {
+	pos
+	kind   literalKind
+	value  string
+	cooked string
+	flags  string
+}
+
+type templateLit struct {
+	pos
+	quasis []*token
+	exprs  []expr
+}
+
+type taggedTemplate struct {
+	pos
+	tag   expr
+	quasi *templateLit
+}
+
+type arrayLit struct {
+	pos
+	elems []expr // nil for holes
+}
+
+type propKind int
+
+const (
+	propInit propKind = iota
+	propGet
+	propSet
+	propSpread
+)
+
+type property struct {
+	pos
+	kind      propKind
+	key       expr // an *ident, *literal, or any expression if computed
+	computed  bool
+	shorthand bool
+	method    bool
+	value     expr
+}
+
+type objectLit struct {
+	pos
+	props []*property
+}
+
+type function struct {
+	pos
+	id        *ident
+	params    []pattern
+	rest      pattern
+	body      *blockStmt
+	exprBody  expr
+	arrow     bool
+	async     bool
+	generator bool
+	method    bool
+	strict    bool
+	scope     *scope
+}
+
+type funcExpr struct {
+	*function
+}
+
+type class struct {
+	pos
+	id         *ident
+	superClass expr
+	members    []*classMember
+	scope      *scope
+}
+
+type memberKind int
+
+const (
+	memberMethod memberKind = iota
+	memberGet
+	memberSet
+	memberConstructor
+	memberField
+	memberStaticBlock
+)
+
+type classMember struct {
+	pos
+	kind     memberKind
+	static   bool
+	key      expr
+	computed bool
+	private  bool
+	value    *function // the method, the function whose expression body is the initializer of a field, or the static block
+}
+
+type classExpr struct {
+	*class
+}
+
+type unary struct {
+	pos
+	op string
+	x  expr
+}
+
+type update struct {
+	pos
+	op     string
+	prefix bool
+	x      expr
+}
+
+type binary struct {
+	pos
+	op   string
+	x, y expr
+}
+
+type assign struct {
+	pos
+	op     string
+	target pattern
+	value  expr
+}
+
+type conditional struct {
+	pos
+	test, cons, alt expr
+}
+
+type call struct {
+	pos
+	callee   expr
+	args     []expr
+	optional bool
+}
+
+type newExpr struct {
+	pos
+	callee expr
+	args   []expr
+}
+
+type member struct {
+	pos
+	object   expr
+	property expr // an *ident unless computed
+	computed bool
+	optional bool
+	private  bool
+}
+
+// optionalChain wraps a chain of members and calls with at least one optional link
+type optionalChain struct {
+	pos
+	x expr
+}
+
+type sequence struct {
+	pos
+	exprs []expr
+}
+
+type spread struct {
+	pos
+	x expr
+}
+
+type yieldExpr struct {
+	pos
+	arg      expr
+	delegate bool
+}
+
+type awaitExpr struct {
+	pos
+	x expr
+}
+
+type metaProperty struct {
+	pos
+	meta, property string
+}
+
+type importCall struct {
+	pos
+	source expr
+}
+
+// paren records the parentheses around an expression, which end an optional chain
+// and make an object or array literal an invalid assignment target
+type paren struct {
+	pos
+	x expr
+}
+
+// Patterns
+
+type arrayPattern struct {
+	pos
+	elems []pattern // nil for holes
+	rest  pattern
+}
+
+type patternProp struct {
+	pos
+	key      expr
+	computed bool
+	value    pattern
+}
+
+type objectPattern struct {
+	pos
+	props []*patternProp
+	rest  pattern
+}
+
+type assignPattern struct {
+	pos
+	target pattern
+	def    expr
+}
+
+// Statements
+
+type declarator struct {
+	pos
+	target pattern
+	init   expr
+}
+
+type varDecl struct {
+	pos
+	kind  string
+	decls []*declarator
+}
+
+type funcDecl struct {
+	*function
+}
+
+type classDecl struct {
+	*class
+}
+
+type exprStmt struct {
+	pos
+	x expr
+}
+
+type blockStmt struct {
+	pos
+	body  []stmt
+	end   int
+	scope *scope
+}
+
+type emptyStmt struct{ pos }
+
+type ifStmt struct {
+	pos
+	test      expr
+	cons, alt stmt
+}
+
+type forStmt struct {
+	pos
+	init   node // a *varDecl or an expression
+	test   expr
+	update expr
+	body   stmt
+	scope  *scope
+	loop   *loopInfo
+}
+
+// forInStmt is a for-in or for-of statement, whose left is a *varDecl or a pattern
+type forInStmt struct {
+	pos
+	of    bool
+	await bool
+	left  node
+	right expr
+	body  stmt
+	scope *scope
+	loop  *loopInfo
+}
+
+type whileStmt struct {
+	pos
+	test expr
+	body stmt
+	loop *loopInfo
+}
+
+type doWhileStmt struct {
+	pos
+	body stmt
+	test expr
+	loop *loopInfo
+}
+
+type returnStmt struct {
+	pos
+	arg expr
+}
+
+type branchStmt struct {
+	pos
+	tok   string // break or continue
+	label string
+}
+
+type throwStmt struct {
+	pos
+	arg expr
+}
+
+type tryStmt struct {
+	pos
+	block     *blockStmt
+	param     pattern
+	handler   *blockStmt
+	finalizer *blockStmt
+	scope     *scope // the scope of the catch clause
+}
+
+type switchCase struct {
+	pos
+	test expr // nil for default
+	body []stmt
+}
+
+type switchStmt struct {
+	pos
+	disc  expr
+	cases []*switchCase
+	scope *scope
+}
+
+type labeledStmt struct {
+	pos
+	label string
+	body  stmt
+}
+
+type debuggerStmt struct{ pos }
+
+type withStmt struct {
+	pos
+	object expr
+	body   stmt
+}
+
+// Modules
+
+type importSpec struct {
+	pos
+	imported string // "default", "*" or the imported name
+	local    *ident
+}
+
+type importDecl struct {
+	pos
+	specs  []*importSpec
+	source *literal
+}
+
+type exportSpec struct {
+	pos
+	local    string
+	ref      *ident // the local binding, unless re-exported from another module
+	exported string
+}
+
+// exportDecl is an export of a declaration, a list of names, or everything from another module
+type exportDecl struct {
+	pos
+	decl   stmt
+	specs  []*exportSpec
+	source *literal
+	all    bool
+	star   string // the name everything is exported as, for export * as name
+}
+
+// exportDefault is an export default of a declaration or an expression
+type exportDefault struct {
+	pos
+	decl stmt // a *funcDecl or *classDecl, whose id may be nil
+	x    expr
+}
+
+type program struct {
+	body   []stmt
+	module bool
+	strict bool
+	scope  *scope
+}
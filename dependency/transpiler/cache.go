@@ -0,0 +1,256 @@
+package transpiler
+
+import (
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"sync/atomic"
+	"time"
+)
+
+// NativeTranspilerVersion identifies the output of the native transpiler, and changes whenever the code
+// it generates for a source changes
+const NativeTranspilerVersion = "native-1"
+
+// Version returns the version of the transpiler implementation with the given name, which identifies
+// the results it produces. The version of the external transpiler is derived from the binary on the PATH
+func Version(name string) (string, error) {
+	switch name {
+	case NativeTranspilerName:
+		return NativeTranspilerVersion, nil
+	case ExternalTranspilerName:
+		path, err := exec.LookPath(DefaultTranspilerCommand)
+		if err != nil {
+			return "", err
+		}
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", err
+		}
+		return fmt.Sprintf("%s-%s-%d-%d", ExternalTranspilerName, path, info.Size(), info.ModTime().UnixNano()), nil
+	}
+	return "", fmt.Errorf("unknown transpiler '%s'", name)
+}
+
+// CacheStats counts the sources whose results were found in a cache and those that had to be transpiled
+type CacheStats struct {
+	Hits   int
+	Misses int
+}
+
+// HitRatio returns the fraction of the sources whose results were found in the cache
+func (cs CacheStats) HitRatio() float64 {
+	if cs.Hits+cs.Misses == 0 {
+		return 0
+	}
+	return float64(cs.Hits) / float64(cs.Hits+cs.Misses)
+}
+
+// CachingTranspiler is a Transpiler that stores the results of another Transpiler in a directory,
+// keyed by the hash of each source and the version of the transpiler, and only transpiles the sources
+// whose results are not stored yet
+type CachingTranspiler struct {
+	transpiler Transpiler
+	dir        string
+
+	hits   int64
+	misses int64
+}
+
+// NewCachingTranspiler returns a CachingTranspiler that stores the results of tr, whose version
+// is given, under dir
+func NewCachingTranspiler(tr Transpiler, dir, version string) *CachingTranspiler {
+	return &CachingTranspiler{
+		transpiler: tr,
+		dir:        filepath.Join(dir, versionDirName(version)),
+	}
+}
+
+// Transpile returns the stored result of each code, and transpiles the others with the underlying
+// Transpiler. The indexes of the errors it returns refer to codes
+func (ct *CachingTranspiler) Transpile(ctx context.Context, codes ...string) ([]TranspileResult, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	results := make([]TranspileResult, len(codes))
+	var missIndexes []int
+	var missCodes []string
+	for i, code := range codes {
+		if result, ok := ct.load(code); ok {
+			results[i] = result
+			continue
+		}
+		missIndexes = append(missIndexes, i)
+		missCodes = append(missCodes, code)
+	}
+	atomic.AddInt64(&ct.hits, int64(len(codes)-len(missCodes)))
+	atomic.AddInt64(&ct.misses, int64(len(missCodes)))
+
+	if len(missCodes) == 0 {
+		return results, nil
+	}
+
+	transpiled, err := ct.transpiler.Transpile(ctx, missCodes...)
+	if err != nil {
+		if errs, ok := err.(TranspileErrors); ok {
+			for _, e := range errs {
+				if e.Index >= 0 && e.Index < len(missIndexes) {
+					e.Index = missIndexes[e.Index]
+				}
+			}
+		}
+		return nil, err
+	}
+
+	for i, result := range transpiled {
+		results[missIndexes[i]] = result
+		// a result that cannot be stored is transpiled again next time
+		ct.store(missCodes[i], result)
+	}
+	return results, nil
+}
+
+// Stats returns the number of sources whose results were found in the cache and of those that were
+// transpiled since the CachingTranspiler was created
+func (ct *CachingTranspiler) Stats() CacheStats {
+	return CacheStats{
+		Hits:   int(atomic.LoadInt64(&ct.hits)),
+		Misses: int(atomic.LoadInt64(&ct.misses)),
+	}
+}
+
+func (ct *CachingTranspiler) entryPath(code string) string {
+	sum := sha256.Sum256([]byte(code))
+	key := hex.EncodeToString(sum[:])
+	return filepath.Join(ct.dir, key[:2], key+".json")
+}
+
+// load returns the stored result of code. A stored result that cannot be read is treated as missing
+func (ct *CachingTranspiler) load(code string) (TranspileResult, bool) {
+	path := ct.entryPath(code)
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return TranspileResult{}, false
+	}
+
+	var result TranspileResult
+	if err := json.Unmarshal(data, &result); err != nil {
+		return TranspileResult{}, false
+	}
+
+	// the modification time records the last use of the entry, which pruning relies on
+	now := time.Now()
+	os.Chtimes(path, now, now)
+	return result, true
+}
+
+func (ct *CachingTranspiler) store(code string, result TranspileResult) error {
+	path := ct.entryPath(code)
+	if err := os.MkdirAll(filepath.Dir(path), 0700); err != nil {
+		return err
+	}
+
+	data, err := json.Marshal(result)
+	if err != nil {
+		return err
+	}
+
+	// the entry is renamed into place so that concurrent imports never read part of it
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+	return nil
+}
+
+// versionDirName returns the name of the directory that holds the results of a transpiler version
+func versionDirName(version string) string {
+	sum := sha256.Sum256([]byte(version))
+	return hex.EncodeToString(sum[:8])
+}
+
+// PruneResult describes the entries removed from a transpile cache
+type PruneResult struct {
+	Entries int
+	Bytes   int64
+}
+
+// PruneCache removes the entries of the cache in dir that were stored by a transpiler version other than
+// versions, or that were last used more than maxAge before now unless maxAge is 0. Every entry is removed
+// if maxAge is negative
+func PruneCache(dir string, versions []string, maxAge time.Duration, now time.Time) (PruneResult, error) {
+	var result PruneResult
+
+	keep := map[string]bool{}
+	for _, version := range versions {
+		keep[versionDirName(version)] = true
+	}
+
+	versionDirs, err := ioutil.ReadDir(dir)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return result, nil
+		}
+		return result, err
+	}
+
+	for _, versionDir := range versionDirs {
+		if !versionDir.IsDir() {
+			continue
+		}
+		versionPath := filepath.Join(dir, versionDir.Name())
+		current := keep[versionDir.Name()] && maxAge >= 0
+
+		err := filepath.Walk(versionPath, func(path string, info os.FileInfo, err error) error {
+			if err != nil {
+				return err
+			}
+			if info.IsDir() {
+				return nil
+			}
+			if current && (maxAge == 0 || now.Sub(info.ModTime()) <= maxAge) {
+				return nil
+			}
+			if err := os.Remove(path); err != nil {
+				return err
+			}
+			result.Entries++
+			result.Bytes += info.Size()
+			return nil
+		})
+		if err != nil {
+			return result, err
+		}
+
+		if !current {
+			if err := os.RemoveAll(versionPath); err != nil {
+				return result, err
+			}
+		}
+	}
+	return result, nil
+}
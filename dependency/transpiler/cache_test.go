@@ -0,0 +1,159 @@
+package transpiler
+
+import (
+	"context"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+// countingTranspiler is a Transpiler that records the codes it is asked to transpile
+type countingTranspiler struct {
+	transpiled []string
+}
+
+func (ct *countingTranspiler) Transpile(ctx context.Context, codes ...string) ([]TranspileResult, error) {
+	ct.transpiled = append(ct.transpiled, codes...)
+	return NewNativeTranspiler().Transpile(ctx, codes...)
+}
+
+func TestCachingTranspiler(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-transpile-cache")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	t.Run("should only transpile the codes whose results are not cached", func(t *testing.T) {
+		inner := &countingTranspiler{}
+		tr := NewCachingTranspiler(inner, dir, "v1")
+
+		first, err := tr.Transpile(context.Background(), "let a = 1;", "const b = () => 2;")
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, inner.transpiled, gc.ShouldResemble, []string{"let a = 1;", "const b = () => 2;"})
+		u.So(t, tr.Stats(), gc.ShouldResemble, CacheStats{Hits: 0, Misses: 2})
+
+		second, err := tr.Transpile(context.Background(), "const b = () => 2;", "let c = 3;", "let a = 1;")
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, inner.transpiled, gc.ShouldResemble, []string{"let a = 1;", "const b = () => 2;", "let c = 3;"})
+		u.So(t, second[0], gc.ShouldResemble, first[1])
+		u.So(t, second[1].Code, gc.ShouldEqual, "\"use strict\";\n\nvar c = 3;")
+		u.So(t, second[2], gc.ShouldResemble, first[0])
+
+		stats := tr.Stats()
+		u.So(t, stats, gc.ShouldResemble, CacheStats{Hits: 2, Misses: 3})
+		u.So(t, stats.HitRatio(), gc.ShouldEqual, 0.4)
+	})
+
+	t.Run("should not share the results of different transpiler versions", func(t *testing.T) {
+		inner := &countingTranspiler{}
+		tr := NewCachingTranspiler(inner, dir, "v2")
+
+		_, err := tr.Transpile(context.Background(), "let a = 1;")
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, inner.transpiled, gc.ShouldResemble, []string{"let a = 1;"})
+	})
+
+	t.Run("should transpile the codes whose cached results cannot be read", func(t *testing.T) {
+		inner := &countingTranspiler{}
+		tr := NewCachingTranspiler(inner, dir, "v1")
+		u.So(t, ioutil.WriteFile(tr.entryPath("let a = 1;"), []byte("{"), 0600), gc.ShouldBeNil)
+
+		results, err := tr.Transpile(context.Background(), "let a = 1;")
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, inner.transpiled, gc.ShouldResemble, []string{"let a = 1;"})
+		u.So(t, results[0].Code, gc.ShouldEqual, "\"use strict\";\n\nvar a = 1;")
+	})
+
+	t.Run("should return the errors with the indexes of the given codes", func(t *testing.T) {
+		tr := NewCachingTranspiler(&countingTranspiler{}, dir, "v1")
+
+		_, err := tr.Transpile(context.Background(), "let a = 1;", "let x = ;", "let c = 3;", ")")
+		u.So(t, err, gc.ShouldNotBeNil)
+
+		errs, ok := err.(TranspileErrors)
+		u.So(t, ok, gc.ShouldBeTrue)
+		u.So(t, len(errs), gc.ShouldEqual, 2)
+		u.So(t, errs[0].Index, gc.ShouldEqual, 1)
+		u.So(t, errs[1].Index, gc.ShouldEqual, 3)
+	})
+}
+
+func TestPruneCache(t *testing.T) {
+	now := time.Date(2020, 6, 1, 0, 0, 0, 0, time.UTC)
+
+	setup := func(t *testing.T) string {
+		dir, err := ioutil.TempDir("", "realm-cli-transpile-cache")
+		u.So(t, err, gc.ShouldBeNil)
+
+		for _, tc := range []struct {
+			version string
+			code    string
+			used    time.Time
+		}{
+			{"v1", "let a = 1;", now.Add(-time.Hour)},
+			{"v1", "let b = 2;", now.Add(-10 * 24 * time.Hour)},
+			{"v0", "let a = 1;", now.Add(-time.Hour)},
+		} {
+			tr := NewCachingTranspiler(NewNativeTranspiler(), dir, tc.version)
+			_, err := tr.Transpile(context.Background(), tc.code)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, os.Chtimes(tr.entryPath(tc.code), tc.used, tc.used), gc.ShouldBeNil)
+		}
+		return dir
+	}
+
+	cached := func(dir, version, code string) bool {
+		_, err := os.Stat(NewCachingTranspiler(nil, dir, version).entryPath(code))
+		return err == nil
+	}
+
+	t.Run("should remove the entries of other versions and those not used within the max age", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		result, err := PruneCache(dir, []string{"v1"}, 7*24*time.Hour, now)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, result.Entries, gc.ShouldEqual, 2)
+		u.So(t, result.Bytes, gc.ShouldBeGreaterThan, 0)
+
+		u.So(t, cached(dir, "v1", "let a = 1;"), gc.ShouldBeTrue)
+		u.So(t, cached(dir, "v1", "let b = 2;"), gc.ShouldBeFalse)
+		u.So(t, cached(dir, "v0", "let a = 1;"), gc.ShouldBeFalse)
+
+		_, err = os.Stat(filepath.Join(dir, versionDirName("v0")))
+		u.So(t, os.IsNotExist(err), gc.ShouldBeTrue)
+	})
+
+	t.Run("should keep the entries of the versions regardless of their age with no max age", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		result, err := PruneCache(dir, []string{"v1"}, 0, now)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, result.Entries, gc.ShouldEqual, 1)
+		u.So(t, cached(dir, "v1", "let b = 2;"), gc.ShouldBeTrue)
+	})
+
+	t.Run("should remove every entry with a negative max age", func(t *testing.T) {
+		dir := setup(t)
+		defer os.RemoveAll(dir)
+
+		result, err := PruneCache(dir, []string{"v1"}, -1, now)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, result.Entries, gc.ShouldEqual, 3)
+
+		entries, err := ioutil.ReadDir(dir)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, entries, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should do nothing if there is no cache", func(t *testing.T) {
+		result, err := PruneCache(filepath.Join(os.TempDir(), "realm-cli-missing-transpile-cache"), nil, -1, now)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, result, gc.ShouldResemble, PruneResult{})
+	})
+}
@@ -0,0 +1,259 @@
+package transpiler
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"flag"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	u "github.com/10gen/realm-cli/utils/test"
+	"github.com/robertkrimen/otto"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+// conformancePrelude stubs the CommonJS environment that transpiled modules run in
+const conformancePrelude = `
+var exports = {};
+var module = { exports: exports };
+function require(name) {
+  if (name === "lib") {
+    return { named: "named", other: function () { return "other"; } };
+  }
+  throw new Error("Cannot find module '" + name + "'");
+}
+`
+
+// updateGolden replaces the golden outputs of the conformance fixtures with those of the external transpiler
+var updateGolden = flag.Bool("update", false, "replace the golden outputs of the conformance fixtures with those of the external transpiler")
+
+// conformanceGolden is the expected output of a fixture: the JSON value that its result variable holds
+// once transpiled and run, or the error that transpiling it fails with. The golden outputs are written by
+// hand, and can be replaced with those of the external transpiler with -update where it is installed
+type conformanceGolden struct {
+	Result interface{} `json:"result,omitempty"`
+	Error  string      `json:"error,omitempty"`
+}
+
+// conformanceFixture is a source and the golden output next to it. The fixtures whose header comment
+// lists the transpilers they apply to use syntax that the external transpiler does not support,
+// so -update leaves their golden output alone
+type conformanceFixture struct {
+	name        string
+	code        string
+	goldenPath  string
+	golden      conformanceGolden
+	transpilers []string
+}
+
+func loadConformanceFixtures(t *testing.T) []conformanceFixture {
+	paths, err := filepath.Glob("../../testdata/transpiler/*.js")
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, paths, gc.ShouldNotBeEmpty)
+
+	var fixtures []conformanceFixture
+	for _, path := range paths {
+		data, err := ioutil.ReadFile(path)
+		u.So(t, err, gc.ShouldBeNil)
+
+		fixture := conformanceFixture{
+			name:        filepath.Base(path),
+			code:        string(data),
+			goldenPath:  strings.TrimSuffix(path, ".js") + ".golden.json",
+			transpilers: TranspilerNames,
+		}
+		for _, line := range strings.Split(fixture.code, "\n") {
+			if !strings.HasPrefix(line, "// transpilers: ") {
+				break
+			}
+			fixture.transpilers = strings.Split(strings.TrimPrefix(line, "// transpilers: "), ", ")
+		}
+
+		golden, err := ioutil.ReadFile(fixture.goldenPath)
+		if err != nil && !(os.IsNotExist(err) && *updateGolden) {
+			t.Fatalf("failed to read the golden output of %s: %s", fixture.name, err)
+		}
+		if err == nil {
+			u.So(t, json.Unmarshal(golden, &fixture.golden), gc.ShouldBeNil)
+		}
+		fixtures = append(fixtures, fixture)
+	}
+	return fixtures
+}
+
+// conformanceOutput transpiles the code of fixture with tr and runs it, returning the output to compare
+// with the golden output of fixture
+func conformanceOutput(t *testing.T, tr Transpiler, fixture conformanceFixture) conformanceGolden {
+	results, err := tr.Transpile(context.Background(), fixture.code)
+	if err != nil {
+		if _, ok := err.(TranspileErrors); !ok {
+			t.Fatalf("failed to transpile %s: %s", fixture.name, err)
+		}
+		return conformanceGolden{Error: err.Error()}
+	}
+	u.So(t, results, gc.ShouldHaveLength, 1)
+
+	output, err := runTranspiled(results[0].Code)
+	u.So(t, err, gc.ShouldBeNil)
+
+	var result interface{}
+	u.So(t, json.Unmarshal([]byte(output), &result), gc.ShouldBeNil)
+	return conformanceGolden{Result: result}
+}
+
+func runTranspiled(code string) (string, error) {
+	vm := otto.New()
+	if _, err := vm.Run(conformancePrelude); err != nil {
+		return "", err
+	}
+	if _, err := vm.Run(code); err != nil {
+		return "", err
+	}
+	value, err := vm.Run("JSON.stringify(result)")
+	if err != nil {
+		return "", err
+	}
+	return value.String(), nil
+}
+
+func TestConformance(t *testing.T) {
+	fixtures := loadConformanceFixtures(t)
+
+	if *updateGolden {
+		if _, err := exec.LookPath(DefaultTranspilerCommand); err != nil {
+			t.Fatalf("the %s binary must be installed to record the golden outputs", DefaultTranspilerCommand)
+		}
+		for _, fixture := range fixtures {
+			if !contains(fixture.transpilers, ExternalTranspilerName) {
+				continue
+			}
+			data, err := json.Marshal(conformanceOutput(t, NewExternalTranspiler(DefaultTranspilerCommand), fixture))
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, ioutil.WriteFile(fixture.goldenPath, append(data, '\n'), 0644), gc.ShouldBeNil)
+		}
+		fixtures = loadConformanceFixtures(t)
+	}
+
+	for _, name := range TranspilerNames {
+		tr, err := NewTranspiler(name)
+		u.So(t, err, gc.ShouldBeNil)
+
+		t.Run(fmt.Sprintf("the %s transpiler", name), func(t *testing.T) {
+			// the external transpiler is only checked against the golden outputs where it is installed
+			if name == ExternalTranspilerName {
+				if _, err := exec.LookPath(DefaultTranspilerCommand); err != nil {
+					t.Skipf("the %s binary is not installed", DefaultTranspilerCommand)
+				}
+			}
+
+			for _, fixture := range fixtures {
+				if !contains(fixture.transpilers, name) {
+					continue
+				}
+				fixture := fixture
+				t.Run(fmt.Sprintf("should match the golden output of %s", fixture.name), func(t *testing.T) {
+					u.So(t, conformanceOutput(t, tr, fixture), gc.ShouldResemble, fixture.golden)
+				})
+			}
+		})
+	}
+}
+
+func TestNativeTranspiler(t *testing.T) {
+	t.Run("should report the position of the errors of every failing source", func(t *testing.T) {
+		_, err := NewNativeTranspiler().Transpile(context.Background(), "var a = 1;", "var b = ;\n", "\n  class { }")
+		u.So(t, err, gc.ShouldResemble, TranspileErrors{
+			&TranspileError{Index: 1, Message: "unknown: Unexpected token (1:8)", Line: 1, Column: 8},
+			&TranspileError{Index: 2, Message: "unknown: Unexpected token (2:8)", Line: 2, Column: 8},
+		})
+	})
+
+	t.Run("should report the errors raised in the body of a loop whose bindings closures capture", func(t *testing.T) {
+		_, err := NewNativeTranspiler().Transpile(context.Background(), "for (let i=0;i<3;i++) { g(() => i); f(async function () {}); }")
+		u.So(t, err, gc.ShouldResemble, TranspileErrors{
+			&TranspileError{Index: 0, Message: "unknown: Async functions are not supported (1:38)", Line: 1, Column: 38},
+		})
+	})
+
+	t.Run("should map runtime errors back to the source", func(t *testing.T) {
+		code := "class Duck{\nquack(){\nthrow new Error();return 'quack'\n}\n}\nvar x = new Duck();\n var y = x.quack()"
+		results, err := NewNativeTranspiler().Transpile(context.Background(), code)
+		u.So(t, err, gc.ShouldBeNil)
+
+		vm := otto.New()
+		script, err := vm.CompileWithSourceMap("foo", results[0].Code, bytes.NewBuffer(results[0].SourceMap))
+		u.So(t, err, gc.ShouldBeNil)
+
+		_, err = vm.Run(script)
+		ottoErr, ok := err.(*otto.Error)
+		u.So(t, ok, gc.ShouldBeTrue)
+		u.So(t, ottoErr.String(), gc.ShouldEqual, "Error\n    at quack (unknown:3:10)\n    at unknown:7:9\n")
+	})
+
+	t.Run("should report the unsupported and invalid syntax of a source", func(t *testing.T) {
+		for _, tc := range []struct {
+			code    string
+			message string
+		}{
+			{"function* g() {}", "Generators are not supported (1:0)"},
+			{"async function f() {}", "Async functions are not supported (1:0)"},
+			{"function F() { return new.target; }", "new.target is not supported (1:22)"},
+			{"if (a) function f() {}", "In strict mode code, functions can only be declared at top level or inside a block (1:7)"},
+			{"a: function f() {}", "In strict mode code, functions can only be declared at top level or inside a block (1:3)"},
+			{"while (a) const x = 1;", "Unexpected token (1:10)"},
+			{"const x = 1; x = 2;", `"x" is read-only (1:13)`},
+			{"const x = 1; function f() { x++; }", `"x" is read-only (1:28)`},
+			{"for (const i of [1]) { [i] = [2]; }", `"i" is read-only (1:24)`},
+			{"import a from 'lib'; a = 1;", `"a" is read-only (1:21)`},
+		} {
+			_, err := NewNativeTranspiler().Transpile(context.Background(), tc.code)
+			u.So(t, err, gc.ShouldNotBeNil)
+			u.So(t, err.Error(), gc.ShouldContainSubstring, "unknown: "+tc.message)
+		}
+	})
+
+	t.Run("should transpile a source that shadows a const binding", func(t *testing.T) {
+		_, err := NewNativeTranspiler().Transpile(context.Background(), "const x = 1; function f(x) { x = 2; }")
+		u.So(t, err, gc.ShouldBeNil)
+	})
+
+	t.Run("should stop when the context is done", func(t *testing.T) {
+		ctx, cancel := context.WithCancel(context.Background())
+		cancel()
+		_, err := NewNativeTranspiler().Transpile(ctx, "var a = 1;")
+		u.So(t, err, gc.ShouldEqual, context.Canceled)
+	})
+}
+
+func TestNewTranspiler(t *testing.T) {
+	t.Run("should return the transpiler with the given name", func(t *testing.T) {
+		tr, err := NewTranspiler(NativeTranspilerName)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, tr, gc.ShouldResemble, NewNativeTranspiler())
+
+		tr, err = NewTranspiler(ExternalTranspilerName)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, tr, gc.ShouldResemble, NewExternalTranspiler(DefaultTranspilerCommand))
+	})
+
+	t.Run("should fail for an unknown name", func(t *testing.T) {
+		_, err := NewTranspiler("babel")
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEqual, "unknown transpiler 'babel', must be one of: native, external")
+	})
+}
+
+func contains(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,1078 @@
+package transpiler
+
+import (
+	"fmt"
+	"strconv"
+	"strings"
+	"unicode/utf8"
+)
+
+// the precedence of the generated expressions, from loosest to tightest
+const (
+	precSeq = iota
+	precAssign
+	precCond
+	precOr
+	precAnd
+	precBitOr
+	precBitXor
+	precBitAnd
+	precEq
+	precRel
+	precShift
+	precAdd
+	precMul
+	precUnary
+	precPostfix
+	precCall
+	precPrimary
+)
+
+var operatorPrecedence = map[string]int{
+	"||": precOr, "&&": precAnd, "|": precBitOr, "^": precBitXor, "&": precBitAnd,
+	"==": precEq, "!=": precEq, "===": precEq, "!==": precEq,
+	"<": precRel, ">": precRel, "<=": precRel, ">=": precRel, "instanceof": precRel, "in": precRel,
+	"<<": precShift, ">>": precShift, ">>>": precShift,
+	"+": precAdd, "-": precAdd, "*": precMul, "/": precMul, "%": precMul,
+}
+
+// wrap generates what f generates, in parentheses if its precedence p is looser than prec
+func (g *generator) wrap(p, prec int, f func()) {
+	if p < prec {
+		g.w("(")
+		f()
+		g.w(")")
+		return
+	}
+	f()
+}
+
+// isSimple reports whether x can be evaluated more than once without side effects
+func isSimple(x expr) bool {
+	switch x := x.(type) {
+	case *ident:
+		return x.binding == nil || x.binding.ref == ""
+	case *thisExpr:
+		return true
+	}
+	return false
+}
+
+func hasSpread(xs []expr) bool {
+	for _, x := range xs {
+		if _, ok := x.(*spread); ok {
+			return true
+		}
+	}
+	return false
+}
+
+func (g *generator) identRef(id *ident) string {
+	b := id.binding
+	switch {
+	case b == nil:
+		return id.name
+	case b.ref != "":
+		return b.ref
+	case b.kind == bindGlobal && b.name == "arguments":
+		return g.arguments()
+	}
+	return b.name
+}
+
+func (g *generator) expr(x expr, prec int) {
+	switch x := x.(type) {
+	case *ident:
+		g.mark(x)
+		g.w(g.identRef(x))
+	case *thisExpr:
+		g.w(g.this())
+	case *superExpr:
+		raise(x.start(), "'super' keyword outside a method")
+	case *literal:
+		g.literal(x)
+	case *templateLit:
+		g.template(x, prec)
+	case *taggedTemplate:
+		g.taggedTemplate(x, prec)
+	case *arrayLit:
+		if hasSpread(x.elems) {
+			g.spreadArray(x.elems)
+		} else {
+			g.array(x.elems)
+		}
+	case *objectLit:
+		g.object(x, prec)
+	case *funcExpr:
+		name := ""
+		if x.id != nil {
+			name = x.id.binding.name
+		}
+		g.function(x.function, name)
+	case *classExpr:
+		var name string
+		if x.id != nil {
+			name = x.id.binding.name
+		} else {
+			name = g.names.fresh("_class")
+		}
+		g.class(x.class, name)
+	case *unary:
+		g.unary(x, prec)
+	case *update:
+		g.update(x, prec)
+	case *binary:
+		g.binary(x, prec)
+	case *assign:
+		g.assign(x, prec)
+	case *conditional:
+		g.wrap(precCond, prec, func() {
+			g.expr(x.test, precOr)
+			g.w(" ? ")
+			g.expr(x.cons, precAssign)
+			g.w(" : ")
+			g.expr(x.alt, precAssign)
+		})
+	case *call:
+		g.call(x, prec)
+	case *newExpr:
+		g.newExpr(x, prec)
+	case *member:
+		g.member(x, prec)
+	case *optionalChain:
+		g.optionalChain(x, prec)
+	case *sequence:
+		g.wrap(precSeq, prec, func() {
+			for i, e := range x.exprs {
+				if i != 0 {
+					g.w(", ")
+				}
+				g.expr(e, precAssign)
+			}
+		})
+	case *spread:
+		raise(x.start(), "Unexpected token")
+	case *yieldExpr:
+		raise(x.start(), "Generators are not supported")
+	case *awaitExpr:
+		raise(x.start(), "Async functions are not supported")
+	case *metaProperty:
+		raise(x.start(), "%s.%s is not supported", x.meta, x.property)
+	case *importCall:
+		g.wrap(precCall, prec, func() {
+			specifier := g.names.fresh("_specifier")
+			g.w("Promise.resolve(")
+			g.expr(x.source, precAssign)
+			g.w(").then(function (", specifier, ") { return ", g.helper("interopRequireWildcard"), "(require(", specifier, ")); })")
+		})
+	case *paren:
+		g.expr(x.x, prec)
+	default:
+		panic(fmt.Sprintf("unexpected expression %T", x))
+	}
+}
+
+// Literals
+
+func (g *generator) literal(x *literal) {
+	switch x.kind {
+	case litString:
+		g.w(stringLiteral(x))
+	case litRegexp:
+		if strings.Trim(x.flags, "gim") == "" {
+			g.w("/", x.value, "/", x.flags)
+		} else {
+			g.w("new RegExp(", quote(x.value), ", ", quote(x.flags), ")")
+		}
+	default:
+		g.w(x.value)
+	}
+}
+
+// stringLiteral returns the source of a string literal, unless it uses escapes or characters that ES5 does not allow
+func stringLiteral(x *literal) string {
+	if strings.Contains(x.value, `\u{`) || strings.ContainsAny(x.value, "  ") {
+		return quote(x.cooked)
+	}
+	return x.value
+}
+
+// quote returns a double-quoted string literal of s, in which lone surrogates are encoded as WTF-8
+func quote(s string) string {
+	var sb strings.Builder
+	sb.WriteByte('"')
+	for i := 0; i < len(s); {
+		if s[i] == 0xed && i+2 < len(s) && s[i+1] >= 0xa0 {
+			code := rune(s[i]&0x0f)<<12 | rune(s[i+1]&0x3f)<<6 | rune(s[i+2]&0x3f)
+			fmt.Fprintf(&sb, `\u%04x`, code)
+			i += 3
+			continue
+		}
+		r, size := utf8.DecodeRuneInString(s[i:])
+		i += size
+		switch r {
+		case '"':
+			sb.WriteString(`\"`)
+		case '\\':
+			sb.WriteString(`\\`)
+		case '\n':
+			sb.WriteString(`\n`)
+		case '\r':
+			sb.WriteString(`\r`)
+		case '\t':
+			sb.WriteString(`\t`)
+		case '\b':
+			sb.WriteString(`\b`)
+		case '\f':
+			sb.WriteString(`\f`)
+		case '\v':
+			sb.WriteString(`\v`)
+		case ' ', ' ':
+			fmt.Fprintf(&sb, `\u%04x`, r)
+		default:
+			if r < 0x20 || r == 0x7f {
+				fmt.Fprintf(&sb, `\x%02x`, r)
+			} else {
+				sb.WriteString(s[i-size : i])
+			}
+		}
+	}
+	sb.WriteByte('"')
+	return sb.String()
+}
+
+func (g *generator) template(x *templateLit, prec int) {
+	for _, q := range x.quasis {
+		if q.invalidEscape {
+			raise(q.start, "Invalid escape sequence in template")
+		}
+	}
+	if len(x.exprs) == 0 {
+		g.w(quote(x.quasis[0].cooked))
+		return
+	}
+	g.wrap(precAdd, prec, func() {
+		g.w(quote(x.quasis[0].cooked))
+		for i, e := range x.exprs {
+			g.w(" + ")
+			g.expr(e, precMul)
+			if cooked := x.quasis[i+1].cooked; cooked != "" {
+				g.w(" + ", quote(cooked))
+			}
+		}
+	})
+}
+
+func (g *generator) taggedTemplate(x *taggedTemplate, prec int) {
+	cooked := make([]string, len(x.quasi.quasis))
+	raw := make([]string, len(x.quasi.quasis))
+	for i, q := range x.quasi.quasis {
+		if q.invalidEscape {
+			cooked[i] = "void 0"
+		} else {
+			cooked[i] = quote(q.cooked)
+		}
+		raw[i] = quote(q.raw)
+	}
+
+	object := g.names.fresh("_templateObject")
+	root := g.ctx
+	for root.parent != nil {
+		root = root.parent
+	}
+	root.addVar(object, g.helper("taggedTemplateLiteral")+"(["+strings.Join(cooked, ", ")+"], ["+strings.Join(raw, ", ")+"])")
+
+	g.wrap(precCall, prec, func() {
+		g.callee(x.tag)
+		g.w("(", object)
+		for _, e := range x.quasi.exprs {
+			g.w(", ")
+			g.expr(e, precAssign)
+		}
+		g.w(")")
+	})
+}
+
+func (g *generator) array(elems []expr) {
+	g.w("[")
+	for i, e := range elems {
+		if i != 0 {
+			g.w(", ")
+		}
+		if e != nil {
+			g.expr(e, precAssign)
+		}
+	}
+	if len(elems) != 0 && elems[len(elems)-1] == nil {
+		g.w(",")
+	}
+	g.w("]")
+}
+
+// spreadArray generates an array of elements with spreads, as a concatenation of arrays
+func (g *generator) spreadArray(elems []expr) {
+	var parts []*buffer
+	var group []expr
+	flush := func() {
+		if group != nil {
+			group := group
+			parts = append(parts, g.capture(func() { g.array(group) }))
+		}
+		group = nil
+	}
+	for _, e := range elems {
+		if s, ok := e.(*spread); ok {
+			flush()
+			parts = append(parts, cat(g.helper("toConsumableArray"), "(", g.exprCode(s.x, precAssign), ")"))
+			continue
+		}
+		group = append(group, e)
+	}
+	flush()
+
+	g.buf.append(parts[0])
+	if len(parts) == 1 {
+		return
+	}
+	g.w(".concat(")
+	for i, part := range parts[1:] {
+		if i != 0 {
+			g.w(", ")
+		}
+		g.buf.append(part)
+	}
+	g.w(")")
+}
+
+// Objects
+
+// propertyKey generates the value of a property key
+func (g *generator) propertyKey(key expr, computed bool) {
+	if computed {
+		g.expr(key, precAssign)
+		return
+	}
+	switch key := key.(type) {
+	case *literal:
+		if key.kind == litString {
+			g.w(stringLiteral(key))
+		} else {
+			g.w(key.value)
+		}
+	default:
+		g.w(quote(propertyName(key)))
+	}
+}
+
+// objectKey generates a non-computed property key of an object literal
+func (g *generator) objectKey(key expr) {
+	switch key := key.(type) {
+	case *literal:
+		g.propertyKey(key, false)
+	case *ident:
+		g.w(key.name)
+	}
+}
+
+func (g *generator) object(x *objectLit, prec int) {
+	for _, prop := range x.props {
+		if prop.shorthand {
+			if a, ok := prop.value.(*assign); ok {
+				raise(a.start(), "Invalid shorthand property initializer")
+			}
+		}
+	}
+
+	spread := false
+	for _, prop := range x.props {
+		if prop.kind == propSpread {
+			spread = true
+		}
+	}
+	if !spread {
+		g.objectParts(x.props, prec)
+		return
+	}
+
+	g.wrap(precCall, prec, func() {
+		g.w(g.helper("extends"), "({}")
+		start := 0
+		for i, prop := range append(x.props, nil) {
+			if prop != nil && prop.kind != propSpread {
+				continue
+			}
+			if i > start {
+				g.w(", ")
+				g.objectParts(x.props[start:i], precAssign)
+			}
+			if prop != nil {
+				g.w(", ")
+				g.expr(prop.value, precAssign)
+			}
+			start = i + 1
+		}
+		g.w(")")
+	})
+}
+
+// objectParts generates an object literal with props, defining the properties from the first computed one on
+// with helpers
+func (g *generator) objectParts(props []*property, prec int) {
+	computed := len(props)
+	for i, prop := range props {
+		if prop.computed {
+			computed = i
+			break
+		}
+	}
+	if computed == len(props) {
+		g.objectLiteral(props)
+		return
+	}
+
+	obj := g.temp("_obj")
+	g.wrap(precSeq, prec, func() {
+		g.w(obj, " = ")
+		g.objectLiteral(props[:computed])
+		for _, prop := range props[computed:] {
+			g.w(", ")
+			g.mark(prop)
+			switch prop.kind {
+			case propGet, propSet:
+				kind := "get"
+				if prop.kind == propSet {
+					kind = "set"
+				}
+				g.w(g.helper("defineAccessor"), "(", quote(kind), ", ", obj, ", ")
+				g.propertyKey(prop.key, prop.computed)
+				g.w(", ")
+				g.method(prop, "function ")
+				g.w(")")
+			default:
+				g.w(g.helper("defineProperty"), "(", obj, ", ")
+				g.propertyKey(prop.key, prop.computed)
+				g.w(", ")
+				g.propertyValue(prop)
+				g.w(")")
+			}
+		}
+		g.w(", ", obj)
+	})
+}
+
+func (g *generator) objectLiteral(props []*property) {
+	if len(props) == 0 {
+		g.w("{}")
+		return
+	}
+	g.w("{ ")
+	for i, prop := range props {
+		if i != 0 {
+			g.w(", ")
+		}
+		g.mark(prop)
+		switch prop.kind {
+		case propGet, propSet:
+			kind := "get "
+			if prop.kind == propSet {
+				kind = "set "
+			}
+			g.method(prop, kind+g.capture(func() { g.objectKey(prop.key) }).String())
+		default:
+			g.objectKey(prop.key)
+			g.w(": ")
+			g.propertyValue(prop)
+		}
+	}
+	g.w(" }")
+}
+
+func (g *generator) propertyValue(prop *property) {
+	if prop.method {
+		g.method(prop, "")
+		return
+	}
+	g.expr(prop.value, precAssign)
+}
+
+// method generates the function of an object literal method or accessor, starting with prefix
+// or with function and the name of the method
+func (g *generator) method(prop *property, prefix string) {
+	fn := prop.value.(*funcExpr).function
+	g.checkFunction(fn)
+	if prefix == "" {
+		prefix = "function " + methodName(prop.key, prop.computed, fn)
+	}
+	g.functionWith(&funcCtx{parent: g.ctx}, fn, prefix, nil, nil)
+}
+
+// Operators
+
+func (g *generator) unary(x *unary, prec int) {
+	if x.op == "delete" {
+		if m, ok := x.x.(*member); ok && m.private {
+			raise(m.start(), "Private class members are not supported")
+		}
+	}
+	g.wrap(precUnary, prec, func() {
+		operand := g.exprCode(x.x, precUnary)
+		g.w(x.op)
+		if len(x.op) > 1 || (len(operand.b) != 0 && (operand.b[0] == '+' || operand.b[0] == '-') && (x.op == "+" || x.op == "-")) {
+			g.w(" ")
+		}
+		g.buf.append(operand)
+	})
+}
+
+func (g *generator) update(x *update, prec int) {
+	target := g.targetCode(x.x)
+	if x.prefix {
+		g.wrap(precUnary, prec, func() {
+			g.w(x.op)
+			g.buf.append(target)
+		})
+		return
+	}
+	g.wrap(precPostfix, prec, func() {
+		g.buf.append(target)
+		g.w(x.op)
+	})
+}
+
+func (g *generator) binary(x *binary, prec int) {
+	switch x.op {
+	case "**":
+		g.wrap(precCall, prec, func() {
+			g.w("Math.pow(")
+			g.expr(x.x, precAssign)
+			g.w(", ")
+			g.expr(x.y, precAssign)
+			g.w(")")
+		})
+	case "??":
+		g.wrap(precCond, prec, func() {
+			ref := g.evaluateOnce(g.exprCode(x.x, precAssign), isSimple(x.x))
+			g.w(" !== null && ", ref, " !== void 0 ? ", ref, " : ")
+			g.expr(x.y, precAssign)
+		})
+	default:
+		p := operatorPrecedence[x.op]
+		g.wrap(p, prec, func() {
+			g.expr(x.x, p)
+			g.w(" ", x.op, " ")
+			g.expr(x.y, p+1)
+		})
+	}
+}
+
+// evaluateOnce generates code, stored in a temporary variable unless it is simple,
+// and returns the code that refers to its value afterwards
+func (g *generator) evaluateOnce(code *buffer, simple bool) string {
+	if simple {
+		g.buf.append(code)
+		return code.String()
+	}
+	ref := g.temp("_ref")
+	g.w("(", ref, " = ")
+	g.buf.append(code)
+	g.w(")")
+	return ref
+}
+
+// targetCode returns the code of an identifier or member that is assigned to
+func (g *generator) targetCode(target expr) *buffer {
+	switch t := target.(type) {
+	case *ident:
+		return cat(g.identRef(t))
+	case *member:
+		if _, ok := t.object.(*superExpr); ok {
+			raise(t.start(), "Assigning to 'super' properties is not supported")
+		}
+		return g.exprCode(t, precCall)
+	case *paren:
+		return g.targetCode(t.x)
+	}
+	raise(target.start(), "Invalid left-hand side in assignment expression")
+	return nil
+}
+
+// reference returns the code of an assignment target for its first evaluation, which stores its object
+// and key in temporary variables unless they are simple, and for the evaluations after it
+func (g *generator) reference(target expr) (*buffer, *buffer) {
+	m, ok := target.(*member)
+	if !ok {
+		code := g.targetCode(target)
+		return code, code
+	}
+	if _, ok := m.object.(*superExpr); ok {
+		raise(m.start(), "Assigning to 'super' properties is not supported")
+	}
+
+	objFirst := g.capture(func() { g.memberObject(m.object) })
+	objAgain := objFirst
+	if !isSimple(m.object) {
+		obj := g.temp("_obj")
+		objFirst = cat("(", obj, " = ", g.exprCode(m.object, precAssign), ")")
+		objAgain = cat(obj)
+	}
+	if !m.computed {
+		prop := "." + m.property.(*ident).name
+		return cat(objFirst, prop), cat(objAgain, prop)
+	}
+	if lit, ok := m.property.(*literal); ok {
+		key := g.exprCode(lit, precSeq)
+		return cat(objFirst, "[", key, "]"), cat(objAgain, "[", key, "]")
+	}
+	key := g.temp("_key")
+	return cat(objFirst, "[", key, " = ", g.exprCode(m.property, precAssign), "]"), cat(objAgain, "[", key, "]")
+}
+
+func (g *generator) assign(x *assign, prec int) {
+	switch x.target.(type) {
+	case *arrayPattern, *objectPattern:
+		g.buf.append(g.patternAssign(x.target, x.value, prec))
+		return
+	}
+
+	switch x.op {
+	case "**=":
+		first, again := g.reference(x.target)
+		g.wrap(precAssign, prec, func() {
+			g.buf.append(first)
+			g.w(" = Math.pow(")
+			g.buf.append(again)
+			g.w(", ")
+			g.expr(x.value, precAssign)
+			g.w(")")
+		})
+	case "||=", "&&=":
+		op := x.op[:2]
+		p := operatorPrecedence[op]
+		first, again := g.reference(x.target)
+		g.wrap(p, prec, func() {
+			g.buf.append(first)
+			g.w(" ", op, " (")
+			g.buf.append(again)
+			g.w(" = ")
+			g.expr(x.value, precAssign)
+			g.w(")")
+		})
+	case "??=":
+		first, again := g.reference(x.target)
+		g.wrap(precCond, prec, func() {
+			_, simple := x.target.(*ident)
+			ref := g.evaluateOnce(first, simple)
+			g.w(" !== null && ", ref, " !== void 0 ? ", ref, " : ")
+			g.buf.append(again)
+			g.w(" = ")
+			g.expr(x.value, precAssign)
+		})
+	default:
+		target := g.targetCode(x.target)
+		g.wrap(precAssign, prec, func() {
+			g.buf.append(target)
+			g.w(" ", x.op, " ")
+			g.expr(x.value, precAssign)
+		})
+	}
+}
+
+// Calls and members
+
+// callee generates the function of a call, which is not called as a method if it is imported
+func (g *generator) callee(x expr) {
+	if id, ok := x.(*ident); ok && id.binding != nil && strings.Contains(id.binding.ref, ".") {
+		g.w("(0, ", id.binding.ref, ")")
+		return
+	}
+	g.expr(x, precCall)
+}
+
+func (g *generator) args(args []expr) {
+	for i, arg := range args {
+		if i != 0 {
+			g.w(", ")
+		}
+		g.expr(arg, precAssign)
+	}
+}
+
+func (g *generator) call(x *call, prec int) {
+	g.mark(x)
+	switch callee := x.callee.(type) {
+	case *superExpr:
+		home := g.home(x)
+		var call *buffer
+		if hasSpread(x.args) {
+			call = cat(home.superName, ".apply(this, ", g.capture(func() { g.spreadArray(x.args) }), ")")
+		} else {
+			call = g.capture(func() {
+				g.w(home.superName, ".call(this")
+				for _, arg := range x.args {
+					g.w(", ")
+					g.expr(arg, precAssign)
+				}
+				g.w(")")
+			})
+		}
+		g.superCall(x, call, prec)
+		return
+	case *member:
+		if _, ok := callee.object.(*superExpr); ok {
+			g.wrap(precCall, prec, func() {
+				g.superProperty(callee)
+				if hasSpread(x.args) {
+					g.w(".apply(", g.this(), ", ")
+					g.spreadArray(x.args)
+				} else {
+					g.w(".call(", g.this())
+					for _, arg := range x.args {
+						g.w(", ")
+						g.expr(arg, precAssign)
+					}
+				}
+				g.w(")")
+			})
+			return
+		}
+	}
+
+	if hasSpread(x.args) {
+		g.wrap(precCall, prec, func() {
+			if m, ok := x.callee.(*member); ok && !m.private {
+				obj := g.capture(func() { g.memberObject(m.object) }).String()
+				if isSimple(m.object) {
+					g.w(obj)
+				} else {
+					temp := g.temp("_obj")
+					g.w("(", temp, " = ")
+					g.expr(m.object, precAssign)
+					g.w(")")
+					obj = temp
+				}
+				g.memberProperty(m)
+				g.w(".apply(", obj, ", ")
+			} else {
+				g.callee(x.callee)
+				g.w(".apply(void 0, ")
+			}
+			g.spreadArray(x.args)
+			g.w(")")
+		})
+		return
+	}
+
+	g.wrap(precCall, prec, func() {
+		g.callee(x.callee)
+		g.w("(")
+		g.args(x.args)
+		g.w(")")
+	})
+}
+
+// containsCall reports whether the callee of a new expression needs parentheses so that a call in it
+// is not taken for the arguments of new
+func containsCall(x expr) bool {
+	for {
+		switch n := x.(type) {
+		case *member:
+			x = n.object
+		case *call, *taggedTemplate, *optionalChain:
+			return true
+		case *paren:
+			x = n.x
+		default:
+			return false
+		}
+	}
+}
+
+func (g *generator) newExpr(x *newExpr, prec int) {
+	g.mark(x)
+	g.wrap(precCall, prec, func() {
+		if hasSpread(x.args) {
+			g.w("new (Function.prototype.bind.apply(")
+			g.expr(x.callee, precAssign)
+			g.w(", [null].concat(")
+			g.spreadArray(x.args)
+			g.w(")))()")
+			return
+		}
+		g.w("new ")
+		if containsCall(x.callee) {
+			g.w("(")
+			g.expr(x.callee, precSeq)
+			g.w(")")
+		} else {
+			g.expr(x.callee, precCall)
+		}
+		g.w("(")
+		g.args(x.args)
+		g.w(")")
+	})
+}
+
+func (g *generator) memberObject(x expr) {
+	if lit, ok := x.(*literal); ok && lit.kind == litNumber {
+		g.w("(", lit.value, ")")
+		return
+	}
+	g.expr(x, precCall)
+}
+
+func (g *generator) memberProperty(m *member) {
+	if m.computed {
+		g.w("[")
+		g.expr(m.property, precSeq)
+		g.w("]")
+		return
+	}
+	g.w(".", m.property.(*ident).name)
+}
+
+func (g *generator) member(x *member, prec int) {
+	if _, ok := x.object.(*superExpr); ok {
+		g.superProperty(x)
+		return
+	}
+	if x.private {
+		raise(x.start(), "Private class members are not supported")
+	}
+	g.wrap(precCall, prec, func() {
+		g.memberObject(x.object)
+		g.memberProperty(x)
+	})
+}
+
+// superProperty generates the lookup of a property of the prototype of the class of a method
+func (g *generator) superProperty(m *member) {
+	home := g.home(m)
+	proto := home.superName
+	switch {
+	case proto == "" && home.static:
+		proto = "Function.prototype"
+	case proto == "":
+		proto = "Object.prototype"
+	case !home.static:
+		proto += ".prototype"
+	}
+	g.w(g.helper("get"), "(", proto, ", ")
+	g.propertyKey(m.property, m.computed)
+	g.w(", ", g.this(), ")")
+}
+
+// optionalChain generates a chain of members and calls that evaluates to undefined as soon as
+// the object of an optional link is null or undefined
+func (g *generator) optionalChain(x *optionalChain, prec int) {
+	var links []expr
+	base := x.x
+loop:
+	for {
+		switch n := base.(type) {
+		case *member:
+			links = append(links, n)
+			base = n.object
+		case *call:
+			links = append(links, n)
+			base = n.callee
+		default:
+			break loop
+		}
+	}
+	for i, j := 0, len(links)-1; i < j; i, j = i+1, j-1 {
+		links[i], links[j] = links[j], links[i]
+	}
+	if _, ok := base.(*superExpr); ok {
+		raise(base.start(), "'super' in optional chains is not supported")
+	}
+
+	var conds []*buffer
+	cur := g.capture(func() { g.memberObject(base) })
+	simple := isSimple(base)
+	var this *buffer
+	for i, link := range links {
+		optional := false
+		switch l := link.(type) {
+		case *member:
+			optional = l.optional
+		case *call:
+			optional = l.optional
+		}
+		if optional {
+			if simple {
+				conds = append(conds, cat(cur, " === null || ", cur, " === void 0"))
+			} else {
+				temp := g.temp("_ref")
+				conds = append(conds, cat("(", temp, " = ", cur, ") === null || ", temp, " === void 0"))
+				cur = cat(temp)
+			}
+		}
+
+		switch l := link.(type) {
+		case *member:
+			if l.private {
+				raise(l.start(), "Private class members are not supported")
+			}
+			if i+1 < len(links) {
+				if next, ok := links[i+1].(*call); ok && next.optional {
+					if simple {
+						this = cur
+					} else {
+						temp := g.temp("_ref")
+						cur = cat("(", temp, " = ", cur, ")")
+						this = cat(temp)
+					}
+				}
+			}
+			cur = cat(cur, g.capture(func() { g.memberProperty(l) }))
+		case *call:
+			if hasSpread(l.args) {
+				raise(l.start(), "Spread arguments in optional chains are not supported")
+			}
+			args := g.capture(func() { g.args(l.args) })
+			if l.optional && this != nil {
+				if len(l.args) != 0 {
+					args = cat(", ", args)
+				}
+				cur = cat(cur, ".call(", this, args, ")")
+			} else {
+				cur = cat(cur, "(", args, ")")
+			}
+			this = nil
+		}
+		simple = false
+	}
+
+	g.wrap(precCond, prec, func() {
+		for i, cond := range conds {
+			if i != 0 {
+				g.w(" || ")
+			}
+			g.buf.append(cond)
+		}
+		g.w(" ? void 0 : ")
+		g.buf.append(cur)
+	})
+}
+
+// Destructuring
+
+// pair is a declarator, or the assignment of a value to a name or a target
+type pair struct {
+	name   string
+	target *buffer
+	value  *buffer
+}
+
+// destructure appends the pairs that assign the parts of value to the names and targets of a pattern,
+// where value can be evaluated more than once if it is simple, and temporary variables are declared
+// by the pairs rather than in the current function if declare is set
+func (g *generator) destructure(target pattern, value *buffer, simple, declare bool, pairs []pair) []pair {
+	temp := func(value *buffer) *buffer {
+		name := g.names.fresh("_ref")
+		if !declare {
+			g.ctx.addVar(name, "")
+		}
+		pairs = append(pairs, pair{name: name, value: value})
+		return cat(name)
+	}
+
+	switch t := target.(type) {
+	case *ident:
+		return append(pairs, pair{name: g.identRef(t), value: value})
+	case *member:
+		return append(pairs, pair{target: g.targetCode(t), value: value})
+	case *assignPattern:
+		if !simple {
+			value = temp(value)
+		}
+		def := g.exprCode(t.def, precAssign)
+		return g.destructure(t.target, cat(value, " === void 0 ? ", def, " : ", value), false, declare, pairs)
+	case *arrayPattern:
+		n := ""
+		if t.rest == nil {
+			n = ", " + strconv.Itoa(len(t.elems))
+		}
+		arr := temp(cat(g.helper("slicedToArray"), "(", value, n, ")"))
+		for i, elem := range t.elems {
+			if elem != nil {
+				pairs = g.destructure(elem, cat(arr, "["+strconv.Itoa(i)+"]"), true, declare, pairs)
+			}
+		}
+		if t.rest != nil {
+			pairs = g.destructure(t.rest, cat(arr, ".slice("+strconv.Itoa(len(t.elems))+")"), false, declare, pairs)
+		}
+		return pairs
+	case *objectPattern:
+		if !simple {
+			value = temp(value)
+		}
+		var keys []*buffer
+		for _, prop := range t.props {
+			var access, key *buffer
+			if prop.computed {
+				key = g.exprCode(prop.key, precAssign)
+				if t.rest != nil {
+					key = temp(key)
+				}
+				access = cat(value, "[", key, "]")
+			} else {
+				name := propertyName(prop.key)
+				if isIdentifierName(name) {
+					access = cat(value, "."+name)
+				} else {
+					access = cat(value, "["+quote(name)+"]")
+				}
+				key = cat(quote(name))
+			}
+			keys = append(keys, key)
+			pairs = g.destructure(prop.value, access, true, declare, pairs)
+		}
+		if t.rest != nil {
+			list := &buffer{}
+			for i, key := range keys {
+				if i != 0 {
+					list.write(", ")
+				}
+				list.append(key)
+			}
+			pairs = g.destructure(t.rest, cat(g.helper("objectWithoutProperties"), "(", value, ", [", list, "])"), false, declare, pairs)
+		}
+		return pairs
+	}
+	raise(target.start(), "Invalid destructuring assignment target")
+	return nil
+}
+
+// patternAssign returns the assignment of value to target
+func (g *generator) patternAssign(target pattern, value expr, prec int) *buffer {
+	return g.assignTo(target, g.exprCode(value, precAssign), isSimple(value), prec)
+}
+
+// assignTo returns the assignment of the code of a value to target, which evaluates to the value
+func (g *generator) assignTo(target pattern, value *buffer, simple bool, prec int) *buffer {
+	return g.capture(func() {
+		switch target.(type) {
+		case *ident, *member:
+			g.wrap(precAssign, prec, func() {
+				g.buf.append(g.targetCode(target))
+				g.w(" = ")
+				g.buf.append(value)
+			})
+			return
+		}
+
+		var pairs []pair
+		if !simple {
+			temp := g.temp("_ref")
+			pairs = append(pairs, pair{name: temp, value: value})
+			value = cat(temp)
+		}
+		pairs = g.destructure(target, value, true, false, pairs)
+		g.wrap(precSeq, prec, func() {
+			g.writeAssignPairs(pairs)
+			g.w(", ")
+			g.buf.append(value)
+		})
+	})
+}
@@ -0,0 +1,1350 @@
+package transpiler
+
+import (
+	"strconv"
+	"strings"
+)
+
+// mapping maps an offset in the generated code to an offset in the source
+type mapping struct {
+	gen, src int
+}
+
+// buffer holds generated code and the source offsets it was generated from
+type buffer struct {
+	b    []byte
+	maps []mapping
+}
+
+func (b *buffer) write(ss ...string) {
+	for _, s := range ss {
+		b.b = append(b.b, s...)
+	}
+}
+
+func (b *buffer) mark(src int) {
+	b.maps = append(b.maps, mapping{len(b.b), src})
+}
+
+func (b *buffer) append(o *buffer) {
+	offset := len(b.b)
+	b.b = append(b.b, o.b...)
+	for _, m := range o.maps {
+		b.maps = append(b.maps, mapping{m.gen + offset, m.src})
+	}
+}
+
+func (b *buffer) String() string {
+	return string(b.b)
+}
+
+// cat concatenates strings and buffers into a new buffer
+func cat(parts ...interface{}) *buffer {
+	b := &buffer{}
+	for _, part := range parts {
+		switch part := part.(type) {
+		case string:
+			b.write(part)
+		case *buffer:
+			b.append(part)
+		}
+	}
+	return b
+}
+
+type variable struct {
+	name, init string
+}
+
+// funcCtx is the context of the function being generated, which is a function of the source, the program,
+// the function wrapping the body of a loop whose bindings are captured, or the function wrapping a class
+type funcCtx struct {
+	parent  *funcCtx
+	program bool
+
+	// arrow is set for arrow functions, loop wrappers and class wrappers, which use the this and arguments
+	// of their parent
+	arrow bool
+
+	vars          []variable
+	thisName      string
+	argumentsName string
+
+	// thisOverride replaces this in static initializers
+	thisOverride string
+
+	// derived is set for the constructor of a derived class, where thisName holds the constructed object
+	derived bool
+
+	home    *classHome
+	wrapper *loopWrapper
+	targets []*target
+
+	loopDepth int
+}
+
+func (c *funcCtx) addVar(name, init string) {
+	for _, v := range c.vars {
+		if v.name == name {
+			return
+		}
+	}
+	c.vars = append(c.vars, variable{name, init})
+}
+
+// classHome describes the class of a method
+type classHome struct {
+	name      string
+	superName string
+	static    bool
+
+	// fields are the instance fields initialized by the constructor, and fieldKeys the variables
+	// holding their computed keys
+	fields    []*classMember
+	fieldKeys map[*classMember]string
+}
+
+// target is a statement that break or continue can target
+type target struct {
+	labels []string
+	loop   bool
+	swtch  bool
+}
+
+func (t *target) matches(tok, label string) bool {
+	if label == "" {
+		return t.loop || (tok == "break" && t.swtch)
+	}
+	for _, l := range t.labels {
+		if l == label {
+			return tok == "break" || t.loop
+		}
+	}
+	return false
+}
+
+// loopWrapper is the function that the body of a loop is moved to when closures capture its bindings,
+// which reports how the body completed through its return value
+type loopWrapper struct {
+	name   string
+	target *target
+
+	brk   bool
+	ret   bool
+	codes []string
+}
+
+func (w *loopWrapper) addCode(code string) {
+	for _, c := range w.codes {
+		if c == code {
+			return
+		}
+	}
+	w.codes = append(w.codes, code)
+}
+
+type generator struct {
+	names  *namer
+	buf    *buffer
+	ctx    *funcCtx
+	indent int
+
+	// pendingLabels are written after the indentation of the next line
+	pendingLabels string
+
+	helpers     []string
+	helperNames map[string]string
+
+	defaultName map[*exportDefault]string
+}
+
+func newGenerator(names *namer) *generator {
+	return &generator{
+		names:       names,
+		buf:         &buffer{},
+		helperNames: map[string]string{},
+		defaultName: map[*exportDefault]string{},
+	}
+}
+
+func (g *generator) w(ss ...string) {
+	g.buf.write(ss...)
+}
+
+func (g *generator) mark(n node) {
+	g.buf.mark(n.start())
+}
+
+// line starts a new line at the current indentation
+func (g *generator) line() {
+	g.w("\n", strings.Repeat("  ", g.indent), g.pendingLabels)
+	g.pendingLabels = ""
+}
+
+// capture returns what f generates
+func (g *generator) capture(f func()) *buffer {
+	saved := g.buf
+	g.buf = &buffer{}
+	f()
+	b := g.buf
+	g.buf = saved
+	return b
+}
+
+func (g *generator) exprCode(x expr, prec int) *buffer {
+	return g.capture(func() { g.expr(x, prec) })
+}
+
+// temp returns a new variable declared in the current function
+func (g *generator) temp(base string) string {
+	name := g.names.fresh(base)
+	g.ctx.addVar(name, "")
+	return name
+}
+
+// varCtx returns the context that the vars of the current context are declared in
+func (g *generator) varCtx() *funcCtx {
+	c := g.ctx
+	for c.wrapper != nil {
+		c = c.parent
+	}
+	return c
+}
+
+func (g *generator) this() string {
+	for c := g.ctx; ; c = c.parent {
+		switch {
+		case c.thisOverride != "":
+			return c.thisOverride
+		case c.program:
+			return "undefined"
+		case c.derived:
+			return c.thisName
+		case !c.arrow:
+			if c == g.ctx {
+				return "this"
+			}
+			if c.thisName == "" {
+				c.thisName = g.names.fresh("_this")
+				c.addVar(c.thisName, "this")
+			}
+			return c.thisName
+		}
+	}
+}
+
+func (g *generator) arguments() string {
+	for c := g.ctx; ; c = c.parent {
+		switch {
+		case c.program:
+			return "arguments"
+		case !c.arrow:
+			if c == g.ctx {
+				return "arguments"
+			}
+			if c.argumentsName == "" {
+				c.argumentsName = g.names.fresh("_arguments")
+				c.addVar(c.argumentsName, "arguments")
+			}
+			return c.argumentsName
+		}
+	}
+}
+
+// home returns the class of the method being generated
+func (g *generator) home(n node) *classHome {
+	for c := g.ctx; c != nil; c = c.parent {
+		if !c.arrow {
+			if c.home == nil {
+				break
+			}
+			return c.home
+		}
+	}
+	raise(n.start(), "'super' outside of class methods is not supported")
+	return nil
+}
+
+func (g *generator) declareVars(c *funcCtx) {
+	if len(c.vars) == 0 {
+		return
+	}
+	decls := make([]string, len(c.vars))
+	for i, v := range c.vars {
+		decls[i] = v.name
+		if v.init != "" {
+			decls[i] += " = " + v.init
+		}
+	}
+	g.line()
+	g.w("var ", strings.Join(decls, ", "), ";")
+}
+
+// functionBody generates a function body in ctx, preceded by the vars that the body declares
+func (g *generator) functionBody(ctx *funcCtx, f func()) {
+	var body, vars *buffer
+	func() {
+		// restore the context even when a syntax error unwinds f, so that the deferred pops of the
+		// loops around this function apply to the targets they pushed
+		savedCtx := g.ctx
+		defer func() { g.ctx = savedCtx }()
+		g.ctx = ctx
+		g.indent++
+		body = g.capture(f)
+		vars = g.capture(func() { g.declareVars(ctx) })
+		g.indent--
+	}()
+
+	g.w("{")
+	g.buf.append(vars)
+	g.buf.append(body)
+	if len(body.b) != 0 || len(vars.b) != 0 {
+		g.line()
+	}
+	g.w("}")
+}
+
+// Statements
+
+// blockFunction returns s if it is a function declaration scoped to a block
+func blockFunction(s stmt) *funcDecl {
+	if fd, ok := s.(*funcDecl); ok && fd.id.binding.scope != fd.id.binding.scope.fn {
+		return fd
+	}
+	return nil
+}
+
+func (g *generator) stmts(body []stmt) {
+	for _, s := range body {
+		if fd := blockFunction(s); fd != nil {
+			g.line()
+			g.mark(fd)
+			g.w("var ", fd.id.binding.name, " = ")
+			g.function(fd.function, fd.id.name)
+			g.w(";")
+		}
+	}
+	for _, s := range body {
+		if blockFunction(s) == nil {
+			g.stmt(s)
+		}
+	}
+}
+
+func (g *generator) block(b *blockStmt) {
+	if len(b.body) == 0 {
+		g.w("{}")
+		return
+	}
+	g.w("{")
+	g.indent++
+	g.stmts(b.body)
+	g.indent--
+	g.line()
+	g.w("}")
+}
+
+// body generates the body of a compound statement as a block, starting with prefix
+func (g *generator) body(s stmt, prefix func()) {
+	g.w("{")
+	g.indent++
+	if prefix != nil {
+		prefix()
+	}
+	if b, ok := s.(*blockStmt); ok {
+		g.stmts(b.body)
+	} else {
+		g.stmts([]stmt{s})
+	}
+	g.indent--
+	g.line()
+	g.w("}")
+}
+
+func (g *generator) stmt(s stmt) {
+	switch s := s.(type) {
+	case *varDecl:
+		code := g.capture(func() { g.varDecl(s) })
+		if len(code.b) != 0 {
+			g.line()
+			g.buf.append(code)
+			g.w(";")
+		}
+	case *funcDecl:
+		g.line()
+		g.mark(s)
+		g.function(s.function, s.id.binding.name)
+	case *classDecl:
+		g.line()
+		g.mark(s)
+		g.w("var ", s.id.binding.name, " = ")
+		g.class(s.class, s.id.binding.name)
+		g.w(";")
+	case *exprStmt:
+		g.line()
+		g.mark(s)
+		g.exprStmt(s.x)
+	case *blockStmt:
+		g.line()
+		g.block(s)
+	case *emptyStmt:
+		g.line()
+		g.w(";")
+	case *ifStmt:
+		g.line()
+		g.mark(s)
+		g.ifStmt(s)
+	case *forStmt, *forInStmt, *whileStmt, *doWhileStmt:
+		g.loop(s, nil)
+	case *returnStmt:
+		g.line()
+		g.mark(s)
+		g.returnStmt(s)
+	case *branchStmt:
+		g.line()
+		g.mark(s)
+		g.w(g.branch(s.tok, s.label))
+	case *throwStmt:
+		g.line()
+		g.mark(s)
+		g.w("throw ")
+		g.expr(s.arg, precSeq)
+		g.w(";")
+	case *tryStmt:
+		g.line()
+		g.mark(s)
+		g.tryStmt(s)
+	case *switchStmt:
+		g.line()
+		g.mark(s)
+		g.switchStmt(s, nil)
+	case *labeledStmt:
+		g.labeledStmt(s)
+	case *debuggerStmt:
+		g.line()
+		g.w("debugger;")
+	case *withStmt:
+		raise(s.start(), "'with' in strict mode")
+	case *importDecl:
+		// imports are generated with the module header
+	case *exportDecl:
+		if s.decl != nil {
+			g.stmt(s.decl)
+		}
+	case *exportDefault:
+		g.exportDefault(s)
+	}
+}
+
+func (g *generator) exprStmt(x expr) {
+	code := g.exprCode(x, precSeq)
+	if s := code.String(); strings.HasPrefix(s, "function") || strings.HasPrefix(s, "{") || strings.HasPrefix(s, "class") {
+		code = cat("(", code, ")")
+	}
+	g.buf.append(code)
+	g.w(";")
+}
+
+func (g *generator) ifStmt(s *ifStmt) {
+	g.w("if (")
+	g.expr(s.test, precSeq)
+	g.w(") ")
+	g.body(s.cons, nil)
+	if s.alt == nil {
+		return
+	}
+	g.w(" else ")
+	if alt, ok := s.alt.(*ifStmt); ok {
+		g.ifStmt(alt)
+	} else {
+		g.body(s.alt, nil)
+	}
+}
+
+func (g *generator) returnStmt(s *returnStmt) {
+	switch {
+	case g.ctx.wrapper != nil:
+		g.ctx.wrapper.ret = true
+		g.w("return { v: ")
+		if s.arg != nil {
+			g.expr(s.arg, precAssign)
+		} else {
+			g.w("void 0")
+		}
+		g.w(" };")
+	case g.ctx.derived && s.arg != nil:
+		g.w("return ", g.helper("possibleConstructorReturn"), "(", g.ctx.thisName, ", ")
+		g.expr(s.arg, precAssign)
+		g.w(");")
+	case g.ctx.derived:
+		g.w("return ", g.ctx.thisName, ";")
+	case s.arg != nil:
+		g.w("return ")
+		g.expr(s.arg, precSeq)
+		g.w(";")
+	default:
+		g.w("return;")
+	}
+}
+
+// branch returns the statement that performs a break or continue, which leaves the function
+// wrapping a loop body with a completion code if it targets a statement outside of it
+func (g *generator) branch(tok, label string) string {
+	stmt := tok
+	if label != "" {
+		stmt += " " + label
+	}
+	stmt += ";"
+
+	c := g.ctx
+	for i := len(c.targets) - 1; i >= 0; i-- {
+		if c.targets[i].matches(tok, label) {
+			return stmt
+		}
+	}
+
+	w := c.wrapper
+	if w == nil {
+		return stmt
+	}
+	if w.target.matches(tok, label) {
+		if tok == "continue" {
+			return "return;"
+		}
+		w.brk = true
+		return `return "break";`
+	}
+	code := tok + "|" + label
+	w.addCode(code)
+	return "return " + strconv.Quote(code) + ";"
+}
+
+func (g *generator) pushTarget(t *target) {
+	g.ctx.targets = append(g.ctx.targets, t)
+}
+
+func (g *generator) popTarget() {
+	g.ctx.targets = g.ctx.targets[:len(g.ctx.targets)-1]
+}
+
+func (g *generator) labeledStmt(s *labeledStmt) {
+	labels := []string{s.label}
+	body := s.body
+	for {
+		inner, ok := body.(*labeledStmt)
+		if !ok {
+			break
+		}
+		labels = append(labels, inner.label)
+		body = inner.body
+	}
+
+	switch body := body.(type) {
+	case *forStmt, *forInStmt, *whileStmt, *doWhileStmt:
+		g.loop(body, labels)
+	case *switchStmt:
+		g.line()
+		g.writeLabels(labels)
+		g.switchStmt(body, labels)
+	default:
+		g.pushTarget(&target{labels: labels})
+		g.pendingLabels = strings.Join(labels, ": ") + ": "
+		g.stmt(body)
+		g.pendingLabels = ""
+		g.popTarget()
+	}
+}
+
+func (g *generator) writeLabels(labels []string) {
+	for _, label := range labels {
+		g.w(label, ": ")
+	}
+}
+
+func (g *generator) switchStmt(s *switchStmt, labels []string) {
+	g.w("switch (")
+	g.expr(s.disc, precSeq)
+	g.w(") {")
+	g.pushTarget(&target{labels: labels, swtch: true})
+	for _, c := range s.cases {
+		g.line()
+		g.mark(c)
+		if c.test != nil {
+			g.w("case ")
+			g.expr(c.test, precSeq)
+			g.w(":")
+		} else {
+			g.w("default:")
+		}
+		g.indent++
+		g.stmts(c.body)
+		g.indent--
+	}
+	g.popTarget()
+	g.line()
+	g.w("}")
+}
+
+func (g *generator) tryStmt(s *tryStmt) {
+	g.w("try ")
+	g.block(s.block)
+	if s.handler != nil {
+		var prefix func()
+		switch param := s.param.(type) {
+		case nil:
+			g.w(" catch (", g.names.fresh("_unused"), ") ")
+		case *ident:
+			g.w(" catch (", param.binding.name, ") ")
+		default:
+			name := g.names.fresh("_e")
+			g.w(" catch (", name, ") ")
+			prefix = func() {
+				g.line()
+				g.declarePattern(param, &buffer{b: []byte(name)}, true, false)
+				g.w(";")
+			}
+		}
+		g.body(s.handler, prefix)
+	}
+	if s.finalizer != nil {
+		g.w(" finally ")
+		g.block(s.finalizer)
+	}
+}
+
+// varDecl generates a declaration without its semicolon, as an assignment if it is a var declared
+// in a loop wrapper, whose names are declared in the function around the loop instead
+func (g *generator) varDecl(decl *varDecl) {
+	g.mark(decl)
+	if decl.kind == "var" && g.ctx.wrapper != nil {
+		vars := g.varCtx()
+		var assigns []*buffer
+		for _, d := range decl.decls {
+			for _, id := range boundNames(d.target) {
+				vars.addVar(id.binding.name, "")
+			}
+			if d.init != nil {
+				assigns = append(assigns, g.patternAssign(d.target, d.init, precAssign))
+			}
+		}
+		for i, a := range assigns {
+			if i != 0 {
+				g.w(", ")
+			}
+			g.buf.append(a)
+		}
+		return
+	}
+
+	var pairs []pair
+	for _, d := range decl.decls {
+		if id, ok := d.target.(*ident); ok {
+			p := pair{name: id.binding.name}
+			if d.init != nil {
+				p.value = g.exprCode(d.init, precAssign)
+			} else if decl.kind != "var" && g.ctx.loopDepth > 0 {
+				p.value = cat("void 0")
+			}
+			pairs = append(pairs, p)
+			continue
+		}
+		var value *buffer
+		if d.init != nil {
+			value = g.exprCode(d.init, precAssign)
+		} else {
+			value = cat("void 0")
+		}
+		pairs = g.destructure(d.target, value, isSimple(d.init), true, pairs)
+	}
+	g.w("var ")
+	g.writePairs(pairs)
+}
+
+// declarePattern declares the names bound by target to value
+func (g *generator) declarePattern(target pattern, value *buffer, simple, assign bool) {
+	if assign {
+		for _, id := range boundNames(target) {
+			g.varCtx().addVar(id.binding.name, "")
+		}
+		g.writeAssignPairs(g.destructure(target, value, simple, false, nil))
+		return
+	}
+	g.w("var ")
+	g.writePairs(g.destructure(target, value, simple, true, nil))
+}
+
+func (g *generator) writePairs(pairs []pair) {
+	for i, p := range pairs {
+		if i != 0 {
+			g.w(", ")
+		}
+		g.w(p.name)
+		if p.value != nil {
+			g.w(" = ")
+			g.buf.append(p.value)
+		}
+	}
+}
+
+func (g *generator) writeAssignPairs(pairs []pair) {
+	for i, p := range pairs {
+		if i != 0 {
+			g.w(", ")
+		}
+		if p.target != nil {
+			g.buf.append(p.target)
+		} else {
+			g.w(p.name)
+		}
+		g.w(" = ")
+		g.buf.append(p.value)
+	}
+}
+
+// Loops
+
+func loopOf(s stmt) *loopInfo {
+	switch s := s.(type) {
+	case *forStmt:
+		return s.loop
+	case *forInStmt:
+		return s.loop
+	case *whileStmt:
+		return s.loop
+	case *doWhileStmt:
+		return s.loop
+	}
+	return nil
+}
+
+func loopBody(s stmt) stmt {
+	switch s := s.(type) {
+	case *forStmt:
+		return s.body
+	case *forInStmt:
+		return s.body
+	case *whileStmt:
+		return s.body
+	case *doWhileStmt:
+		return s.body
+	}
+	return nil
+}
+
+// loop generates a loop, moving its body to a function called on each iteration if closures capture
+// the bindings of an iteration
+func (g *generator) loop(s stmt, labels []string) {
+	t := &target{labels: labels, loop: true}
+	g.pushTarget(t)
+	defer g.popTarget()
+
+	info := loopOf(s)
+	var w *loopWrapper
+	var params []string
+	if info.captured {
+		// the bindings of a for-in or for-of head are not carried over to the next iteration,
+		// so the body can reassign the copies the wrapper gets
+		if _, ok := s.(*forInStmt); info.reassigned && !ok {
+			raise(s.start(), "Loop variables captured by a closure cannot be reassigned in the loop body")
+		}
+		for _, b := range info.head {
+			params = append(params, b.name)
+		}
+		w = &loopWrapper{name: g.names.fresh("_loop"), target: t}
+		ctx := &funcCtx{parent: g.ctx, arrow: true, wrapper: w}
+		g.line()
+		g.w("var ", w.name, " = function ", w.name, "(", strings.Join(params, ", "), ") ")
+		g.functionBody(ctx, func() {
+			if b, ok := loopBody(s).(*blockStmt); ok {
+				g.stmts(b.body)
+			} else {
+				g.stmts([]stmt{loopBody(s)})
+			}
+		})
+		g.w(";")
+	}
+
+	g.line()
+	g.mark(s)
+	g.writeLabels(labels)
+
+	body := func(prefix func()) {
+		g.ctx.loopDepth++
+		defer func() { g.ctx.loopDepth-- }()
+		if w == nil {
+			g.body(loopBody(s), prefix)
+			return
+		}
+		g.w("{")
+		g.indent++
+		if prefix != nil {
+			prefix()
+		}
+		g.loopCall(w, params)
+		g.indent--
+		g.line()
+		g.w("}")
+	}
+
+	switch s := s.(type) {
+	case *forStmt:
+		g.w("for (")
+		switch init := s.init.(type) {
+		case nil:
+		case *varDecl:
+			g.varDecl(init)
+		default:
+			g.expr(init, precSeq)
+		}
+		g.w(";")
+		if s.test != nil {
+			g.w(" ")
+			g.expr(s.test, precSeq)
+		}
+		g.w(";")
+		if s.update != nil {
+			g.w(" ")
+			g.expr(s.update, precSeq)
+		}
+		g.w(") ")
+		body(nil)
+	case *forInStmt:
+		g.forIn(s, body)
+	case *whileStmt:
+		g.w("while (")
+		g.expr(s.test, precSeq)
+		g.w(") ")
+		body(nil)
+	case *doWhileStmt:
+		g.w("do ")
+		body(nil)
+		g.w(" while (")
+		g.expr(s.test, precSeq)
+		g.w(");")
+	}
+}
+
+// loopCall calls the function wrapping a loop body and completes the iteration as the body did
+func (g *generator) loopCall(w *loopWrapper, params []string) {
+	call := w.name + "(" + strings.Join(params, ", ") + ")"
+	g.line()
+	if !w.brk && !w.ret && len(w.codes) == 0 {
+		g.w(call, ";")
+		return
+	}
+
+	ret := g.names.fresh("_ret")
+	g.w("var ", ret, " = ", call, ";")
+	if w.brk {
+		g.line()
+		g.w("if (", ret, ` === "break") break;`)
+	}
+	for _, code := range w.codes {
+		parts := strings.SplitN(code, "|", 2)
+		g.line()
+		g.w("if (", ret, " === ", strconv.Quote(code), ") ", g.branch(parts[0], parts[1]))
+	}
+	if w.ret {
+		g.line()
+		if outer := g.ctx.wrapper; outer != nil {
+			outer.ret = true
+			g.w("if (typeof ", ret, ` === "object") return `, ret, ";")
+		} else {
+			g.w("if (typeof ", ret, ` === "object") return `, ret, ".v;")
+		}
+	}
+}
+
+func (g *generator) forIn(s *forInStmt, body func(func())) {
+	if s.await {
+		raise(s.start(), "Async functions are not supported")
+	}
+
+	// bind assigns the value of an iteration to the left side of the loop
+	var bind func(value string)
+	var simpleLeft string
+	switch left := s.left.(type) {
+	case *varDecl:
+		target := left.decls[0].target
+		assign := left.kind == "var" && g.ctx.wrapper != nil
+		if id, ok := target.(*ident); ok && !s.of {
+			if assign {
+				g.varCtx().addVar(id.binding.name, "")
+				simpleLeft = id.binding.name
+			} else {
+				simpleLeft = "var " + id.binding.name
+			}
+			break
+		}
+		bind = func(value string) {
+			g.line()
+			g.declarePattern(target, cat(value), true, assign)
+			g.w(";")
+		}
+	default:
+		if id, ok := left.(*ident); ok && !s.of {
+			simpleLeft = id.binding.name
+			break
+		}
+		if m, ok := left.(*member); ok && !s.of {
+			simpleLeft = g.exprCode(m, precCall).String()
+			break
+		}
+		bind = func(value string) {
+			g.line()
+			g.buf.append(g.assignTo(left.(pattern), cat(value), true, precSeq))
+			g.w(";")
+		}
+	}
+
+	if !s.of {
+		if simpleLeft == "" {
+			simpleLeft = "var " + g.names.fresh("_key")
+		}
+		g.w("for (", simpleLeft, " in ")
+		g.expr(s.right, precSeq)
+		g.w(") ")
+		if bind == nil {
+			body(nil)
+		} else {
+			key := strings.TrimPrefix(simpleLeft, "var ")
+			body(func() { bind(key) })
+		}
+		return
+	}
+
+	iterator := g.names.fresh("_iterator")
+	step := g.names.fresh("_step")
+	g.w("for (var ", iterator, " = ", g.helper("iterate"), "(")
+	g.expr(s.right, precAssign)
+	g.w("), ", step, "; !(", step, " = ", iterator, "()).done;) ")
+	body(func() { bind(step + ".value") })
+}
+
+// Functions
+
+func (g *generator) checkFunction(fn *function) {
+	if fn.async {
+		raise(fn.start(), "Async functions are not supported")
+	}
+	if fn.generator {
+		raise(fn.start(), "Generators are not supported")
+	}
+}
+
+// function generates fn as a function declaration or expression named name
+func (g *generator) function(fn *function, name string) {
+	g.checkFunction(fn)
+	ctx := &funcCtx{parent: g.ctx, arrow: fn.arrow}
+	g.functionWith(ctx, fn, "function "+name, nil, nil)
+}
+
+// functionWith generates fn in ctx starting with prefix, which is function and its name or the kind and key of
+// an accessor, with head and tail generated before and after its body
+func (g *generator) functionWith(ctx *funcCtx, fn *function, prefix string, head, tail func()) {
+	g.mark(fn)
+	g.w(prefix)
+
+	var formals []string
+	var lower []func()
+	defaults := false
+	for i, param := range fn.params {
+		i, param := i, param
+		if _, ok := param.(*assignPattern); ok {
+			defaults = true
+		}
+		if defaults {
+			lower = append(lower, func() {
+				arg := "arguments[" + strconv.Itoa(i) + "]"
+				value := arg
+				target := param
+				if ap, ok := param.(*assignPattern); ok {
+					target = ap.target
+					value = "arguments.length > " + strconv.Itoa(i) + " && " + arg + " !== undefined ? " + arg + " : "
+					g.line()
+					g.w("var ")
+					def := g.exprCode(ap.def, precAssign)
+					if id, ok := target.(*ident); ok {
+						g.writePairs([]pair{{name: id.binding.name, value: cat(value, def)}})
+						g.w(";")
+						return
+					}
+					g.writePairs(g.destructure(target, cat(value, def), false, true, nil))
+					g.w(";")
+					return
+				}
+				g.line()
+				g.declarePattern(target, cat(value), true, false)
+				g.w(";")
+			})
+			continue
+		}
+		if id, ok := param.(*ident); ok {
+			formals = append(formals, id.binding.name)
+			continue
+		}
+		ref := g.names.fresh("_ref")
+		formals = append(formals, ref)
+		lower = append(lower, func() {
+			g.line()
+			g.declarePattern(param, cat(ref), true, false)
+			g.w(";")
+		})
+	}
+	if fn.rest != nil {
+		n := strconv.Itoa(len(fn.params))
+		lower = append(lower, func() {
+			length, key := g.names.fresh("_len"), g.names.fresh("_key")
+			rest := ""
+			if id, ok := fn.rest.(*ident); ok {
+				rest = id.binding.name
+			} else {
+				rest = g.names.fresh("_rest")
+			}
+			g.line()
+			g.w("for (var ", length, " = arguments.length, ", rest, " = Array(", length, " > ", n, " ? ", length, " - ", n, " : 0), ",
+				key, " = ", n, "; ", key, " < ", length, "; ", key, "++) {")
+			g.indent++
+			g.line()
+			g.w(rest, "[", key, " - ", n, "] = arguments[", key, "];")
+			g.indent--
+			g.line()
+			g.w("}")
+			if _, ok := fn.rest.(*ident); !ok {
+				g.line()
+				g.declarePattern(fn.rest, cat(rest), true, false)
+				g.w(";")
+			}
+		})
+	}
+
+	g.w("(", strings.Join(formals, ", "), ") ")
+	g.functionBody(ctx, func() {
+		if head != nil {
+			head()
+		}
+		for _, f := range lower {
+			f()
+		}
+		if fn.exprBody != nil {
+			g.line()
+			g.mark(fn.exprBody)
+			g.w("return ")
+			g.expr(fn.exprBody, precSeq)
+			g.w(";")
+		} else if fn.body != nil {
+			g.stmts(fn.body.body)
+		}
+		if tail != nil {
+			tail()
+		}
+	})
+}
+
+// methodName returns the name that the function of a method can have without hiding a binding it uses
+func methodName(key expr, computed bool, fn *function) string {
+	if computed {
+		return ""
+	}
+	name := propertyName(key)
+	if !isIdentifierName(name) || reservedWords[name] || name == "arguments" || name == "eval" || fn.scope.free[name] {
+		return ""
+	}
+	return name
+}
+
+func isIdentifierName(name string) bool {
+	if name == "" {
+		return false
+	}
+	for i, r := range name {
+		if r > 0x7f {
+			return false
+		}
+		if !(r == '_' || r == '$' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') || (i > 0 && r >= '0' && r <= '9')) {
+			return false
+		}
+	}
+	return true
+}
+
+// Classes
+
+// class generates c as a call of a function that creates the class name
+func (g *generator) class(c *class, name string) {
+	for _, m := range c.members {
+		if m.private {
+			raise(m.start(), "Private class members are not supported")
+		}
+		if m.kind != memberField && m.kind != memberStaticBlock {
+			g.checkFunction(m.value)
+		}
+	}
+
+	home := &classHome{name: name, fieldKeys: map[*classMember]string{}}
+	if c.superClass != nil {
+		home.superName = g.names.fresh("_super")
+	}
+	var ctor *classMember
+	for _, m := range c.members {
+		switch {
+		case m.kind == memberConstructor:
+			ctor = m
+		case m.kind == memberField && !m.static:
+			home.fields = append(home.fields, m)
+		}
+	}
+
+	ctx := &funcCtx{parent: g.ctx, arrow: true}
+	g.mark(c)
+	g.w("function (", home.superName, ") ")
+	g.functionBody(ctx, func() {
+		if home.superName != "" {
+			g.line()
+			g.w(g.helper("inherits"), "(", name, ", ", home.superName, ");")
+		}
+		for _, m := range c.members {
+			if m.kind == memberField && m.computed && !m.static {
+				key := g.temp("_key")
+				home.fieldKeys[m] = key
+				g.line()
+				g.w(key, " = ")
+				g.expr(m.key, precAssign)
+				g.w(";")
+			}
+		}
+
+		g.line()
+		g.constructor(c, ctor, home)
+
+		protos, statics := g.descriptors(c)
+		if len(protos) != 0 || len(statics) != 0 {
+			g.line()
+			g.w(g.helper("createClass"), "(", name)
+			g.descriptorList(protos, home)
+			if len(statics) != 0 {
+				g.descriptorList(statics, home)
+			}
+			g.w(");")
+		}
+
+		for _, m := range c.members {
+			switch {
+			case m.kind == memberField && m.static:
+				g.line()
+				g.mark(m)
+				g.w(g.helper("defineProperty"), "(", name, ", ")
+				g.withThis(name, func() {
+					g.propertyKey(m.key, m.computed)
+					g.w(", ")
+					if m.value.exprBody != nil {
+						g.expr(m.value.exprBody, precAssign)
+					} else {
+						g.w("void 0")
+					}
+				})
+				g.w(");")
+			case m.kind == memberStaticBlock:
+				g.line()
+				g.mark(m)
+				g.w("(")
+				g.functionWith(&funcCtx{parent: g.ctx, home: &classHome{name: name, superName: home.superName, static: true}},
+					m.value, "function ", nil, nil)
+				g.w(").call(", name, ");")
+			}
+		}
+
+		g.line()
+		g.w("return ", name, ";")
+	})
+	g.w("(")
+	if c.superClass != nil {
+		g.expr(c.superClass, precAssign)
+	}
+	g.w(")")
+}
+
+// withThis generates what f generates with this replaced by name
+func (g *generator) withThis(name string, f func()) {
+	saved := g.ctx.thisOverride
+	g.ctx.thisOverride = name
+	f()
+	g.ctx.thisOverride = saved
+}
+
+func (g *generator) constructor(c *class, ctor *classMember, home *classHome) {
+	ctx := &funcCtx{parent: g.ctx, home: home, derived: home.superName != ""}
+	if ctx.derived {
+		ctx.thisName = g.names.fresh("_this")
+		ctx.addVar(ctx.thisName, "")
+	}
+
+	head := func() {
+		g.line()
+		g.w(g.helper("classCallCheck"), "(this, ", home.name, ");")
+		if !ctx.derived {
+			for _, m := range home.fields {
+				g.line()
+				g.initField(m, "this")
+				g.w(";")
+			}
+		}
+	}
+
+	if ctor == nil {
+		fn := &function{pos: c.pos, method: true}
+		var tail func()
+		if ctx.derived {
+			tail = func() {
+				g.line()
+				g.w("return ")
+				g.superCall(c, cat(home.superName, ".apply(this, arguments)"), precSeq)
+				g.w(";")
+			}
+		}
+		g.functionWith(ctx, fn, "function "+home.name, head, tail)
+		return
+	}
+
+	tail := func() {
+		if !ctx.derived {
+			return
+		}
+		body := ctor.value.body.body
+		if len(body) != 0 {
+			if _, ok := body[len(body)-1].(*returnStmt); ok {
+				return
+			}
+		}
+		g.line()
+		g.w("return ", ctx.thisName, ";")
+	}
+	g.functionWith(ctx, ctor.value, "function "+home.name, head, tail)
+}
+
+// initField generates the initialization of an instance field of the object named this
+func (g *generator) initField(m *classMember, this string) {
+	g.mark(m)
+	g.w(g.helper("defineProperty"), "(", this, ", ")
+	if key, ok := g.ctx.home.fieldKeys[m]; ok && m.computed {
+		g.w(key)
+	} else {
+		g.propertyKey(m.key, false)
+	}
+	g.w(", ")
+	if m.value.exprBody != nil {
+		g.expr(m.value.exprBody, precAssign)
+	} else {
+		g.w("void 0")
+	}
+	g.w(")")
+}
+
+// superCall generates the call of the super constructor of a derived constructor, after which the fields of
+// the class are initialized
+func (g *generator) superCall(n node, call *buffer, prec int) {
+	ctx := g.ctx
+	home := ctx.home
+	if !ctx.derived {
+		raise(n.start(), "'super' call outside of a derived constructor is not supported")
+	}
+	assign := cat(ctx.thisName, " = ", g.helper("possibleConstructorReturn"), "(this, ", call, ")")
+	if len(home.fields) == 0 {
+		g.wrap(precAssign, prec, func() { g.buf.append(assign) })
+		return
+	}
+	g.wrap(precSeq, prec, func() {
+		g.buf.append(assign)
+		for _, m := range home.fields {
+			g.w(", ")
+			g.initField(m, ctx.thisName)
+		}
+		g.w(", ", ctx.thisName)
+	})
+}
+
+type descriptor struct {
+	key      expr
+	computed bool
+	value    *classMember
+	get, set *classMember
+}
+
+func (g *generator) descriptors(c *class) ([]*descriptor, []*descriptor) {
+	var protos, statics []*descriptor
+	for _, m := range c.members {
+		if m.kind == memberConstructor || m.kind == memberField || m.kind == memberStaticBlock {
+			continue
+		}
+		list := &protos
+		if m.static {
+			list = &statics
+		}
+
+		var d *descriptor
+		if (m.kind == memberGet || m.kind == memberSet) && !m.computed {
+			for _, other := range *list {
+				if !other.computed && other.value == nil && propertyName(other.key) == propertyName(m.key) {
+					d = other
+				}
+			}
+		}
+		if d == nil {
+			d = &descriptor{key: m.key, computed: m.computed}
+			*list = append(*list, d)
+		}
+		switch m.kind {
+		case memberGet:
+			d.get = m
+		case memberSet:
+			d.set = m
+		default:
+			d.value = m
+			d.get, d.set = nil, nil
+		}
+	}
+	return protos, statics
+}
+
+func (g *generator) descriptorList(list []*descriptor, home *classHome) {
+	if len(list) == 0 {
+		g.w(", null")
+		return
+	}
+	g.w(", [")
+	for i, d := range list {
+		if i != 0 {
+			g.w(", ")
+		}
+		g.w("{")
+		g.indent++
+		g.line()
+		g.w("key: ")
+		if d.computed {
+			g.expr(d.key, precAssign)
+		} else {
+			g.propertyKey(d.key, false)
+		}
+		for _, part := range []struct {
+			name string
+			m    *classMember
+		}{{"value", d.value}, {"get", d.get}, {"set", d.set}} {
+			if part.m == nil {
+				continue
+			}
+			g.w(",")
+			g.line()
+			g.w(part.name, ": ")
+			name := methodName(part.m.key, part.m.computed, part.m.value)
+			if part.name != "value" {
+				name = methodName(&ident{name: part.name}, false, part.m.value)
+			}
+			ctx := &funcCtx{parent: g.ctx, home: &classHome{name: home.name, superName: home.superName, static: part.m.static}}
+			g.functionWith(ctx, part.m.value, "function "+name, nil, nil)
+		}
+		g.indent--
+		g.line()
+		g.w("}")
+	}
+	g.w("]")
+}
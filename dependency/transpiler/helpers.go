@@ -0,0 +1,194 @@
+package transpiler
+
+import (
+	"regexp"
+	"strings"
+)
+
+// helperSources holds the ES5 functions that generated code relies on, which refer to each other by their
+// default names
+var helperSources = map[string]string{
+	"classCallCheck": `function _classCallCheck(instance, Constructor) {
+  if (!(instance instanceof Constructor)) {
+    throw new TypeError("Cannot call a class as a function");
+  }
+}`,
+	"createClass": `var _createClass = function () {
+  function defineProperties(target, props) {
+    for (var i = 0; i < props.length; i++) {
+      var descriptor = props[i];
+      descriptor.enumerable = descriptor.enumerable || false;
+      descriptor.configurable = true;
+      if ("value" in descriptor) descriptor.writable = true;
+      Object.defineProperty(target, descriptor.key, descriptor);
+    }
+  }
+  return function (Constructor, protoProps, staticProps) {
+    if (protoProps) defineProperties(Constructor.prototype, protoProps);
+    if (staticProps) defineProperties(Constructor, staticProps);
+    return Constructor;
+  };
+}();`,
+	"inherits": `function _inherits(subClass, superClass) {
+  if (typeof superClass !== "function" && superClass !== null) {
+    throw new TypeError("Super expression must either be null or a function, not " + typeof superClass);
+  }
+  subClass.prototype = Object.create(superClass && superClass.prototype, {
+    constructor: { value: subClass, enumerable: false, writable: true, configurable: true }
+  });
+  if (superClass) Object.setPrototypeOf ? Object.setPrototypeOf(subClass, superClass) : subClass.__proto__ = superClass;
+}`,
+	"possibleConstructorReturn": `function _possibleConstructorReturn(self, call) {
+  if (!self) {
+    throw new ReferenceError("this hasn't been initialised - super() hasn't been called");
+  }
+  return call && (typeof call === "object" || typeof call === "function") ? call : self;
+}`,
+	"get": `var _get = function get(object, property, receiver) {
+  if (object === null) object = Function.prototype;
+  var desc = Object.getOwnPropertyDescriptor(object, property);
+  if (desc === undefined) {
+    var parent = Object.getPrototypeOf(object);
+    if (parent === null) {
+      return undefined;
+    }
+    return get(parent, property, receiver);
+  }
+  if ("value" in desc) {
+    return desc.value;
+  }
+  var getter = desc.get;
+  if (getter === undefined) {
+    return undefined;
+  }
+  return getter.call(receiver);
+};`,
+	"defineProperty": `function _defineProperty(obj, key, value) {
+  if (key in obj) {
+    Object.defineProperty(obj, key, { value: value, enumerable: true, configurable: true, writable: true });
+  } else {
+    obj[key] = value;
+  }
+  return obj;
+}`,
+	"defineAccessor": `function _defineAccessor(type, obj, key, fn) {
+  var desc = { configurable: true, enumerable: true };
+  desc[type] = fn;
+  return Object.defineProperty(obj, key, desc);
+}`,
+	"extends": `var _extends = Object.assign || function (target) {
+  for (var i = 1; i < arguments.length; i++) {
+    var source = arguments[i];
+    for (var key in source) {
+      if (Object.prototype.hasOwnProperty.call(source, key)) {
+        target[key] = source[key];
+      }
+    }
+  }
+  return target;
+};`,
+	"objectWithoutProperties": `function _objectWithoutProperties(obj, keys) {
+  var target = {};
+  for (var i in obj) {
+    if (keys.indexOf(i) >= 0) continue;
+    if (!Object.prototype.hasOwnProperty.call(obj, i)) continue;
+    target[i] = obj[i];
+  }
+  return target;
+}`,
+	"iterate": `function _iterate(obj) {
+  if (typeof Symbol === "function" && obj != null && typeof obj[Symbol.iterator] === "function") {
+    var iterator = obj[Symbol.iterator]();
+    return function () {
+      return iterator.next();
+    };
+  }
+  if (obj == null || typeof obj !== "string" && typeof obj.length !== "number") {
+    throw new TypeError(obj + " is not iterable");
+  }
+  var i = 0;
+  return function () {
+    if (i >= obj.length) {
+      return { done: true, value: undefined };
+    }
+    var value = obj[i++];
+    if (typeof obj === "string" && /[\ud800-\udbff]/.test(value) && /[\udc00-\udfff]/.test(obj.charAt(i))) {
+      value += obj[i++];
+    }
+    return { done: false, value: value };
+  };
+}`,
+	"slicedToArray": `function _slicedToArray(arr, n) {
+  if (Array.isArray(arr)) {
+    return n === undefined ? arr.slice() : arr;
+  }
+  var next = _iterate(arr);
+  var result = [];
+  for (var step; (n === undefined || result.length < n) && !(step = next()).done;) {
+    result.push(step.value);
+  }
+  return result;
+}`,
+	"toConsumableArray": `function _toConsumableArray(arr) {
+  return _slicedToArray(arr);
+}`,
+	"taggedTemplateLiteral": `function _taggedTemplateLiteral(strings, raw) {
+  return Object.freeze(Object.defineProperties(strings, { raw: { value: Object.freeze(raw) } }));
+}`,
+	"interopRequireDefault": `function _interopRequireDefault(obj) {
+  return obj && obj.__esModule ? obj : { default: obj };
+}`,
+	"interopRequireWildcard": `function _interopRequireWildcard(obj) {
+  if (obj && obj.__esModule) {
+    return obj;
+  }
+  var newObj = {};
+  if (obj != null) {
+    for (var key in obj) {
+      if (Object.prototype.hasOwnProperty.call(obj, key)) newObj[key] = obj[key];
+    }
+  }
+  newObj.default = obj;
+  return newObj;
+}`,
+}
+
+// helperDependencies lists the helpers that each helper calls
+var helperDependencies = map[string][]string{
+	"slicedToArray":     {"iterate"},
+	"toConsumableArray": {"slicedToArray"},
+}
+
+var helperRef = regexp.MustCompile(`\b_[A-Za-z]+\b`)
+
+// helper returns the name of a helper function, which is declared at the top of the program
+func (g *generator) helper(name string) string {
+	if n, ok := g.helperNames[name]; ok {
+		return n
+	}
+	for _, dep := range helperDependencies[name] {
+		g.helper(dep)
+	}
+	n := g.names.fresh(name)
+	g.helperNames[name] = n
+	g.helpers = append(g.helpers, name)
+	return n
+}
+
+// helperCode returns the declarations of the helpers that the program uses
+func (g *generator) helperCode() string {
+	var sb strings.Builder
+	for _, name := range g.helpers {
+		if sb.Len() != 0 {
+			sb.WriteString("\n")
+		}
+		sb.WriteString("\n")
+		sb.WriteString(helperRef.ReplaceAllStringFunc(helperSources[name], func(ref string) string {
+			if n, ok := g.helperNames[ref[1:]]; ok {
+				return n
+			}
+			return ref
+		}))
+	}
+	return sb.String()
+}
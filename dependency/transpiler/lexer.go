@@ -0,0 +1,559 @@
+package transpiler
+
+import (
+	"fmt"
+	"math/big"
+	"strconv"
+	"strings"
+	"unicode"
+	"unicode/utf16"
+	"unicode/utf8"
+)
+
+type tokenType int
+
+const (
+	tokEOF tokenType = iota
+	tokIdent
+	tokPunct
+	tokNumber
+	tokString
+	tokTemplate
+	tokRegexp
+	tokPrivateName
+)
+
+// token is a lexical token of the source, where value is the name of an identifier with its escapes decoded,
+// a punctuator, the ES5 spelling of a number or the raw text of a string, template or regular expression
+type token struct {
+	typ           tokenType
+	value         string
+	start, end    int
+	newlineBefore bool
+
+	// escaped is set for an identifier spelled with a unicode escape, which is never a keyword
+	escaped bool
+
+	// the parts of a template chunk, which is the text between its delimiters
+	cooked        string
+	raw           string
+	tail          bool
+	invalidEscape bool
+
+	// the parts of a regular expression
+	pattern, flags string
+}
+
+// syntaxError is raised by the lexer and the parser, and by the generator for syntax it cannot transpile
+type syntaxError struct {
+	pos int
+	msg string
+}
+
+func (se *syntaxError) Error() string {
+	return se.msg
+}
+
+func raise(pos int, format string, args ...interface{}) {
+	panic(&syntaxError{pos, fmt.Sprintf(format, args...)})
+}
+
+type lexer struct {
+	src string
+	pos int
+
+	// names holds every identifier name in the source, so that generated names can avoid them
+	names map[string]bool
+}
+
+func newLexer(src string) *lexer {
+	l := &lexer{src: src, names: map[string]bool{}}
+	if strings.HasPrefix(src, "#!") {
+		for l.pos < len(src) && !isLineTerminatorAt(src, l.pos) {
+			l.pos++
+		}
+	}
+	return l
+}
+
+func isLineTerminator(r rune) bool {
+	return r == '\n' || r == '\r' || r == '\u2028' || r == '\u2029'
+}
+
+func isLineTerminatorAt(src string, pos int) bool {
+	r, _ := utf8.DecodeRuneInString(src[pos:])
+	return isLineTerminator(r)
+}
+
+func isIdentStart(r rune) bool {
+	return r == '$' || r == '_' || (r >= 'a' && r <= 'z') || (r >= 'A' && r <= 'Z') ||
+		(r >= utf8.RuneSelf && (unicode.IsLetter(r) || unicode.Is(unicode.Nl, r) || unicode.Is(unicode.Other_ID_Start, r)))
+}
+
+func isIdentPart(r rune) bool {
+	return isIdentStart(r) || (r >= '0' && r <= '9') || r == '\u200c' || r == '\u200d' ||
+		(r >= utf8.RuneSelf && (unicode.In(r, unicode.Mn, unicode.Mc, unicode.Nd, unicode.Pc) || unicode.Is(unicode.Other_ID_Continue, r)))
+}
+
+// skipSpace skips whitespace and comments, reporting whether a line terminator was among them
+func (l *lexer) skipSpace() bool {
+	newline := false
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\v' || c == '\f':
+			l.pos++
+		case c == '\n' || c == '\r':
+			newline = true
+			l.pos++
+		case c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '/':
+			for l.pos < len(l.src) && !isLineTerminatorAt(l.src, l.pos) {
+				l.pos++
+			}
+		case c == '/' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '*':
+			end := strings.Index(l.src[l.pos+2:], "*/")
+			if end < 0 {
+				raise(l.pos, "Unterminated comment")
+			}
+			comment := l.src[l.pos+2 : l.pos+2+end]
+			if strings.ContainsAny(comment, "\n\r\u2028\u2029") {
+				newline = true
+			}
+			l.pos += end + 4
+		case c >= utf8.RuneSelf:
+			r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			if isLineTerminator(r) {
+				newline = true
+			} else if r != '\u00a0' && r != '\ufeff' && !unicode.Is(unicode.Zs, r) {
+				return newline
+			}
+			l.pos += size
+		default:
+			return newline
+		}
+	}
+	return newline
+}
+
+// next scans the token at the current position, treating a '/' as a division operator
+func (l *lexer) next() token {
+	newline := l.skipSpace()
+	tok := l.scan()
+	tok.newlineBefore = newline
+	return tok
+}
+
+func (l *lexer) scan() token {
+	start := l.pos
+	if l.pos >= len(l.src) {
+		return token{typ: tokEOF, start: start, end: start}
+	}
+
+	c := l.src[l.pos]
+	switch {
+	case c == '"' || c == '\'':
+		return l.scanString(c)
+	case c == '`':
+		l.pos++
+		return l.scanTemplate(start)
+	case c >= '0' && c <= '9', c == '.' && l.pos+1 < len(l.src) && l.src[l.pos+1] >= '0' && l.src[l.pos+1] <= '9':
+		return l.scanNumber()
+	case c == '#':
+		l.pos++
+		name, _ := l.scanIdentName()
+		if name == "" {
+			raise(start, "Unexpected character '#'")
+		}
+		return token{typ: tokPrivateName, value: name, start: start, end: l.pos}
+	}
+
+	r, _ := utf8.DecodeRuneInString(l.src[l.pos:])
+	if isIdentStart(r) || r == '\\' {
+		name, escaped := l.scanIdentName()
+		l.names[name] = true
+		return token{typ: tokIdent, value: name, escaped: escaped, start: start, end: l.pos}
+	}
+
+	if punct := l.scanPunct(); punct != "" {
+		return token{typ: tokPunct, value: punct, start: start, end: l.pos}
+	}
+
+	raise(start, "Unexpected character '%c'", r)
+	return token{}
+}
+
+func (l *lexer) scanIdentName() (string, bool) {
+	var sb strings.Builder
+	escaped := false
+	for l.pos < len(l.src) {
+		r, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		if r == '\\' {
+			if !strings.HasPrefix(l.src[l.pos:], "\\u") {
+				raise(l.pos, "Expecting Unicode escape sequence \\uXXXX")
+			}
+			escapeStart := l.pos
+			l.pos += 2
+			r = l.scanUnicodeEscape(escapeStart)
+			if !isIdentPart(r) || (sb.Len() == 0 && !isIdentStart(r)) {
+				raise(escapeStart, "Invalid Unicode escape")
+			}
+			escaped = true
+			sb.WriteRune(r)
+			continue
+		}
+		if !isIdentPart(r) {
+			break
+		}
+		sb.WriteRune(r)
+		l.pos += size
+	}
+	return sb.String(), escaped
+}
+
+// scanUnicodeEscape scans the part of a \u escape after the 'u', either XXXX or {X...}
+func (l *lexer) scanUnicodeEscape(start int) rune {
+	if l.pos < len(l.src) && l.src[l.pos] == '{' {
+		end := strings.IndexByte(l.src[l.pos:], '}')
+		if end < 0 {
+			raise(start, "Bad character escape sequence")
+		}
+		code, err := strconv.ParseUint(l.src[l.pos+1:l.pos+end], 16, 32)
+		if err != nil || code > unicode.MaxRune {
+			raise(start, "Bad character escape sequence")
+		}
+		l.pos += end + 1
+		return rune(code)
+	}
+
+	if l.pos+4 > len(l.src) {
+		raise(start, "Bad character escape sequence")
+	}
+	code, err := strconv.ParseUint(l.src[l.pos:l.pos+4], 16, 32)
+	if err != nil {
+		raise(start, "Bad character escape sequence")
+	}
+	l.pos += 4
+	return rune(code)
+}
+
+var puncts = []string{
+	">>>=", "...", "===", "!==", "**=", "<<=", ">>=", ">>>", "&&=", "||=", "??=",
+	"=>", "==", "!=", "<=", ">=", "&&", "||", "??", "?.", "++", "--", "+=", "-=", "*=", "/=", "%=", "&=", "|=", "^=", "<<", ">>", "**",
+	"{", "}", "(", ")", "[", "]", ";", ",", "<", ">", "+", "-", "*", "/", "%", "&", "|", "^", "!", "~", "?", ":", "=", ".", "@",
+}
+
+func (l *lexer) scanPunct() string {
+	rest := l.src[l.pos:]
+	for _, punct := range puncts {
+		if strings.HasPrefix(rest, punct) {
+			// a?.5:0 is a conditional rather than an optional chain
+			if punct == "?." && len(rest) > 2 && rest[2] >= '0' && rest[2] <= '9' {
+				continue
+			}
+			l.pos += len(punct)
+			return punct
+		}
+	}
+	return ""
+}
+
+func (l *lexer) scanString(quote byte) token {
+	start := l.pos
+	l.pos++
+	for {
+		if l.pos >= len(l.src) {
+			raise(start, "Unterminated string constant")
+		}
+		c := l.src[l.pos]
+		if c == quote {
+			l.pos++
+			break
+		}
+		if c == '\n' || c == '\r' {
+			raise(start, "Unterminated string constant")
+		}
+		if c == '\\' {
+			l.pos++
+			if l.pos < len(l.src) {
+				_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+				l.pos += size
+			}
+			continue
+		}
+		l.pos++
+	}
+
+	raw := l.src[start:l.pos]
+	if i := octalEscape(raw); i >= 0 {
+		raise(start+i, "Octal literal in strict mode")
+	}
+	cooked, ok := cookString(raw[1 : len(raw)-1])
+	if !ok {
+		raise(start, "Bad character escape sequence")
+	}
+	return token{typ: tokString, value: raw, cooked: cooked, start: start, end: l.pos}
+}
+
+// octalEscape returns the offset of the first legacy octal escape of a string literal, or -1
+func octalEscape(raw string) int {
+	for i := 0; i+1 < len(raw); i++ {
+		if raw[i] != '\\' {
+			continue
+		}
+		e := raw[i+1]
+		if e >= '1' && e <= '7' || e == '0' && i+2 < len(raw) && raw[i+2] >= '0' && raw[i+2] <= '9' {
+			return i
+		}
+		i++
+	}
+	return -1
+}
+
+// cookString returns the value of the body of a string literal or template chunk, with lone surrogates
+// replaced, reporting whether its escapes are valid
+func cookString(body string) (string, bool) {
+	if !strings.ContainsRune(body, '\\') {
+		return strings.Replace(strings.Replace(body, "\r\n", "\n", -1), "\r", "\n", -1), true
+	}
+
+	var sb strings.Builder
+	for i := 0; i < len(body); {
+		c := body[i]
+		if c == '\r' {
+			sb.WriteByte('\n')
+			i++
+			if i < len(body) && body[i] == '\n' {
+				i++
+			}
+			continue
+		}
+		if c != '\\' {
+			sb.WriteByte(c)
+			i++
+			continue
+		}
+
+		i++
+		if i >= len(body) {
+			return "", false
+		}
+		e := body[i]
+		i++
+		switch e {
+		case 'n':
+			sb.WriteByte('\n')
+		case 't':
+			sb.WriteByte('\t')
+		case 'r':
+			sb.WriteByte('\r')
+		case 'b':
+			sb.WriteByte('\b')
+		case 'f':
+			sb.WriteByte('\f')
+		case 'v':
+			sb.WriteByte('\v')
+		case '\r':
+			if i < len(body) && body[i] == '\n' {
+				i++
+			}
+		case '\n':
+		case 'x':
+			if i+2 > len(body) {
+				return "", false
+			}
+			code, err := strconv.ParseUint(body[i:i+2], 16, 8)
+			if err != nil {
+				return "", false
+			}
+			sb.WriteRune(rune(code))
+			i += 2
+		case 'u':
+			l := &lexer{src: body, pos: i}
+			var r rune
+			ok := func() (ok bool) {
+				defer func() {
+					if recover() != nil {
+						ok = false
+					}
+				}()
+				r = l.scanUnicodeEscape(i)
+				return true
+			}()
+			if !ok {
+				return "", false
+			}
+			i = l.pos
+			if utf16.IsSurrogate(r) {
+				writeSurrogate(&sb, r, body, &i)
+				continue
+			}
+			sb.WriteRune(r)
+		default:
+			if e >= '0' && e <= '7' {
+				if e != '0' || i < len(body) && body[i] >= '0' && body[i] <= '9' {
+					// legacy octal escapes are not allowed in strict mode
+					return "", false
+				}
+				sb.WriteByte(0)
+				continue
+			}
+			r, size := utf8.DecodeRuneInString(body[i-1:])
+			if r == '\u2028' || r == '\u2029' {
+				i += size - 1
+				continue
+			}
+			sb.WriteString(body[i-1 : i-1+size])
+			i += size - 1
+		}
+	}
+	return sb.String(), true
+}
+
+// writeSurrogate writes the code point of a surrogate pair, where r is the surrogate escaped before body[*i],
+// or a lone surrogate encoded as WTF-8 so that it can be escaped again in the generated code
+func writeSurrogate(sb *strings.Builder, r rune, body string, i *int) {
+	if r < 0xdc00 && strings.HasPrefix(body[*i:], "\\u") {
+		l := &lexer{src: body, pos: *i + 2}
+		if low := l.scanUnicodeEscape(*i); low >= 0xdc00 && low <= 0xdfff {
+			sb.WriteRune(utf16.DecodeRune(r, low))
+			*i = l.pos
+			return
+		}
+	}
+	sb.WriteByte(byte(0xe0 | r>>12))
+	sb.WriteByte(byte(0x80 | (r>>6)&0x3f))
+	sb.WriteByte(byte(0x80 | r&0x3f))
+}
+
+// scanTemplate scans a template chunk starting after its opening '`' or '}'
+func (l *lexer) scanTemplate(start int) token {
+	chunkStart := l.pos
+	for {
+		if l.pos >= len(l.src) {
+			raise(start, "Unterminated template")
+		}
+		c := l.src[l.pos]
+		if c == '`' || (c == '$' && l.pos+1 < len(l.src) && l.src[l.pos+1] == '{') {
+			break
+		}
+		if c == '\\' {
+			l.pos++
+		}
+		if l.pos < len(l.src) {
+			_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+			l.pos += size
+		}
+	}
+
+	body := l.src[chunkStart:l.pos]
+	tail := l.src[l.pos] == '`'
+	if tail {
+		l.pos++
+	} else {
+		l.pos += 2
+	}
+
+	cooked, ok := cookString(body)
+	raw := strings.Replace(strings.Replace(body, "\r\n", "\n", -1), "\r", "\n", -1)
+	return token{typ: tokTemplate, start: start, end: l.pos, cooked: cooked, raw: raw, tail: tail, invalidEscape: !ok}
+}
+
+// scanRegexp rescans the '/' or '/=' token tok as the start of a regular expression literal
+func (l *lexer) scanRegexp(tok token) token {
+	l.pos = tok.start + 1
+	inClass := false
+	for {
+		if l.pos >= len(l.src) || isLineTerminatorAt(l.src, l.pos) {
+			raise(tok.start, "Unterminated regular expression")
+		}
+		c := l.src[l.pos]
+		if c == '\\' {
+			l.pos++
+		} else if c == '[' {
+			inClass = true
+		} else if c == ']' {
+			inClass = false
+		} else if c == '/' && !inClass {
+			break
+		}
+		_, size := utf8.DecodeRuneInString(l.src[l.pos:])
+		l.pos += size
+	}
+
+	pattern := l.src[tok.start+1 : l.pos]
+	l.pos++
+	flagsStart := l.pos
+	l.scanIdentName()
+	flags := l.src[flagsStart:l.pos]
+	for i, f := range flags {
+		if !strings.ContainsRune("gimsuyd", f) || strings.ContainsRune(flags[i+1:], f) {
+			raise(tok.start, "Invalid regular expression flag")
+		}
+	}
+
+	return token{typ: tokRegexp, value: l.src[tok.start:l.pos], pattern: pattern, flags: flags, start: tok.start, end: l.pos, newlineBefore: tok.newlineBefore}
+}
+
+func (l *lexer) scanNumber() token {
+	start := l.pos
+	src := l.src
+
+	digits := func(valid func(byte) bool) {
+		for l.pos < len(src) && (valid(src[l.pos]) || (src[l.pos] == '_' && l.pos+1 < len(src) && valid(src[l.pos+1]))) {
+			l.pos++
+		}
+	}
+	isDec := func(c byte) bool { return c >= '0' && c <= '9' }
+
+	value := ""
+	if src[l.pos] == '0' && l.pos+1 < len(src) && strings.IndexByte("xXoObB", src[l.pos+1]) >= 0 {
+		base := map[byte]int{'x': 16, 'o': 8, 'b': 2}[src[l.pos+1]|0x20]
+		l.pos += 2
+		digitsStart := l.pos
+		digits(func(c byte) bool {
+			d, err := strconv.ParseUint(string(c), base, 8)
+			return err == nil && int(d) < base
+		})
+		if l.pos == digitsStart {
+			raise(start, "Expected number in radix %d", base)
+		}
+		if base == 16 && !strings.ContainsRune(src[start:l.pos], '_') {
+			value = src[start:l.pos]
+		} else {
+			n, _ := new(big.Int).SetString(strings.Replace(src[digitsStart:l.pos], "_", "", -1), base)
+			value = n.String()
+		}
+	} else if src[l.pos] == '0' && l.pos+1 < len(src) && isDec(src[l.pos+1]) {
+		// legacy octal and leading zero literals are not allowed in strict mode
+		raise(start, "Invalid number")
+	} else {
+		digits(isDec)
+		if l.pos < len(src) && src[l.pos] == '.' {
+			l.pos++
+			digits(isDec)
+		}
+		if l.pos < len(src) && (src[l.pos] == 'e' || src[l.pos] == 'E') {
+			l.pos++
+			if l.pos < len(src) && (src[l.pos] == '+' || src[l.pos] == '-') {
+				l.pos++
+			}
+			expStart := l.pos
+			digits(isDec)
+			if l.pos == expStart {
+				raise(start, "Invalid number")
+			}
+		}
+		value = strings.Replace(src[start:l.pos], "_", "", -1)
+	}
+
+	if l.pos < len(src) && src[l.pos] == 'n' {
+		raise(start, "BigInt literals are not supported")
+	}
+	if l.pos < len(src) {
+		if r, _ := utf8.DecodeRuneInString(src[l.pos:]); isIdentStart(r) || isDec(src[l.pos]) {
+			raise(l.pos, "Identifier directly after number")
+		}
+	}
+
+	return token{typ: tokNumber, value: value, start: start, end: l.pos}
+}
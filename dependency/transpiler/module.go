@@ -0,0 +1,227 @@
+package transpiler
+
+import (
+	"strings"
+	"unicode"
+)
+
+// moduleHeader generates the definition of the exports of a module and the requires of its imports,
+// and makes the imported bindings refer to the required modules
+func (g *generator) moduleHeader(prog *program) {
+	g.line()
+	g.w(`Object.defineProperty(exports, "__esModule", { value: true });`)
+
+	// the imports are generated first so that the exports of imported bindings refer to the required modules
+	requires := g.capture(func() {
+		for _, s := range prog.body {
+			switch s := s.(type) {
+			case *importDecl:
+				g.importDecl(s)
+			case *exportDecl:
+				if s.source != nil {
+					g.reexport(s)
+				}
+			}
+		}
+	})
+
+	for _, s := range prog.body {
+		switch s := s.(type) {
+		case *exportDecl:
+			if s.source != nil {
+				continue
+			}
+			if s.decl != nil {
+				for _, id := range declaredNames(s.decl) {
+					g.exportGetter(id.name, id.binding.name)
+				}
+				continue
+			}
+			for _, spec := range s.specs {
+				g.exportGetter(spec.exported, g.identRef(spec.ref))
+			}
+		case *exportDefault:
+			fd, ok := s.decl.(*funcDecl)
+			if !ok {
+				continue
+			}
+			name := g.names.fresh("_default")
+			if fd.id != nil {
+				name = fd.id.binding.name
+			}
+			g.defaultName[s] = name
+			g.line()
+			g.w("exports.default = ", name, ";")
+		}
+	}
+	g.buf.append(requires)
+}
+
+// declaredNames returns the identifiers that an exported declaration declares
+func declaredNames(decl stmt) []*ident {
+	switch decl := decl.(type) {
+	case *varDecl:
+		var ids []*ident
+		for _, d := range decl.decls {
+			ids = append(ids, boundNames(d.target)...)
+		}
+		return ids
+	case *funcDecl:
+		return []*ident{decl.id}
+	case *classDecl:
+		return []*ident{decl.id}
+	}
+	return nil
+}
+
+func (g *generator) exportGetter(exported, code string) {
+	g.line()
+	g.w("Object.defineProperty(exports, ", quote(exported), ", { enumerable: true, get: function () { return ", code, "; } });")
+}
+
+func (g *generator) require(source *literal) string {
+	return "require(" + stringLiteral(source) + ")"
+}
+
+func (g *generator) importDecl(s *importDecl) {
+	g.line()
+	g.mark(s)
+	if len(s.specs) == 0 {
+		g.w(g.require(s.source), ";")
+		return
+	}
+
+	var namespace *importSpec
+	hasDefault, hasNamed := false, false
+	for _, spec := range s.specs {
+		switch spec.imported {
+		case "*":
+			namespace = spec
+		case "default":
+			hasDefault = true
+		default:
+			hasNamed = true
+		}
+	}
+
+	var name, init string
+	switch {
+	case namespace != nil:
+		name = namespace.local.binding.name
+		init = g.helper("interopRequireWildcard") + "(" + g.require(s.source) + ")"
+	case hasDefault && hasNamed:
+		name = g.names.fresh(moduleVarName(s.source.cooked))
+		init = g.helper("interopRequireWildcard") + "(" + g.require(s.source) + ")"
+	case hasDefault:
+		name = g.names.fresh(moduleVarName(s.source.cooked))
+		init = g.helper("interopRequireDefault") + "(" + g.require(s.source) + ")"
+	default:
+		name = g.names.fresh(moduleVarName(s.source.cooked))
+		init = g.require(s.source)
+	}
+	g.w("var ", name, " = ", init, ";")
+
+	for _, spec := range s.specs {
+		if spec.imported == "*" {
+			continue
+		}
+		spec.local.binding.ref = name + memberAccess(spec.imported)
+	}
+}
+
+// reexport generates the exports of the bindings of another module
+func (g *generator) reexport(s *exportDecl) {
+	g.line()
+	g.mark(s)
+	name := g.names.fresh(moduleVarName(s.source.cooked))
+	if s.all && s.star == "" {
+		g.w("var ", name, " = ", g.require(s.source), ";")
+		g.line()
+		g.w("Object.keys(", name, ").forEach(function (key) {")
+		g.indent++
+		g.line()
+		g.w(`if (key === "default" || key === "__esModule" || Object.prototype.hasOwnProperty.call(exports, key)) return;`)
+		g.line()
+		g.w("Object.defineProperty(exports, key, { enumerable: true, get: function () { return ", name, "[key]; } });")
+		g.indent--
+		g.line()
+		g.w("});")
+		return
+	}
+
+	g.w("var ", name, " = ", g.helper("interopRequireWildcard"), "(", g.require(s.source), ");")
+	if s.all {
+		g.exportGetter(s.star, name)
+		return
+	}
+	for _, spec := range s.specs {
+		g.exportGetter(spec.exported, name+memberAccess(spec.local))
+	}
+}
+
+// exportDefault generates an export default declaration in place
+func (g *generator) exportDefault(s *exportDefault) {
+	switch decl := s.decl.(type) {
+	case *funcDecl:
+		g.line()
+		g.mark(s)
+		g.function(decl.function, g.defaultName[s])
+	case *classDecl:
+		name := g.names.fresh("_default")
+		if decl.id != nil {
+			name = decl.id.binding.name
+		}
+		g.line()
+		g.mark(s)
+		g.w("var ", name, " = ")
+		g.class(decl.class, name)
+		g.w(";")
+		g.line()
+		g.w("exports.default = ", name, ";")
+	default:
+		g.line()
+		g.mark(s)
+		g.w("exports.default = ")
+		g.expr(s.x, precAssign)
+		g.w(";")
+	}
+}
+
+// memberAccess returns the access of the property name of an object
+func memberAccess(name string) string {
+	if isIdentifierName(name) {
+		return "." + name
+	}
+	return "[" + quote(name) + "]"
+}
+
+// moduleVarName returns the base of the name of the variable holding a required module,
+// which is the last segment of its path in camel case
+func moduleVarName(source string) string {
+	source = strings.TrimSuffix(source, "/")
+	if i := strings.LastIndex(source, "/"); i >= 0 {
+		source = source[i+1:]
+	}
+	if i := strings.LastIndex(source, "."); i > 0 {
+		source = source[:i]
+	}
+
+	var sb strings.Builder
+	sb.WriteString("_")
+	upper := false
+	for _, r := range source {
+		if !(r == '_' || r == '$' || r < 0x80 && (unicode.IsLetter(r) || unicode.IsDigit(r))) {
+			upper = sb.Len() > 1
+			continue
+		}
+		if upper {
+			r = unicode.ToUpper(r)
+			upper = false
+		}
+		sb.WriteRune(r)
+	}
+	if sb.Len() == 1 {
+		return "_module"
+	}
+	return sb.String()
+}
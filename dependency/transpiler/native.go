@@ -0,0 +1,145 @@
+package transpiler
+
+import (
+	"context"
+	"fmt"
+	"strings"
+)
+
+// the names of the transpiler implementations
+const (
+	NativeTranspilerName   = "native"
+	ExternalTranspilerName = "external"
+)
+
+// TranspilerNames are the names of the transpiler implementations that can be selected
+var TranspilerNames = []string{NativeTranspilerName, ExternalTranspilerName}
+
+// NewTranspiler returns the transpiler implementation with the given name
+func NewTranspiler(name string) (Transpiler, error) {
+	switch name {
+	case NativeTranspilerName:
+		return NewNativeTranspiler(), nil
+	case ExternalTranspilerName:
+		return NewExternalTranspiler(DefaultTranspilerCommand), nil
+	}
+	return nil, fmt.Errorf("unknown transpiler '%s', must be one of: %s", name, strings.Join(TranspilerNames, ", "))
+}
+
+type nativeTranspiler struct{}
+
+// NewNativeTranspiler returns an instance of Transpiler that compiles ES2015+ sources to ES5 in-process.
+// Sources that use generators, async functions, for await, new.target or import.meta, private class members
+// or BigInt literals fail to transpile with an error naming the unsupported syntax
+func NewNativeTranspiler() Transpiler {
+	return nativeTranspiler{}
+}
+
+// Transpile performs a transpile step for each code without leaving the process
+func (nt nativeTranspiler) Transpile(ctx context.Context, codes ...string) ([]TranspileResult, error) {
+	if len(codes) == 0 {
+		return nil, nil
+	}
+
+	results := make([]TranspileResult, len(codes))
+	var errs TranspileErrors
+	for i, code := range codes {
+		if err := ctx.Err(); err != nil {
+			return nil, err
+		}
+		result, err := transpile(code)
+		if err != nil {
+			line, column := newLineIndex(code).position(err.pos)
+			errs = append(errs, &TranspileError{
+				Index:   i,
+				Message: fmt.Sprintf("%s: %s (%d:%d)", transpileErrorSourceName, err.msg, line+1, column),
+				Line:    line + 1,
+				Column:  column,
+			})
+			continue
+		}
+		results[i] = result
+	}
+	if len(errs) != 0 {
+		return nil, errs
+	}
+	return results, nil
+}
+
+// transpile compiles a single source, returning the syntax error that stopped it if it fails.
+// Any other panic is reported as an internal error at the start of the source, so that a bug in
+// the transpiler fails that source rather than the whole command
+func transpile(src string) (result TranspileResult, err *syntaxError) {
+	defer func() {
+		if r := recover(); r != nil {
+			e, ok := r.(*syntaxError)
+			if !ok {
+				e = &syntaxError{msg: fmt.Sprintf("Internal transpiler error: %v", r)}
+			}
+			err = e
+		}
+	}()
+
+	prog, used := parse(src)
+	names := &namer{used: used}
+	resolve(prog, names)
+	code := generate(prog, names)
+	// the end of the code maps to the end of the source, so that positions after the last mapping resolve
+	code.mark(len(src))
+
+	sourceMap, mapErr := sourceMap(src, code)
+	if mapErr != nil {
+		panic(mapErr)
+	}
+	return TranspileResult{Code: code.String(), SourceMap: sourceMap}, nil
+}
+
+// generate returns the ES5 code of prog, which starts with its directives, the use strict directive
+// and the helpers that the code uses
+func generate(prog *program, names *namer) *buffer {
+	g := newGenerator(names)
+	g.ctx = &funcCtx{program: true}
+
+	strict := false
+	n := 0
+	for ; n < len(prog.body); n++ {
+		lit := directive(prog.body[n])
+		if lit == nil {
+			break
+		}
+		if lit.value[1:len(lit.value)-1] == "use strict" {
+			strict = true
+		}
+	}
+
+	directives := g.capture(func() { g.stmts(prog.body[:n]) })
+	body := g.capture(func() {
+		if prog.module {
+			g.moduleHeader(prog)
+		}
+		g.stmts(prog.body[n:])
+	})
+	vars := g.capture(func() { g.declareVars(g.ctx) })
+
+	out := cat(directives)
+	if !strict {
+		out.write("\n\"use strict\";")
+	}
+	if len(g.helpers) != 0 {
+		out.write("\n", g.helperCode())
+	}
+	if len(vars.b) != 0 || len(body.b) != 0 {
+		out.write("\n")
+		out.append(vars)
+		out.append(body)
+	}
+
+	code := &buffer{b: out.b[1:]}
+	for _, m := range out.maps {
+		if m.gen > 0 {
+			m.gen--
+		}
+		code.maps = append(code.maps, m)
+	}
+	return code
+}
@@ -0,0 +1,1506 @@
+package transpiler
+
+var reservedWords = map[string]bool{
+	"break": true, "case": true, "catch": true, "class": true, "const": true, "continue": true, "debugger": true,
+	"default": true, "delete": true, "do": true, "else": true, "enum": true, "export": true, "extends": true,
+	"false": true, "finally": true, "for": true, "function": true, "if": true, "import": true, "in": true,
+	"instanceof": true, "new": true, "null": true, "return": true, "super": true, "switch": true, "this": true,
+	"throw": true, "true": true, "try": true, "typeof": true, "var": true, "void": true, "while": true, "with": true,
+}
+
+// strictReservedWords cannot be identifiers in strict mode, which all code is parsed in
+var strictReservedWords = map[string]bool{
+	"implements": true, "interface": true, "let": true, "package": true, "private": true, "protected": true,
+	"public": true, "static": true, "yield": true,
+}
+
+var binaryPrecedence = map[string]int{
+	"??": 1, "||": 1, "&&": 2, "|": 3, "^": 4, "&": 5,
+	"==": 6, "!=": 6, "===": 6, "!==": 6,
+	"<": 7, ">": 7, "<=": 7, ">=": 7, "instanceof": 7, "in": 7,
+	"<<": 8, ">>": 8, ">>>": 8,
+	"+": 9, "-": 9, "*": 10, "/": 10, "%": 10, "**": 11,
+}
+
+var assignOperators = map[string]bool{
+	"=": true, "+=": true, "-=": true, "*=": true, "/=": true, "%=": true, "**=": true, "<<=": true, ">>=": true,
+	">>>=": true, "&=": true, "|=": true, "^=": true, "&&=": true, "||=": true, "??=": true,
+}
+
+type parser struct {
+	lex     *lexer
+	tok     token
+	prevEnd int
+
+	inFunction  bool
+	inGenerator bool
+	inAsync     bool
+	module      bool
+}
+
+// parse parses src as a script, or as a module if it has import or export declarations
+func parse(src string) (*program, map[string]bool) {
+	p := &parser{lex: newLexer(src)}
+	p.next()
+
+	prog := &program{}
+	prog.body, prog.strict = p.parseBody(func() bool { return p.tok.typ == tokEOF })
+	prog.module = p.module
+	prog.strict = prog.strict || prog.module
+	return prog, p.lex.names
+}
+
+func (p *parser) next() {
+	p.prevEnd = p.tok.end
+	p.tok = p.lex.next()
+}
+
+// peek returns the token after the current one
+func (p *parser) peek() token {
+	l := *p.lex
+	return l.next()
+}
+
+func (p *parser) is(punct string) bool {
+	return p.tok.typ == tokPunct && p.tok.value == punct
+}
+
+func (p *parser) isKeyword(word string) bool {
+	return p.tok.typ == tokIdent && !p.tok.escaped && p.tok.value == word
+}
+
+func (p *parser) eat(punct string) bool {
+	if p.is(punct) {
+		p.next()
+		return true
+	}
+	return false
+}
+
+func (p *parser) expect(punct string) {
+	if !p.eat(punct) {
+		raise(p.tok.start, "Unexpected token, expected %s", punct)
+	}
+}
+
+func (p *parser) unexpected() {
+	raise(p.tok.start, "Unexpected token")
+}
+
+func (p *parser) semicolon() {
+	if p.eat(";") || p.is("}") || p.tok.typ == tokEOF || p.tok.newlineBefore {
+		return
+	}
+	p.unexpected()
+}
+
+func (p *parser) isIdentifier(tok token) bool {
+	return tok.typ == tokIdent && (tok.escaped || !reservedWords[tok.value]) &&
+		!(p.inGenerator && tok.value == "yield") && !(p.inAsync && tok.value == "await")
+}
+
+func (p *parser) parseIdent() *ident {
+	if !p.isIdentifier(p.tok) {
+		p.unexpected()
+	}
+	if strictReservedWords[p.tok.value] && !(p.inGenerator && p.tok.value == "yield") {
+		raise(p.tok.start, "The keyword '%s' is reserved", p.tok.value)
+	}
+	id := &ident{pos: pos(p.tok.start), name: p.tok.value}
+	p.next()
+	return id
+}
+
+// parseIdentName parses any identifier name, including reserved words, as used for property names
+func (p *parser) parseIdentName() *ident {
+	if p.tok.typ != tokIdent {
+		p.unexpected()
+	}
+	id := &ident{pos: pos(p.tok.start), name: p.tok.value}
+	p.next()
+	return id
+}
+
+type parserContext struct {
+	inFunction, inGenerator, inAsync bool
+}
+
+func (p *parser) enterFunction(async, generator bool) parserContext {
+	saved := parserContext{p.inFunction, p.inGenerator, p.inAsync}
+	p.inFunction, p.inGenerator, p.inAsync = true, generator, async
+	return saved
+}
+
+func (p *parser) restore(saved parserContext) {
+	p.inFunction, p.inGenerator, p.inAsync = saved.inFunction, saved.inGenerator, saved.inAsync
+}
+
+// parseBody parses statements until done, reporting whether they start with a "use strict" directive
+func (p *parser) parseBody(done func() bool) ([]stmt, bool) {
+	var body []stmt
+	strict := false
+	directives := true
+	for !done() {
+		s := p.parseStatementListItem()
+		if directives {
+			if lit := directive(s); lit != nil {
+				if lit.value[1:len(lit.value)-1] == "use strict" {
+					strict = true
+				}
+			} else {
+				directives = false
+			}
+		}
+		body = append(body, s)
+	}
+	return body, strict
+}
+
+// directive returns the string literal of s if it is a directive
+func directive(s stmt) *literal {
+	if es, ok := s.(*exprStmt); ok {
+		if lit, ok := es.x.(*literal); ok && lit.kind == litString {
+			return lit
+		}
+	}
+	return nil
+}
+
+// Statements
+
+func (p *parser) isLet() bool {
+	if !p.isKeyword("let") {
+		return false
+	}
+	next := p.peek()
+	return (next.typ == tokIdent && !(next.value == "in" || next.value == "instanceof")) ||
+		(next.typ == tokPunct && (next.value == "[" || next.value == "{"))
+}
+
+func (p *parser) isAsyncFunction() bool {
+	if !p.isKeyword("async") {
+		return false
+	}
+	next := p.peek()
+	return next.typ == tokIdent && next.value == "function" && !next.newlineBefore
+}
+
+func (p *parser) parseStatementListItem() stmt {
+	switch {
+	case p.isKeyword("function"), p.isAsyncFunction():
+		return &funcDecl{p.parseFunction(true)}
+	case p.isKeyword("class"):
+		return &classDecl{p.parseClass(true)}
+	case p.isKeyword("const"), p.isLet():
+		decl := p.parseVarDecl(false)
+		p.semicolon()
+		return decl
+	case p.isKeyword("import"):
+		if next := p.peek(); !(next.typ == tokPunct && (next.value == "(" || next.value == ".")) {
+			return p.parseImport()
+		}
+	case p.isKeyword("export"):
+		return p.parseExport()
+	}
+	return p.parseStatement()
+}
+
+func (p *parser) parseStatement() stmt {
+	start := pos(p.tok.start)
+
+	if p.tok.typ == tokPunct {
+		switch p.tok.value {
+		case "{":
+			return p.parseBlock()
+		case ";":
+			p.next()
+			return &emptyStmt{start}
+		}
+	}
+
+	if p.tok.typ == tokIdent && !p.tok.escaped {
+		switch p.tok.value {
+		case "var":
+			decl := p.parseVarDecl(false)
+			p.semicolon()
+			return decl
+		case "if":
+			p.next()
+			s := &ifStmt{pos: start}
+			s.test = p.parseParenExpression()
+			s.cons = p.parseStatement()
+			if p.isKeyword("else") {
+				p.next()
+				s.alt = p.parseStatement()
+			}
+			return s
+		case "for":
+			return p.parseFor()
+		case "while":
+			p.next()
+			s := &whileStmt{pos: start}
+			s.test = p.parseParenExpression()
+			s.body = p.parseStatement()
+			return s
+		case "do":
+			p.next()
+			s := &doWhileStmt{pos: start}
+			s.body = p.parseStatement()
+			if !p.isKeyword("while") {
+				p.unexpected()
+			}
+			p.next()
+			s.test = p.parseParenExpression()
+			p.eat(";")
+			return s
+		case "continue", "break":
+			s := &branchStmt{pos: start, tok: p.tok.value}
+			p.next()
+			if p.isIdentifier(p.tok) && !p.tok.newlineBefore {
+				s.label = p.tok.value
+				p.next()
+			}
+			p.semicolon()
+			return s
+		case "return":
+			p.next()
+			s := &returnStmt{pos: start}
+			if !p.is(";") && !p.is("}") && p.tok.typ != tokEOF && !p.tok.newlineBefore {
+				s.arg = p.parseExpression(false)
+			}
+			p.semicolon()
+			return s
+		case "throw":
+			p.next()
+			if p.tok.newlineBefore {
+				raise(p.prevEnd, "Illegal newline after throw")
+			}
+			s := &throwStmt{pos: start, arg: p.parseExpression(false)}
+			p.semicolon()
+			return s
+		case "try":
+			return p.parseTry()
+		case "switch":
+			return p.parseSwitch()
+		case "with":
+			p.next()
+			s := &withStmt{pos: start}
+			s.object = p.parseParenExpression()
+			s.body = p.parseStatement()
+			return s
+		case "debugger":
+			p.next()
+			p.semicolon()
+			return &debuggerStmt{start}
+		case "function":
+			raise(p.tok.start, "In strict mode code, functions can only be declared at top level or inside a block")
+		case "class", "const":
+			p.unexpected()
+		case "export":
+			return p.parseStatementListItem()
+		case "import":
+			if next := p.peek(); !(next.typ == tokPunct && (next.value == "(" || next.value == ".")) {
+				return p.parseStatementListItem()
+			}
+		}
+		if p.isAsyncFunction() {
+			raise(p.tok.start, "In strict mode code, functions can only be declared at top level or inside a block")
+		}
+		if p.isLet() {
+			p.unexpected()
+		}
+
+		if p.isIdentifier(p.tok) {
+			if next := p.peek(); next.typ == tokPunct && next.value == ":" {
+				label := p.tok.value
+				p.next()
+				p.next()
+				return &labeledStmt{pos: start, label: label, body: p.parseStatement()}
+			}
+		}
+	}
+
+	s := &exprStmt{pos: start, x: p.parseExpression(false)}
+	p.semicolon()
+	return s
+}
+
+func (p *parser) parseParenExpression() expr {
+	p.expect("(")
+	x := p.parseExpression(false)
+	p.expect(")")
+	return x
+}
+
+func (p *parser) parseBlock() *blockStmt {
+	b := &blockStmt{pos: pos(p.tok.start)}
+	p.expect("{")
+	for !p.is("}") {
+		if p.tok.typ == tokEOF {
+			p.unexpected()
+		}
+		b.body = append(b.body, p.parseStatementListItem())
+	}
+	b.end = p.tok.end
+	p.next()
+	return b
+}
+
+// parseVarDecl parses a var, let or const declaration, without its semicolon
+func (p *parser) parseVarDecl(noIn bool) *varDecl {
+	decl := &varDecl{pos: pos(p.tok.start), kind: p.tok.value}
+	p.next()
+	for {
+		d := &declarator{pos: pos(p.tok.start), target: p.parseBindingTarget()}
+		if p.eat("=") {
+			d.init = p.parseAssign(noIn)
+		}
+		decl.decls = append(decl.decls, d)
+		if !p.eat(",") {
+			return decl
+		}
+	}
+}
+
+func (p *parser) parseFor() stmt {
+	start := pos(p.tok.start)
+	p.next()
+
+	await := false
+	if p.inAsync && p.isKeyword("await") {
+		await = true
+		p.next()
+	}
+	p.expect("(")
+
+	var init node
+	if !p.is(";") {
+		if p.isKeyword("var") || p.isKeyword("const") || p.isLet() {
+			decl := p.parseVarDecl(true)
+			if (p.isKeyword("of") || p.isKeyword("in")) && len(decl.decls) == 1 {
+				return p.parseForIn(start, await, decl)
+			}
+			init = decl
+		} else {
+			x := p.parseExpression(true)
+			if p.isKeyword("of") || p.isKeyword("in") {
+				return p.parseForIn(start, await, p.toPattern(x))
+			}
+			init = x
+		}
+	}
+	if await {
+		p.unexpected()
+	}
+
+	s := &forStmt{pos: start, init: init}
+	p.expect(";")
+	if !p.is(";") {
+		s.test = p.parseExpression(false)
+	}
+	p.expect(";")
+	if !p.is(")") {
+		s.update = p.parseExpression(false)
+	}
+	p.expect(")")
+	s.body = p.parseStatement()
+	return s
+}
+
+func (p *parser) parseForIn(start pos, await bool, left node) stmt {
+	s := &forInStmt{pos: start, of: p.isKeyword("of"), await: await, left: left}
+	p.next()
+	if s.of {
+		s.right = p.parseAssign(false)
+	} else {
+		s.right = p.parseExpression(false)
+	}
+	p.expect(")")
+	s.body = p.parseStatement()
+	return s
+}
+
+func (p *parser) parseTry() stmt {
+	s := &tryStmt{pos: pos(p.tok.start)}
+	p.next()
+	s.block = p.parseBlock()
+	if p.isKeyword("catch") {
+		p.next()
+		if p.eat("(") {
+			s.param = p.parseBindingTarget()
+			p.expect(")")
+		}
+		s.handler = p.parseBlock()
+	}
+	if p.isKeyword("finally") {
+		p.next()
+		s.finalizer = p.parseBlock()
+	}
+	if s.handler == nil && s.finalizer == nil {
+		raise(p.tok.start, "Missing catch or finally clause")
+	}
+	return s
+}
+
+func (p *parser) parseSwitch() stmt {
+	s := &switchStmt{pos: pos(p.tok.start)}
+	p.next()
+	s.disc = p.parseParenExpression()
+	p.expect("{")
+	for !p.is("}") {
+		c := &switchCase{pos: pos(p.tok.start)}
+		if p.isKeyword("case") {
+			p.next()
+			c.test = p.parseExpression(false)
+		} else if p.isKeyword("default") {
+			p.next()
+		} else {
+			p.unexpected()
+		}
+		p.expect(":")
+		for !p.is("}") && !p.isKeyword("case") && !p.isKeyword("default") {
+			if p.tok.typ == tokEOF {
+				p.unexpected()
+			}
+			c.body = append(c.body, p.parseStatementListItem())
+		}
+		s.cases = append(s.cases, c)
+	}
+	p.next()
+	return s
+}
+
+// Functions and classes
+
+// parseFunction parses a function declaration or expression, including async and generator functions,
+// which must have a name if named is set
+func (p *parser) parseFunction(named bool) *function {
+	fn := &function{pos: pos(p.tok.start)}
+	if p.isKeyword("async") {
+		fn.async = true
+		p.next()
+	}
+	p.next()
+	if p.eat("*") {
+		fn.generator = true
+	}
+
+	if p.tok.typ == tokIdent {
+		fn.id = p.parseIdent()
+	} else if named {
+		p.unexpected()
+	}
+
+	p.parseFunctionRest(fn)
+	return fn
+}
+
+func (p *parser) parseFunctionRest(fn *function) {
+	saved := p.enterFunction(fn.async, fn.generator)
+	defer p.restore(saved)
+
+	fn.params, fn.rest = p.parseParams()
+	fn.body, fn.strict = p.parseFunctionBody()
+}
+
+func (p *parser) parseParams() ([]pattern, pattern) {
+	p.expect("(")
+	var params []pattern
+	for !p.is(")") {
+		if p.eat("...") {
+			rest := p.parseBindingTarget()
+			p.expect(")")
+			return params, rest
+		}
+		params = append(params, p.parseBindingElement())
+		if !p.is(")") {
+			p.expect(",")
+		}
+	}
+	p.next()
+	return params, nil
+}
+
+func (p *parser) parseFunctionBody() (*blockStmt, bool) {
+	b := &blockStmt{pos: pos(p.tok.start)}
+	p.expect("{")
+	var strict bool
+	b.body, strict = p.parseBody(func() bool {
+		if p.tok.typ == tokEOF {
+			p.unexpected()
+		}
+		return p.is("}")
+	})
+	b.end = p.tok.end
+	p.next()
+	return b, strict
+}
+
+func (p *parser) parseArrow(start int, params []pattern, rest pattern, async bool) expr {
+	fn := &function{pos: pos(start), params: params, rest: rest, arrow: true, async: async}
+	if p.tok.newlineBefore {
+		p.unexpected()
+	}
+	p.expect("=>")
+
+	saved := p.enterFunction(async, false)
+	defer p.restore(saved)
+
+	if p.is("{") {
+		fn.body, fn.strict = p.parseFunctionBody()
+	} else {
+		fn.exprBody = p.parseAssign(false)
+	}
+	return &funcExpr{fn}
+}
+
+func (p *parser) parseMethod(start int, async, generator bool) *function {
+	fn := &function{pos: pos(start), method: true, async: async, generator: generator}
+	p.parseFunctionRest(fn)
+	return fn
+}
+
+// parsePropertyName parses the name of a property, which is an identifier name, a string or number literal,
+// or a computed expression
+func (p *parser) parsePropertyName() (expr, bool) {
+	switch p.tok.typ {
+	case tokIdent:
+		return p.parseIdentName(), false
+	case tokString, tokNumber:
+		return p.parseLiteral(), false
+	case tokPunct:
+		if p.is("[") {
+			p.next()
+			key := p.parseAssign(false)
+			p.expect("]")
+			return key, true
+		}
+	}
+	p.unexpected()
+	return nil, false
+}
+
+// isMethodModifier reports whether the current token is get, set, async or static used as a modifier,
+// rather than as the name of a property
+func (p *parser) isMethodModifier(word string) bool {
+	if !p.isKeyword(word) {
+		return false
+	}
+	next := p.peek()
+	if next.typ == tokPunct && (next.value == "(" || next.value == "," || next.value == ":" || next.value == "}" ||
+		next.value == "=" || next.value == ";") {
+		return false
+	}
+	return !(word == "async" && next.newlineBefore) && next.typ != tokEOF
+}
+
+func (p *parser) parseClass(named bool) *class {
+	c := &class{pos: pos(p.tok.start)}
+	p.next()
+
+	if p.tok.typ == tokIdent && !p.isKeyword("extends") {
+		c.id = p.parseIdent()
+	} else if named {
+		p.unexpected()
+	}
+	if p.isKeyword("extends") {
+		p.next()
+		c.superClass = p.parseLeftHandSide()
+	}
+
+	p.expect("{")
+	for !p.is("}") {
+		if p.eat(";") {
+			continue
+		}
+		if p.tok.typ == tokEOF {
+			p.unexpected()
+		}
+		c.members = append(c.members, p.parseClassMember())
+	}
+	p.next()
+	return c
+}
+
+func (p *parser) parseClassMember() *classMember {
+	start := p.tok.start
+	m := &classMember{pos: pos(start)}
+
+	if p.isMethodModifier("static") {
+		p.next()
+		m.static = true
+		if p.is("{") {
+			m.kind = memberStaticBlock
+			saved := p.enterFunction(false, false)
+			m.value = &function{pos: pos(start), method: true, body: p.parseBlock()}
+			p.restore(saved)
+			return m
+		}
+	}
+
+	async, generator := false, false
+	kind := memberMethod
+	if p.isMethodModifier("get") {
+		kind = memberGet
+		p.next()
+	} else if p.isMethodModifier("set") {
+		kind = memberSet
+		p.next()
+	} else if p.isMethodModifier("async") {
+		async = true
+		p.next()
+	}
+	if p.eat("*") {
+		generator = true
+	}
+
+	if p.tok.typ == tokPrivateName {
+		m.private = true
+		m.key = &ident{pos: pos(p.tok.start), name: "#" + p.tok.value}
+		p.next()
+	} else {
+		m.key, m.computed = p.parsePropertyName()
+	}
+
+	if p.is("(") {
+		m.kind = kind
+		m.value = p.parseMethod(start, async, generator)
+		if kind == memberMethod && !m.static && !m.computed && propertyName(m.key) == "constructor" {
+			m.kind = memberConstructor
+		}
+		return m
+	}
+
+	if kind != memberMethod || async || generator {
+		p.unexpected()
+	}
+
+	m.kind = memberField
+	m.value = &function{pos: pos(start), method: true, strict: true}
+	if p.eat("=") {
+		saved := p.enterFunction(false, false)
+		m.value.exprBody = p.parseAssign(false)
+		p.restore(saved)
+	}
+	p.semicolon()
+	return m
+}
+
+// propertyName returns the name of a non-computed property key
+func propertyName(key expr) string {
+	switch key := key.(type) {
+	case *ident:
+		return key.name
+	case *literal:
+		if key.kind == litString {
+			return key.cooked
+		}
+		return key.value
+	}
+	return ""
+}
+
+// Modules
+
+func (p *parser) parseModuleSource() *literal {
+	if p.tok.typ != tokString {
+		p.unexpected()
+	}
+	return p.parseLiteral()
+}
+
+// parseModuleExportName parses an identifier name or a string naming an import or export
+func (p *parser) parseModuleExportName() (string, int) {
+	start := p.tok.start
+	if p.tok.typ == tokString {
+		return p.parseLiteral().cooked, start
+	}
+	return p.parseIdentName().name, start
+}
+
+func (p *parser) parseImport() stmt {
+	decl := &importDecl{pos: pos(p.tok.start)}
+	p.next()
+	p.module = true
+
+	if p.tok.typ != tokString {
+		if p.tok.typ == tokIdent {
+			id := p.parseIdent()
+			decl.specs = append(decl.specs, &importSpec{pos: id.pos, imported: "default", local: id})
+			if !p.eat(",") {
+				goto from
+			}
+		}
+
+		if p.is("*") {
+			start := pos(p.tok.start)
+			p.next()
+			if !p.isKeyword("as") {
+				p.unexpected()
+			}
+			p.next()
+			decl.specs = append(decl.specs, &importSpec{pos: start, imported: "*", local: p.parseIdent()})
+		} else {
+			p.expect("{")
+			for !p.is("}") {
+				imported, start := p.parseModuleExportName()
+				spec := &importSpec{pos: pos(start), imported: imported}
+				if p.isKeyword("as") {
+					p.next()
+					spec.local = p.parseIdent()
+				} else {
+					if reservedWords[imported] {
+						raise(start, "Unexpected keyword '%s'", imported)
+					}
+					spec.local = &ident{pos: pos(start), name: imported}
+				}
+				decl.specs = append(decl.specs, spec)
+				if !p.is("}") {
+					p.expect(",")
+				}
+			}
+			p.next()
+		}
+
+	from:
+		if !p.isKeyword("from") {
+			p.unexpected()
+		}
+		p.next()
+	}
+
+	decl.source = p.parseModuleSource()
+	p.semicolon()
+	return decl
+}
+
+func (p *parser) parseExport() stmt {
+	start := pos(p.tok.start)
+	p.next()
+	p.module = true
+
+	if p.eat("*") {
+		decl := &exportDecl{pos: start, all: true}
+		if p.isKeyword("as") {
+			p.next()
+			decl.star, _ = p.parseModuleExportName()
+		}
+		if !p.isKeyword("from") {
+			p.unexpected()
+		}
+		p.next()
+		decl.source = p.parseModuleSource()
+		p.semicolon()
+		return decl
+	}
+
+	if p.isKeyword("default") {
+		p.next()
+		switch {
+		case p.isKeyword("function"), p.isAsyncFunction():
+			return &exportDefault{pos: start, decl: &funcDecl{p.parseFunction(false)}}
+		case p.isKeyword("class"):
+			return &exportDefault{pos: start, decl: &classDecl{p.parseClass(false)}}
+		}
+		x := p.parseAssign(false)
+		p.semicolon()
+		return &exportDefault{pos: start, x: x}
+	}
+
+	if p.eat("{") {
+		decl := &exportDecl{pos: start}
+		for !p.is("}") {
+			local, specStart := p.parseModuleExportName()
+			spec := &exportSpec{pos: pos(specStart), local: local, exported: local}
+			if p.isKeyword("as") {
+				p.next()
+				spec.exported, _ = p.parseModuleExportName()
+			}
+			decl.specs = append(decl.specs, spec)
+			if !p.is("}") {
+				p.expect(",")
+			}
+		}
+		p.next()
+		if p.isKeyword("from") {
+			p.next()
+			decl.source = p.parseModuleSource()
+		} else {
+			for _, spec := range decl.specs {
+				spec.ref = &ident{pos: spec.pos, name: spec.local}
+			}
+		}
+		p.semicolon()
+		return decl
+	}
+
+	switch {
+	case p.isKeyword("var"), p.isKeyword("const"), p.isLet(), p.isKeyword("function"), p.isAsyncFunction(), p.isKeyword("class"):
+		return &exportDecl{pos: start, decl: p.parseStatementListItem()}
+	}
+	p.unexpected()
+	return nil
+}
+
+// Expressions
+
+func (p *parser) parseExpression(noIn bool) expr {
+	start := p.tok.start
+	x := p.parseAssign(noIn)
+	if !p.is(",") {
+		return x
+	}
+	seq := &sequence{pos: pos(start), exprs: []expr{x}}
+	for p.eat(",") {
+		seq.exprs = append(seq.exprs, p.parseAssign(noIn))
+	}
+	return seq
+}
+
+func (p *parser) parseAssign(noIn bool) expr {
+	start := p.tok.start
+	if p.inGenerator && p.isKeyword("yield") {
+		return p.parseYield(noIn)
+	}
+
+	left := p.parseConditional(noIn)
+	if p.tok.typ != tokPunct || !assignOperators[p.tok.value] {
+		return left
+	}
+
+	op := p.tok.value
+	var target pattern
+	if op == "=" {
+		target = p.toPattern(left)
+	} else {
+		target = p.toSimpleTarget(left)
+	}
+	p.next()
+	return &assign{pos: pos(start), op: op, target: target, value: p.parseAssign(noIn)}
+}
+
+func (p *parser) parseYield(noIn bool) expr {
+	y := &yieldExpr{pos: pos(p.tok.start)}
+	p.next()
+	if p.tok.newlineBefore {
+		return y
+	}
+	if p.eat("*") {
+		y.delegate = true
+		y.arg = p.parseAssign(noIn)
+		return y
+	}
+	if p.tok.typ == tokPunct {
+		switch p.tok.value {
+		case ")", "]", "}", ",", ";", ":", "?.":
+			return y
+		}
+	}
+	if p.tok.typ == tokEOF || p.isKeyword("in") || p.isKeyword("of") {
+		return y
+	}
+	y.arg = p.parseAssign(noIn)
+	return y
+}
+
+func (p *parser) parseConditional(noIn bool) expr {
+	start := p.tok.start
+	test := p.parseBinary(0, noIn)
+	if !p.eat("?") {
+		return test
+	}
+	c := &conditional{pos: pos(start), test: test}
+	c.cons = p.parseAssign(false)
+	p.expect(":")
+	c.alt = p.parseAssign(noIn)
+	return c
+}
+
+func (p *parser) binaryOperator(noIn bool) (string, int) {
+	switch p.tok.typ {
+	case tokPunct:
+		return p.tok.value, binaryPrecedence[p.tok.value]
+	case tokIdent:
+		if p.isKeyword("instanceof") || (p.isKeyword("in") && !noIn) {
+			return p.tok.value, binaryPrecedence[p.tok.value]
+		}
+	}
+	return "", 0
+}
+
+func (p *parser) parseBinary(minPrec int, noIn bool) expr {
+	start := p.tok.start
+	left := p.parseUnary()
+	for {
+		op, prec := p.binaryOperator(noIn)
+		if prec == 0 || prec <= minPrec {
+			return left
+		}
+		p.next()
+
+		var right expr
+		if op == "**" {
+			right = p.parseBinary(prec-1, noIn)
+		} else {
+			right = p.parseBinary(prec, noIn)
+		}
+		left = &binary{pos: pos(start), op: op, x: left, y: right}
+	}
+}
+
+func (p *parser) parseUnary() expr {
+	start := pos(p.tok.start)
+	if p.tok.typ == tokPunct {
+		switch p.tok.value {
+		case "!", "~", "+", "-":
+			op := p.tok.value
+			p.next()
+			return &unary{pos: start, op: op, x: p.parseUnary()}
+		case "++", "--":
+			op := p.tok.value
+			p.next()
+			return &update{pos: start, op: op, prefix: true, x: p.toSimpleTarget(p.parseUnary())}
+		}
+	}
+	if p.isKeyword("typeof") || p.isKeyword("void") || p.isKeyword("delete") {
+		op := p.tok.value
+		p.next()
+		return &unary{pos: start, op: op, x: p.parseUnary()}
+	}
+	if p.inAsync && p.isKeyword("await") {
+		p.next()
+		return &awaitExpr{pos: start, x: p.parseUnary()}
+	}
+
+	x := p.parseLeftHandSide()
+	if (p.is("++") || p.is("--")) && !p.tok.newlineBefore {
+		op := p.tok.value
+		p.next()
+		return &update{pos: start, op: op, x: p.toSimpleTarget(x)}
+	}
+	return x
+}
+
+func (p *parser) parseLeftHandSide() expr {
+	start := p.tok.start
+	var x expr
+	switch {
+	case p.isKeyword("new"):
+		x = p.parseNew()
+	case p.isKeyword("super"):
+		x = &superExpr{pos(start)}
+		p.next()
+		if !p.is("(") && !p.is(".") && !p.is("[") {
+			raise(start, "'super' keyword outside a method")
+		}
+	case p.isKeyword("import"):
+		p.next()
+		if p.eat(".") {
+			if !p.isKeyword("meta") {
+				p.unexpected()
+			}
+			p.next()
+			x = &metaProperty{pos: pos(start), meta: "import", property: "meta"}
+		} else {
+			p.expect("(")
+			x = &importCall{pos: pos(start), source: p.parseAssign(false)}
+			p.eat(",")
+			p.expect(")")
+		}
+	default:
+		x = p.parsePrimary()
+	}
+	return p.parseSubscripts(start, x, false)
+}
+
+func (p *parser) parseNew() expr {
+	start := p.tok.start
+	p.next()
+	if p.eat(".") {
+		if !p.isKeyword("target") {
+			p.unexpected()
+		}
+		p.next()
+		return &metaProperty{pos: pos(start), meta: "new", property: "target"}
+	}
+
+	calleeStart := p.tok.start
+	var callee expr
+	if p.isKeyword("new") {
+		callee = p.parseNew()
+	} else {
+		callee = p.parsePrimary()
+	}
+	n := &newExpr{pos: pos(start), callee: p.parseSubscripts(calleeStart, callee, true)}
+	if p.is("(") {
+		n.args = p.parseArgs()
+	}
+	return n
+}
+
+func (p *parser) parseArgs() []expr {
+	p.expect("(")
+	args := []expr{}
+	for !p.is(")") {
+		if p.is("...") {
+			start := pos(p.tok.start)
+			p.next()
+			args = append(args, &spread{pos: start, x: p.parseAssign(false)})
+		} else {
+			args = append(args, p.parseAssign(false))
+		}
+		if !p.is(")") {
+			p.expect(",")
+		}
+	}
+	p.next()
+	return args
+}
+
+// parseMemberProperty parses the property after a '.' or '?.'
+func (p *parser) parseMemberProperty(start int, object expr, optional bool) expr {
+	if p.tok.typ == tokPrivateName {
+		prop := &ident{pos: pos(p.tok.start), name: "#" + p.tok.value}
+		p.next()
+		return &member{pos: pos(start), object: object, property: prop, optional: optional, private: true}
+	}
+	return &member{pos: pos(start), object: object, property: p.parseIdentName(), optional: optional}
+}
+
+func (p *parser) parseSubscripts(start int, x expr, noCall bool) expr {
+	chained := false
+	for {
+		switch {
+		case p.is("."):
+			p.next()
+			x = p.parseMemberProperty(start, x, false)
+		case p.is("?."):
+			if noCall {
+				raise(p.tok.start, "Constructors in/after an Optional Chain are not allowed")
+			}
+			chained = true
+			p.next()
+			switch {
+			case p.is("("):
+				x = &call{pos: pos(start), callee: x, args: p.parseArgs(), optional: true}
+			case p.is("["):
+				p.next()
+				prop := p.parseExpression(false)
+				p.expect("]")
+				x = &member{pos: pos(start), object: x, property: prop, computed: true, optional: true}
+			default:
+				x = p.parseMemberProperty(start, x, true)
+			}
+		case p.is("["):
+			p.next()
+			prop := p.parseExpression(false)
+			p.expect("]")
+			x = &member{pos: pos(start), object: x, property: prop, computed: true}
+		case p.is("(") && !noCall:
+			x = &call{pos: pos(start), callee: x, args: p.parseArgs()}
+		case p.tok.typ == tokTemplate:
+			if chained {
+				raise(p.tok.start, "Tagged Template Literals are not allowed in optionalChain")
+			}
+			x = &taggedTemplate{pos: pos(start), tag: x, quasi: p.parseTemplate()}
+		default:
+			if chained {
+				return &optionalChain{pos: pos(start), x: x}
+			}
+			return x
+		}
+	}
+}
+
+func (p *parser) parseLiteral() *literal {
+	lit := &literal{pos: pos(p.tok.start), value: p.tok.value}
+	switch p.tok.typ {
+	case tokNumber:
+		lit.kind = litNumber
+	case tokString:
+		lit.kind = litString
+		lit.cooked = p.tok.cooked
+	}
+	p.next()
+	return lit
+}
+
+func (p *parser) parsePrimary() expr {
+	tok := p.tok
+	start := tok.start
+
+	switch tok.typ {
+	case tokIdent:
+		if !tok.escaped {
+			switch tok.value {
+			case "this":
+				p.next()
+				return &thisExpr{pos(start)}
+			case "null":
+				p.next()
+				return &literal{pos: pos(start), kind: litNull, value: "null"}
+			case "true", "false":
+				p.next()
+				return &literal{pos: pos(start), kind: litBool, value: tok.value}
+			case "function":
+				return &funcExpr{p.parseFunction(false)}
+			case "class":
+				return &classExpr{p.parseClass(false)}
+			case "async":
+				next := p.peek()
+				if !next.newlineBefore {
+					if next.typ == tokIdent && next.value == "function" {
+						return &funcExpr{p.parseFunction(false)}
+					}
+					if p.isIdentifier(next) {
+						p.next()
+						param := p.parseIdent()
+						if !p.is("=>") {
+							p.unexpected()
+						}
+						return p.parseArrow(start, []pattern{param}, nil, true)
+					}
+					if next.typ == tokPunct && next.value == "(" {
+						callee := p.parseIdentName()
+						args := p.parseArgs()
+						if p.is("=>") && !p.tok.newlineBefore {
+							params, rest := p.toParams(args)
+							return p.parseArrow(start, params, rest, true)
+						}
+						return &call{pos: pos(start), callee: callee, args: args}
+					}
+				}
+			}
+		}
+
+		id := p.parseIdent()
+		if p.is("=>") && !p.tok.newlineBefore {
+			return p.parseArrow(start, []pattern{id}, nil, false)
+		}
+		return id
+
+	case tokNumber, tokString:
+		return p.parseLiteral()
+
+	case tokTemplate:
+		return p.parseTemplate()
+
+	case tokPrivateName:
+		raise(start, "Private names are not supported")
+
+	case tokPunct:
+		switch tok.value {
+		case "(":
+			return p.parseParenOrArrow()
+		case "[":
+			return p.parseArrayLiteral()
+		case "{":
+			return p.parseObjectLiteral()
+		case "/", "/=":
+			tok = p.lex.scanRegexp(tok)
+			p.tok = tok
+			p.next()
+			return &literal{pos: pos(start), kind: litRegexp, value: tok.pattern, flags: tok.flags}
+		}
+	}
+
+	p.unexpected()
+	return nil
+}
+
+func (p *parser) parseTemplate() *templateLit {
+	t := &templateLit{pos: pos(p.tok.start)}
+	for {
+		tok := p.tok
+		t.quasis = append(t.quasis, &tok)
+		if tok.tail {
+			p.next()
+			return t
+		}
+		p.next()
+		t.exprs = append(t.exprs, p.parseExpression(false))
+		if !p.is("}") {
+			raise(p.tok.start, "Unexpected token, expected }")
+		}
+		p.tok = p.lex.scanTemplate(p.tok.start)
+	}
+}
+
+func (p *parser) parseParenOrArrow() expr {
+	start := p.tok.start
+	p.next()
+
+	var items []expr
+	var rest pattern
+	trailingComma := false
+	for !p.is(")") {
+		if p.eat("...") {
+			rest = p.parseBindingTarget()
+			break
+		}
+		items = append(items, p.parseAssign(false))
+		if !p.is(")") {
+			p.expect(",")
+			trailingComma = p.is(")")
+		}
+	}
+	p.expect(")")
+
+	if p.is("=>") && !p.tok.newlineBefore {
+		params := make([]pattern, len(items))
+		for i, item := range items {
+			params[i] = p.toPattern(item)
+		}
+		return p.parseArrow(start, params, rest, false)
+	}
+
+	if rest != nil || len(items) == 0 || trailingComma {
+		p.unexpected()
+	}
+	if len(items) == 1 {
+		return &paren{pos: pos(start), x: items[0]}
+	}
+	return &paren{pos: pos(start), x: &sequence{pos: pos(items[0].start()), exprs: items}}
+}
+
+// toParams converts the arguments of what turned out to be the parameters of an async arrow function
+func (p *parser) toParams(args []expr) ([]pattern, pattern) {
+	var params []pattern
+	for i, arg := range args {
+		if s, ok := arg.(*spread); ok {
+			if i != len(args)-1 {
+				raise(s.start(), "Rest element must be last element")
+			}
+			return params, p.toPattern(s.x)
+		}
+		params = append(params, p.toPattern(arg))
+	}
+	return params, nil
+}
+
+func (p *parser) parseArrayLiteral() expr {
+	a := &arrayLit{pos: pos(p.tok.start), elems: []expr{}}
+	p.next()
+	for !p.is("]") {
+		if p.is(",") {
+			p.next()
+			a.elems = append(a.elems, nil)
+			continue
+		}
+		if p.is("...") {
+			start := pos(p.tok.start)
+			p.next()
+			a.elems = append(a.elems, &spread{pos: start, x: p.parseAssign(false)})
+		} else {
+			a.elems = append(a.elems, p.parseAssign(false))
+		}
+		if !p.is("]") {
+			p.expect(",")
+		}
+	}
+	p.next()
+	return a
+}
+
+func (p *parser) parseObjectLiteral() expr {
+	o := &objectLit{pos: pos(p.tok.start), props: []*property{}}
+	p.next()
+	for !p.is("}") {
+		o.props = append(o.props, p.parseObjectMember())
+		if !p.is("}") {
+			p.expect(",")
+		}
+	}
+	p.next()
+	return o
+}
+
+func (p *parser) parseObjectMember() *property {
+	start := p.tok.start
+	prop := &property{pos: pos(start)}
+
+	if p.eat("...") {
+		prop.kind = propSpread
+		prop.value = p.parseAssign(false)
+		return prop
+	}
+
+	async, generator := false, false
+	if p.isMethodModifier("get") {
+		prop.kind = propGet
+		p.next()
+	} else if p.isMethodModifier("set") {
+		prop.kind = propSet
+		p.next()
+	} else if p.isMethodModifier("async") {
+		async = true
+		p.next()
+	}
+	if p.eat("*") {
+		generator = true
+	}
+
+	prop.key, prop.computed = p.parsePropertyName()
+
+	if prop.kind != propInit || p.is("(") {
+		prop.method = prop.kind == propInit
+		prop.value = &funcExpr{p.parseMethod(start, async, generator)}
+		return prop
+	}
+	if async || generator {
+		p.unexpected()
+	}
+
+	if p.eat(":") {
+		prop.value = p.parseAssign(false)
+		return prop
+	}
+
+	key, ok := prop.key.(*ident)
+	if !ok || prop.computed || !p.isIdentifier(token{typ: tokIdent, value: key.name}) {
+		p.unexpected()
+	}
+	prop.shorthand = true
+	value := &ident{pos: key.pos, name: key.name}
+	prop.value = value
+	if p.is("=") {
+		// only valid as a destructuring target, which toPattern checks
+		p.next()
+		prop.value = &assign{pos: key.pos, op: "=", target: value, value: p.parseAssign(false)}
+	}
+	return prop
+}
+
+// Patterns
+
+func (p *parser) parseBindingTarget() pattern {
+	start := pos(p.tok.start)
+	switch {
+	case p.is("["):
+		ap := &arrayPattern{pos: start}
+		p.next()
+		for !p.is("]") {
+			if p.is(",") {
+				p.next()
+				ap.elems = append(ap.elems, nil)
+				continue
+			}
+			if p.eat("...") {
+				ap.rest = p.parseBindingTarget()
+				break
+			}
+			ap.elems = append(ap.elems, p.parseBindingElement())
+			if !p.is("]") {
+				p.expect(",")
+			}
+		}
+		p.expect("]")
+		return ap
+
+	case p.is("{"):
+		op := &objectPattern{pos: start}
+		p.next()
+		for !p.is("}") {
+			if p.eat("...") {
+				op.rest = p.parseIdent()
+				break
+			}
+			prop := &patternProp{pos: pos(p.tok.start)}
+			prop.key, prop.computed = p.parsePropertyName()
+			if p.eat(":") {
+				prop.value = p.parseBindingElement()
+			} else {
+				key, ok := prop.key.(*ident)
+				if !ok || prop.computed || !p.isIdentifier(token{typ: tokIdent, value: key.name}) {
+					p.unexpected()
+				}
+				var value pattern = &ident{pos: key.pos, name: key.name}
+				if p.eat("=") {
+					value = &assignPattern{pos: key.pos, target: value, def: p.parseAssign(false)}
+				}
+				prop.value = value
+			}
+			op.props = append(op.props, prop)
+			if !p.is("}") {
+				p.expect(",")
+			}
+		}
+		p.expect("}")
+		return op
+	}
+	return p.parseIdent()
+}
+
+func (p *parser) parseBindingElement() pattern {
+	start := pos(p.tok.start)
+	target := p.parseBindingTarget()
+	if p.eat("=") {
+		return &assignPattern{pos: start, target: target, def: p.parseAssign(false)}
+	}
+	return target
+}
+
+// toPattern converts an expression parsed before finding out it is the target of an assignment,
+// or a parameter of an arrow function
+func (p *parser) toPattern(x expr) pattern {
+	switch x := x.(type) {
+	case *ident, *member:
+		return x
+	case *paren:
+		switch x.x.(type) {
+		case *ident, *member:
+			return x.x
+		}
+	case *arrayLit:
+		ap := &arrayPattern{pos: x.pos}
+		for i, elem := range x.elems {
+			if s, ok := elem.(*spread); ok {
+				if i != len(x.elems)-1 {
+					raise(s.start(), "Rest element must be last element")
+				}
+				ap.rest = p.toPattern(s.x)
+				break
+			}
+			if elem == nil {
+				ap.elems = append(ap.elems, nil)
+				continue
+			}
+			ap.elems = append(ap.elems, p.toPattern(elem))
+		}
+		return ap
+	case *objectLit:
+		op := &objectPattern{pos: x.pos}
+		for i, prop := range x.props {
+			switch {
+			case prop.kind == propSpread:
+				if i != len(x.props)-1 {
+					raise(prop.start(), "Rest element must be last element")
+				}
+				op.rest = p.toPattern(prop.value)
+			case prop.kind == propInit && !prop.method:
+				op.props = append(op.props, &patternProp{pos: prop.pos, key: prop.key, computed: prop.computed, value: p.toPattern(prop.value)})
+			default:
+				raise(prop.start(), "Object pattern can't contain getter or setter")
+			}
+		}
+		return op
+	case *assign:
+		if x.op == "=" {
+			return &assignPattern{pos: x.pos, target: x.target, def: x.value}
+		}
+	}
+	raise(x.start(), "Invalid destructuring assignment target")
+	return nil
+}
+
+// toSimpleTarget checks that x can be the target of a compound assignment or an update
+func (p *parser) toSimpleTarget(x expr) pattern {
+	switch t := x.(type) {
+	case *ident, *member:
+		return x
+	case *paren:
+		return p.toSimpleTarget(t.x)
+	}
+	raise(x.start(), "Invalid left-hand side in assignment expression")
+	return nil
+}
@@ -0,0 +1,737 @@
+package transpiler
+
+import (
+	"strconv"
+)
+
+type bindingKind int
+
+const (
+	bindVar bindingKind = iota
+	bindLet
+	bindConst
+	bindClass
+	bindFunc
+	bindParam
+	bindCatch
+	bindImport
+	bindCallee // the name of a function or class expression, bound inside it
+	bindGlobal
+)
+
+// binding is a declared name, or a global one referenced without a declaration
+type binding struct {
+	name  string // the name in the output, which differs from orig if the binding was renamed
+	orig  string
+	kind  bindingKind
+	scope *scope
+
+	// loop is the loop whose iterations each get a fresh copy of the binding, and head is set if the
+	// binding is declared in the head of that loop rather than in its body
+	loop *loopInfo
+	head bool
+
+	// ref is the code that refers to an imported binding
+	ref string
+}
+
+// scope is a function, block, loop head, catch clause, switch or class scope
+type scope struct {
+	parent   *scope
+	fn       *scope // the nearest function scope, which may be the scope itself
+	bindings map[string]*binding
+
+	// for function scopes, the block-scoped bindings of nested scopes, which become vars of the function,
+	// the bindings referenced from within the function that are declared at its level or above it,
+	// and the names referenced from within the function that are declared outside of it
+	nested  []*binding
+	visible map[string][]*binding
+	free    map[string]bool
+}
+
+// loopInfo describes how closures created in a loop use its per-iteration bindings
+type loopInfo struct {
+	head       []*binding
+	captured   bool
+	reassigned bool
+}
+
+func newScope(parent *scope, function bool) *scope {
+	s := &scope{parent: parent, bindings: map[string]*binding{}}
+	if function {
+		s.fn = s
+		s.visible = map[string][]*binding{}
+		s.free = map[string]bool{}
+	} else {
+		s.fn = parent.fn
+	}
+	return s
+}
+
+func (s *scope) lookup(name string) *binding {
+	for ; s != nil; s = s.parent {
+		if b, ok := s.bindings[name]; ok {
+			return b
+		}
+	}
+	return nil
+}
+
+// namer generates names that are used neither in the source nor by other generated names
+type namer struct {
+	used map[string]bool
+}
+
+func (n *namer) fresh(base string) string {
+	if base == "" || base[0] != '_' {
+		base = "_" + base
+	}
+	name := base
+	for i := 2; n.used[name] || reservedWords[name]; i++ {
+		name = base + strconv.Itoa(i)
+	}
+	n.used[name] = true
+	return name
+}
+
+type resolver struct {
+	scope   *scope
+	names   *namer
+	globals map[string]*binding
+
+	// loops holds the loops of the current function that enclose the current scope,
+	// and bodies the loops whose body is being resolved, across functions
+	loops  []*loopInfo
+	bodies []*loopInfo
+
+	functions []*scope
+}
+
+// resolve binds every identifier of prog to its declaration and renames the block-scoped bindings
+// that would clash with other names once they are declared with var
+func resolve(prog *program, names *namer) {
+	r := &resolver{names: names, globals: map[string]*binding{}}
+	prog.scope = r.pushFunction()
+	r.hoistVars(prog.body)
+	r.declareLexical(prog.body)
+	r.stmts(prog.body)
+	r.scope = nil
+
+	for _, fn := range r.functions {
+		r.rename(fn)
+	}
+}
+
+func (r *resolver) push() *scope {
+	r.scope = newScope(r.scope, false)
+	return r.scope
+}
+
+func (r *resolver) pushFunction() *scope {
+	r.scope = newScope(r.scope, true)
+	r.functions = append(r.functions, r.scope)
+	return r.scope
+}
+
+func (r *resolver) pop() {
+	r.scope = r.scope.parent
+}
+
+func (r *resolver) declare(id *ident, kind bindingKind) *binding {
+	if id.name == "eval" || id.name == "arguments" {
+		raise(id.start(), "Binding %s in strict mode", id.name)
+	}
+	s := r.scope
+	if kind == bindVar {
+		s = s.fn
+	}
+	if b, ok := s.bindings[id.name]; ok {
+		id.binding = b
+		return b
+	}
+
+	b := &binding{name: id.name, orig: id.name, kind: kind, scope: s}
+	s.bindings[id.name] = b
+	id.binding = b
+
+	if s != s.fn && kind != bindCatch && kind != bindCallee {
+		s.fn.nested = append(s.fn.nested, b)
+		if len(r.loops) != 0 {
+			b.loop = r.loops[len(r.loops)-1]
+		}
+	}
+	return b
+}
+
+func (r *resolver) declarePattern(target pattern, kind bindingKind) {
+	for _, id := range boundNames(target) {
+		r.declare(id, kind)
+	}
+}
+
+// boundNames returns the identifiers bound by a binding pattern
+func boundNames(target pattern) []*ident {
+	var ids []*ident
+	var walk func(pattern)
+	walk = func(target pattern) {
+		switch t := target.(type) {
+		case *ident:
+			ids = append(ids, t)
+		case *assignPattern:
+			walk(t.target)
+		case *arrayPattern:
+			for _, elem := range t.elems {
+				if elem != nil {
+					walk(elem)
+				}
+			}
+			if t.rest != nil {
+				walk(t.rest)
+			}
+		case *objectPattern:
+			for _, prop := range t.props {
+				walk(prop.value)
+			}
+			if t.rest != nil {
+				walk(t.rest)
+			}
+		}
+	}
+	walk(target)
+	return ids
+}
+
+// hoistVars declares the vars of a function body in the function scope
+func (r *resolver) hoistVars(body []stmt) {
+	for _, s := range body {
+		r.hoistVarsIn(s)
+	}
+}
+
+func (r *resolver) hoistVarsIn(s stmt) {
+	switch s := s.(type) {
+	case *varDecl:
+		if s.kind == "var" {
+			for _, d := range s.decls {
+				r.declarePattern(d.target, bindVar)
+			}
+		}
+	case *blockStmt:
+		r.hoistVars(s.body)
+	case *ifStmt:
+		r.hoistVarsIn(s.cons)
+		if s.alt != nil {
+			r.hoistVarsIn(s.alt)
+		}
+	case *forStmt:
+		if decl, ok := s.init.(*varDecl); ok {
+			r.hoistVarsIn(decl)
+		}
+		r.hoistVarsIn(s.body)
+	case *forInStmt:
+		if decl, ok := s.left.(*varDecl); ok {
+			r.hoistVarsIn(decl)
+		}
+		r.hoistVarsIn(s.body)
+	case *whileStmt:
+		r.hoistVarsIn(s.body)
+	case *doWhileStmt:
+		r.hoistVarsIn(s.body)
+	case *tryStmt:
+		r.hoistVarsIn(s.block)
+		if s.handler != nil {
+			r.hoistVarsIn(s.handler)
+		}
+		if s.finalizer != nil {
+			r.hoistVarsIn(s.finalizer)
+		}
+	case *switchStmt:
+		for _, c := range s.cases {
+			r.hoistVars(c.body)
+		}
+	case *labeledStmt:
+		r.hoistVarsIn(s.body)
+	case *withStmt:
+		r.hoistVarsIn(s.body)
+	case *exportDecl:
+		if s.decl != nil {
+			r.hoistVarsIn(s.decl)
+		}
+	}
+}
+
+// declareLexical declares the let, const, class, function and import declarations of a statement list
+// in the current scope
+func (r *resolver) declareLexical(body []stmt) {
+	for _, s := range body {
+		r.declareLexicalIn(s)
+	}
+}
+
+func (r *resolver) declareLexicalIn(s stmt) {
+	switch s := s.(type) {
+	case *varDecl:
+		kind := bindLet
+		switch s.kind {
+		case "var":
+			return
+		case "const":
+			kind = bindConst
+		}
+		for _, d := range s.decls {
+			r.declarePattern(d.target, kind)
+		}
+	case *funcDecl:
+		if s.id != nil {
+			r.declare(s.id, bindFunc)
+		}
+	case *classDecl:
+		if s.id != nil {
+			r.declare(s.id, bindClass)
+		}
+	case *importDecl:
+		for _, spec := range s.specs {
+			r.declare(spec.local, bindImport)
+		}
+	case *exportDecl:
+		if s.decl != nil {
+			r.declareLexicalIn(s.decl)
+		}
+	case *exportDefault:
+		if s.decl != nil {
+			r.declareLexicalIn(s.decl)
+		}
+	}
+}
+
+// reference resolves an identifier that refers to a binding
+func (r *resolver) reference(id *ident) {
+	b := r.scope.lookup(id.name)
+	if b == nil {
+		b = r.globals[id.name]
+		if b == nil {
+			b = &binding{name: id.name, orig: id.name, kind: bindGlobal}
+			r.globals[id.name] = b
+		}
+	}
+	id.binding = b
+
+	var declared *scope
+	if b.scope != nil {
+		declared = b.scope.fn
+	}
+	for fn := r.scope.fn; fn != nil; fn = fn.parent.fn {
+		fn.visible[id.name] = append(fn.visible[id.name], b)
+		if fn == declared {
+			break
+		}
+		fn.free[id.name] = true
+		if fn.parent == nil {
+			break
+		}
+	}
+
+	if b.loop != nil && declared != r.scope.fn {
+		b.loop.captured = true
+	}
+}
+
+// assigned resolves an identifier that is the target of an assignment
+func (r *resolver) assigned(id *ident) {
+	if id.name == "eval" || id.name == "arguments" {
+		raise(id.start(), "Assigning to %s in strict mode", id.name)
+	}
+	r.reference(id)
+	if b := id.binding; b.kind == bindConst || b.kind == bindImport {
+		raise(id.start(), "\"%s\" is read-only", id.name)
+	}
+	if b := id.binding; b.head {
+		for _, loop := range r.bodies {
+			if loop == b.loop {
+				loop.reassigned = true
+			}
+		}
+	}
+}
+
+func (r *resolver) stmts(body []stmt) {
+	for _, s := range body {
+		r.stmt(s)
+	}
+}
+
+func (r *resolver) block(b *blockStmt) {
+	b.scope = r.push()
+	r.declareLexical(b.body)
+	r.stmts(b.body)
+	r.pop()
+}
+
+// loopBody resolves the body of loop, in which assignments to the bindings of its head are tracked
+func (r *resolver) loopBody(loop *loopInfo, body stmt) {
+	r.bodies = append(r.bodies, loop)
+	r.stmt(body)
+	r.bodies = r.bodies[:len(r.bodies)-1]
+}
+
+// loopHead declares the let or const declaration in the head of a for statement in a new scope
+func (r *resolver) loopHead(init node, loop *loopInfo) *scope {
+	decl, ok := init.(*varDecl)
+	if !ok || decl.kind == "var" {
+		return nil
+	}
+	s := r.push()
+	r.declareLexicalIn(decl)
+	for _, b := range s.bindings {
+		b.head = true
+	}
+	for _, d := range decl.decls {
+		for _, id := range boundNames(d.target) {
+			loop.head = append(loop.head, id.binding)
+		}
+	}
+	return s
+}
+
+func (r *resolver) stmt(s stmt) {
+	switch s := s.(type) {
+	case *varDecl:
+		for _, d := range s.decls {
+			r.pattern(d.target, false)
+			if d.init != nil {
+				r.expr(d.init)
+			}
+		}
+	case *funcDecl:
+		r.function(s.function)
+	case *classDecl:
+		r.class(s.class)
+	case *exprStmt:
+		r.expr(s.x)
+	case *blockStmt:
+		r.block(s)
+	case *ifStmt:
+		r.expr(s.test)
+		r.stmt(s.cons)
+		if s.alt != nil {
+			r.stmt(s.alt)
+		}
+	case *forStmt:
+		s.loop = &loopInfo{}
+		r.loops = append(r.loops, s.loop)
+		s.scope = r.loopHead(s.init, s.loop)
+		if s.init != nil {
+			if decl, ok := s.init.(*varDecl); ok {
+				r.stmt(decl)
+			} else {
+				r.expr(s.init)
+			}
+		}
+		if s.test != nil {
+			r.expr(s.test)
+		}
+		if s.update != nil {
+			r.expr(s.update)
+		}
+		r.loopBody(s.loop, s.body)
+		if s.scope != nil {
+			r.pop()
+		}
+		r.loops = r.loops[:len(r.loops)-1]
+	case *forInStmt:
+		r.expr(s.right)
+		s.loop = &loopInfo{}
+		r.loops = append(r.loops, s.loop)
+		s.scope = r.loopHead(s.left, s.loop)
+		if decl, ok := s.left.(*varDecl); ok {
+			r.stmt(decl)
+		} else {
+			r.pattern(s.left, true)
+		}
+		r.loopBody(s.loop, s.body)
+		if s.scope != nil {
+			r.pop()
+		}
+		r.loops = r.loops[:len(r.loops)-1]
+	case *whileStmt:
+		r.expr(s.test)
+		s.loop = &loopInfo{}
+		r.loops = append(r.loops, s.loop)
+		r.loopBody(s.loop, s.body)
+		r.loops = r.loops[:len(r.loops)-1]
+	case *doWhileStmt:
+		s.loop = &loopInfo{}
+		r.loops = append(r.loops, s.loop)
+		r.loopBody(s.loop, s.body)
+		r.loops = r.loops[:len(r.loops)-1]
+		r.expr(s.test)
+	case *returnStmt:
+		if s.arg != nil {
+			r.expr(s.arg)
+		}
+	case *throwStmt:
+		r.expr(s.arg)
+	case *tryStmt:
+		r.block(s.block)
+		if s.handler != nil {
+			s.scope = r.push()
+			if s.param != nil {
+				if id, ok := s.param.(*ident); ok {
+					r.declare(id, bindCatch)
+				} else {
+					r.declarePattern(s.param, bindLet)
+				}
+				r.pattern(s.param, false)
+			}
+			r.block(s.handler)
+			r.pop()
+		}
+		if s.finalizer != nil {
+			r.block(s.finalizer)
+		}
+	case *switchStmt:
+		r.expr(s.disc)
+		s.scope = r.push()
+		for _, c := range s.cases {
+			r.declareLexical(c.body)
+		}
+		for _, c := range s.cases {
+			if c.test != nil {
+				r.expr(c.test)
+			}
+			r.stmts(c.body)
+		}
+		r.pop()
+	case *labeledStmt:
+		r.stmt(s.body)
+	case *withStmt:
+		r.expr(s.object)
+		r.stmt(s.body)
+	case *exportDecl:
+		if s.decl != nil {
+			r.stmt(s.decl)
+		}
+		for _, spec := range s.specs {
+			if spec.ref != nil {
+				r.reference(spec.ref)
+			}
+		}
+	case *exportDefault:
+		if s.decl != nil {
+			r.stmt(s.decl)
+		} else {
+			r.expr(s.x)
+		}
+	}
+}
+
+// pattern resolves the identifiers of a binding or assignment target, and the expressions in it
+func (r *resolver) pattern(target pattern, assignment bool) {
+	switch t := target.(type) {
+	case *ident:
+		if assignment {
+			r.assigned(t)
+		} else {
+			r.reference(t)
+		}
+	case *member:
+		r.expr(t)
+	case *assignPattern:
+		r.pattern(t.target, assignment)
+		r.expr(t.def)
+	case *arrayPattern:
+		for _, elem := range t.elems {
+			if elem != nil {
+				r.pattern(elem, assignment)
+			}
+		}
+		if t.rest != nil {
+			r.pattern(t.rest, assignment)
+		}
+	case *objectPattern:
+		for _, prop := range t.props {
+			if prop.computed {
+				r.expr(prop.key)
+			}
+			r.pattern(prop.value, assignment)
+		}
+		if t.rest != nil {
+			r.pattern(t.rest, assignment)
+		}
+	}
+}
+
+func (r *resolver) function(fn *function) {
+	saved := r.loops
+	r.loops = nil
+
+	fn.scope = r.pushFunction()
+	for _, param := range fn.params {
+		r.declarePattern(param, bindParam)
+	}
+	if fn.rest != nil {
+		r.declarePattern(fn.rest, bindParam)
+	}
+	for _, param := range fn.params {
+		r.pattern(param, false)
+	}
+	if fn.rest != nil {
+		r.pattern(fn.rest, false)
+	}
+
+	if fn.body != nil {
+		fn.body.scope = fn.scope
+		r.hoistVars(fn.body.body)
+		r.declareLexical(fn.body.body)
+		r.stmts(fn.body.body)
+	} else if fn.exprBody != nil {
+		r.expr(fn.exprBody)
+	}
+
+	r.pop()
+	r.loops = saved
+}
+
+// functionExpr resolves a function expression, whose name is bound in a scope of its own
+func (r *resolver) functionExpr(fn *function) {
+	if fn.id == nil {
+		r.function(fn)
+		return
+	}
+	r.push()
+	r.declare(fn.id, bindCallee)
+	r.function(fn)
+	r.pop()
+}
+
+func (r *resolver) class(c *class) {
+	if c.superClass != nil {
+		r.expr(c.superClass)
+	}
+
+	c.scope = r.push()
+	if c.id != nil && c.id.binding == nil {
+		r.declare(c.id, bindCallee)
+	}
+	for _, m := range c.members {
+		if m.computed {
+			r.expr(m.key)
+		}
+		r.function(m.value)
+	}
+	r.pop()
+}
+
+func (r *resolver) exprs(xs []expr) {
+	for _, x := range xs {
+		if x != nil {
+			r.expr(x)
+		}
+	}
+}
+
+func (r *resolver) expr(x expr) {
+	switch x := x.(type) {
+	case *ident:
+		r.reference(x)
+	case *templateLit:
+		r.exprs(x.exprs)
+	case *taggedTemplate:
+		r.expr(x.tag)
+		r.expr(x.quasi)
+	case *arrayLit:
+		r.exprs(x.elems)
+	case *objectLit:
+		for _, prop := range x.props {
+			if prop.computed {
+				r.expr(prop.key)
+			}
+			r.expr(prop.value)
+		}
+	case *funcExpr:
+		if x.arrow {
+			r.function(x.function)
+		} else {
+			r.functionExpr(x.function)
+		}
+	case *classExpr:
+		r.class(x.class)
+	case *unary:
+		r.expr(x.x)
+	case *update:
+		if id, ok := x.x.(*ident); ok {
+			r.assigned(id)
+		} else {
+			r.expr(x.x)
+		}
+	case *binary:
+		r.expr(x.x)
+		r.expr(x.y)
+	case *assign:
+		r.pattern(x.target, true)
+		r.expr(x.value)
+	case *conditional:
+		r.expr(x.test)
+		r.expr(x.cons)
+		r.expr(x.alt)
+	case *call:
+		r.expr(x.callee)
+		r.exprs(x.args)
+	case *newExpr:
+		r.expr(x.callee)
+		r.exprs(x.args)
+	case *member:
+		r.expr(x.object)
+		if x.computed {
+			r.expr(x.property)
+		}
+	case *optionalChain:
+		r.expr(x.x)
+	case *sequence:
+		r.exprs(x.exprs)
+	case *spread:
+		r.expr(x.x)
+	case *yieldExpr:
+		if x.arg != nil {
+			r.expr(x.arg)
+		}
+	case *awaitExpr:
+		r.expr(x.x)
+	case *importCall:
+		r.expr(x.source)
+	case *paren:
+		r.expr(x.x)
+	}
+}
+
+// rename gives the block-scoped bindings hoisted into fn a name that no other binding visible in fn uses
+func (r *resolver) rename(fn *scope) {
+	hoisted := map[string]bool{}
+	for name := range fn.bindings {
+		hoisted[name] = true
+	}
+
+	for _, b := range fn.nested {
+		if r.clashes(fn, b, hoisted) {
+			b.name = r.names.fresh(b.orig)
+		}
+		hoisted[b.name] = true
+	}
+}
+
+func (r *resolver) clashes(fn *scope, b *binding, hoisted map[string]bool) bool {
+	if hoisted[b.name] || b.name == "arguments" {
+		return true
+	}
+	for _, other := range fn.visible[b.name] {
+		if other != b && other.name == b.name {
+			return true
+		}
+	}
+	for s := b.scope.parent; s != fn; s = s.parent {
+		if other, ok := s.bindings[b.name]; ok && other.name == b.name {
+			return true
+		}
+	}
+	return false
+}
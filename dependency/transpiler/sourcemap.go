@@ -0,0 +1,105 @@
+package transpiler
+
+import (
+	"encoding/json"
+	"sort"
+	"strings"
+	"unicode/utf8"
+)
+
+// lineIndex converts byte offsets of a text into lines and UTF-16 columns, both starting at 0
+type lineIndex struct {
+	text   string
+	starts []int
+}
+
+func newLineIndex(text string) *lineIndex {
+	idx := &lineIndex{text: text, starts: []int{0}}
+	for i := 0; i < len(text); {
+		r, size := utf8.DecodeRuneInString(text[i:])
+		i += size
+		if r == '\r' && i < len(text) && text[i] == '\n' {
+			i++
+		}
+		if isLineTerminator(r) {
+			idx.starts = append(idx.starts, i)
+		}
+	}
+	return idx
+}
+
+func (idx *lineIndex) position(offset int) (int, int) {
+	line := sort.Search(len(idx.starts), func(i int) bool { return idx.starts[i] > offset }) - 1
+	column := 0
+	for _, r := range idx.text[idx.starts[line]:offset] {
+		column++
+		if r > 0xffff {
+			column++
+		}
+	}
+	return line, column
+}
+
+const base64Digits = "ABCDEFGHIJKLMNOPQRSTUVWXYZabcdefghijklmnopqrstuvwxyz0123456789+/"
+
+// writeVLQ writes n as a base64 variable-length quantity
+func writeVLQ(sb *strings.Builder, n int) {
+	v := n << 1
+	if n < 0 {
+		v = -n<<1 | 1
+	}
+	for {
+		digit := v & 0x1f
+		v >>= 5
+		if v != 0 {
+			digit |= 0x20
+		}
+		sb.WriteByte(base64Digits[digit])
+		if v == 0 {
+			return
+		}
+	}
+}
+
+// sourceMap returns the version 3 source map of code generated from src
+func sourceMap(src string, code *buffer) (json.RawMessage, error) {
+	maps := append([]mapping(nil), code.maps...)
+	sort.SliceStable(maps, func(i, j int) bool { return maps[i].gen < maps[j].gen })
+
+	genIndex, srcIndex := newLineIndex(string(code.b)), newLineIndex(src)
+	var sb strings.Builder
+	genLine, prevGenColumn, prevSrcLine, prevSrcColumn := 0, 0, 0, 0
+	first := true
+	lastGen := -1
+	for _, m := range maps {
+		if m.gen == lastGen {
+			continue
+		}
+		lastGen = m.gen
+
+		line, column := genIndex.position(m.gen)
+		srcLine, srcColumn := srcIndex.position(m.src)
+		for ; genLine < line; genLine++ {
+			sb.WriteByte(';')
+			prevGenColumn = 0
+			first = true
+		}
+		if !first {
+			sb.WriteByte(',')
+		}
+		first = false
+		writeVLQ(&sb, column-prevGenColumn)
+		writeVLQ(&sb, 0)
+		writeVLQ(&sb, srcLine-prevSrcLine)
+		writeVLQ(&sb, srcColumn-prevSrcColumn)
+		prevGenColumn, prevSrcLine, prevSrcColumn = column, srcLine, srcColumn
+	}
+
+	return json.Marshal(struct {
+		Version        int      `json:"version"`
+		Sources        []string `json:"sources"`
+		Names          []string `json:"names"`
+		Mappings       string   `json:"mappings"`
+		SourcesContent []string `json:"sourcesContent"`
+	}{3, []string{"unknown"}, []string{}, sb.String(), []string{src}})
+}
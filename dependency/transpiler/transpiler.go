@@ -4,12 +4,26 @@ import (
 	"bytes"
 	"context"
 	"encoding/json"
+	"fmt"
 	"os/exec"
+	"strconv"
+	"strings"
 )
 
 // DefaultTranspilerCommand is the binary used for executing the transpiler
 const DefaultTranspilerCommand = "transpiler"
 
+const (
+	// transpileErrorSourceName is the name that the transpilers give every source in their error messages
+	transpileErrorSourceName = "unknown"
+
+	// excerptContextLines is the number of lines shown before and after the line of an error
+	excerptContextLines = 2
+
+	// excerptMaxWidth is the most runes of a line shown in an excerpt
+	excerptMaxWidth = 100
+)
+
 // TranspileError contains the error message from a failed transpile attempt, and
 // the line/column if available
 type TranspileError struct {
@@ -40,8 +54,83 @@ func (tes TranspileErrors) Error() string {
 	case 1:
 		return tes[0].Error()
 	default:
-		return "multiple errors in []*TranspileError"
+		messages := make([]string, len(tes))
+		for i, te := range tes {
+			messages[i] = fmt.Sprintf("code %d: %s", te.Index, te.Message)
+		}
+		return fmt.Sprintf("%d errors occurred:\n\t%s", len(tes), strings.Join(messages, "\n\t"))
+	}
+}
+
+// Reason returns the message of the error without the name of the source and the position
+// that the transpiler adds to it
+func (te TranspileError) Reason() string {
+	reason := strings.TrimPrefix(te.Message, transpileErrorSourceName+": ")
+	return strings.TrimSuffix(reason, fmt.Sprintf(" (%d:%d)", te.Line, te.Column))
+}
+
+// Excerpt returns the lines of code around the position of the error, marking the column of the error
+// under its line, or an empty string if the error has no position
+func (te TranspileError) Excerpt(code string) string {
+	lines := strings.Split(code, "\n")
+	if te.Line < 1 || te.Line > len(lines) {
+		return ""
+	}
+
+	first, last := te.Line-excerptContextLines, te.Line+excerptContextLines
+	if first < 1 {
+		first = 1
+	}
+	if last > len(lines) {
+		last = len(lines)
+	}
+	width := len(strconv.Itoa(last))
+
+	// the lines of minified sources are cut to the window of runes around the column of the error
+	start := 0
+	if te.Column > excerptMaxWidth/2 {
+		start = te.Column - excerptMaxWidth/2
+	}
+
+	var sb strings.Builder
+	for n := first; n <= last; n++ {
+		marker := " "
+		if n == te.Line {
+			marker = ">"
+		}
+		line := excerptWindow(strings.TrimSuffix(lines[n-1], "\r"), start)
+		sb.WriteString(strings.TrimRight(fmt.Sprintf("%s %*d | %s", marker, width, n, line), " \t") + "\n")
+
+		if n == te.Line {
+			// tabs before the column are kept so that the caret lines up with it
+			var indent strings.Builder
+			for i, r := range []rune(line) {
+				if i >= te.Column-start {
+					break
+				}
+				if r == '\t' {
+					indent.WriteRune('\t')
+				} else {
+					indent.WriteRune(' ')
+				}
+			}
+			fmt.Fprintf(&sb, "  %*s | %s^\n", width, "", indent.String())
+		}
+	}
+	return strings.TrimSuffix(sb.String(), "\n")
+}
+
+// excerptWindow returns the runes of line from start that fit in an excerpt
+func excerptWindow(line string, start int) string {
+	runes := []rune(line)
+	if start > len(runes) {
+		return ""
+	}
+	runes = runes[start:]
+	if len(runes) > excerptMaxWidth {
+		runes = runes[:excerptMaxWidth]
 	}
+	return string(runes)
 }
 
 // Transpiler allows building transpiled source code and a source map from a given ES6 source string
@@ -3,9 +3,12 @@ package transpiler
 import (
 	"bytes"
 	"context"
+	"strings"
 	"testing"
 
+	u "github.com/10gen/realm-cli/utils/test"
 	"github.com/robertkrimen/otto"
+	gc "github.com/smartystreets/goconvey/convey"
 )
 
 func TestTranspiler(t *testing.T) {
@@ -145,3 +148,35 @@ func TestTranspiler(t *testing.T) {
 	}
 
 }
+
+func TestTranspileError(t *testing.T) {
+	te := TranspileError{Index: 2, Message: "unknown: Unexpected token (3:9)", Line: 3, Column: 9}
+
+	t.Run("should return the reason without the source name and position", func(t *testing.T) {
+		u.So(t, te.Reason(), gc.ShouldEqual, "Unexpected token")
+		u.So(t, TranspileError{Message: "unknown: Unexpected token"}.Reason(), gc.ShouldEqual, "Unexpected token")
+	})
+
+	t.Run("should return the lines around the error with its column marked", func(t *testing.T) {
+		code := "var a = 1;\nvar b = 2;\nfunction f() {\n\tlet x = ;\n}\nvar c = 3;\nvar d = 4;"
+		te := TranspileError{Message: "unknown: Unexpected token (4:9)", Line: 4, Column: 9}
+		u.So(t, te.Excerpt(code), gc.ShouldEqual, "  2 | var b = 2;\n  3 | function f() {\n> 4 | \tlet x = ;\n    | \t        ^\n  5 | }\n  6 | var c = 3;")
+	})
+
+	t.Run("should cut long lines around the column of the error", func(t *testing.T) {
+		code := strings.Repeat("a,", 200) + "}"
+		te := TranspileError{Line: 1, Column: 400}
+		u.So(t, te.Excerpt(code), gc.ShouldEqual, "> 1 | "+strings.Repeat("a,", 25)+"}\n    | "+strings.Repeat(" ", 50)+"^")
+	})
+
+	t.Run("should return no excerpt for an error without a position", func(t *testing.T) {
+		u.So(t, TranspileError{Message: "unknown: boom"}.Excerpt("var a;"), gc.ShouldBeEmpty)
+		u.So(t, TranspileError{Line: 3}.Excerpt("var a;"), gc.ShouldBeEmpty)
+	})
+
+	t.Run("should list the index and message of every error", func(t *testing.T) {
+		errs := TranspileErrors{&te, &TranspileError{Index: 5, Message: "unknown: Unexpected token (1:0)", Line: 1}}
+		u.So(t, errs.Error(), gc.ShouldEqual, "2 errors occurred:\n\tcode 2: unknown: Unexpected token (3:9)\n\tcode 5: unknown: Unexpected token (1:0)")
+		u.So(t, TranspileErrors{&te}.Error(), gc.ShouldEqual, "unknown: Unexpected token (3:9)")
+	})
+}
@@ -3,6 +3,7 @@ module github.com/10gen/realm-cli
 go 1.13
 
 require (
+	github.com/andybalholm/brotli v1.0.4
 	github.com/armon/go-radix v0.0.0-20170727155443-1fca145dffbc // indirect
 	github.com/bgentry/speakeasy v0.1.0 // indirect
 	github.com/blang/semver v3.5.1+incompatible
@@ -0,0 +1,146 @@
+package hosting
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"time"
+)
+
+const (
+	// cacheLockTimeout is how long to wait for another process to release the lock on the cache file
+	cacheLockTimeout = 30 * time.Second
+
+	// cacheLockStaleAge is the age after which a lock is assumed to have been left behind by a process
+	// that exited without releasing it, since the lock is only held while the cache file is written
+	cacheLockStaleAge = time.Minute
+
+	cacheLockRetryInterval = 50 * time.Millisecond
+)
+
+// verifyingAssetCache is an AssetCache that never returns an entry, so that every file is rehashed,
+// while still recording the new hashes in the underlying AssetCache
+type verifyingAssetCache struct {
+	AssetCache
+}
+
+// NewVerifyingAssetCache returns an AssetCache wrapping assetCache that ignores its entries
+// when looking up a file hash, but updates them with the hashes that are generated instead
+func NewVerifyingAssetCache(assetCache AssetCache) AssetCache {
+	return verifyingAssetCache{assetCache}
+}
+
+// Get never finds an entry
+func (vac verifyingAssetCache) Get(appID, filePath string) (AssetCacheEntry, bool) {
+	return AssetCacheEntry{}, false
+}
+
+// lockCacheFile takes the lock on the cache file at path, shared by every process writing it,
+// and returns the function that releases it
+func lockCacheFile(path string) (func(), error) {
+	lockPath := path + ".lock"
+	deadline := time.Now().Add(cacheLockTimeout)
+
+	for {
+		f, err := os.OpenFile(lockPath, os.O_CREATE|os.O_EXCL|os.O_WRONLY, 0600)
+		if err == nil {
+			f.Close()
+			return func() { os.Remove(lockPath) }, nil
+		}
+
+		if !os.IsExist(err) {
+			return nil, err
+		}
+
+		if info, statErr := os.Stat(lockPath); statErr == nil && time.Since(info.ModTime()) > cacheLockStaleAge {
+			os.Remove(lockPath)
+			continue
+		}
+
+		if time.Now().After(deadline) {
+			return nil, fmt.Errorf("timed out waiting for another process to update %s, remove %s if there is none", path, lockPath)
+		}
+
+		time.Sleep(cacheLockRetryInterval)
+	}
+}
+
+// writeFileAtomically writes data to a temporary file next to path and renames it to path,
+// so that readers see either the previous or the new contents of the file but never part of them
+func writeFileAtomically(path string, data []byte) error {
+	f, err := ioutil.TempFile(filepath.Dir(path), filepath.Base(path)+".*.tmp")
+	if err != nil {
+		return err
+	}
+	tmpPath := f.Name()
+
+	if _, err := f.Write(data); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := f.Sync(); err != nil {
+		f.Close()
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := f.Close(); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Chmod(tmpPath, 0600); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	if err := os.Rename(tmpPath, path); err != nil {
+		os.Remove(tmpPath)
+		return err
+	}
+
+	return nil
+}
+
+// updateCacheFileLocked applies update to the AssetCache stored in the file at path, which is created if it
+// does not exist or cannot be parsed, and writes the result back while holding the lock on the file
+func updateCacheFileLocked(path string, update func(stored AssetCache)) error {
+	unlock, err := lockCacheFile(path)
+	if err != nil {
+		return err
+	}
+	defer unlock()
+
+	stored, err := CacheFileToAssetCache(path)
+	if err != nil {
+		// a missing or corrupted cache is replaced, as it only saves rehashing files
+		stored = NewAssetCache()
+	}
+
+	update(stored)
+
+	data, err := json.Marshal(stored)
+	if err != nil {
+		return err
+	}
+
+	return writeFileAtomically(path, data)
+}
+
+// ClearCacheFile removes the entries of appID from the cache file at path, or every entry if appID is empty
+func ClearCacheFile(path, appID string) error {
+	return updateCacheFileLocked(path, func(stored AssetCache) {
+		entries := stored.Entries()
+		if appID == "" {
+			for id := range entries {
+				delete(entries, id)
+			}
+			return
+		}
+		delete(entries, appID)
+	})
+}
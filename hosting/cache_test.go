@@ -0,0 +1,166 @@
+package hosting_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUpdateCacheFileSafety(t *testing.T) {
+	setup := func(t *testing.T) (string, func()) {
+		dir, err := ioutil.TempDir("", "realm-cli-asset-cache")
+		u.So(t, err, gc.ShouldBeNil)
+		return filepath.Join(dir, ".asset-cache.json"), func() { os.RemoveAll(dir) }
+	}
+
+	t.Run("should merge the entries into those already stored", func(t *testing.T) {
+		cachePath, teardown := setup(t)
+		defer teardown()
+
+		stored := hosting.NewAssetCache()
+		stored.Set("app-a", hosting.AssetCacheEntry{FilePath: "/a.html", FileHash: "a1"})
+		stored.Set("app-b", hosting.AssetCacheEntry{FilePath: "/b.html", FileHash: "b1"})
+		u.So(t, hosting.UpdateCacheFile(cachePath, stored), gc.ShouldBeNil)
+
+		// another import of app-a, which read the cache before app-b was added to it
+		updated := hosting.NewAssetCache()
+		updated.Set("app-a", hosting.AssetCacheEntry{FilePath: "/a.html", FileHash: "a2"})
+		u.So(t, hosting.UpdateCacheFile(cachePath, updated), gc.ShouldBeNil)
+
+		assetCache, err := hosting.CacheFileToAssetCache(cachePath)
+		u.So(t, err, gc.ShouldBeNil)
+
+		ace, ok := assetCache.Get("app-a", "/a.html")
+		u.So(t, ok, gc.ShouldBeTrue)
+		u.So(t, ace.FileHash, gc.ShouldEqual, "a2")
+
+		ace, ok = assetCache.Get("app-b", "/b.html")
+		u.So(t, ok, gc.ShouldBeTrue)
+		u.So(t, ace.FileHash, gc.ShouldEqual, "b1")
+
+		t.Run("without leaving temporary or lock files behind", func(t *testing.T) {
+			files, err := ioutil.ReadDir(filepath.Dir(cachePath))
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, files, gc.ShouldHaveLength, 1)
+			u.So(t, files[0].Name(), gc.ShouldEqual, ".asset-cache.json")
+		})
+	})
+
+	t.Run("should replace a corrupted cache file", func(t *testing.T) {
+		cachePath, teardown := setup(t)
+		defer teardown()
+
+		u.So(t, ioutil.WriteFile(cachePath, []byte(`{"app-a": {"/a.html"`), 0600), gc.ShouldBeNil)
+
+		assetCache := hosting.NewAssetCache()
+		assetCache.Set("app-a", hosting.AssetCacheEntry{FilePath: "/a.html", FileHash: "a1"})
+		u.So(t, hosting.UpdateCacheFile(cachePath, assetCache), gc.ShouldBeNil)
+
+		stored, err := hosting.CacheFileToAssetCache(cachePath)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, stored.Entries(), gc.ShouldResemble, assetCache.Entries())
+	})
+
+	t.Run("should wait for another process to release the lock", func(t *testing.T) {
+		cachePath, teardown := setup(t)
+		defer teardown()
+
+		lockPath := cachePath + ".lock"
+		u.So(t, ioutil.WriteFile(lockPath, nil, 0600), gc.ShouldBeNil)
+
+		released := make(chan struct{})
+		go func() {
+			time.Sleep(200 * time.Millisecond)
+			os.Remove(lockPath)
+			close(released)
+		}()
+
+		assetCache := hosting.NewAssetCache()
+		assetCache.Set("app-a", hosting.AssetCacheEntry{FilePath: "/a.html", FileHash: "a1"})
+		u.So(t, hosting.UpdateCacheFile(cachePath, assetCache), gc.ShouldBeNil)
+
+		select {
+		case <-released:
+		default:
+			t.Error("expected the cache file to be written after the lock was released")
+		}
+	})
+
+	t.Run("should take over a stale lock", func(t *testing.T) {
+		cachePath, teardown := setup(t)
+		defer teardown()
+
+		lockPath := cachePath + ".lock"
+		u.So(t, ioutil.WriteFile(lockPath, nil, 0600), gc.ShouldBeNil)
+		staleTime := time.Now().Add(-time.Hour)
+		u.So(t, os.Chtimes(lockPath, staleTime, staleTime), gc.ShouldBeNil)
+
+		u.So(t, hosting.UpdateCacheFile(cachePath, hosting.NewAssetCache()), gc.ShouldBeNil)
+
+		_, err := os.Stat(lockPath)
+		u.So(t, os.IsNotExist(err), gc.ShouldBeTrue)
+	})
+}
+
+func TestClearCacheFile(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-asset-cache")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	cachePath := filepath.Join(dir, ".asset-cache.json")
+
+	assetCache := hosting.NewAssetCache()
+	assetCache.Set("app-a", hosting.AssetCacheEntry{FilePath: "/a.html", FileHash: "a1"})
+	assetCache.Set("app-b", hosting.AssetCacheEntry{FilePath: "/b.html", FileHash: "b1"})
+	u.So(t, hosting.UpdateCacheFile(cachePath, assetCache), gc.ShouldBeNil)
+
+	t.Run("should clear the entries of a single app", func(t *testing.T) {
+		u.So(t, hosting.ClearCacheFile(cachePath, "app-a"), gc.ShouldBeNil)
+
+		stored, err := hosting.CacheFileToAssetCache(cachePath)
+		u.So(t, err, gc.ShouldBeNil)
+		_, ok := stored.Get("app-a", "/a.html")
+		u.So(t, ok, gc.ShouldBeFalse)
+		_, ok = stored.Get("app-b", "/b.html")
+		u.So(t, ok, gc.ShouldBeTrue)
+	})
+
+	t.Run("should clear every entry", func(t *testing.T) {
+		u.So(t, hosting.ClearCacheFile(cachePath, ""), gc.ShouldBeNil)
+
+		stored, err := hosting.CacheFileToAssetCache(cachePath)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, stored.Entries(), gc.ShouldBeEmpty)
+	})
+}
+
+func TestVerifyingAssetCache(t *testing.T) {
+	appID := "3720"
+
+	localPath, err := filepath.Abs("../testdata/full_app/hosting/files/asset_file0.json")
+	u.So(t, err, gc.ShouldBeNil)
+	info := mustGetFileInfo(localPath)
+
+	assetCache := hosting.NewAssetCache()
+	assetCache.Set(appID, hosting.AssetCacheEntry{
+		FilePath:     "/asset_file0.json",
+		LastModified: info.ModTime().Unix(),
+		FileSize:     info.Size(),
+		FileHash:     "stale",
+	})
+
+	am, err := hosting.FileToAssetMetadata(appID, localPath, "/asset_file0.json", info, nil, nil, hosting.NewVerifyingAssetCache(assetCache), hosting.CompressionNone)
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, am.FileHash, gc.ShouldEqual, mustGenerateFileHash(localPath))
+
+	ace, ok := assetCache.Get(appID, "/asset_file0.json")
+	u.So(t, ok, gc.ShouldBeTrue)
+	u.So(t, ace.FileHash, gc.ShouldEqual, mustGenerateFileHash(localPath))
+}
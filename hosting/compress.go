@@ -0,0 +1,155 @@
+package hosting
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"encoding/hex"
+	"fmt"
+	"io"
+	"os"
+	"path/filepath"
+	"strings"
+
+	"github.com/10gen/realm-cli/utils"
+
+	"github.com/andybalholm/brotli"
+)
+
+// Compression is the Content-Encoding applied to compressible assets before they are uploaded
+type Compression string
+
+// The set of supported Compressions
+const (
+	CompressionNone   Compression = ""
+	CompressionGzip   Compression = "gzip"
+	CompressionBrotli Compression = "br"
+)
+
+// compressibleContentTypes are the non-text content types worth compressing
+var compressibleContentTypes = map[string]bool{
+	"application/javascript":   true,
+	"application/x-javascript": true,
+	"application/json":         true,
+	"application/xml":          true,
+}
+
+// ParseCompression returns the Compression with the given name, accepting "brotli" for CompressionBrotli
+func ParseCompression(name string) (Compression, error) {
+	switch strings.ToLower(name) {
+	case "", "none":
+		return CompressionNone, nil
+	case string(CompressionGzip):
+		return CompressionGzip, nil
+	case string(CompressionBrotli), "brotli":
+		return CompressionBrotli, nil
+	}
+	return CompressionNone, fmt.Errorf("unsupported compression %q, must be one of gzip or br", name)
+}
+
+// IsCompressible reports whether the asset at filePath is text-like, based on the
+// default content type of its extension
+func IsCompressible(filePath string) bool {
+	ext := filepath.Ext(filePath)
+	if ext == "" {
+		return false
+	}
+
+	ext = strings.ToLower(ext[1:])
+	if ext == "json" || ext == "mjs" || ext == "map" {
+		return true
+	}
+
+	contentType, ok := utils.GetContentTypeByExtension(ext)
+	if !ok {
+		return false
+	}
+
+	return strings.HasPrefix(contentType, "text/") ||
+		strings.HasSuffix(contentType, "+xml") ||
+		compressibleContentTypes[contentType]
+}
+
+// appliesTo reports whether the Compression should be applied to an asset with the given attributes,
+// which is not the case for files that are not compressible or already declare a Content-Encoding
+func (c Compression) appliesTo(filePath string, attrs []AssetAttribute) bool {
+	if c == CompressionNone || !IsCompressible(filePath) {
+		return false
+	}
+
+	for _, attr := range attrs {
+		if strings.EqualFold(attr.Name, AttributeContentEncoding) {
+			return false
+		}
+	}
+
+	return true
+}
+
+// Compress returns the compressed contents of r. The output only depends on the input,
+// so that the hash of a compressed asset is stable between runs
+func (c Compression) Compress(r io.Reader) ([]byte, error) {
+	var buf bytes.Buffer
+
+	var w io.WriteCloser
+	switch c {
+	case CompressionGzip:
+		gw, err := gzip.NewWriterLevel(&buf, gzip.BestCompression)
+		if err != nil {
+			return nil, err
+		}
+		w = gw
+	case CompressionBrotli:
+		w = brotli.NewWriterLevel(&buf, brotli.BestCompression)
+	default:
+		return nil, fmt.Errorf("unsupported compression %q", c)
+	}
+
+	if _, err := io.Copy(w, r); err != nil {
+		return nil, err
+	}
+	if err := w.Close(); err != nil {
+		return nil, err
+	}
+
+	return buf.Bytes(), nil
+}
+
+// compressFile returns the compressed contents of the file at path
+func (c Compression) compressFile(path string) ([]byte, error) {
+	f, err := os.Open(path)
+	if err != nil {
+		return nil, err
+	}
+	defer f.Close()
+
+	return c.Compress(f)
+}
+
+// HashFile returns the hash and size of the body uploaded for the file at path,
+// which is its contents compressed with c unless c is CompressionNone
+func (c Compression) HashFile(path string) (string, int64, error) {
+	if c == CompressionNone {
+		info, err := os.Stat(path)
+		if err != nil {
+			return "", 0, err
+		}
+		hash, err := utils.GenerateFileHashStr(path)
+		if err != nil {
+			return "", 0, err
+		}
+		return hash, info.Size(), nil
+	}
+
+	compressed, err := c.compressFile(path)
+	if err != nil {
+		return "", 0, err
+	}
+	return hashBytes(compressed), int64(len(compressed)), nil
+}
+
+// hashBytes returns the hash of data in the same format as utils.GenerateFileHashStr
+func hashBytes(data []byte) string {
+	sum := md5.Sum(data)
+	return hex.EncodeToString(sum[:])
+}
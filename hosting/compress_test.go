@@ -0,0 +1,184 @@
+package hosting_test
+
+import (
+	"bytes"
+	"compress/gzip"
+	"crypto/md5"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	"github.com/andybalholm/brotli"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func decompress(t *testing.T, compression hosting.Compression, data []byte) string {
+	t.Helper()
+
+	var r io.Reader
+	switch compression {
+	case hosting.CompressionGzip:
+		gr, err := gzip.NewReader(bytes.NewReader(data))
+		u.So(t, err, gc.ShouldBeNil)
+		r = gr
+	case hosting.CompressionBrotli:
+		r = brotli.NewReader(bytes.NewReader(data))
+	}
+
+	out, err := ioutil.ReadAll(r)
+	u.So(t, err, gc.ShouldBeNil)
+	return string(out)
+}
+
+func TestParseCompression(t *testing.T) {
+	for _, tc := range []struct {
+		name     string
+		expected hosting.Compression
+	}{
+		{"", hosting.CompressionNone},
+		{"gzip", hosting.CompressionGzip},
+		{"br", hosting.CompressionBrotli},
+		{"Brotli", hosting.CompressionBrotli},
+	} {
+		compression, err := hosting.ParseCompression(tc.name)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, compression, gc.ShouldEqual, tc.expected)
+	}
+
+	_, err := hosting.ParseCompression("zstd")
+	u.So(t, err, gc.ShouldNotBeNil)
+	u.So(t, err.Error(), gc.ShouldEqual, `unsupported compression "zstd", must be one of gzip or br`)
+}
+
+func TestIsCompressible(t *testing.T) {
+	for path, expected := range map[string]bool{
+		"/index.html":      true,
+		"/css/main.css":    true,
+		"/js/app.js":       true,
+		"/data.json":       true,
+		"/feed.xml":        true,
+		"/logo.svg":        true,
+		"/README.txt":      true,
+		"/img/photo.png":   false,
+		"/fonts/font.woff": false,
+		"/archive.zip":     false,
+		"/LICENSE":         false,
+	} {
+		t.Run(path, func(t *testing.T) {
+			u.So(t, hosting.IsCompressible(path), gc.ShouldEqual, expected)
+		})
+	}
+}
+
+func TestCompress(t *testing.T) {
+	contents := strings.Repeat("<p>hello world</p>\n", 100)
+
+	for _, compression := range []hosting.Compression{hosting.CompressionGzip, hosting.CompressionBrotli} {
+		t.Run(string(compression), func(t *testing.T) {
+			compressed, err := compression.Compress(strings.NewReader(contents))
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, len(compressed), gc.ShouldBeLessThan, len(contents))
+			u.So(t, decompress(t, compression, compressed), gc.ShouldEqual, contents)
+
+			again, err := compression.Compress(strings.NewReader(contents))
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, again, gc.ShouldResemble, compressed)
+		})
+	}
+}
+
+func TestFileToAssetMetadataCompression(t *testing.T) {
+	appID := "3720"
+
+	dir, err := ioutil.TempDir("", "realm-cli-hosting-compress")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	contents := strings.Repeat("body { color: red; }\n", 50)
+	localPath := filepath.Join(dir, "main.css")
+	u.So(t, ioutil.WriteFile(localPath, []byte(contents), 0600), gc.ShouldBeNil)
+	info := mustGetFileInfo(localPath)
+
+	compressed, err := hosting.CompressionGzip.Compress(strings.NewReader(contents))
+	u.So(t, err, gc.ShouldBeNil)
+	compressedHash := fmt.Sprintf("%x", md5.Sum(compressed))
+
+	t.Run("should describe the compressed body of a compressible file", func(t *testing.T) {
+		assetCache := hosting.NewAssetCache()
+
+		am, err := hosting.FileToAssetMetadata(appID, localPath, "/main.css", info, nil, nil, assetCache, hosting.CompressionGzip)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, am.Compression, gc.ShouldEqual, hosting.CompressionGzip)
+		u.So(t, am.FileHash, gc.ShouldEqual, compressedHash)
+		u.So(t, am.FileSize, gc.ShouldEqual, len(compressed))
+		u.So(t, am.Attrs, gc.ShouldResemble, []hosting.AssetAttribute{
+			{Name: hosting.AttributeContentType, Value: "text/css"},
+			{Name: hosting.AttributeContentEncoding, Value: "gzip"},
+		})
+
+		entry, ok := assetCache.Get(appID, "/main.css")
+		u.So(t, ok, gc.ShouldBeTrue)
+		u.So(t, entry, gc.ShouldResemble, hosting.AssetCacheEntry{
+			FilePath:       "/main.css",
+			LastModified:   info.ModTime().Unix(),
+			FileSize:       info.Size(),
+			FileHash:       mustGenerateFileHash(localPath),
+			Compression:    hosting.CompressionGzip,
+			CompressedHash: compressedHash,
+			CompressedSize: int64(len(compressed)),
+		})
+	})
+
+	t.Run("should use the compressed hash from the cache", func(t *testing.T) {
+		assetCache := hosting.NewAssetCache()
+		assetCache.Set(appID, hosting.AssetCacheEntry{
+			FilePath:       "/main.css",
+			LastModified:   info.ModTime().Unix(),
+			FileSize:       info.Size(),
+			FileHash:       "cached",
+			Compression:    hosting.CompressionGzip,
+			CompressedHash: "cachedgzip",
+			CompressedSize: 7,
+		})
+
+		am, err := hosting.FileToAssetMetadata(appID, localPath, "/main.css", info, nil, nil, assetCache, hosting.CompressionGzip)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, am.FileHash, gc.ShouldEqual, "cachedgzip")
+		u.So(t, am.FileSize, gc.ShouldEqual, 7)
+
+		t.Run("but not when it was cached with another compression", func(t *testing.T) {
+			am, err := hosting.FileToAssetMetadata(appID, localPath, "/main.css", info, nil, nil, assetCache, hosting.CompressionBrotli)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, am.Compression, gc.ShouldEqual, hosting.CompressionBrotli)
+			u.So(t, am.FileHash, gc.ShouldNotEqual, "cachedgzip")
+			u.So(t, am.FileHash, gc.ShouldNotEqual, mustGenerateFileHash(localPath))
+		})
+
+		t.Run("and the uncompressed hash without compression", func(t *testing.T) {
+			am, err := hosting.FileToAssetMetadata(appID, localPath, "/main.css", info, nil, nil, assetCache, hosting.CompressionNone)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, am.Compression, gc.ShouldEqual, hosting.CompressionNone)
+			u.So(t, am.FileHash, gc.ShouldEqual, mustGenerateFileHash(localPath))
+		})
+	})
+
+	t.Run("should not compress a file that declares a Content-Encoding", func(t *testing.T) {
+		desc := &hosting.AssetDescription{
+			FilePath: "/main.css",
+			Attrs:    []hosting.AssetAttribute{{Name: hosting.AttributeContentEncoding, Value: "identity"}},
+		}
+
+		am, err := hosting.FileToAssetMetadata(appID, localPath, "/main.css", info, desc, nil, hosting.NewAssetCache(), hosting.CompressionGzip)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, am.Compression, gc.ShouldEqual, hosting.CompressionNone)
+		u.So(t, am.FileHash, gc.ShouldEqual, mustGenerateFileHash(localPath))
+		u.So(t, am.Attrs, gc.ShouldResemble, desc.Attrs)
+	})
+}
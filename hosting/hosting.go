@@ -4,19 +4,23 @@ import (
 	"encoding/json"
 	"fmt"
 	"os"
+	"path"
 	"path/filepath"
+	"sort"
 	"strings"
 
 	"github.com/10gen/realm-cli/utils"
 )
 
-// ListLocalAssetMetadata walks all files from the rootDirectory
-// and builds []AssetMetadata from those files
+// ListLocalAssetMetadata walks all files from the rootDirectory, skipping those matched by ignoreRules,
+// and builds []AssetMetadata from those files, describing compressible files as compressed with compression
+// and applying attributeRules to the files without an entry in assetDescriptions
+// symlinked files are described by their targets, and symlinked directories are only walked if followSymlinks is set
 // returns the assetMetadata and possibly alters the assetCache
-func ListLocalAssetMetadata(appID, rootDirectory string, assetDescriptions map[string]AssetDescription, assetCache AssetCache) ([]AssetMetadata, error) {
+func ListLocalAssetMetadata(appID, rootDirectory string, assetDescriptions map[string]AssetDescription, attributeRules AttributeRules, assetCache AssetCache, ignoreRules *IgnoreRules, compression Compression, followSymlinks bool) ([]AssetMetadata, error) {
 	var assetMetadata []AssetMetadata
 
-	err := filepath.Walk(rootDirectory, buildAssetMetadata(appID, &assetMetadata, rootDirectory, assetDescriptions, assetCache))
+	err := utils.Walk(rootDirectory, followSymlinks, buildAssetMetadata(appID, &assetMetadata, rootDirectory, assetDescriptions, attributeRules, assetCache, ignoreRules, compression))
 	if err != nil {
 		return nil, err
 	}
@@ -29,6 +33,9 @@ func ListLocalAssetMetadata(appID, rootDirectory string, assetDescriptions map[s
 
 	for key := range assetDescriptions {
 		if _, ok := metadataOnDisk[key]; !ok {
+			if ignoreRules.Ignored(key, false) {
+				return nil, fmt.Errorf("file '%s' has an entry in metadata file, but is excluded by %s or --exclude", key, IgnoreFileName)
+			}
 			return nil, fmt.Errorf("file '%s' has an entry in metadata file, but does not appear in files directory", key)
 		}
 	}
@@ -36,61 +43,76 @@ func ListLocalAssetMetadata(appID, rootDirectory string, assetDescriptions map[s
 	return assetMetadata, nil
 }
 
-func buildAssetMetadata(appID string, assetMetadata *[]AssetMetadata, rootDir string, assetDescriptions map[string]AssetDescription, assetCache AssetCache) filepath.WalkFunc {
+func buildAssetMetadata(appID string, assetMetadata *[]AssetMetadata, rootDir string, assetDescriptions map[string]AssetDescription, attributeRules AttributeRules, assetCache AssetCache, ignoreRules *IgnoreRules, compression Compression) filepath.WalkFunc {
 	return func(path string, info os.FileInfo, err error) error {
 		if err != nil {
 			return err
 		}
-		if !info.IsDir() {
-			relPath, pathErr := filepath.Rel(rootDir, path)
-			if pathErr != nil {
-				return pathErr
-			}
-			assetPath := fmt.Sprintf("/%s", replacePathSeparator(relPath))
 
-			var desc *AssetDescription
-			if assetDescriptions != nil {
-				if descEntry, ok := assetDescriptions[assetPath]; ok {
-					desc = &descEntry
-				}
+		relPath, pathErr := filepath.Rel(rootDir, path)
+		if pathErr != nil {
+			return pathErr
+		}
+		assetPath := fmt.Sprintf("/%s", replacePathSeparator(relPath))
+
+		if info.IsDir() {
+			if relPath != "." && ignoreRules.Ignored(assetPath, true) {
+				return filepath.SkipDir
 			}
+			return nil
+		}
+
+		if ignoreRules.Ignored(assetPath, false) {
+			return nil
+		}
 
-			am, fileErr := FileToAssetMetadata(appID, path, assetPath, info, desc, assetCache)
-			if fileErr != nil {
-				return fileErr
+		var desc *AssetDescription
+		if assetDescriptions != nil {
+			if descEntry, ok := assetDescriptions[assetPath]; ok {
+				desc = &descEntry
 			}
+		}
 
-			*assetMetadata = append(*assetMetadata, *am)
+		am, fileErr := FileToAssetMetadata(appID, path, assetPath, info, desc, attributeRules, assetCache, compression)
+		if fileErr != nil {
+			return fileErr
 		}
 
+		*assetMetadata = append(*assetMetadata, *am)
+
 		return nil
 	}
 }
 
 // FileToAssetMetadata generates a file hash for the given file
 // and generates the assetAttributes and creates an AssetMetadata from these
+// if the file has no AssetDescription, its attributes are given by the attributeRules matching it
+// if the file is compressible, its hash and size are those of the body compressed with compression
+// and its Content-Encoding is set accordingly
 // if the file hash has changed this will update the assetCache
-func FileToAssetMetadata(appID, path, assetPath string, info os.FileInfo, desc *AssetDescription, assetCache AssetCache) (*AssetMetadata, error) {
+func FileToAssetMetadata(appID, path, assetPath string, info os.FileInfo, desc *AssetDescription, attributeRules AttributeRules, assetCache AssetCache, compression Compression) (*AssetMetadata, error) {
+	attrs := assetAttributes(assetPath, desc, attributeRules)
 
-	attrs := []AssetAttribute{}
-	if desc != nil {
-		attrs = desc.Attrs
-	} else {
-		// This asset doesn't have an entry in the metadata. Try to assign a Content-Type
-		// based on the file extension, if possible.
-		if extension := filepath.Ext(assetPath); extension != "" {
-			if contentType, ok := utils.GetContentTypeByExtension(extension[1:]); ok {
-				attrs = []AssetAttribute{
-					{Name: "Content-Type", Value: contentType},
-				}
-			}
+	if !compression.appliesTo(assetPath, attrs) {
+		compression = CompressionNone
+	}
+
+	newAssetMetadata := func(ace AssetCacheEntry) *AssetMetadata {
+		if compression == CompressionNone {
+			return NewAssetMetadata(appID, assetPath, ace.FileHash, info.Size(), attrs, info.ModTime().Unix())
 		}
+
+		compressedAttrs := append(append([]AssetAttribute{}, attrs...), AssetAttribute{Name: AttributeContentEncoding, Value: string(compression)})
+		am := NewAssetMetadata(appID, assetPath, ace.CompressedHash, ace.CompressedSize, compressedAttrs, info.ModTime().Unix())
+		am.Compression = compression
+		return am
 	}
 
 	// check cache for file hash
 	if ace, ok := assetCache.Get(appID, assetPath); ok {
-		if ace.FileSize == info.Size() && ace.LastModified == info.ModTime().Unix() {
-			return NewAssetMetadata(appID, assetPath, ace.FileHash, info.Size(), attrs, info.ModTime().Unix()), nil
+		if ace.FileSize == info.Size() && ace.LastModified == info.ModTime().Unix() &&
+			(compression == CompressionNone || (ace.Compression == compression && ace.CompressedHash != "")) {
+			return newAssetMetadata(ace), nil
 		}
 	}
 
@@ -100,19 +122,80 @@ func FileToAssetMetadata(appID, path, assetPath string, info os.FileInfo, desc *
 		return nil, err
 	}
 
-	assetCache.Set(appID, AssetCacheEntry{
-		assetPath,
-		info.ModTime().Unix(),
-		info.Size(),
-		generated,
-	})
+	ace := AssetCacheEntry{
+		FilePath:     assetPath,
+		LastModified: info.ModTime().Unix(),
+		FileSize:     info.Size(),
+		FileHash:     generated,
+	}
+
+	if compression != CompressionNone {
+		compressed, err := compression.compressFile(path)
+		if err != nil {
+			return nil, err
+		}
+		ace.Compression = compression
+		ace.CompressedHash = hashBytes(compressed)
+		ace.CompressedSize = int64(len(compressed))
+	}
 
-	return NewAssetMetadata(appID, assetPath, generated, info.Size(), attrs, info.ModTime().Unix()), nil
+	assetCache.Set(appID, ace)
+
+	return newAssetMetadata(ace), nil
+}
+
+// assetAttributes returns the attributes of the asset at assetPath, which are those of its AssetDescription
+// if it has one, and otherwise the Content-Type of its file extension overridden by the attributeRules matching it
+func assetAttributes(assetPath string, desc *AssetDescription, attributeRules AttributeRules) []AssetAttribute {
+	if desc != nil {
+		return desc.Attrs
+	}
+
+	attrs := []AssetAttribute{}
+	// This asset doesn't have an entry in the metadata. Try to assign a Content-Type
+	// based on the file extension, if possible.
+	if extension := filepath.Ext(assetPath); extension != "" {
+		if contentType, ok := utils.GetContentTypeByExtension(extension[1:]); ok {
+			attrs = []AssetAttribute{
+				{Name: "Content-Type", Value: contentType},
+			}
+		}
+	}
+	return attributeRules.Apply(assetPath, attrs)
 }
 
 // MetadataFileToAssetDescriptions attempts to open the file at the path given
-// and build AssetDescriptions from this file
+// and build AssetDescriptions from the entries of this file that are not attribute rules
 func MetadataFileToAssetDescriptions(path string) (map[string]AssetDescription, error) {
+	descs, err := readMetadataFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	descM := make(map[string]AssetDescription, len(descs))
+	for _, desc := range descs {
+		descFilePath := replacePathSeparator(desc.FilePath)
+		if isAttributeRulePattern(descFilePath) {
+			continue
+		}
+		descM[descFilePath] = desc
+	}
+
+	return descM, nil
+}
+
+// MetadataFileToAttributeRules attempts to open the file at the path given
+// and build AttributeRules from the entries of this file whose paths are glob patterns
+func MetadataFileToAttributeRules(path string) (AttributeRules, error) {
+	descs, err := readMetadataFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	return NewAttributeRules(descs)
+}
+
+func readMetadataFile(path string) ([]AssetDescription, error) {
 	f, err := os.Open(path)
 	if err != nil {
 		return nil, err
@@ -126,13 +209,7 @@ func MetadataFileToAssetDescriptions(path string) (map[string]AssetDescription,
 		return nil, decErr
 	}
 
-	descM := make(map[string]AssetDescription, len(descs))
-	for _, desc := range descs {
-		descFilePath := replacePathSeparator(desc.FilePath)
-		descM[descFilePath] = desc
-	}
-
-	return descM, nil
+	return descs, nil
 }
 
 // CacheFileToAssetCache attempts to open the file at the path given
@@ -153,33 +230,29 @@ func CacheFileToAssetCache(path string) (AssetCache, error) {
 	return &assetCache, nil
 }
 
-// UpdateCacheFile attempts to update the file at the path given
-// with the AssetCache passed in
+// UpdateCacheFile attempts to update the file at the path given with the entries of the AssetCache passed in.
+// The entries are merged into those already stored, which other processes may have written since the file was
+// read, and the file is replaced atomically while holding a lock on it
 func UpdateCacheFile(path string, assetCache AssetCache) error {
-	mAssetCache, mErr := json.Marshal(assetCache)
-	if mErr != nil {
-		return mErr
-	}
-
-	f, err := os.Create(path)
-	if err != nil {
-		return err
-	}
-
-	_, wErr := f.Write(mAssetCache)
-	if wErr != nil {
-		return wErr
-	}
-
-	return f.Close()
+	return updateCacheFileLocked(path, func(stored AssetCache) {
+		for appID, aces := range assetCache.Entries() {
+			for _, ace := range aces {
+				stored.Set(appID, ace)
+			}
+		}
+	})
 }
 
 // DiffAssetMetadata compares a local and remote []AssetMetadata and returns a AssetMetadataDiffs
 // which contains information about the differences between the two.
-// If the merge parameter is true, we ignore deleted assets
+// An asset added locally with the same contents as a remote asset is renamed rather than uploaded,
+// by moving the remote asset if it was deleted locally, or otherwise copying it.
+// If the merge parameter is true, we ignore deleted assets, so that a file renamed locally
+// is copied from the remote asset at its old path, which is kept
 func DiffAssetMetadata(local, remote []AssetMetadata, merge bool) *AssetMetadataDiffs {
 	var addedLocally []AssetMetadata
 	var modifiedLocally []ModifiedAssetMetadata
+	var kept []AssetMetadata
 	remoteAM := AssetsMetadata(remote).MapByPath()
 
 	// Ignore the root directory
@@ -192,6 +265,8 @@ func DiffAssetMetadata(local, remote []AssetMetadata, merge bool) *AssetMetadata
 			modifiedAM := GetModifiedAssetMetadata(lAM, rAM)
 			if modifiedAM.BodyModified || modifiedAM.AttrModified {
 				modifiedLocally = append(modifiedLocally, modifiedAM)
+			} else {
+				kept = append(kept, rAM)
 			}
 			delete(remoteAM, lAM.FilePath)
 		}
@@ -200,13 +275,19 @@ func DiffAssetMetadata(local, remote []AssetMetadata, merge bool) *AssetMetadata
 	var deletedLocally []AssetMetadata
 	//at this point the remoteAM map only contains AssetMetadata that were deleted locally
 	//if this is a merge then just ignore files deleted locally
-	if !merge {
-		for _, rAM := range remoteAM {
+	for _, rAM := range remoteAM {
+		if merge {
+			kept = append(kept, rAM)
+		} else {
 			deletedLocally = append(deletedLocally, rAM)
 		}
 	}
 
-	return NewAssetMetadataDiffs(addedLocally, deletedLocally, modifiedLocally)
+	addedLocally, deletedLocally, renamedLocally := detectRenames(addedLocally, deletedLocally, kept)
+
+	diffs := NewAssetMetadataDiffs(addedLocally, deletedLocally, modifiedLocally)
+	diffs.RenamedLocally = renamedLocally
+	return diffs
 }
 
 // Diff returns a list of strings representing the diff
@@ -234,9 +315,110 @@ func (amd *AssetMetadataDiffs) Diff() []string {
 		diff = append(diff, fmt.Sprintf("\t* %s", modified.AssetMetadata.FilePath))
 	}
 
+	if len(amd.RenamedLocally) > 0 {
+		diff = append(diff, "Renamed Files:")
+	}
+	for _, renamed := range amd.RenamedLocally {
+		diff = append(diff, renamedDiffLine(renamed.FromPath, renamed.AssetMetadata.FilePath, renamed.Copy))
+	}
+
 	return diff
 }
 
+// DefaultInvalidationThreshold is the number of changed paths above which
+// they are collapsed into directory wildcards when invalidating the CDN cache
+const DefaultInvalidationThreshold = 20
+
+// renamedDiffLine describes the move, or copy, of the asset at fromPath to toPath
+func renamedDiffLine(fromPath, toPath string, copy bool) string {
+	line := fmt.Sprintf("\t> %s -> %s", fromPath, toPath)
+	if copy {
+		line += " (copy)"
+	}
+	return line
+}
+
+// InvalidationPaths returns the CDN cache paths to invalidate for the added, deleted, renamed and modified assets.
+// When there are more than threshold of them, the paths sharing the most common directory are repeatedly
+// collapsed into a wildcard for that directory, down to a single wildcard for the whole site
+func (amd *AssetMetadataDiffs) InvalidationPaths(threshold int) []string {
+	var paths []string
+	for _, added := range amd.AddedLocally {
+		paths = append(paths, added.FilePath)
+	}
+	for _, deleted := range amd.DeletedLocally {
+		paths = append(paths, deleted.FilePath)
+	}
+	for _, modified := range amd.ModifiedLocally {
+		paths = append(paths, modified.AssetMetadata.FilePath)
+	}
+	for _, renamed := range amd.RenamedLocally {
+		paths = append(paths, renamed.AssetMetadata.FilePath)
+		if !renamed.Copy {
+			paths = append(paths, renamed.FromPath)
+		}
+	}
+
+	return collapseInvalidationPaths(paths, threshold)
+}
+
+func collapseInvalidationPaths(paths []string, threshold int) []string {
+	if threshold < 1 {
+		threshold = 1
+	}
+
+	set := make(map[string]bool, len(paths))
+	for _, p := range paths {
+		set[p] = true
+	}
+
+	for len(set) > threshold && !set[rootWildcard] {
+		counts := map[string]int{}
+		for p := range set {
+			counts[invalidationParent(p)]++
+		}
+
+		// prefer the directory with the most paths, then the deepest one so paths move up one level at a time
+		var dir string
+		for candidate, count := range counts {
+			if dir == "" ||
+				count > counts[dir] ||
+				count == counts[dir] && (strings.Count(candidate, "/") > strings.Count(dir, "/") ||
+					strings.Count(candidate, "/") == strings.Count(dir, "/") && candidate < dir) {
+				dir = candidate
+			}
+		}
+
+		wildcard := path.Join(dir, "*")
+		prefix := strings.TrimSuffix(dir, "/") + "/"
+		for p := range set {
+			if strings.HasPrefix(p, prefix) {
+				delete(set, p)
+			}
+		}
+		set[wildcard] = true
+	}
+
+	if set[rootWildcard] {
+		return []string{rootWildcard}
+	}
+
+	collapsed := make([]string, 0, len(set))
+	for p := range set {
+		collapsed = append(collapsed, p)
+	}
+	sort.Strings(collapsed)
+	return collapsed
+}
+
+const rootWildcard = "/*"
+
+// invalidationParent returns the directory containing the path, where a wildcard
+// path is contained by the parent of the directory it covers
+func invalidationParent(p string) string {
+	return path.Dir(strings.TrimSuffix(p, "/*"))
+}
+
 // ReplacePathSeparator returns path with os dependent path separators replaced by uniform '/'
 func replacePathSeparator(path string) string {
 	return strings.ReplaceAll(path, string(os.PathSeparator), "/")
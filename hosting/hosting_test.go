@@ -56,7 +56,7 @@ func TestListLocalAssetMetadata(t *testing.T) {
 			},
 		},
 	}
-	assetMetadata, listErr := hosting.ListLocalAssetMetadata(appID, rootDir, assetDescriptions, assetCache)
+	assetMetadata, listErr := hosting.ListLocalAssetMetadata(appID, rootDir, assetDescriptions, nil, assetCache, nil, hosting.CompressionNone, false)
 	u.So(t, listErr, gc.ShouldBeNil)
 
 	localPath0, localPath1, localPath2 := filepath.Join(filesRoot, path0), filepath.Join(filesRoot, path1), filepath.Join(filesRoot, path2)
@@ -107,10 +107,26 @@ func TestListLocalAssetMetadata(t *testing.T) {
 			Attrs:    []hosting.AssetAttribute{jsonAttr},
 		},
 	}
-	_, listErr = hosting.ListLocalAssetMetadata(appID, rootDir, assetDescriptions, assetCache)
+	_, listErr = hosting.ListLocalAssetMetadata(appID, rootDir, assetDescriptions, nil, assetCache, nil, hosting.CompressionNone, false)
 	expectedError := fmt.Sprintf("file '%s' has an entry in metadata file, but does not appear in files directory", path3)
 	u.So(t, listErr.Error(), gc.ShouldEqual, expectedError)
 
+	t.Run("should skip files excluded by the ignore rules", func(t *testing.T) {
+		ignoreRules, err := hosting.NewIgnoreRules([]string{"ships/", "*.html"})
+		u.So(t, err, gc.ShouldBeNil)
+
+		assetMetadata, err := hosting.ListLocalAssetMetadata(appID, rootDir, nil, nil, hosting.NewAssetCache(), ignoreRules, hosting.CompressionNone, false)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, len(assetMetadata), gc.ShouldEqual, 1)
+		u.So(t, assetMetadata[0].FilePath, gc.ShouldEqual, path0)
+
+		_, err = hosting.ListLocalAssetMetadata(appID, rootDir, map[string]hosting.AssetDescription{
+			path1: {FilePath: path1},
+		}, nil, hosting.NewAssetCache(), ignoreRules, hosting.CompressionNone, false)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEqual, fmt.Sprintf("file '%s' has an entry in metadata file, but is excluded by .realmignore or --exclude", path1))
+	})
+
 	t.Run("asset cache should be updated from local listing", func(t *testing.T) {
 		entry, ok := assetCache.Get(testAppID, path0)
 		u.So(t, ok, gc.ShouldBeTrue)
@@ -253,10 +269,10 @@ func TestUpdateCacheFile(t *testing.T) {
 	fileSize := int64(12)
 	fileHash := "l3in5h1p"
 	assetCacheEntry := hosting.AssetCacheEntry{
-		filePath,
-		lastModified,
-		fileSize,
-		fileHash,
+		FilePath:     filePath,
+		LastModified: lastModified,
+		FileSize:     fileSize,
+		FileHash:     fileHash,
 	}
 
 	assetCache := hosting.NewAssetCache()
@@ -281,10 +297,10 @@ func TestUpdateCacheFile(t *testing.T) {
 	t.Run("when a second update occurs the original data should be intact", func(t *testing.T) {
 		newFilePath := "slowShip"
 		newAssetCacheEntry := hosting.AssetCacheEntry{
-			newFilePath,
-			lastModified,
-			fileSize,
-			fileHash,
+			FilePath:     newFilePath,
+			LastModified: lastModified,
+			FileSize:     fileSize,
+			FileHash:     fileHash,
 		}
 		updatedCache.Set(appID, newAssetCacheEntry)
 
@@ -304,10 +320,10 @@ func TestAssetCache(t *testing.T) {
 	fileSize := int64(12)
 	fileHash := "l3in5h1p"
 	assetCacheEntry := hosting.AssetCacheEntry{
-		filePath,
-		lastModified,
-		fileSize,
-		fileHash,
+		FilePath:     filePath,
+		LastModified: lastModified,
+		FileSize:     fileSize,
+		FileHash:     fileHash,
 	}
 	assetCache := hosting.NewAssetCache()
 	assetCache.Set(appID, assetCacheEntry)
@@ -330,10 +346,10 @@ func TestAssetCache(t *testing.T) {
 
 	fp0 := "/hello/there"
 	setEntry := hosting.AssetCacheEntry{
-		fp0,
-		int64(10887),
-		int64(66),
-		"0rd3r",
+		FilePath:     fp0,
+		LastModified: int64(10887),
+		FileSize:     int64(66),
+		FileHash:     "0rd3r",
 	}
 
 	t.Run("Set should work for an existing appID", func(t *testing.T) {
@@ -354,10 +370,10 @@ func TestAssetCache(t *testing.T) {
 
 func TestRoundTripAssetCacheEntry(t *testing.T) {
 	cacheEntry := hosting.AssetCacheEntry{
-		"/fast/ship",
-		int64(10887),
-		int64(12),
-		"l3in5h1p",
+		FilePath:     "/fast/ship",
+		LastModified: int64(10887),
+		FileSize:     int64(12),
+		FileHash:     "l3in5h1p",
 	}
 
 	md, mErr := json.Marshal(cacheEntry)
@@ -686,3 +702,84 @@ func TestAssetMetadataDiff(t *testing.T) {
 		u.So(t, amd.Diff(), gc.ShouldResemble, append(append(addDiff, deleteDiff...), modifyDiff...))
 	})
 }
+
+func TestInvalidationPaths(t *testing.T) {
+	assetMetadataDiffs := func(paths ...string) *hosting.AssetMetadataDiffs {
+		var added []hosting.AssetMetadata
+		for _, path := range paths {
+			added = append(added, hosting.AssetMetadata{FilePath: path})
+		}
+		return hosting.NewAssetMetadataDiffs(added, nil, nil)
+	}
+
+	for _, tc := range []struct {
+		description string
+		diffs       *hosting.AssetMetadataDiffs
+		threshold   int
+		expected    []string
+	}{
+		{
+			description: "should return nothing when no assets changed",
+			diffs:       assetMetadataDiffs(),
+			threshold:   5,
+			expected:    []string{},
+		},
+		{
+			description: "should return the changed paths within the threshold",
+			diffs: hosting.NewAssetMetadataDiffs(
+				[]hosting.AssetMetadata{{FilePath: "/index.html"}},
+				[]hosting.AssetMetadata{{FilePath: "/old.css"}},
+				[]hosting.ModifiedAssetMetadata{{AssetMetadata: hosting.AssetMetadata{FilePath: "/js/app.js"}}},
+			),
+			threshold: 3,
+			expected:  []string{"/index.html", "/js/app.js", "/old.css"},
+		},
+		{
+			description: "should collapse the directory with the most changed paths first",
+			diffs:       assetMetadataDiffs("/index.html", "/img/a.png", "/img/b.png", "/img/c.png", "/js/app.js"),
+			threshold:   3,
+			expected:    []string{"/img/*", "/index.html", "/js/app.js"},
+		},
+		{
+			description: "should collapse nested directories into their parent",
+			diffs:       assetMetadataDiffs("/img/a/1.png", "/img/a/2.png", "/img/b/1.png", "/img/b/2.png", "/index.html"),
+			threshold:   2,
+			expected:    []string{"/img/*", "/index.html"},
+		},
+		{
+			description: "should collapse everything into a single wildcard",
+			diffs:       assetMetadataDiffs("/index.html", "/img/a.png", "/js/app.js"),
+			threshold:   1,
+			expected:    []string{"/*"},
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			u.So(t, tc.diffs.InvalidationPaths(tc.threshold), gc.ShouldResemble, tc.expected)
+		})
+	}
+}
+
+func TestParseAssetAttribute(t *testing.T) {
+	for _, tc := range []struct {
+		pair     string
+		expected hosting.AssetAttribute
+		err      string
+	}{
+		{pair: "Content-Type=text/html", expected: hosting.AssetAttribute{Name: "Content-Type", Value: "text/html"}},
+		{pair: "cache-control=max-age=60", expected: hosting.AssetAttribute{Name: "Cache-Control", Value: "max-age=60"}},
+		{pair: "Content-Language=", expected: hosting.AssetAttribute{Name: "Content-Language", Value: ""}},
+		{pair: "Content-Type", err: `attribute "Content-Type" must be of the form Name=Value`},
+		{pair: "X-Custom=1", err: `unsupported attribute "X-Custom"`},
+	} {
+		t.Run(tc.pair, func(t *testing.T) {
+			attr, err := hosting.ParseAssetAttribute(tc.pair)
+			if tc.err != "" {
+				u.So(t, err, gc.ShouldNotBeNil)
+				u.So(t, err.Error(), gc.ShouldEqual, tc.err)
+				return
+			}
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, attr, gc.ShouldResemble, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,184 @@
+package hosting
+
+import (
+	"bufio"
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// IgnoreFileName is the name of the file in the hosting files directory
+// listing the files that should not be uploaded, using gitignore syntax
+const IgnoreFileName = ".realmignore"
+
+// ignorePattern is a single line of an ignore file
+type ignorePattern struct {
+	pattern string
+	regex   *regexp.Regexp
+	negate  bool
+	dirOnly bool
+}
+
+// IgnoreRules decides which files in the hosting files directory are skipped
+// when building local AssetMetadata
+type IgnoreRules struct {
+	patterns []ignorePattern
+}
+
+// NewIgnoreRules builds IgnoreRules from lines in gitignore syntax,
+// where later lines take precedence over earlier ones
+func NewIgnoreRules(lines []string) (*IgnoreRules, error) {
+	rules := &IgnoreRules{}
+	for _, line := range lines {
+		if err := rules.add(line); err != nil {
+			return nil, err
+		}
+	}
+	return rules, nil
+}
+
+// LoadIgnoreRules builds IgnoreRules from the IgnoreFileName in rootDirectory, if there is one,
+// followed by the given exclude patterns
+func LoadIgnoreRules(rootDirectory string, excludes []string) (*IgnoreRules, error) {
+	var lines []string
+
+	f, err := os.Open(filepath.Join(rootDirectory, IgnoreFileName))
+	if err != nil && !os.IsNotExist(err) {
+		return nil, err
+	}
+	if err == nil {
+		defer f.Close()
+
+		scanner := bufio.NewScanner(f)
+		for scanner.Scan() {
+			lines = append(lines, scanner.Text())
+		}
+		if err := scanner.Err(); err != nil {
+			return nil, fmt.Errorf("failed to read %s: %s", IgnoreFileName, err)
+		}
+	}
+
+	return NewIgnoreRules(append(lines, excludes...))
+}
+
+func (ir *IgnoreRules) add(line string) error {
+	line = strings.TrimRight(strings.TrimSuffix(line, "\r"), " ")
+	if line == "" || strings.HasPrefix(line, "#") {
+		return nil
+	}
+
+	p := ignorePattern{pattern: line}
+
+	if strings.HasPrefix(line, "!") {
+		p.negate = true
+		line = line[1:]
+	} else if strings.HasPrefix(line, `\!`) || strings.HasPrefix(line, `\#`) {
+		line = line[1:]
+	}
+
+	if strings.HasSuffix(line, "/") {
+		p.dirOnly = true
+		line = strings.TrimSuffix(line, "/")
+	}
+
+	// patterns containing a slash are relative to the root, others match at any depth
+	prefix := "^(?:.*/)?"
+	if strings.Contains(line, "/") {
+		prefix = "^"
+		line = strings.TrimPrefix(line, "/")
+	}
+
+	if line == "" {
+		return nil
+	}
+
+	regex, err := regexp.Compile(prefix + globToRegex(line) + "$")
+	if err != nil {
+		return fmt.Errorf("invalid ignore pattern %q: %s", p.pattern, err)
+	}
+	p.regex = regex
+
+	ir.patterns = append(ir.patterns, p)
+	return nil
+}
+
+// globToRegex converts a gitignore glob into the equivalent regular expression
+func globToRegex(glob string) string {
+	var sb strings.Builder
+
+	for i := 0; i < len(glob); i++ {
+		c := glob[i]
+		switch {
+		case strings.HasPrefix(glob[i:], "**/"):
+			sb.WriteString("(?:.*/)?")
+			i += 2
+		case strings.HasPrefix(glob[i:], "/**") && i+3 == len(glob):
+			sb.WriteString("/.*")
+			i += 2
+		case strings.HasPrefix(glob[i:], "**"):
+			sb.WriteString(".*")
+			i++
+		case c == '*':
+			sb.WriteString("[^/]*")
+		case c == '?':
+			sb.WriteString("[^/]")
+		case c == '\\' && i+1 < len(glob):
+			i++
+			sb.WriteString(regexp.QuoteMeta(string(glob[i])))
+		case c == '[':
+			end := strings.IndexByte(glob[i+1:], ']')
+			if end == -1 {
+				sb.WriteString(`\[`)
+				continue
+			}
+			class := glob[i+1 : i+1+end]
+			if strings.HasPrefix(class, "!") {
+				class = "^" + class[1:]
+			}
+			sb.WriteString("[" + strings.ReplaceAll(class, `\`, `\\`) + "]")
+			i += end + 1
+		default:
+			sb.WriteString(regexp.QuoteMeta(string(c)))
+		}
+	}
+
+	return sb.String()
+}
+
+// match reports whether the path itself is ignored, without considering its parent directories
+func (ir *IgnoreRules) match(relPath string, isDir bool) bool {
+	ignored := false
+	for _, p := range ir.patterns {
+		if p.dirOnly && !isDir {
+			continue
+		}
+		if p.regex.MatchString(relPath) {
+			ignored = !p.negate
+		}
+	}
+	return ignored
+}
+
+// Ignored reports whether the slash-separated path relative to the hosting files directory is ignored,
+// either by a matching pattern or because one of its parent directories is ignored
+func (ir *IgnoreRules) Ignored(relPath string, isDir bool) bool {
+	if ir == nil {
+		return false
+	}
+
+	relPath = strings.TrimPrefix(relPath, "/")
+	if relPath == IgnoreFileName {
+		return true
+	}
+
+	parts := strings.Split(relPath, "/")
+	for i := 1; i < len(parts); i++ {
+		if ir.match(strings.Join(parts[:i], "/"), true) {
+			return true
+		}
+	}
+
+	return ir.match(relPath, isDir)
+}
@@ -0,0 +1,69 @@
+package hosting_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestIgnoreRules(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		lines       []string
+		path        string
+		isDir       bool
+		ignored     bool
+	}{
+		{"should ignore a file name at any depth", []string{".DS_Store"}, "/images/.DS_Store", false, true},
+		{"should match wildcards within a path segment", []string{"*.map"}, "/js/app.js.map", false, true},
+		{"should not match wildcards across path segments", []string{"js/*.map"}, "/js/vendor/lib.js.map", false, false},
+		{"should anchor patterns containing a slash", []string{"/build"}, "/src/build", false, false},
+		{"should match an anchored pattern at the root", []string{"/build"}, "/build", false, true},
+		{"should match any number of directories with **", []string{"docs/**/*.md"}, "/docs/a/b/readme.md", false, true},
+		{"should match everything inside a directory with /**", []string{"tmp/**"}, "/tmp/a/b.txt", false, true},
+		{"should match files within an ignored directory", []string{"node_modules/"}, "/node_modules/lib/index.js", false, true},
+		{"should only match directories with a trailing slash", []string{"cache/"}, "/cache", false, false},
+		{"should re-include negated files", []string{"*.txt", "!robots.txt"}, "/robots.txt", false, false},
+		{"should apply the last matching pattern", []string{"!robots.txt", "*.txt"}, "/robots.txt", false, true},
+		{"should match character classes", []string{"*.sw[op]"}, "/index.html.swp", false, true},
+		{"should skip comments and blank lines", []string{"# *.html", "", "   "}, "/index.html", false, false},
+		{"should match escaped special characters", []string{`\#notes`}, "/#notes", false, true},
+		{"should always ignore the ignore file itself", nil, "/.realmignore", false, true},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			rules, err := hosting.NewIgnoreRules(tc.lines)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, rules.Ignored(tc.path, tc.isDir), gc.ShouldEqual, tc.ignored)
+		})
+	}
+
+	t.Run("should not ignore anything without rules", func(t *testing.T) {
+		var rules *hosting.IgnoreRules
+		u.So(t, rules.Ignored("/.DS_Store", false), gc.ShouldBeFalse)
+	})
+
+	t.Run("should load the ignore file followed by the excludes", func(t *testing.T) {
+		dir, err := ioutil.TempDir("", "realm-cli-ignore")
+		u.So(t, err, gc.ShouldBeNil)
+		defer os.RemoveAll(dir)
+
+		u.So(t, ioutil.WriteFile(filepath.Join(dir, hosting.IgnoreFileName), []byte("*.map\n!keep.map\n"), 0600), gc.ShouldBeNil)
+
+		rules, err := hosting.LoadIgnoreRules(dir, []string{"*.psd"})
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, rules.Ignored("/app.js.map", false), gc.ShouldBeTrue)
+		u.So(t, rules.Ignored("/keep.map", false), gc.ShouldBeFalse)
+		u.So(t, rules.Ignored("/design.psd", false), gc.ShouldBeTrue)
+		u.So(t, rules.Ignored("/index.html", false), gc.ShouldBeFalse)
+
+		rules, err = hosting.LoadIgnoreRules(filepath.Join(dir, "missing"), nil)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, rules.Ignored("/app.js.map", false), gc.ShouldBeFalse)
+	})
+}
@@ -2,6 +2,7 @@ package hosting
 
 import (
 	"encoding/json"
+	"fmt"
 	"path"
 	"sort"
 	"strings"
@@ -29,6 +30,32 @@ var ValidAttributeNames = map[string]bool{
 	AttributeWebsiteRedirectLocation: true,
 }
 
+// ParseAssetAttribute parses a Name=Value pair into an AssetAttribute, matching the name
+// against ValidAttributeNames case-insensitively
+func ParseAssetAttribute(pair string) (AssetAttribute, error) {
+	idx := strings.Index(pair, "=")
+	if idx == -1 {
+		return AssetAttribute{}, fmt.Errorf("attribute %q must be of the form Name=Value", pair)
+	}
+
+	name, err := CanonicalAttributeName(pair[:idx])
+	if err != nil {
+		return AssetAttribute{}, err
+	}
+
+	return AssetAttribute{Name: name, Value: pair[idx+1:]}, nil
+}
+
+// CanonicalAttributeName returns the name of the valid attribute matching name case-insensitively
+func CanonicalAttributeName(name string) (string, error) {
+	for validName := range ValidAttributeNames {
+		if strings.EqualFold(validName, name) {
+			return validName, nil
+		}
+	}
+	return "", fmt.Errorf("unsupported attribute %q", name)
+}
+
 // AssetMetadata represents the metadata of a static hosted asset
 type AssetMetadata struct {
 	AppID        string           `json:"appId,omitempty"`
@@ -38,6 +65,10 @@ type AssetMetadata struct {
 	Attrs        []AssetAttribute `json:"attrs"`
 	LastModified int64            `json:"last_modified,omitempty"`
 	URL          string           `json:"url,omitempty"`
+
+	// Compression is applied to the local file before it is uploaded,
+	// in which case FileHash and FileSize describe the compressed body
+	Compression Compression `json:"-"`
 }
 
 // IsDir is true if the asset represents a directory
@@ -170,12 +201,22 @@ func GetModifiedAssetMetadata(local, remote AssetMetadata) ModifiedAssetMetadata
 	}
 }
 
+// RenamedAssetMetadata is an asset added locally with the same contents as the remote asset at FromPath,
+// which is moved to it rather than uploaded again, or copied if Copy is set because the remote asset is kept
+type RenamedAssetMetadata struct {
+	AssetMetadata AssetMetadata
+	FromPath      string
+	Copy          bool
+	AttrModified  bool
+}
+
 // AssetMetadataDiffs represents a set of
-//locally deleted, locally added, and locally modified AssetMetadata
+//locally deleted, locally added, locally renamed and locally modified AssetMetadata
 type AssetMetadataDiffs struct {
 	AddedLocally    []AssetMetadata
 	DeletedLocally  []AssetMetadata
 	ModifiedLocally []ModifiedAssetMetadata
+	RenamedLocally  []RenamedAssetMetadata
 }
 
 // NewAssetMetadataDiffs is a constructor for AssetMetadataDiffs
@@ -193,6 +234,11 @@ type AssetCacheEntry struct {
 	LastModified int64  `json:"last_modified,omitempty"`
 	FileSize     int64  `json:"size,omitempty"`
 	FileHash     string `json:"hash,omitempty"`
+
+	// the hash and size of the file's body compressed with Compression, if any
+	Compression    Compression `json:"compression,omitempty"`
+	CompressedHash string      `json:"compressed_hash,omitempty"`
+	CompressedSize int64       `json:"compressed_size,omitempty"`
 }
 
 // entryMap is a map of appID to filePath to AssetCacheEntry
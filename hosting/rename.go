@@ -0,0 +1,97 @@
+package hosting
+
+import (
+	"sort"
+	"strings"
+)
+
+// renameKey identifies the stored contents of an asset, which are the same for two assets
+// with the same hash and Content-Encoding
+type renameKey struct {
+	fileHash        string
+	contentEncoding string
+}
+
+func renameKeyOf(am AssetMetadata) renameKey {
+	key := renameKey{fileHash: am.FileHash}
+	for _, attr := range am.Attrs {
+		if strings.EqualFold(attr.Name, AttributeContentEncoding) {
+			key.contentEncoding = attr.Value
+		}
+	}
+	return key
+}
+
+// detectRenames matches the assets added locally with a remote asset of the same contents, which is moved
+// if it was deleted locally or copied if it is kept. Each deleted asset is moved at most once, and the
+// added and deleted assets that were not matched are returned along with the renames
+func detectRenames(added, deleted, kept []AssetMetadata) ([]AssetMetadata, []AssetMetadata, []RenamedAssetMetadata) {
+	if len(added) == 0 || len(deleted)+len(kept) == 0 {
+		return added, deleted, nil
+	}
+
+	deletedByKey := map[renameKey][]AssetMetadata{}
+	for _, am := range sortedByPath(deleted) {
+		key := renameKeyOf(am)
+		deletedByKey[key] = append(deletedByKey[key], am)
+	}
+
+	keptByKey := map[renameKey]AssetMetadata{}
+	for _, am := range sortedByPath(kept) {
+		key := renameKeyOf(am)
+		if _, ok := keptByKey[key]; !ok {
+			keptByKey[key] = am
+		}
+	}
+
+	var remainingAdded []AssetMetadata
+	var renamed []RenamedAssetMetadata
+	moved := map[string]bool{}
+
+	for _, am := range added {
+		if am.FileHash == "" {
+			remainingAdded = append(remainingAdded, am)
+			continue
+		}
+
+		key := renameKeyOf(am)
+		if candidates := deletedByKey[key]; len(candidates) > 0 {
+			from := candidates[0]
+			deletedByKey[key] = candidates[1:]
+			moved[from.FilePath] = true
+			renamed = append(renamed, RenamedAssetMetadata{
+				AssetMetadata: am,
+				FromPath:      from.FilePath,
+				AttrModified:  !AssetAttributesEqual(am.Attrs, from.Attrs),
+			})
+			continue
+		}
+
+		if from, ok := keptByKey[key]; ok {
+			renamed = append(renamed, RenamedAssetMetadata{
+				AssetMetadata: am,
+				FromPath:      from.FilePath,
+				Copy:          true,
+				AttrModified:  !AssetAttributesEqual(am.Attrs, from.Attrs),
+			})
+			continue
+		}
+
+		remainingAdded = append(remainingAdded, am)
+	}
+
+	var remainingDeleted []AssetMetadata
+	for _, am := range deleted {
+		if !moved[am.FilePath] {
+			remainingDeleted = append(remainingDeleted, am)
+		}
+	}
+
+	return remainingAdded, remainingDeleted, renamed
+}
+
+func sortedByPath(assetMetadata []AssetMetadata) []AssetMetadata {
+	sorted := append([]AssetMetadata{}, assetMetadata...)
+	sort.Slice(sorted, func(i, j int) bool { return sorted[i].FilePath < sorted[j].FilePath })
+	return sorted
+}
@@ -0,0 +1,108 @@
+package hosting_test
+
+import (
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiffAssetMetadataRenames(t *testing.T) {
+	htmlAttrs := []hosting.AssetAttribute{{Name: hosting.AttributeContentType, Value: "text/html"}}
+	pngAttrs := []hosting.AssetAttribute{{Name: hosting.AttributeContentType, Value: "image/png"}}
+	gzipAttrs := []hosting.AssetAttribute{
+		{Name: hosting.AttributeContentType, Value: "text/html"},
+		{Name: hosting.AttributeContentEncoding, Value: "gzip"},
+	}
+
+	asset := func(path, hash string, attrs []hosting.AssetAttribute) hosting.AssetMetadata {
+		return hosting.AssetMetadata{FilePath: path, FileHash: hash, Attrs: attrs}
+	}
+
+	t.Run("should move a remote asset deleted locally to the added path with the same hash", func(t *testing.T) {
+		diffs := hosting.DiffAssetMetadata(
+			[]hosting.AssetMetadata{asset("/docs/new.html", "h1", htmlAttrs)},
+			[]hosting.AssetMetadata{asset("/docs/old.html", "h1", htmlAttrs)},
+			false,
+		)
+
+		u.So(t, diffs.AddedLocally, gc.ShouldBeEmpty)
+		u.So(t, diffs.DeletedLocally, gc.ShouldBeEmpty)
+		u.So(t, diffs.RenamedLocally, gc.ShouldResemble, []hosting.RenamedAssetMetadata{
+			{AssetMetadata: asset("/docs/new.html", "h1", htmlAttrs), FromPath: "/docs/old.html"},
+		})
+		u.So(t, diffs.Diff(), gc.ShouldResemble, []string{"Renamed Files:", "\t> /docs/old.html -> /docs/new.html"})
+		u.So(t, diffs.InvalidationPaths(hosting.DefaultInvalidationThreshold), gc.ShouldResemble, []string{"/docs/new.html", "/docs/old.html"})
+	})
+
+	t.Run("should set the attributes of a moved asset that differ", func(t *testing.T) {
+		diffs := hosting.DiffAssetMetadata(
+			[]hosting.AssetMetadata{asset("/logo.png", "h1", pngAttrs)},
+			[]hosting.AssetMetadata{asset("/logo.html", "h1", htmlAttrs)},
+			false,
+		)
+
+		u.So(t, diffs.RenamedLocally, gc.ShouldHaveLength, 1)
+		u.So(t, diffs.RenamedLocally[0].AttrModified, gc.ShouldBeTrue)
+	})
+
+	t.Run("should move a remote asset only once and copy a kept asset", func(t *testing.T) {
+		diffs := hosting.DiffAssetMetadata(
+			[]hosting.AssetMetadata{
+				asset("/a.html", "h1", htmlAttrs),
+				asset("/b.html", "h1", htmlAttrs),
+				asset("/c.html", "h1", htmlAttrs),
+				asset("/d.html", "h2", htmlAttrs),
+			},
+			[]hosting.AssetMetadata{
+				asset("/a.html", "h1", htmlAttrs),
+				asset("/old.html", "h1", htmlAttrs),
+				asset("/gone.html", "h3", htmlAttrs),
+			},
+			false,
+		)
+
+		u.So(t, diffs.AddedLocally, gc.ShouldResemble, []hosting.AssetMetadata{asset("/d.html", "h2", htmlAttrs)})
+		u.So(t, diffs.DeletedLocally, gc.ShouldResemble, []hosting.AssetMetadata{asset("/gone.html", "h3", htmlAttrs)})
+		u.So(t, diffs.RenamedLocally, gc.ShouldResemble, []hosting.RenamedAssetMetadata{
+			{AssetMetadata: asset("/b.html", "h1", htmlAttrs), FromPath: "/old.html"},
+			{AssetMetadata: asset("/c.html", "h1", htmlAttrs), FromPath: "/a.html", Copy: true},
+		})
+		u.So(t, diffs.Diff(), gc.ShouldResemble, []string{
+			"New Files:",
+			"\t+ /d.html",
+			"Removed Files:",
+			"\t- /gone.html",
+			"Renamed Files:",
+			"\t> /old.html -> /b.html",
+			"\t> /a.html -> /c.html (copy)",
+		})
+	})
+
+	t.Run("should copy a remote asset that is kept when merging", func(t *testing.T) {
+		diffs := hosting.DiffAssetMetadata(
+			[]hosting.AssetMetadata{asset("/new.html", "h1", htmlAttrs)},
+			[]hosting.AssetMetadata{asset("/old.html", "h1", htmlAttrs)},
+			true,
+		)
+
+		u.So(t, diffs.RenamedLocally, gc.ShouldResemble, []hosting.RenamedAssetMetadata{
+			{AssetMetadata: asset("/new.html", "h1", htmlAttrs), FromPath: "/old.html", Copy: true},
+		})
+		u.So(t, diffs.InvalidationPaths(hosting.DefaultInvalidationThreshold), gc.ShouldResemble, []string{"/new.html"})
+	})
+
+	t.Run("should not rename an asset stored with a different Content-Encoding", func(t *testing.T) {
+		diffs := hosting.DiffAssetMetadata(
+			[]hosting.AssetMetadata{asset("/new.html", "h1", gzipAttrs)},
+			[]hosting.AssetMetadata{asset("/old.html", "h1", htmlAttrs)},
+			false,
+		)
+
+		u.So(t, diffs.RenamedLocally, gc.ShouldBeEmpty)
+		u.So(t, diffs.AddedLocally, gc.ShouldHaveLength, 1)
+		u.So(t, diffs.DeletedLocally, gc.ShouldHaveLength, 1)
+	})
+}
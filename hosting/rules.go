@@ -0,0 +1,87 @@
+package hosting
+
+import (
+	"fmt"
+	"regexp"
+	"strings"
+)
+
+// AttributeRule sets the attributes of every asset whose path matches a glob pattern, such as "/static/**/*.js",
+// unless the asset has its own entry in the metadata file
+type AttributeRule struct {
+	Pattern string
+	Attrs   []AssetAttribute
+
+	regex *regexp.Regexp
+}
+
+// AttributeRules is a list of AttributeRule, where later rules take precedence over earlier ones
+type AttributeRules []AttributeRule
+
+// isAttributeRulePattern reports whether the path of a metadata file entry is a glob pattern rather than a file path
+func isAttributeRulePattern(path string) bool {
+	return strings.ContainsAny(path, "*?[")
+}
+
+// NewAttributeRules builds AttributeRules from the metadata file entries whose paths are glob patterns,
+// in the order they are listed. Every attribute must be one of ValidAttributeNames
+func NewAttributeRules(descs []AssetDescription) (AttributeRules, error) {
+	var rules AttributeRules
+
+	for _, desc := range descs {
+		pattern := replacePathSeparator(desc.FilePath)
+		if !isAttributeRulePattern(pattern) {
+			continue
+		}
+
+		if !strings.HasPrefix(pattern, "/") {
+			return nil, fmt.Errorf("attribute rule '%s' must start with '/'", pattern)
+		}
+
+		regex, err := regexp.Compile("^" + globToRegex(strings.TrimPrefix(pattern, "/")) + "$")
+		if err != nil {
+			return nil, fmt.Errorf("invalid attribute rule '%s': %s", pattern, err)
+		}
+
+		attrs := make([]AssetAttribute, 0, len(desc.Attrs))
+		for _, attr := range desc.Attrs {
+			name, err := CanonicalAttributeName(attr.Name)
+			if err != nil {
+				return nil, fmt.Errorf("attribute rule '%s' has an %s", pattern, err)
+			}
+			attrs = append(attrs, AssetAttribute{Name: name, Value: attr.Value})
+		}
+
+		rules = append(rules, AttributeRule{Pattern: pattern, Attrs: attrs, regex: regex})
+	}
+
+	return rules, nil
+}
+
+// Apply returns attrs with the attributes of every rule matching assetPath applied in order,
+// replacing any attributes of the same name
+func (rules AttributeRules) Apply(assetPath string, attrs []AssetAttribute) []AssetAttribute {
+	applied := append([]AssetAttribute{}, attrs...)
+
+	relPath := strings.TrimPrefix(assetPath, "/")
+	for _, rule := range rules {
+		if !rule.regex.MatchString(relPath) {
+			continue
+		}
+
+		for _, attr := range rule.Attrs {
+			replaced := false
+			for i := range applied {
+				if applied[i].Name == attr.Name {
+					applied[i].Value = attr.Value
+					replaced = true
+				}
+			}
+			if !replaced {
+				applied = append(applied, attr)
+			}
+		}
+	}
+
+	return applied
+}
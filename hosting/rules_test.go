@@ -0,0 +1,149 @@
+package hosting_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestAttributeRules(t *testing.T) {
+	rules, err := hosting.NewAttributeRules([]hosting.AssetDescription{
+		{FilePath: "/index.html", Attrs: []hosting.AssetAttribute{{Name: "Cache-Control", Value: "no-cache"}}},
+		{FilePath: "/static/**/*.js", Attrs: []hosting.AssetAttribute{{Name: "cache-control", Value: "max-age=31536000, immutable"}}},
+		{FilePath: "/static/legacy/*", Attrs: []hosting.AssetAttribute{
+			{Name: "Cache-Control", Value: "max-age=60"},
+			{Name: "Content-Language", Value: "fr"},
+		}},
+		{FilePath: "/*.txt", Attrs: []hosting.AssetAttribute{{Name: "Content-Type", Value: "text/plain; charset=utf-8"}}},
+	})
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, rules, gc.ShouldHaveLength, 3)
+
+	contentType := []hosting.AssetAttribute{{Name: "Content-Type", Value: "application/x-javascript"}}
+
+	for _, tc := range []struct {
+		path     string
+		attrs    []hosting.AssetAttribute
+		expected []hosting.AssetAttribute
+	}{
+		{
+			path:     "/index.html",
+			expected: []hosting.AssetAttribute{},
+		},
+		{
+			path:  "/static/js/main.1a2b3c.js",
+			attrs: contentType,
+			expected: []hosting.AssetAttribute{
+				{Name: "Content-Type", Value: "application/x-javascript"},
+				{Name: "Cache-Control", Value: "max-age=31536000, immutable"},
+			},
+		},
+		{
+			path:  "/static/app.js",
+			attrs: contentType,
+			expected: []hosting.AssetAttribute{
+				{Name: "Content-Type", Value: "application/x-javascript"},
+				{Name: "Cache-Control", Value: "max-age=31536000, immutable"},
+			},
+		},
+		{
+			path:  "/static/legacy/old.js",
+			attrs: contentType,
+			expected: []hosting.AssetAttribute{
+				{Name: "Content-Type", Value: "application/x-javascript"},
+				{Name: "Cache-Control", Value: "max-age=60"},
+				{Name: "Content-Language", Value: "fr"},
+			},
+		},
+		{
+			path:     "/robots.txt",
+			attrs:    []hosting.AssetAttribute{{Name: "Content-Type", Value: "text/plain"}},
+			expected: []hosting.AssetAttribute{{Name: "Content-Type", Value: "text/plain; charset=utf-8"}},
+		},
+		{
+			path:     "/docs/notes.txt",
+			attrs:    []hosting.AssetAttribute{{Name: "Content-Type", Value: "text/plain"}},
+			expected: []hosting.AssetAttribute{{Name: "Content-Type", Value: "text/plain"}},
+		},
+	} {
+		t.Run(tc.path, func(t *testing.T) {
+			u.So(t, rules.Apply(tc.path, tc.attrs), gc.ShouldResemble, tc.expected)
+		})
+	}
+
+	t.Run("should not modify the given attributes", func(t *testing.T) {
+		attrs := []hosting.AssetAttribute{{Name: "Cache-Control", Value: "no-store"}}
+		rules.Apply("/static/legacy/old.js", attrs)
+		u.So(t, attrs, gc.ShouldResemble, []hosting.AssetAttribute{{Name: "Cache-Control", Value: "no-store"}})
+	})
+
+	for _, tc := range []struct {
+		description string
+		desc        hosting.AssetDescription
+		expectedErr string
+	}{
+		{
+			description: "should reject unsupported attributes",
+			desc:        hosting.AssetDescription{FilePath: "/*.js", Attrs: []hosting.AssetAttribute{{Name: "X-Frame-Options", Value: "DENY"}}},
+			expectedErr: `attribute rule '/*.js' has an unsupported attribute "X-Frame-Options"`,
+		},
+		{
+			description: "should reject relative patterns",
+			desc:        hosting.AssetDescription{FilePath: "*.js"},
+			expectedErr: "attribute rule '*.js' must start with '/'",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			_, err := hosting.NewAttributeRules([]hosting.AssetDescription{tc.desc})
+			u.So(t, err, gc.ShouldNotBeNil)
+			u.So(t, err.Error(), gc.ShouldEqual, tc.expectedErr)
+		})
+	}
+}
+
+func TestListLocalAssetMetadataWithAttributeRules(t *testing.T) {
+	rootDir, err := filepath.Abs("../testdata/full_app/hosting/files")
+	u.So(t, err, gc.ShouldBeNil)
+
+	metadataFile, err := ioutil.TempFile("", "metadata.json")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.Remove(metadataFile.Name())
+
+	_, err = metadataFile.WriteString(`[
+		{"path": "/asset_file0.json", "attrs": [{"name": "Content-Type", "value": "application/json"}]},
+		{"path": "/**/*.json", "attrs": [{"name": "Cache-Control", "value": "max-age=3600"}]},
+		{"path": "/ships/*", "attrs": [{"name": "Cache-Control", "value": "no-cache"}]}
+	]`)
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, metadataFile.Close(), gc.ShouldBeNil)
+
+	assetDescriptions, err := hosting.MetadataFileToAssetDescriptions(metadataFile.Name())
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, assetDescriptions, gc.ShouldHaveLength, 1)
+
+	attributeRules, err := hosting.MetadataFileToAttributeRules(metadataFile.Name())
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, attributeRules, gc.ShouldHaveLength, 2)
+
+	assetMetadata, err := hosting.ListLocalAssetMetadata("3720", rootDir, assetDescriptions, attributeRules, hosting.NewAssetCache(), nil, hosting.CompressionNone, false)
+	u.So(t, err, gc.ShouldBeNil)
+
+	attrsByPath := map[string][]hosting.AssetAttribute{}
+	for _, am := range assetMetadata {
+		attrsByPath[am.FilePath] = am.Attrs
+	}
+
+	u.So(t, attrsByPath, gc.ShouldResemble, map[string][]hosting.AssetAttribute{
+		"/asset_file0.json": {{Name: "Content-Type", Value: "application/json"}},
+		"/asset_file1.html": {{Name: "Content-Type", Value: "text/html"}},
+		"/ships/nostromo.json": {
+			{Name: "Cache-Control", Value: "no-cache"},
+		},
+	})
+}
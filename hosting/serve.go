@@ -0,0 +1,131 @@
+package hosting
+
+import (
+	"io"
+	"net/http"
+	"os"
+	"path"
+	"path/filepath"
+	"strconv"
+
+	"github.com/10gen/realm-cli/utils"
+)
+
+// indexFileName is the file served for a request of a directory
+const indexFileName = "index.html"
+
+// previewHandler serves the files in a local hosting files directory
+// with the attributes they would have once uploaded
+type previewHandler struct {
+	rootDir           string
+	assetDescriptions map[string]AssetDescription
+	attributeRules    AttributeRules
+	ignoreRules       *IgnoreRules
+	config            *utils.HostingConfig
+}
+
+// NewPreviewHandler returns an http.Handler serving the files in rootDir as they would be hosted: each response
+// carries the attributes of the asset described by assetDescriptions or attributeRules as headers, falling back
+// to the Content-Type of its file extension, and an asset with a Website-Redirect-Location is a redirect to
+// that location. Files matched by ignoreRules are not found, as they would not be uploaded. A path without a file
+// is answered with the DefaultErrorPath of config, if it has one, served with its DefaultResponseCode
+func NewPreviewHandler(rootDir string, assetDescriptions map[string]AssetDescription, attributeRules AttributeRules, ignoreRules *IgnoreRules, config *utils.HostingConfig) http.Handler {
+	return &previewHandler{rootDir, assetDescriptions, attributeRules, ignoreRules, config}
+}
+
+func (ph *previewHandler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodGet && r.Method != http.MethodHead {
+		w.Header().Set("Allow", "GET, HEAD")
+		http.Error(w, http.StatusText(http.StatusMethodNotAllowed), http.StatusMethodNotAllowed)
+		return
+	}
+
+	assetPath, info, ok := ph.find(path.Clean("/" + r.URL.Path))
+	if !ok {
+		ph.serveError(w, r)
+		return
+	}
+	ph.serve(w, r, assetPath, info, http.StatusOK)
+}
+
+// find returns the path and FileInfo of the file that would be hosted at assetPath, which is the index
+// of the directory at assetPath if there is one, or false if no file would be hosted there
+func (ph *previewHandler) find(assetPath string) (string, os.FileInfo, bool) {
+	info, err := os.Stat(ph.localPath(assetPath))
+	if err == nil && info.IsDir() {
+		assetPath = path.Join(assetPath, indexFileName)
+		info, err = os.Stat(ph.localPath(assetPath))
+	}
+	if err != nil || info.IsDir() || ph.ignoreRules.Ignored(assetPath, false) {
+		return "", nil, false
+	}
+	return assetPath, info, true
+}
+
+// serveError answers a request of a path without a file with the configured error document, if there is one
+func (ph *previewHandler) serveError(w http.ResponseWriter, r *http.Request) {
+	if ph.config == nil || ph.config.DefaultErrorPath == "" {
+		http.NotFound(w, r)
+		return
+	}
+
+	assetPath, info, ok := ph.find(path.Clean(ph.config.DefaultErrorPath))
+	if !ok {
+		http.NotFound(w, r)
+		return
+	}
+
+	status := ph.config.DefaultResponseCode
+	if status == 0 {
+		status = http.StatusNotFound
+	}
+	ph.serve(w, r, assetPath, info, status)
+}
+
+// serve writes the file at assetPath with the attributes of its asset
+func (ph *previewHandler) serve(w http.ResponseWriter, r *http.Request, assetPath string, info os.FileInfo, status int) {
+	var desc *AssetDescription
+	if descEntry, ok := ph.assetDescriptions[assetPath]; ok {
+		desc = &descEntry
+	}
+
+	for _, attr := range assetAttributes(assetPath, desc, ph.attributeRules) {
+		if attr.Name == AttributeWebsiteRedirectLocation {
+			http.Redirect(w, r, attr.Value, http.StatusMovedPermanently)
+			return
+		}
+		w.Header().Set(attr.Name, attr.Value)
+	}
+
+	// an AssetDescription without a Content-Type is served with that of its file extension
+	if w.Header().Get(AttributeContentType) == "" {
+		if extension := path.Ext(assetPath); extension != "" {
+			if contentType, ok := utils.GetContentTypeByExtension(extension[1:]); ok {
+				w.Header().Set(AttributeContentType, contentType)
+			}
+		}
+	}
+
+	f, err := os.Open(ph.localPath(assetPath))
+	if err != nil {
+		http.NotFound(w, r)
+		return
+	}
+	defer f.Close()
+
+	if status == http.StatusOK {
+		http.ServeContent(w, r, assetPath, info.ModTime(), f)
+		return
+	}
+
+	// the error document answers any request of a missing path, so it is served whole and without caching headers
+	w.Header().Set("Content-Length", strconv.FormatInt(info.Size(), 10))
+	w.WriteHeader(status)
+	if r.Method != http.MethodHead {
+		io.Copy(w, f)
+	}
+}
+
+func (ph *previewHandler) localPath(assetPath string) string {
+	return filepath.Join(ph.rootDir, filepath.FromSlash(assetPath))
+}
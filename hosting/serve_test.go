@@ -0,0 +1,181 @@
+package hosting_test
+
+import (
+	"io/ioutil"
+	"net/http"
+	"net/http/httptest"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestPreviewHandler(t *testing.T) {
+	rootDir, err := ioutil.TempDir("", "realm-cli-preview")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(rootDir)
+
+	for path, contents := range map[string]string{
+		"index.html":           "<html>home</html>",
+		"docs/index.html":      "<html>docs</html>",
+		"static/app.js":        "console.log('app')",
+		"report.pdf":           "%PDF",
+		"old.html":             "<html>old</html>",
+		"drafts/todo.md":       "todo",
+		"404.html":             "<html>not found</html>",
+		hosting.IgnoreFileName: "drafts/",
+	} {
+		localPath := filepath.Join(rootDir, filepath.FromSlash(path))
+		u.So(t, os.MkdirAll(filepath.Dir(localPath), 0755), gc.ShouldBeNil)
+		u.So(t, ioutil.WriteFile(localPath, []byte(contents), 0644), gc.ShouldBeNil)
+	}
+
+	assetDescriptions := map[string]hosting.AssetDescription{
+		"/report.pdf": {
+			FilePath: "/report.pdf",
+			Attrs: []hosting.AssetAttribute{
+				{Name: hosting.AttributeContentType, Value: "application/pdf"},
+				{Name: hosting.AttributeContentDisposition, Value: "attachment"},
+			},
+		},
+		"/old.html": {
+			FilePath: "/old.html",
+			Attrs:    []hosting.AssetAttribute{{Name: hosting.AttributeWebsiteRedirectLocation, Value: "/index.html"}},
+		},
+	}
+
+	attributeRules, err := hosting.NewAttributeRules([]hosting.AssetDescription{
+		{FilePath: "/static/**", Attrs: []hosting.AssetAttribute{{Name: hosting.AttributeCacheControl, Value: "max-age=31536000"}}},
+	})
+	u.So(t, err, gc.ShouldBeNil)
+
+	ignoreRules, err := hosting.LoadIgnoreRules(rootDir, nil)
+	u.So(t, err, gc.ShouldBeNil)
+
+	handler := hosting.NewPreviewHandler(rootDir, assetDescriptions, attributeRules, ignoreRules, nil)
+
+	get := func(path string) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+		return w
+	}
+
+	for _, tc := range []struct {
+		description string
+		path        string
+		status      int
+		body        string
+		headers     map[string]string
+	}{
+		{
+			description: "should serve the index of the root directory",
+			path:        "/",
+			status:      http.StatusOK,
+			body:        "<html>home</html>",
+			headers:     map[string]string{"Content-Type": "text/html"},
+		},
+		{
+			description: "should serve the index of a subdirectory",
+			path:        "/docs",
+			status:      http.StatusOK,
+			body:        "<html>docs</html>",
+		},
+		{
+			description: "should apply the attribute rules matching a file",
+			path:        "/static/app.js",
+			status:      http.StatusOK,
+			headers:     map[string]string{"Cache-Control": "max-age=31536000"},
+		},
+		{
+			description: "should apply the attributes of the description of a file",
+			path:        "/report.pdf",
+			status:      http.StatusOK,
+			headers:     map[string]string{"Content-Type": "application/pdf", "Content-Disposition": "attachment"},
+		},
+		{
+			description: "should redirect to the Website-Redirect-Location of a file",
+			path:        "/old.html",
+			status:      http.StatusMovedPermanently,
+			headers:     map[string]string{"Location": "/index.html"},
+		},
+		{
+			description: "should not find ignored files",
+			path:        "/drafts/todo.md",
+			status:      http.StatusNotFound,
+		},
+		{
+			description: "should not find the ignore file",
+			path:        "/" + hosting.IgnoreFileName,
+			status:      http.StatusNotFound,
+		},
+		{
+			description: "should not find files outside of the root directory",
+			path:        "/../../etc/passwd",
+			status:      http.StatusNotFound,
+		},
+		{
+			description: "should not find missing files",
+			path:        "/missing.html",
+			status:      http.StatusNotFound,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			w := get(tc.path)
+			u.So(t, w.Code, gc.ShouldEqual, tc.status)
+			if tc.body != "" {
+				u.So(t, w.Body.String(), gc.ShouldEqual, tc.body)
+			}
+			for name, value := range tc.headers {
+				u.So(t, w.Header().Get(name), gc.ShouldEqual, value)
+			}
+		})
+	}
+
+	for _, tc := range []struct {
+		description string
+		config      utils.HostingConfig
+		status      int
+		body        string
+	}{
+		{
+			description: "should serve the default error path of a single-page app for missing paths",
+			config:      utils.HostingConfig{DefaultErrorPath: "/index.html", DefaultResponseCode: http.StatusOK},
+			status:      http.StatusOK,
+			body:        "<html>home</html>",
+		},
+		{
+			description: "should serve the default error path as not found by default",
+			config:      utils.HostingConfig{DefaultErrorPath: "/404.html"},
+			status:      http.StatusNotFound,
+			body:        "<html>not found</html>",
+		},
+		{
+			description: "should not find missing paths if the default error path is missing too",
+			config:      utils.HostingConfig{DefaultErrorPath: "/missing.html", DefaultResponseCode: http.StatusOK},
+			status:      http.StatusNotFound,
+			body:        "404 page not found\n",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			config := tc.config
+			handler := hosting.NewPreviewHandler(rootDir, assetDescriptions, attributeRules, ignoreRules, &config)
+
+			for _, path := range []string{"/app/settings", "/drafts/todo.md"} {
+				w := httptest.NewRecorder()
+				handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+				u.So(t, w.Code, gc.ShouldEqual, tc.status)
+				u.So(t, w.Body.String(), gc.ShouldEqual, tc.body)
+			}
+
+			w := httptest.NewRecorder()
+			handler.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/static/app.js", nil))
+			u.So(t, w.Code, gc.ShouldEqual, http.StatusOK)
+			u.So(t, w.Body.String(), gc.ShouldEqual, "console.log('app')")
+		})
+	}
+}
@@ -0,0 +1,27 @@
+package hosting
+
+import (
+	"sort"
+	"strings"
+)
+
+// CaseCollisions returns the groups of paths of assetMetadata that differ only by case, which would be
+// the same asset once uploaded, sorted by path
+func CaseCollisions(assetMetadata []AssetMetadata) [][]string {
+	byFoldedPath := map[string][]string{}
+	for _, am := range assetMetadata {
+		folded := strings.ToLower(am.FilePath)
+		byFoldedPath[folded] = append(byFoldedPath[folded], am.FilePath)
+	}
+
+	var collisions [][]string
+	for _, paths := range byFoldedPath {
+		if len(paths) > 1 {
+			sort.Strings(paths)
+			collisions = append(collisions, paths)
+		}
+	}
+
+	sort.Slice(collisions, func(i, j int) bool { return collisions[i][0] < collisions[j][0] })
+	return collisions
+}
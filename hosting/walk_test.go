@@ -0,0 +1,99 @@
+package hosting_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/hosting"
+	u "github.com/10gen/realm-cli/utils/test"
+
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestListLocalAssetMetadataSymlinks(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-symlinks")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	rootDir := filepath.Join(dir, "files")
+	sharedDir := filepath.Join(dir, "shared")
+
+	for path, contents := range map[string]string{
+		filepath.Join(rootDir, "index.html"):  "<html></html>",
+		filepath.Join(sharedDir, "logo.svg"):  "<svg></svg>",
+		filepath.Join(sharedDir, "README.md"): "# shared",
+	} {
+		u.So(t, os.MkdirAll(filepath.Dir(path), 0755), gc.ShouldBeNil)
+		u.So(t, ioutil.WriteFile(path, []byte(contents), 0644), gc.ShouldBeNil)
+	}
+
+	u.So(t, os.Symlink(filepath.Join(sharedDir, "README.md"), filepath.Join(rootDir, "readme.md")), gc.ShouldBeNil)
+	u.So(t, os.Symlink(sharedDir, filepath.Join(rootDir, "shared")), gc.ShouldBeNil)
+
+	list := func(followSymlinks bool) ([]hosting.AssetMetadata, error) {
+		return hosting.ListLocalAssetMetadata("3720", rootDir, nil, nil, hosting.NewAssetCache(), nil, hosting.CompressionNone, followSymlinks)
+	}
+
+	paths := func(assetMetadata []hosting.AssetMetadata) []string {
+		var paths []string
+		for _, am := range assetMetadata {
+			paths = append(paths, am.FilePath)
+		}
+		return paths
+	}
+
+	t.Run("should describe symlinked files by their targets and skip symlinked directories", func(t *testing.T) {
+		assetMetadata, err := list(false)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, paths(assetMetadata), gc.ShouldResemble, []string{"/index.html", "/readme.md"})
+		u.So(t, assetMetadata[1].FileSize, gc.ShouldEqual, len("# shared"))
+		u.So(t, assetMetadata[1].FileHash, gc.ShouldEqual, mustGenerateFileHash(filepath.Join(sharedDir, "README.md")))
+	})
+
+	t.Run("should walk symlinked directories when following symlinks", func(t *testing.T) {
+		assetMetadata, err := list(true)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, paths(assetMetadata), gc.ShouldResemble, []string{"/index.html", "/readme.md", "/shared/README.md", "/shared/logo.svg"})
+	})
+
+	t.Run("should fail on a symlink cycle when following symlinks", func(t *testing.T) {
+		loopPath := filepath.Join(sharedDir, "loop")
+		u.So(t, os.Symlink(dir, loopPath), gc.ShouldBeNil)
+		defer os.Remove(loopPath)
+
+		_, err := list(true)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldContainSubstring, "creates a cycle")
+
+		_, err = list(false)
+		u.So(t, err, gc.ShouldBeNil)
+	})
+
+	t.Run("should fail on a broken symlink", func(t *testing.T) {
+		brokenPath := filepath.Join(rootDir, "broken.html")
+		u.So(t, os.Symlink(filepath.Join(dir, "missing.html"), brokenPath), gc.ShouldBeNil)
+		defer os.Remove(brokenPath)
+
+		_, err := list(false)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldContainSubstring, "failed to resolve symlink "+brokenPath)
+	})
+}
+
+func TestCaseCollisions(t *testing.T) {
+	collisions := hosting.CaseCollisions([]hosting.AssetMetadata{
+		{FilePath: "/index.html"},
+		{FilePath: "/img/Logo.png"},
+		{FilePath: "/img/logo.png"},
+		{FilePath: "/img/LOGO.png"},
+		{FilePath: "/about.html"},
+		{FilePath: "/About.html"},
+	})
+
+	u.So(t, collisions, gc.ShouldResemble, [][]string{
+		{"/About.html", "/about.html"},
+		{"/img/LOGO.png", "/img/Logo.png", "/img/logo.png"},
+	})
+}
@@ -28,11 +28,30 @@ func main() {
 		"export":         commands.NewExportCommandFactory(ui),
 		"import":         commands.NewImportCommandFactory(ui),
 		"diff":           commands.NewDiffCommandFactory(ui),
+		"migrate":        commands.NewMigrateCommandFactory(ui),
 		"secrets":        commands.NewSecretsCommandFactory(ui),
 		"secrets list":   commands.NewSecretsListCommandFactory(ui),
 		"secrets add":    commands.NewSecretsAddCommandFactory(ui),
 		"secrets update": commands.NewSecretsUpdateCommandFactory(ui),
 		"secrets remove": commands.NewSecretsRemoveCommandFactory(ui),
+
+		"hosting":             commands.NewHostingCommandFactory(ui),
+		"hosting ls":          commands.NewHostingListCommandFactory(ui),
+		"hosting cp":          commands.NewHostingCopyCommandFactory(ui),
+		"hosting mv":          commands.NewHostingMoveCommandFactory(ui),
+		"hosting rm":          commands.NewHostingRemoveCommandFactory(ui),
+		"hosting set-attr":    commands.NewHostingSetAttrCommandFactory(ui),
+		"hosting upload":      commands.NewHostingUploadCommandFactory(ui),
+		"hosting invalidate":  commands.NewHostingInvalidateCommandFactory(ui),
+		"hosting import":      commands.NewHostingImportCommandFactory(ui),
+		"hosting deploy":      commands.NewHostingDeployCommandFactory(ui),
+		"hosting serve":       commands.NewHostingServeCommandFactory(ui),
+		"hosting cache":       commands.NewHostingCacheCommandFactory(ui),
+		"hosting cache show":  commands.NewHostingCacheShowCommandFactory(ui),
+		"hosting cache clear": commands.NewHostingCacheClearCommandFactory(ui),
+
+		"dependencies cache":       commands.NewDependenciesCacheCommandFactory(ui),
+		"dependencies cache prune": commands.NewDependenciesCachePruneCommandFactory(ui),
 	}
 
 	exitStatus, err := c.Run()
@@ -14,6 +14,12 @@ type DeploymentStatus string
 // AppConfigFileName is the name of top-level config file describing the app
 const AppConfigFileName string = "config.json"
 
+// LegacyAppConfigFileName is the name of the top-level config file in apps exported before config version 20200603
+const LegacyAppConfigFileName string = "stitch.json"
+
+// ConfigVersion is the app config version that the CLI reads and writes
+const ConfigVersion = 20200603
+
 // Default deployment settings
 const (
 	DefaultLocation        string = "US-VA"
@@ -38,6 +44,7 @@ const (
 	AppNameField            string = "name"
 	AppLocationField        string = "location"
 	AppDeploymentModelField string = "deployment_model"
+	AppConfigVersionField   string = "config_version"
 )
 
 const (
@@ -0,0 +1,13 @@
+function Counter() {
+  this.n = 1;
+  this.get = () => this.n;
+  this.count = function () {
+    return (() => arguments.length)();
+  };
+}
+var counter = new Counter();
+var add = (a, b = 2, ...rest) => a + b + rest.length;
+var makeObject = () => ({ x: 1 });
+var curry = x => y => z => x + y + z;
+
+var result = [counter.get(), counter.count(1, 2, 3), add(1), add(1, 5), add(1, 5, 6, 7), makeObject().x, curry(1)(2)(3)];
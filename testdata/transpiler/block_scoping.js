@@ -0,0 +1,57 @@
+var result = [];
+let a = 1;
+function shadow() {
+  result.push(a);
+  {
+    let a = 2;
+    result.push(a);
+  }
+  result.push(a);
+}
+shadow();
+{
+  let a = 3;
+  result.push(a);
+  {
+    let a = 4;
+    result.push(a);
+  }
+}
+result.push(a);
+function param(x) {
+  {
+    let x = 5;
+    result.push(x);
+  }
+  return x;
+}
+result.push(param(6));
+var _a = "underscore";
+{
+  let a = 7;
+  result.push(a, _a);
+}
+for (let i = 0; i < 1; i++) {
+  let i = 8;
+  result.push(i);
+}
+const countdown = function self(n) {
+  return n ? self(n - 1) + 1 : 0;
+};
+result.push(countdown(3));
+class X {
+  m() {
+    return "X";
+  }
+}
+{
+  class X {}
+  result.push(typeof X);
+}
+result.push(new X().m());
+{
+  function blockFunction() {
+    return true;
+  }
+  result.push(blockFunction());
+}
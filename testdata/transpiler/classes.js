@@ -0,0 +1,101 @@
+var result = [];
+class Animal {
+  constructor(name) {
+    this.name = name;
+  }
+  speak() {
+    return this.name + " makes a sound";
+  }
+  get upper() {
+    return this.name.toUpperCase();
+  }
+  set upper(v) {
+    this.name = v.toLowerCase();
+  }
+  static create(n) {
+    return new this(n);
+  }
+  static get kind() {
+    return "animal";
+  }
+}
+class Dog extends Animal {
+  constructor(name) {
+    super(name);
+    this.dog = true;
+  }
+  speak() {
+    return super.speak() + " (woof)";
+  }
+  static create(n) {
+    return super.create(n + "!");
+  }
+}
+var dog = Dog.create("rex");
+result.push(dog.speak(), dog.upper, dog instanceof Animal, dog instanceof Dog, Animal.kind);
+dog.upper = "MAX";
+result.push(dog.name);
+
+class A {
+  constructor(...args) {
+    this.args = args;
+  }
+  m() {
+    return "A";
+  }
+}
+class B extends A {
+  m() {
+    return super.m() + "B";
+  }
+}
+var b = new B(1, 2);
+result.push(b.args, b.m());
+
+const C = class Named {
+  who() {
+    return "Named";
+  }
+};
+result.push(new C().who());
+
+class E extends Error {
+  constructor(m) {
+    super(m);
+    this.message = m;
+    this.name = "E";
+  }
+}
+var e = new E("msg");
+result.push(e.message, e.name);
+
+const key = "computed";
+class K {
+  [key]() {
+    return 1;
+  }
+  static [key + "S"]() {
+    return 2;
+  }
+}
+result.push(new K().computed(), K.computedS());
+
+let Arrow = class {
+  m() {
+    return () => this;
+  }
+};
+var instance = new Arrow();
+result.push(instance.m()() === instance);
+
+class P {
+  get v() {
+    return 1;
+  }
+}
+class Q extends P {
+  get v() {
+    return super.v + 1;
+  }
+}
+result.push(new Q().v);
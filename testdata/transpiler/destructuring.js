@@ -0,0 +1,26 @@
+var result = [];
+var { a, b: { c = 5 } = {}, ...rest } = { a: 1, d: 4, e: 5 };
+result.push(a, c, rest);
+var [x, , y = 3, ...zs] = [1, 2, undefined, 4, 5];
+result.push(x, y, zs);
+function params({ p, q = 2 }, [m, n] = [7, 8]) {
+  return [p, q, m, n];
+}
+result.push(params({ p: 1 }), params({ p: 1, q: 3 }, [4]));
+var s, t;
+[s, t] = [t, s] = [1, 2];
+result.push(s, t);
+({ s, t = 9 } = { s: 6 });
+result.push(s, t);
+for (var [k, v] of [[1, 2], [3, 4]]) result.push(k + v);
+for (const { name } of [{ name: "n1" }, { name: "n2" }]) result.push(name);
+var [h, ...tail] = "hi";
+result.push(h, tail);
+const key = "dyn";
+const { [key]: dynamic, ...others } = { dyn: 1, other: 2 };
+result.push(dynamic, others);
+try {
+  throw { message: "boom", code: 3 };
+} catch ({ message, code }) {
+  result.push(message, code);
+}
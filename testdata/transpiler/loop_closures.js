@@ -0,0 +1,74 @@
+var result = [];
+var fns = [];
+for (let i = 0; i < 3; i++) {
+  fns.push(() => i);
+}
+result.push(fns.map(f => f()));
+
+function find(list) {
+  for (let i = 0; i < list.length; i++) {
+    var captured = () => i;
+    if (list[i] === "stop") return captured();
+    if (list[i] === "skip") continue;
+    if (list[i] === "end") break;
+    result.push(list[i], captured());
+  }
+  return -1;
+}
+result.push(find(["a", "skip", "b", "stop"]), find(["a", "end", "b"]));
+
+fns = [];
+outer: for (let a of [1, 2, 3]) {
+  for (let b of [1, 2, 3]) {
+    fns.push(() => a * 10 + b);
+    if (b === 2) continue outer;
+    if (a === 3) break outer;
+  }
+}
+result.push(fns.map(f => f()));
+
+fns = [];
+let w = 0;
+while (w < 3) {
+  let copy = w;
+  fns.push(() => copy);
+  w++;
+}
+result.push(fns.map(f => f()));
+
+fns = [];
+for (let key in { p: 1, q: 2 }) {
+  let value = key + "!";
+  fns.push(() => value);
+}
+result.push(fns.map(f => f()));
+
+function withThis() {
+  var out = [];
+  for (let i = 0; i < 2; i++) {
+    out.push(() => this.tag + i + arguments[0]);
+  }
+  return out.map(f => f());
+}
+result.push(withThis.call({ tag: "t" }, "!"));
+
+fns = [];
+for (let i = 0; i < 3; i++) {
+  switch (i) {
+    case 1:
+      fns.push(() => i);
+      break;
+    default:
+      continue;
+  }
+}
+result.push(fns.map(f => f()));
+
+fns = [];
+let d = 0;
+do {
+  let j = d;
+  fns.push(() => j);
+  d++;
+} while (d < 2);
+result.push(fns.map(f => f()));
@@ -0,0 +1,50 @@
+// transpilers: native
+var result = [];
+result.push(2 ** 10, (-2) ** 2, 2 ** 3 ** 2);
+var a = 3;
+a **= 2;
+result.push(a);
+
+var n = null,
+  u,
+  z = 0;
+result.push(n ?? "d", u ?? "d", z ?? "d", z || 0);
+
+var o = {
+  p: {
+    q: null,
+    f() {
+      return this.v;
+    },
+    v: 7
+  }
+};
+result.push(o?.p?.q, o.x?.y.z, o.p?.f(), o.p.g?.(), o.p.f?.(), o?.["p"].v, n?.d);
+
+var obj = { x: 0 };
+obj.x ||= 5;
+obj.y ??= 6;
+obj.x &&= 7;
+result.push(obj);
+
+class Point {
+  x = 1;
+  y = this.x + 1;
+  static origin = new Point();
+}
+class Named extends Point {
+  label = "f";
+}
+result.push(new Point().x, Point.origin.y, new Named().label);
+
+class Initialized {
+  static {
+    this.count = 3;
+  }
+}
+result.push(Initialized.count);
+try {
+  throw new Error();
+} catch {
+  result.push(true);
+}
@@ -0,0 +1,14 @@
+import lib, { named, other as alias } from "lib";
+import * as ns from "lib";
+
+export let counter = 1;
+export function increment() {
+  counter++;
+}
+export { named as reexported };
+export default function () {
+  return "default export";
+}
+
+increment();
+var result = [Object.keys(ns).filter(k => k !== "default").sort(), exports.default(), named, alias() === "other" && lib.named === named, { counter: exports.counter, reexported: exports.reexported }];
@@ -0,0 +1,28 @@
+var x = 1,
+  y = 2;
+var key = "k";
+var o = {
+  x,
+  y,
+  [key]: 3,
+  [key + "2"]: 4,
+  m() {
+    return this.x;
+  },
+  get g() {
+    return 5;
+  },
+  "quoted-key": 6,
+  7: 8
+};
+var accessors = {
+  get [key]() {
+    return "got";
+  },
+  set [key](v) {
+    this.stored = v;
+  }
+};
+accessors.k = 1;
+
+var result = [o, o.m(), accessors.k, accessors.stored];
@@ -0,0 +1,19 @@
+var result = [];
+var arr = [1, ...[2, 3], 4];
+function sum(...n) {
+  return n.reduce((a, b) => a + b, 0);
+}
+var holder = {
+  list: [1, 2],
+  concat(...items) {
+    return this.list.concat(items);
+  }
+};
+var copied = [...arr];
+copied.push(5);
+result.push(arr, sum(...arr), sum(1, ...arr, 1), Math.max(...arr), holder.concat(...[3, 4]));
+result.push({ ...{ x: 1, y: 1 }, y: 2, ...{ z: 3 } });
+function Box(a, b, c, d) {
+  this.items = [a, b, c, d];
+}
+result.push(new Box(...arr).items, [..."abc"].join(""));
@@ -0,0 +1,12 @@
+var name = "world";
+function tag(strings, ...values) {
+  return strings.raw.join("|") + "#" + strings.join("|") + "#" + values.join(",");
+}
+function same(s) {
+  return s;
+}
+var objects = [];
+for (var i = 0; i < 2; i++) objects.push(same`x`);
+
+var result = [`hello ${name}!`, `a${1 + 1}b${"c"}`, `multi
+line`, `${name}`, `\``, tag`a${1}b${2}c\n`, objects[0] === objects[1]];
@@ -0,0 +1,469 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"reflect"
+	"sort"
+	"strings"
+)
+
+// AppEntityType is the kind of app entity compared by DiffApps
+type AppEntityType string
+
+// The set of known AppEntityTypes, in the order they are reported
+const (
+	AppEntityTypeFunction AppEntityType = "function"
+	AppEntityTypeTrigger  AppEntityType = "trigger"
+	AppEntityTypeService  AppEntityType = "service"
+	AppEntityTypeWebhook  AppEntityType = "webhook"
+	AppEntityTypeRule     AppEntityType = "rule"
+	AppEntityTypeValue    AppEntityType = "value"
+	AppEntityTypeHosting  AppEntityType = "hosting"
+)
+
+var appEntityTypes = []AppEntityType{
+	AppEntityTypeFunction,
+	AppEntityTypeTrigger,
+	AppEntityTypeService,
+	AppEntityTypeWebhook,
+	AppEntityTypeRule,
+	AppEntityTypeValue,
+	AppEntityTypeHosting,
+}
+
+var appEntityTypeTitles = map[AppEntityType]string{
+	AppEntityTypeFunction: "Functions",
+	AppEntityTypeTrigger:  "Triggers",
+	AppEntityTypeService:  "Services",
+	AppEntityTypeWebhook:  "Incoming Webhooks",
+	AppEntityTypeRule:     "Rules",
+	AppEntityTypeValue:    "Values",
+	AppEntityTypeHosting:  "Hosting",
+}
+
+// AppEntityChange is the way an app entity differs between two apps
+type AppEntityChange string
+
+// The set of known AppEntityChanges
+const (
+	AppEntityAdded    AppEntityChange = "added"
+	AppEntityDeleted  AppEntityChange = "deleted"
+	AppEntityModified AppEntityChange = "modified"
+)
+
+// entity ids are assigned by the server and differ between apps, so they are not compared
+var appEntityIgnoredFields = map[string]bool{
+	"id":  true,
+	"_id": true,
+}
+
+// AppEntityDiff describes how a single app entity differs between two apps
+type AppEntityDiff struct {
+	Type   AppEntityType   `json:"type"`
+	Name   string          `json:"name"`
+	Change AppEntityChange `json:"change"`
+	// Fields lists the top-level fields of a modified entity that changed
+	Fields []string `json:"fields,omitempty"`
+
+	// From and To hold the entity's files on either side, keyed by their canonical path
+	From AppFiles `json:"-"`
+	To   AppFiles `json:"-"`
+}
+
+// AppEntityDiffs is a list of AppEntityDiff sorted by entity type and name
+type AppEntityDiffs []AppEntityDiff
+
+// Title returns the heading under which entities of the type are listed
+func (t AppEntityType) Title() string {
+	return appEntityTypeTitles[t]
+}
+
+// String returns the change to the entity prefixed with its diff marker
+func (d AppEntityDiff) String() string {
+	switch d.Change {
+	case AppEntityAdded:
+		return "+ " + d.Name
+	case AppEntityDeleted:
+		return "- " + d.Name
+	default:
+		return fmt.Sprintf("* %s (%s)", d.Name, strings.Join(d.Fields, ", "))
+	}
+}
+
+// Diff returns a list of strings representing the diff, grouped by entity type
+func (aed AppEntityDiffs) Diff() []string {
+	var diff []string
+
+	for i, d := range aed {
+		if i == 0 || aed[i-1].Type != d.Type {
+			diff = append(diff, d.Type.Title()+":")
+		}
+		diff = append(diff, "\t"+d.String())
+	}
+
+	return diff
+}
+
+// Summary returns a list of strings counting the changed entities of each type
+func (aed AppEntityDiffs) Summary() []string {
+	var summary []string
+
+	for _, entityType := range appEntityTypes {
+		counts := map[AppEntityChange]int{}
+		for _, d := range aed {
+			if d.Type == entityType {
+				counts[d.Change]++
+			}
+		}
+
+		var parts []string
+		for _, change := range []AppEntityChange{AppEntityAdded, AppEntityModified, AppEntityDeleted} {
+			if counts[change] > 0 {
+				parts = append(parts, fmt.Sprintf("%d %s", counts[change], change))
+			}
+		}
+
+		if len(parts) > 0 {
+			summary = append(summary, fmt.Sprintf("\t%s: %s", entityType.Title(), strings.Join(parts, ", ")))
+		}
+	}
+
+	return summary
+}
+
+// SourceHunks returns the hunks of a modified function or incoming webhook's source,
+// or nothing if its source did not change
+func (d AppEntityDiff) SourceHunks(context int) []DiffHunk {
+	if d.Change != AppEntityModified {
+		return nil
+	}
+
+	for path, to := range d.To {
+		if !strings.HasSuffix(path, "/"+sourceName+jsExt) {
+			continue
+		}
+		return DiffHunks(string(d.From[path]), string(to), context)
+	}
+
+	return nil
+}
+
+// Patch returns a unified diff of the files of every changed entity
+func (aed AppEntityDiffs) Patch() string {
+	from, to := AppFiles{}, AppFiles{}
+	for _, d := range aed {
+		for path, data := range d.From {
+			from[path] = data
+		}
+		for path, data := range d.To {
+			to[path] = data
+		}
+	}
+	return DiffAppFiles(from, to)
+}
+
+// appEntity is a single entity of an app loaded by UnmarshalFromDir
+type appEntity struct {
+	entityType AppEntityType
+	name       string
+	fields     map[string]interface{}
+	files      AppFiles
+}
+
+func (ae appEntity) key() string {
+	return string(ae.entityType) + "/" + ae.name
+}
+
+// DiffApps compares the functions, triggers, services, incoming webhooks, rules and values
+// of two apps loaded by UnmarshalFromDir, describing how to get from the first app to the second
+func DiffApps(from, to map[string]interface{}) (AppEntityDiffs, error) {
+	fromEntities, err := appEntities(from)
+	if err != nil {
+		return nil, err
+	}
+
+	toEntities, err := appEntities(to)
+	if err != nil {
+		return nil, err
+	}
+
+	diffs := AppEntityDiffs{}
+	for key, toEntity := range toEntities {
+		fromEntity, ok := fromEntities[key]
+		if !ok {
+			diffs = append(diffs, AppEntityDiff{
+				Type:   toEntity.entityType,
+				Name:   toEntity.name,
+				Change: AppEntityAdded,
+				To:     toEntity.files,
+			})
+			continue
+		}
+
+		if fields := modifiedFields(fromEntity.fields, toEntity.fields); len(fields) > 0 {
+			diffs = append(diffs, AppEntityDiff{
+				Type:   toEntity.entityType,
+				Name:   toEntity.name,
+				Change: AppEntityModified,
+				Fields: fields,
+				From:   fromEntity.files,
+				To:     toEntity.files,
+			})
+		}
+	}
+
+	for key, fromEntity := range fromEntities {
+		if _, ok := toEntities[key]; !ok {
+			diffs = append(diffs, AppEntityDiff{
+				Type:   fromEntity.entityType,
+				Name:   fromEntity.name,
+				Change: AppEntityDeleted,
+				From:   fromEntity.files,
+			})
+		}
+	}
+
+	typeOrder := map[AppEntityType]int{}
+	for i, entityType := range appEntityTypes {
+		typeOrder[entityType] = i
+	}
+	sort.Slice(diffs, func(i, j int) bool {
+		if diffs[i].Type != diffs[j].Type {
+			return typeOrder[diffs[i].Type] < typeOrder[diffs[j].Type]
+		}
+		return diffs[i].Name < diffs[j].Name
+	})
+
+	return diffs, nil
+}
+
+func modifiedFields(from, to map[string]interface{}) []string {
+	var fields []string
+	for field, value := range to {
+		if !reflect.DeepEqual(from[field], value) {
+			fields = append(fields, field)
+		}
+	}
+	for field := range from {
+		if _, ok := to[field]; !ok {
+			fields = append(fields, field)
+		}
+	}
+	sort.Strings(fields)
+	return fields
+}
+
+// appEntities flattens an app loaded by UnmarshalFromDir into its entities, keyed by type and name
+func appEntities(app map[string]interface{}) (map[string]appEntity, error) {
+	entities := map[string]appEntity{}
+	add := func(entity appEntity) error {
+		if _, ok := entities[entity.key()]; ok {
+			return fmt.Errorf("found more than one %s named %q", entity.entityType, entity.name)
+		}
+		entities[entity.key()] = entity
+		return nil
+	}
+
+	for _, fn := range asSlice(app[FunctionsRoot]) {
+		entity, err := sourceEntity(AppEntityTypeFunction, FunctionsRoot, asMap(fn))
+		if err != nil {
+			return nil, err
+		}
+		if err := add(entity); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, trigger := range asSlice(app[triggersName]) {
+		entity, err := configEntity(AppEntityTypeTrigger, asMap(trigger), jsonFilePath(triggersName))
+		if err != nil {
+			return nil, err
+		}
+		if err := add(entity); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, value := range asSlice(app[valuesName]) {
+		entity, err := configEntity(AppEntityTypeValue, asMap(value), jsonFilePath(valuesName))
+		if err != nil {
+			return nil, err
+		}
+		if err := add(entity); err != nil {
+			return nil, err
+		}
+	}
+
+	if entity, ok, err := hostingConfigEntity(asMap(app[HostingRoot])); err != nil {
+		return nil, err
+	} else if ok {
+		if err := add(entity); err != nil {
+			return nil, err
+		}
+	}
+
+	for _, svc := range asSlice(app[servicesName]) {
+		svcMap := asMap(svc)
+
+		service, err := configEntity(AppEntityTypeService, asMap(svcMap[configName]), func(name string) string {
+			return servicesName + "/" + name + "/" + configName + jsonExt
+		})
+		if err != nil {
+			return nil, err
+		}
+		if err := add(service); err != nil {
+			return nil, err
+		}
+
+		for _, webhook := range asSlice(svcMap[incomingWebhooksName]) {
+			entity, err := sourceEntity(AppEntityTypeWebhook, servicesName+"/"+service.name+"/"+incomingWebhooksName, asMap(webhook))
+			if err != nil {
+				return nil, err
+			}
+			entity.name = service.name + "/" + entity.name
+			if err := add(entity); err != nil {
+				return nil, err
+			}
+		}
+
+		for _, rule := range asSlice(svcMap[rulesName]) {
+			entity, err := configEntity(AppEntityTypeRule, asMap(rule), jsonFilePath(servicesName+"/"+service.name+"/"+rulesName))
+			if err != nil {
+				return nil, err
+			}
+			entity.name = service.name + "/" + entity.name
+			if err := add(entity); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return entities, nil
+}
+
+// configEntity builds an entity described by a single JSON file, stored at the path returned by filePath
+func configEntity(entityType AppEntityType, config map[string]interface{}, filePath func(name string) string) (appEntity, error) {
+	name := entityName(config)
+	if name == "" {
+		return appEntity{}, fmt.Errorf("found a %s without a name", entityType)
+	}
+
+	fields := comparableFields(config)
+	data, err := marshalEntityFile(fields)
+	if err != nil {
+		return appEntity{}, err
+	}
+
+	return appEntity{
+		entityType: entityType,
+		name:       name,
+		fields:     fields,
+		files:      AppFiles{filePath(name): data},
+	}, nil
+}
+
+// hostingConfigEntity builds the entity of the HostingConfig fields of the hosting section of the app config,
+// stored at HostingConfigPath, reporting whether any of them are set
+func hostingConfigEntity(hosting map[string]interface{}) (appEntity, bool, error) {
+	fields := map[string]interface{}{}
+	for _, field := range hostingConfigFields {
+		if value, ok := hosting[field]; ok {
+			fields[field] = value
+		}
+	}
+
+	if len(fields) == 0 {
+		return appEntity{}, false, nil
+	}
+
+	data, err := marshalEntityFile(fields)
+	if err != nil {
+		return appEntity{}, false, err
+	}
+
+	return appEntity{
+		entityType: AppEntityTypeHosting,
+		name:       configName,
+		fields:     fields,
+		files:      AppFiles{HostingConfigPath: data},
+	}, true, nil
+}
+
+// jsonFilePath returns a filePath func for entities stored as <name>.json in dir
+func jsonFilePath(dir string) func(name string) string {
+	return func(name string) string {
+		return dir + "/" + strings.ReplaceAll(name, "/", "_") + jsonExt
+	}
+}
+
+// sourceEntity builds an entity described by a config.json and source.js in a directory of dir
+func sourceEntity(entityType AppEntityType, dir string, directory map[string]interface{}) (appEntity, error) {
+	config := asMap(directory[configName])
+	name := entityName(config)
+	if name == "" {
+		return appEntity{}, fmt.Errorf("found a %s without a name", entityType)
+	}
+
+	fields := comparableFields(config)
+	data, err := marshalEntityFile(fields)
+	if err != nil {
+		return appEntity{}, err
+	}
+
+	source, _ := directory[sourceName].(string)
+	fields[sourceName] = source
+
+	return appEntity{
+		entityType: entityType,
+		name:       name,
+		fields:     fields,
+		files: AppFiles{
+			dir + "/" + name + "/" + configName + jsonExt: data,
+			dir + "/" + name + "/" + sourceName + jsExt:   []byte(source),
+		},
+	}, nil
+}
+
+// entityName returns the name of an entity, which for MongoDB rules is their namespace
+func entityName(config map[string]interface{}) string {
+	if name, ok := config["name"].(string); ok && name != "" {
+		return name
+	}
+
+	database, _ := config["database"].(string)
+	collection, _ := config["collection"].(string)
+	if database != "" && collection != "" {
+		return database + "." + collection
+	}
+
+	return ""
+}
+
+func comparableFields(config map[string]interface{}) map[string]interface{} {
+	fields := make(map[string]interface{}, len(config))
+	for field, value := range config {
+		if !appEntityIgnoredFields[field] {
+			fields[field] = value
+		}
+	}
+	return fields
+}
+
+func marshalEntityFile(fields map[string]interface{}) ([]byte, error) {
+	data, err := json.MarshalIndent(fields, "", "    ")
+	if err != nil {
+		return nil, err
+	}
+	return append(data, '\n'), nil
+}
+
+func asSlice(v interface{}) []interface{} {
+	s, _ := v.([]interface{})
+	return s
+}
+
+func asMap(v interface{}) map[string]interface{} {
+	m, _ := v.(map[string]interface{})
+	return m
+}
@@ -0,0 +1,129 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestDiffApps(t *testing.T) {
+	from := map[string]interface{}{
+		"functions": []interface{}{
+			map[string]interface{}{
+				"config": map[string]interface{}{"id": "1", "name": "greet", "private": false},
+				"source": "exports = function() {\n  return 'hi';\n};\n",
+			},
+			map[string]interface{}{
+				"config": map[string]interface{}{"id": "2", "name": "unused", "private": true},
+				"source": "exports = function() {};\n",
+			},
+		},
+		"values": []interface{}{
+			map[string]interface{}{"id": "3", "name": "greeting", "value": "hi"},
+		},
+		"services": []interface{}{
+			map[string]interface{}{
+				"config": map[string]interface{}{"id": "4", "name": "mongodb-atlas", "type": "mongodb-atlas"},
+				"rules": []interface{}{
+					map[string]interface{}{"id": "5", "database": "db", "collection": "coll", "roles": []interface{}{}},
+				},
+				"incoming_webhooks": []interface{}{},
+			},
+		},
+	}
+
+	t.Run("should report no differences between identical apps", func(t *testing.T) {
+		diffs, err := utils.DiffApps(from, from)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, diffs, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should ignore entity ids", func(t *testing.T) {
+		to := map[string]interface{}{
+			"values": []interface{}{
+				map[string]interface{}{"id": "other", "name": "greeting", "value": "hi"},
+			},
+		}
+		diffs, err := utils.DiffApps(map[string]interface{}{"values": from["values"]}, to)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, diffs, gc.ShouldBeEmpty)
+	})
+
+	t.Run("should describe added, deleted and modified entities", func(t *testing.T) {
+		to := map[string]interface{}{
+			"functions": []interface{}{
+				map[string]interface{}{
+					"config": map[string]interface{}{"id": "1", "name": "greet", "private": false},
+					"source": "exports = function() {\n  return 'hello';\n};\n",
+				},
+			},
+			"triggers": []interface{}{
+				map[string]interface{}{"name": "on_insert", "type": "DATABASE"},
+			},
+			"values": []interface{}{
+				map[string]interface{}{"id": "3", "name": "greeting", "value": "hello", "private": true},
+			},
+			"services": []interface{}{
+				map[string]interface{}{
+					"config":            map[string]interface{}{"id": "4", "name": "mongodb-atlas", "type": "mongodb-atlas"},
+					"rules":             []interface{}{},
+					"incoming_webhooks": []interface{}{},
+				},
+			},
+		}
+
+		diffs, err := utils.DiffApps(from, to)
+		u.So(t, err, gc.ShouldBeNil)
+
+		type entityDiff struct {
+			Type   utils.AppEntityType
+			Name   string
+			Change utils.AppEntityChange
+			Fields []string
+		}
+		var actual []entityDiff
+		for _, d := range diffs {
+			actual = append(actual, entityDiff{d.Type, d.Name, d.Change, d.Fields})
+		}
+
+		u.So(t, actual, gc.ShouldResemble, []entityDiff{
+			{utils.AppEntityTypeFunction, "greet", utils.AppEntityModified, []string{"source"}},
+			{utils.AppEntityTypeFunction, "unused", utils.AppEntityDeleted, nil},
+			{utils.AppEntityTypeTrigger, "on_insert", utils.AppEntityAdded, nil},
+			{utils.AppEntityTypeRule, "mongodb-atlas/db.coll", utils.AppEntityDeleted, nil},
+			{utils.AppEntityTypeValue, "greeting", utils.AppEntityModified, []string{"private", "value"}},
+		})
+
+		u.So(t, diffs.Diff(), gc.ShouldResemble, []string{
+			"Functions:",
+			"\t* greet (source)",
+			"\t- unused",
+			"Triggers:",
+			"\t+ on_insert",
+			"Rules:",
+			"\t- mongodb-atlas/db.coll",
+			"Values:",
+			"\t* greeting (private, value)",
+		})
+
+		patch := diffs.Patch()
+		u.So(t, patch, gc.ShouldContainSubstring, "--- a/functions/greet/source.js\n+++ b/functions/greet/source.js\n@@ -1,3 +1,3 @@\n exports = function() {\n-  return 'hi';\n+  return 'hello';\n };\n")
+		u.So(t, patch, gc.ShouldContainSubstring, "--- /dev/null\n+++ b/triggers/on_insert.json\n")
+		u.So(t, patch, gc.ShouldContainSubstring, "--- a/services/mongodb-atlas/rules/db.coll.json\n+++ /dev/null\n")
+		u.So(t, patch, gc.ShouldNotContainSubstring, "functions/greet/config.json")
+	})
+
+	t.Run("should fail on duplicate entity names", func(t *testing.T) {
+		app := map[string]interface{}{
+			"values": []interface{}{
+				map[string]interface{}{"name": "greeting", "value": "hi"},
+				map[string]interface{}{"name": "greeting", "value": "hello"},
+			},
+		}
+		_, err := utils.DiffApps(app, app)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldContainSubstring, `more than one value named "greeting"`)
+	})
+}
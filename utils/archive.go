@@ -155,8 +155,43 @@ func NewDirReader(dirName string) (ArchiveReader, error) {
 	}
 	files := make([]FileHeader, 0)
 	err = filepath.Walk(dirName, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+
+		if info.IsDir() {
+			return nil
+		}
+		files = append(files, FileHeader{
+			FullPath: path,
+			fi:       info,
+		})
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return &dirReader{
+		files:       files,
+		currFileIdx: -1,
+	}, nil
+}
+
+// NewResolvedDirReader creates a new dir reader like NewDirReader, except that the files and directories that
+// the symlinks in the directory point to are read in their place, failing if a symlink is broken or links to
+// a directory containing it. The directories below dirName for which skipDir returns true are left out
+func NewResolvedDirReader(dirName string, skipDir func(path string) bool) (ArchiveReader, error) {
+	files := make([]FileHeader, 0)
+	err := Walk(dirName, true, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
 
 		if info.IsDir() {
+			if path != dirName && skipDir(path) {
+				return filepath.SkipDir
+			}
 			return nil
 		}
 		files = append(files, FileHeader{
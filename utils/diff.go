@@ -0,0 +1,241 @@
+package utils
+
+import (
+	"fmt"
+	"strings"
+)
+
+// DiffOp is the kind of change a DiffLine represents
+type DiffOp int
+
+// The set of known DiffOps
+const (
+	DiffEqual DiffOp = iota
+	DiffDelete
+	DiffInsert
+)
+
+// maxDiffCells bounds the size of the table used to compute a line diff,
+// beyond which the texts are treated as entirely replaced
+const maxDiffCells = 4 * 1024 * 1024
+
+// DiffLine is a single line of a line-based diff
+type DiffLine struct {
+	Op   DiffOp
+	Text string
+}
+
+// String returns the line prefixed with its unified diff marker
+func (dl DiffLine) String() string {
+	switch dl.Op {
+	case DiffDelete:
+		return "-" + dl.Text
+	case DiffInsert:
+		return "+" + dl.Text
+	default:
+		return " " + dl.Text
+	}
+}
+
+// DiffHunk is a group of changed lines along with their surrounding context
+type DiffHunk struct {
+	FromLine  int
+	FromCount int
+	ToLine    int
+	ToCount   int
+	Lines     []DiffLine
+}
+
+// Header returns the unified diff range header of the hunk
+func (dh DiffHunk) Header() string {
+	return fmt.Sprintf("@@ -%s +%s @@", hunkRange(dh.FromLine, dh.FromCount), hunkRange(dh.ToLine, dh.ToCount))
+}
+
+func hunkRange(line, count int) string {
+	if count == 1 {
+		return fmt.Sprintf("%d", line)
+	}
+	if count == 0 {
+		line--
+	}
+	return fmt.Sprintf("%d,%d", line, count)
+}
+
+// DiffLines computes the line-based diff between a and b
+func DiffLines(a, b string) []DiffLine {
+	linesA, linesB := splitLines(a), splitLines(b)
+
+	// strip the common prefix and suffix to keep the table small
+	prefix := 0
+	for prefix < len(linesA) && prefix < len(linesB) && linesA[prefix] == linesB[prefix] {
+		prefix++
+	}
+	suffix := 0
+	for suffix < len(linesA)-prefix && suffix < len(linesB)-prefix &&
+		linesA[len(linesA)-1-suffix] == linesB[len(linesB)-1-suffix] {
+		suffix++
+	}
+
+	diff := make([]DiffLine, 0, len(linesA)+len(linesB))
+	for _, line := range linesA[:prefix] {
+		diff = append(diff, DiffLine{DiffEqual, line})
+	}
+	diff = append(diff, diffMiddle(linesA[prefix:len(linesA)-suffix], linesB[prefix:len(linesB)-suffix])...)
+	for _, line := range linesA[len(linesA)-suffix:] {
+		diff = append(diff, DiffLine{DiffEqual, line})
+	}
+
+	return diff
+}
+
+// diffMiddle computes a diff from the longest common subsequence of a and b
+func diffMiddle(a, b []string) []DiffLine {
+	var diff []DiffLine
+
+	if len(a)*len(b) > maxDiffCells {
+		for _, line := range a {
+			diff = append(diff, DiffLine{DiffDelete, line})
+		}
+		for _, line := range b {
+			diff = append(diff, DiffLine{DiffInsert, line})
+		}
+		return diff
+	}
+
+	lcs := make([][]int, len(a)+1)
+	for i := range lcs {
+		lcs[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lcs[i][j] = lcs[i+1][j+1] + 1
+			} else if lcs[i+1][j] >= lcs[i][j+1] {
+				lcs[i][j] = lcs[i+1][j]
+			} else {
+				lcs[i][j] = lcs[i][j+1]
+			}
+		}
+	}
+
+	i, j := 0, 0
+	for i < len(a) && j < len(b) {
+		switch {
+		case a[i] == b[j]:
+			diff = append(diff, DiffLine{DiffEqual, a[i]})
+			i++
+			j++
+		case lcs[i+1][j] >= lcs[i][j+1]:
+			diff = append(diff, DiffLine{DiffDelete, a[i]})
+			i++
+		default:
+			diff = append(diff, DiffLine{DiffInsert, b[j]})
+			j++
+		}
+	}
+	for ; i < len(a); i++ {
+		diff = append(diff, DiffLine{DiffDelete, a[i]})
+	}
+	for ; j < len(b); j++ {
+		diff = append(diff, DiffLine{DiffInsert, b[j]})
+	}
+
+	return diff
+}
+
+// DiffHunks groups the line-based diff between a and b into hunks
+// with the given number of context lines around each change
+func DiffHunks(a, b string, context int) []DiffHunk {
+	lines := DiffLines(a, b)
+
+	var hunks []DiffHunk
+	var hunk *DiffHunk
+	fromLine, toLine := 1, 1
+	lastChange := -1
+
+	closeHunk := func() {
+		end := lastChange + 1 + context
+		if end > len(lines) {
+			end = len(lines)
+		}
+		hunk.Lines = append(hunk.Lines, lines[lastChange+1:end]...)
+		hunks = append(hunks, countHunk(*hunk))
+	}
+
+	for idx, line := range lines {
+		if line.Op != DiffEqual {
+			if hunk != nil && idx-lastChange-1 <= 2*context {
+				hunk.Lines = append(hunk.Lines, lines[lastChange+1:idx]...)
+			} else {
+				if hunk != nil {
+					closeHunk()
+				}
+
+				start := idx - context
+				if start < lastChange+1 {
+					start = lastChange + 1
+				}
+				hunk = &DiffHunk{
+					FromLine: fromLine - (idx - start),
+					ToLine:   toLine - (idx - start),
+					Lines:    append([]DiffLine{}, lines[start:idx]...),
+				}
+			}
+			hunk.Lines = append(hunk.Lines, line)
+			lastChange = idx
+		}
+
+		if line.Op != DiffInsert {
+			fromLine++
+		}
+		if line.Op != DiffDelete {
+			toLine++
+		}
+	}
+
+	if hunk != nil {
+		closeHunk()
+	}
+
+	return hunks
+}
+
+// countHunk computes the line counts of a hunk
+func countHunk(hunk DiffHunk) DiffHunk {
+	for _, line := range hunk.Lines {
+		if line.Op != DiffInsert {
+			hunk.FromCount++
+		}
+		if line.Op != DiffDelete {
+			hunk.ToCount++
+		}
+	}
+	return hunk
+}
+
+// UnifiedDiff returns the unified diff between a and b with the given number of context lines,
+// or an empty string if they are identical
+func UnifiedDiff(fromName, toName, a, b string, context int) string {
+	hunks := DiffHunks(a, b, context)
+	if len(hunks) == 0 {
+		return ""
+	}
+
+	var sb strings.Builder
+	sb.WriteString(fmt.Sprintf("--- %s\n+++ %s\n", fromName, toName))
+	for _, hunk := range hunks {
+		sb.WriteString(hunk.Header() + "\n")
+		for _, line := range hunk.Lines {
+			sb.WriteString(line.String() + "\n")
+		}
+	}
+
+	return sb.String()
+}
+
+func splitLines(s string) []string {
+	if s == "" {
+		return nil
+	}
+	return strings.Split(strings.TrimSuffix(s, "\n"), "\n")
+}
@@ -0,0 +1,55 @@
+package utils_test
+
+import (
+	"testing"
+
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestUnifiedDiff(t *testing.T) {
+	a := "a\nb\nc\nd\ne\nf\ng\nh\ni\nj\n"
+	b := "a\nB\nc\nd\ne\nf\ng\nh\nI\nj\nk\n"
+
+	for _, tc := range []struct {
+		description string
+		a           string
+		b           string
+		context     int
+		expected    string
+	}{
+		{
+			description: "should return nothing for identical inputs",
+			a:           a,
+			b:           a,
+			context:     3,
+			expected:    "",
+		},
+		{
+			description: "should merge changes within the context into a single hunk",
+			a:           a,
+			b:           b,
+			context:     3,
+			expected:    "--- a\n+++ b\n@@ -1,10 +1,11 @@\n a\n-b\n+B\n c\n d\n e\n f\n g\n h\n-i\n+I\n j\n+k\n",
+		},
+		{
+			description: "should split changes beyond the context into separate hunks",
+			a:           a,
+			b:           b,
+			context:     1,
+			expected:    "--- a\n+++ b\n@@ -1,3 +1,3 @@\n a\n-b\n+B\n c\n@@ -8,3 +8,4 @@\n h\n-i\n+I\n j\n+k\n",
+		},
+		{
+			description: "should describe an added file",
+			a:           "",
+			b:           "x\ny\n",
+			context:     3,
+			expected:    "--- a\n+++ b\n@@ -0,0 +1,2 @@\n+x\n+y\n",
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			u.So(t, utils.UnifiedDiff("a", "b", tc.a, tc.b, tc.context), gc.ShouldEqual, tc.expected)
+		})
+	}
+}
@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io/ioutil"
+	"net/http"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// The fields of the hosting section of the app config that are stored in HostingConfigPath
+const (
+	hostingDefaultErrorPathField    = "default_error_path"
+	hostingDefaultResponseCodeField = "default_response_code"
+)
+
+var hostingConfigFields = []string{hostingDefaultErrorPathField, hostingDefaultResponseCodeField}
+
+// HostingConfig is the configuration of how an app's hosted assets are served, which is stored next to
+// the hosting metadata rather than in the app config file
+type HostingConfig struct {
+	// DefaultErrorPath is the hosted file served for a path without a file, such as "/index.html"
+	// for a single-page app or "/404.html" for a custom not found page
+	DefaultErrorPath string `json:"default_error_path,omitempty"`
+
+	// DefaultResponseCode is the status DefaultErrorPath is served with, which is 200 for a single-page
+	// app and 404 otherwise. Defaults to 404
+	DefaultResponseCode int `json:"default_response_code,omitempty"`
+}
+
+// ReadHostingConfig reads the HostingConfig of the app at appPath, which is nil if the app has none
+func ReadHostingConfig(appPath string) (*HostingConfig, error) {
+	path := filepath.Join(appPath, HostingConfigPath)
+
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		if os.IsNotExist(err) {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	dec := json.NewDecoder(bytes.NewReader(data))
+	dec.DisallowUnknownFields()
+
+	var config HostingConfig
+	if err := dec.Decode(&config); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	return &config, nil
+}
+
+// ValidateHostingConfig checks the HostingConfig of the app at appPath, if it has one,
+// whose DefaultErrorPath must be a file in the app's hosting files directory
+func ValidateHostingConfig(appPath string) error {
+	config, err := ReadHostingConfig(appPath)
+	if err != nil || config == nil {
+		return err
+	}
+
+	if config.DefaultErrorPath == "" {
+		if config.DefaultResponseCode != 0 {
+			return fmt.Errorf("invalid %s: %s requires %s", HostingConfigPath, hostingDefaultResponseCodeField, hostingDefaultErrorPathField)
+		}
+		return nil
+	}
+
+	if !strings.HasPrefix(config.DefaultErrorPath, "/") {
+		return fmt.Errorf("invalid %s: %s '%s' must start with '/'", HostingConfigPath, hostingDefaultErrorPathField, config.DefaultErrorPath)
+	}
+
+	switch config.DefaultResponseCode {
+	case 0, http.StatusOK, http.StatusNotFound:
+	default:
+		return fmt.Errorf("invalid %s: %s must be %d or %d, but was %d", HostingConfigPath, hostingDefaultResponseCodeField, http.StatusOK, http.StatusNotFound, config.DefaultResponseCode)
+	}
+
+	info, err := os.Stat(filepath.Join(appPath, HostingFilesDirectory, filepath.FromSlash(config.DefaultErrorPath)))
+	if err != nil || info.IsDir() {
+		return fmt.Errorf("invalid %s: %s '%s' is not a file in %s", HostingConfigPath, hostingDefaultErrorPathField, config.DefaultErrorPath, HostingFilesDirectory)
+	}
+
+	return nil
+}
+
+// mergeHostingConfig sets the fields of the HostingConfig of the app at appPath, if it has one,
+// in the hosting section of the app config
+func mergeHostingConfig(appPath string, app map[string]interface{}) error {
+	config, err := ReadHostingConfig(appPath)
+	if err != nil || config == nil {
+		return err
+	}
+
+	// the fields are read like the rest of the app, so that they compare equal to those of an exported app
+	var fields map[string]interface{}
+	if err := readAndUnmarshalJSONInto(filepath.Join(appPath, HostingConfigPath), &fields); err != nil {
+		return err
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	hosting, ok := app[HostingRoot].(map[string]interface{})
+	if !ok {
+		hosting = map[string]interface{}{}
+		app[HostingRoot] = hosting
+	}
+
+	for field, value := range fields {
+		hosting[field] = value
+	}
+
+	return nil
+}
+
+// SplitHostingConfig moves the HostingConfig fields of the hosting section of the config file
+// of the app exported to dir into its HostingConfigPath. The rest of the config file is kept
+// in the order it was exported in
+func SplitHostingConfig(dir string) error {
+	appPath := extractedAppDirectory(dir)
+	configPath := appConfigPath(appPath)
+
+	info, err := os.Stat(configPath)
+	if err != nil {
+		// not an exported app, such as a zip of dependencies
+		return nil
+	}
+
+	data, err := ioutil.ReadFile(configPath)
+	if err != nil {
+		return err
+	}
+
+	doc, err := unmarshalJSONObject(data)
+	if err != nil {
+		return fmt.Errorf("failed to parse %s: %s", configPath, err)
+	}
+
+	hostingIdx := doc.index(HostingRoot)
+	if hostingIdx == -1 {
+		return nil
+	}
+
+	hosting, err := unmarshalJSONObject(doc[hostingIdx].value)
+	if err != nil {
+		// a hosting section that is not an object is left for the server to report
+		return nil
+	}
+
+	var fields jsonObject
+	for _, field := range hostingConfigFields {
+		if i := hosting.index(field); i != -1 {
+			fields = append(fields, hosting[i])
+			hosting = append(hosting[:i], hosting[i+1:]...)
+		}
+	}
+
+	if len(fields) == 0 {
+		return nil
+	}
+
+	hostingData, err := hosting.MarshalJSON()
+	if err != nil {
+		return err
+	}
+	doc[hostingIdx].value = hostingData
+
+	configData, err := marshalIndentJSON(doc)
+	if err != nil {
+		return err
+	}
+	if bytes.HasSuffix(data, []byte("\n")) {
+		configData = append(configData, '\n')
+	}
+
+	hostingConfigData, err := marshalIndentJSON(fields)
+	if err != nil {
+		return err
+	}
+
+	if err := WriteFileToDir(filepath.Join(appPath, HostingConfigPath), bytes.NewReader(hostingConfigData)); err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(configPath, configData, info.Mode())
+}
+
+// jsonMember is a member of a JSON object, whose value is kept as it was read
+type jsonMember struct {
+	key   string
+	value json.RawMessage
+}
+
+// jsonObject is a JSON object that keeps the order of its members
+type jsonObject []jsonMember
+
+var errNotJSONObject = errors.New("not a JSON object")
+
+func unmarshalJSONObject(data []byte) (jsonObject, error) {
+	dec := json.NewDecoder(bytes.NewReader(data))
+
+	tok, err := dec.Token()
+	if err != nil {
+		return nil, err
+	}
+	if delim, ok := tok.(json.Delim); !ok || delim != '{' {
+		return nil, errNotJSONObject
+	}
+
+	obj := jsonObject{}
+	for dec.More() {
+		tok, err := dec.Token()
+		if err != nil {
+			return nil, err
+		}
+
+		var value json.RawMessage
+		if err := dec.Decode(&value); err != nil {
+			return nil, err
+		}
+		obj = append(obj, jsonMember{key: tok.(string), value: value})
+	}
+
+	if _, err := dec.Token(); err != nil {
+		return nil, err
+	}
+	return obj, nil
+}
+
+// index returns the index of the member with the given key, or -1 if there is none
+func (obj jsonObject) index(key string) int {
+	for i, member := range obj {
+		if member.key == key {
+			return i
+		}
+	}
+	return -1
+}
+
+// MarshalJSON writes the members of the object in order
+func (obj jsonObject) MarshalJSON() ([]byte, error) {
+	var buf bytes.Buffer
+	buf.WriteByte('{')
+	for i, member := range obj {
+		if i > 0 {
+			buf.WriteByte(',')
+		}
+		key, err := json.Marshal(member.key)
+		if err != nil {
+			return nil, err
+		}
+		buf.Write(key)
+		buf.WriteByte(':')
+		buf.Write(member.value)
+	}
+	buf.WriteByte('}')
+	return buf.Bytes(), nil
+}
+
+// marshalIndentJSON marshals v like json.MarshalIndent, without escaping HTML characters
+func marshalIndentJSON(v interface{}) ([]byte, error) {
+	var buf bytes.Buffer
+	enc := json.NewEncoder(&buf)
+	enc.SetEscapeHTML(false)
+	enc.SetIndent("", "    ")
+	if err := enc.Encode(v); err != nil {
+		return nil, err
+	}
+	return bytes.TrimSuffix(buf.Bytes(), []byte("\n")), nil
+}
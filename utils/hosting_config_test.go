@@ -0,0 +1,187 @@
+package utils_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"encoding/json"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestValidateHostingConfig(t *testing.T) {
+	setup := func(t *testing.T, config string) (string, func()) {
+		appPath, err := ioutil.TempDir("", "realm-cli-hosting-config")
+		u.So(t, err, gc.ShouldBeNil)
+
+		u.So(t, os.MkdirAll(filepath.Join(appPath, utils.HostingFilesDirectory, "docs"), 0755), gc.ShouldBeNil)
+		u.So(t, ioutil.WriteFile(filepath.Join(appPath, utils.HostingFilesDirectory, "index.html"), []byte("<html></html>"), 0644), gc.ShouldBeNil)
+		if config != "" {
+			u.So(t, ioutil.WriteFile(filepath.Join(appPath, utils.HostingConfigPath), []byte(config), 0644), gc.ShouldBeNil)
+		}
+
+		return appPath, func() { os.RemoveAll(appPath) }
+	}
+
+	for _, tc := range []struct {
+		description string
+		config      string
+		err         string
+	}{
+		{
+			description: "should accept an app without a hosting config",
+		},
+		{
+			description: "should accept a single-page app fallback",
+			config:      `{"default_error_path": "/index.html", "default_response_code": 200}`,
+		},
+		{
+			description: "should accept a custom not found page without a response code",
+			config:      `{"default_error_path": "/index.html"}`,
+		},
+		{
+			description: "should reject a missing file",
+			config:      `{"default_error_path": "/404.html", "default_response_code": 404}`,
+			err:         "invalid hosting/config.json: default_error_path '/404.html' is not a file in hosting/files",
+		},
+		{
+			description: "should reject a directory",
+			config:      `{"default_error_path": "/docs"}`,
+			err:         "invalid hosting/config.json: default_error_path '/docs' is not a file in hosting/files",
+		},
+		{
+			description: "should reject a relative path",
+			config:      `{"default_error_path": "index.html"}`,
+			err:         "invalid hosting/config.json: default_error_path 'index.html' must start with '/'",
+		},
+		{
+			description: "should reject an unsupported response code",
+			config:      `{"default_error_path": "/index.html", "default_response_code": 302}`,
+			err:         "invalid hosting/config.json: default_response_code must be 200 or 404, but was 302",
+		},
+		{
+			description: "should reject a response code without a path",
+			config:      `{"default_response_code": 200}`,
+			err:         "invalid hosting/config.json: default_response_code requires default_error_path",
+		},
+		{
+			description: "should reject an unknown field",
+			config:      `{"spa": true}`,
+			err:         `json: unknown field "spa"`,
+		},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			appPath, teardown := setup(t, tc.config)
+			defer teardown()
+
+			err := utils.ValidateHostingConfig(appPath)
+			if tc.err == "" {
+				u.So(t, err, gc.ShouldBeNil)
+			} else {
+				u.So(t, err, gc.ShouldNotBeNil)
+				u.So(t, err.Error(), gc.ShouldContainSubstring, tc.err)
+			}
+		})
+	}
+}
+
+func TestHostingConfigRoundTrip(t *testing.T) {
+	var buf bytes.Buffer
+	zw := zip.NewWriter(&buf)
+	for _, file := range []struct{ name, contents string }{
+		{"my-app/", ""},
+		{"my-app/config.json", `{
+			"app_id": "my-app-abcde",
+			"name": "my-app",
+			"hosting": {"enabled": true, "default_error_path": "/index.html", "default_response_code": 200}
+		}`},
+	} {
+		w, err := zw.Create(file.name)
+		u.So(t, err, gc.ShouldBeNil)
+		_, err = w.Write([]byte(file.contents))
+		u.So(t, err, gc.ShouldBeNil)
+	}
+	u.So(t, zw.Close(), gc.ShouldBeNil)
+	exported := buf.Bytes()
+
+	dir, err := ioutil.TempDir("", "realm-cli-hosting-config")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	u.So(t, utils.WriteZipToDir(dir, bytes.NewReader(exported), true), gc.ShouldBeNil)
+	appPath := filepath.Join(dir, "my-app")
+
+	t.Run("should leave the app config untouched when writing an exported app", func(t *testing.T) {
+		data, err := ioutil.ReadFile(filepath.Join(appPath, "config.json"))
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, string(data), gc.ShouldContainSubstring, `"default_error_path": "/index.html"`)
+
+		_, err = os.Stat(filepath.Join(appPath, utils.HostingConfigPath))
+		u.So(t, os.IsNotExist(err), gc.ShouldBeTrue)
+	})
+
+	u.So(t, utils.SplitHostingConfig(dir), gc.ShouldBeNil)
+
+	t.Run("should move the hosting config out of the app config when splitting an exported app", func(t *testing.T) {
+		var config map[string]interface{}
+		data, err := ioutil.ReadFile(filepath.Join(appPath, "config.json"))
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, json.Unmarshal(data, &config), gc.ShouldBeNil)
+		u.So(t, config["hosting"], gc.ShouldResemble, map[string]interface{}{"enabled": true})
+
+		t.Run("and keep the order of the app config fields", func(t *testing.T) {
+			u.So(t, string(data), gc.ShouldEqual, `{
+    "app_id": "my-app-abcde",
+    "name": "my-app",
+    "hosting": {
+        "enabled": true
+    }
+}`)
+		})
+
+		hostingConfig, err := utils.ReadHostingConfig(appPath)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, hostingConfig, gc.ShouldResemble, &utils.HostingConfig{DefaultErrorPath: "/index.html", DefaultResponseCode: 200})
+	})
+
+	t.Run("should merge the hosting config into the app config when loading an app", func(t *testing.T) {
+		app, err := utils.UnmarshalFromDir(appPath)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, app["hosting"], gc.ShouldResemble, map[string]interface{}{
+			"enabled":               true,
+			"default_error_path":    "/index.html",
+			"default_response_code": float64(200),
+		})
+
+		t.Run("and compare equal to the exported app", func(t *testing.T) {
+			exportedApp, err := utils.UnmarshalFromZip(bytes.NewReader(exported))
+			u.So(t, err, gc.ShouldBeNil)
+
+			diffs, err := utils.DiffApps(exportedApp, app)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, diffs, gc.ShouldBeEmpty)
+		})
+	})
+
+	t.Run("should report changes to the hosting config", func(t *testing.T) {
+		u.So(t, ioutil.WriteFile(filepath.Join(appPath, utils.HostingConfigPath), []byte(`{"default_error_path": "/404.html"}`), 0644), gc.ShouldBeNil)
+
+		app, err := utils.UnmarshalFromDir(appPath)
+		u.So(t, err, gc.ShouldBeNil)
+
+		exportedApp, err := utils.UnmarshalFromZip(bytes.NewReader(exported))
+		u.So(t, err, gc.ShouldBeNil)
+
+		diffs, err := utils.DiffApps(exportedApp, app)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, diffs.Diff(), gc.ShouldResemble, []string{
+			"Hosting:",
+			"\t* config (default_error_path, default_response_code)",
+		})
+	})
+}
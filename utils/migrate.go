@@ -0,0 +1,276 @@
+package utils
+
+import (
+	"bytes"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+
+	"github.com/10gen/realm-cli/models"
+)
+
+// ErrNoMigrationPath is returned when an app cannot be migrated offline from its config version
+var ErrNoMigrationPath = errors.New("no offline migration is available for this config version")
+
+var configVersionRegex = regexp.MustCompile(`("` + models.AppConfigVersionField + `"\s*:\s*)"?\d+"?`)
+
+// AppFiles holds the contents of an app directory's configuration files,
+// keyed by their slash-separated path relative to the app directory
+type AppFiles map[string][]byte
+
+// Paths returns the sorted paths of the AppFiles
+func (af AppFiles) Paths() []string {
+	paths := make([]string, 0, len(af))
+	for path := range af {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// isAppConfigFile reports whether the relative path is part of an app's configuration,
+// as opposed to hosted assets, dependencies or template variables
+func isAppConfigFile(relPath string) bool {
+	if relPath == VariablesFileName || strings.HasPrefix(relPath, HostingRoot+"/") {
+		return false
+	}
+	if strings.HasPrefix(relPath, FunctionsRoot+"/node_modules") {
+		return false
+	}
+	ext := filepath.Ext(relPath)
+	return ext == jsonExt || ext == jsExt
+}
+
+// ReadAppFiles reads the configuration files of the app at dir
+func ReadAppFiles(dir string) (AppFiles, error) {
+	files := AppFiles{}
+
+	err := filepath.Walk(dir, func(path string, info os.FileInfo, err error) error {
+		if err != nil {
+			return err
+		}
+		if info.IsDir() {
+			return nil
+		}
+
+		relPath, err := filepath.Rel(dir, path)
+		if err != nil {
+			return err
+		}
+		relPath = filepath.ToSlash(relPath)
+		if !isAppConfigFile(relPath) {
+			return nil
+		}
+
+		data, err := ioutil.ReadFile(path)
+		if err != nil {
+			return err
+		}
+		files[relPath] = data
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	return files, nil
+}
+
+// ZipToAppFiles reads the configuration files of an exported app from its zip data, which is extracted
+// the way an export is, so that the files are rooted at the app directory even if the zip wraps it in one
+func ZipToAppFiles(zipData io.Reader) (AppFiles, error) {
+	dir, err := ioutil.TempDir("", "realm-cli-app")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteZipToDir(dir, zipData, true); err != nil {
+		return nil, err
+	}
+
+	return ReadAppFiles(extractedAppDirectory(dir))
+}
+
+// WriteAppFiles updates the app directory at dir from the files in before to those in after,
+// removing any file that no longer exists
+func WriteAppFiles(dir string, before, after AppFiles) error {
+	for _, path := range after.Paths() {
+		if existing, ok := before[path]; ok && bytes.Equal(existing, after[path]) {
+			continue
+		}
+		if err := WriteFileToDir(filepath.Join(dir, filepath.FromSlash(path)), bytes.NewReader(after[path])); err != nil {
+			return err
+		}
+	}
+
+	for _, path := range before.Paths() {
+		if _, ok := after[path]; ok {
+			continue
+		}
+		if err := os.Remove(filepath.Join(dir, filepath.FromSlash(path))); err != nil {
+			return fmt.Errorf("failed to remove file %q: %s", path, err)
+		}
+	}
+
+	return nil
+}
+
+// DiffAppFiles returns a unified diff of every file that differs between before and after
+func DiffAppFiles(before, after AppFiles) string {
+	paths := before.Paths()
+	for _, path := range after.Paths() {
+		if _, ok := before[path]; !ok {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	var sb strings.Builder
+	for _, path := range paths {
+		fromName, toName := "a/"+path, "b/"+path
+		if _, ok := before[path]; !ok {
+			fromName = "/dev/null"
+		}
+		if _, ok := after[path]; !ok {
+			toName = "/dev/null"
+		}
+		sb.WriteString(UnifiedDiff(fromName, toName, string(before[path]), string(after[path]), 3))
+	}
+
+	return sb.String()
+}
+
+// AppInstanceData returns the contents of the app's top-level config file
+func (af AppFiles) AppInstanceData() (models.AppInstanceData, error) {
+	data, ok := af[models.AppConfigFileName]
+	if !ok {
+		if data, ok = af[models.LegacyAppConfigFileName]; !ok {
+			return nil, fmt.Errorf("could not find %s or %s", models.AppConfigFileName, models.LegacyAppConfigFileName)
+		}
+	}
+
+	config := models.AppInstanceData{}
+	if len(data) == 0 {
+		return config, nil
+	}
+	if err := json.Unmarshal(data, &config); err != nil {
+		return nil, fmt.Errorf("failed to parse app config: %s", err)
+	}
+
+	return config, nil
+}
+
+// SetAppInstanceData overwrites the app identity fields of the top-level config file with those in data
+func (af AppFiles) SetAppInstanceData(data models.AppInstanceData) error {
+	config, err := af.AppInstanceData()
+	if err != nil {
+		return err
+	}
+
+	for _, field := range []string{
+		models.AppIDField,
+		models.AppNameField,
+		models.AppLocationField,
+		models.AppDeploymentModelField,
+	} {
+		if value, ok := data[field]; ok {
+			config[field] = value
+		}
+	}
+
+	contents, err := json.MarshalIndent(config, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	af[models.AppConfigFileName] = contents
+	return nil
+}
+
+// ConfigVersion returns the config_version of the app with the given files
+func (af AppFiles) ConfigVersion() (int, error) {
+	config, err := af.AppInstanceData()
+	if err != nil {
+		return 0, err
+	}
+
+	switch version := config[models.AppConfigVersionField].(type) {
+	case float64:
+		return int(version), nil
+	case string:
+		var v int
+		if _, err := fmt.Sscanf(version, "%d", &v); err == nil {
+			return v, nil
+		}
+	}
+
+	return 0, fmt.Errorf("app config does not specify a valid %s", models.AppConfigVersionField)
+}
+
+// configMigration rewrites app files from one config version's layout to the next
+type configMigration struct {
+	from    int
+	to      int
+	migrate func(files AppFiles) error
+}
+
+// configMigrations are the offline migrations between config versions. There are none from 20180301,
+// whose layout differs from 20200603 in its entities as well as the name of its config file,
+// so apps at that version are migrated through a scratch app instead
+var configMigrations []configMigration
+
+// MigrateAppFiles returns a copy of the files rewritten from their config version's layout
+// to the current one, or ErrNoMigrationPath if there is no offline migration from that version
+func MigrateAppFiles(files AppFiles) (AppFiles, error) {
+	version, err := files.ConfigVersion()
+	if err != nil {
+		return nil, err
+	}
+
+	if version > models.ConfigVersion {
+		return nil, fmt.Errorf("config version %d is newer than the supported version %d, please upgrade the CLI", version, models.ConfigVersion)
+	}
+
+	migrated := AppFiles{}
+	for path, data := range files {
+		migrated[path] = data
+	}
+
+	for version < models.ConfigVersion {
+		var step *configMigration
+		for i := range configMigrations {
+			if configMigrations[i].from == version {
+				step = &configMigrations[i]
+				break
+			}
+		}
+		if step == nil {
+			return nil, ErrNoMigrationPath
+		}
+
+		if err := step.migrate(migrated); err != nil {
+			return nil, fmt.Errorf("failed to migrate from config version %d to %d: %s", step.from, step.to, err)
+		}
+		migrated.setConfigVersion(step.to)
+		version = step.to
+	}
+
+	return migrated, nil
+}
+
+// setConfigVersion rewrites the config_version in place to keep the rest of the file untouched
+func (af AppFiles) setConfigVersion(version int) {
+	data, ok := af[models.AppConfigFileName]
+	if !ok {
+		return
+	}
+	af[models.AppConfigFileName] = configVersionRegex.ReplaceAll(data, []byte(fmt.Sprintf("${1}%d", version)))
+}
@@ -0,0 +1,110 @@
+package utils_test
+
+import (
+	"archive/zip"
+	"bytes"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/models"
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestReadAppFiles(t *testing.T) {
+	files, err := utils.ReadAppFiles("../testdata/legacy_app")
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, files.Paths(), gc.ShouldResemble, []string{
+		"functions/hello/config.json",
+		"functions/hello/source.js",
+		"stitch.json",
+		"values/greeting.json",
+	})
+
+	version, err := files.ConfigVersion()
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, version, gc.ShouldEqual, 20180301)
+}
+
+func TestMigrateAppFiles(t *testing.T) {
+	t.Run("should not migrate a legacy app offline", func(t *testing.T) {
+		files, err := utils.ReadAppFiles("../testdata/legacy_app")
+		u.So(t, err, gc.ShouldBeNil)
+
+		_, err = utils.MigrateAppFiles(files)
+		u.So(t, err, gc.ShouldEqual, utils.ErrNoMigrationPath)
+	})
+
+	t.Run("should report when no offline migration is available", func(t *testing.T) {
+		files := utils.AppFiles{models.AppConfigFileName: []byte(`{"config_version": 20170101}`)}
+		_, err := utils.MigrateAppFiles(files)
+		u.So(t, err, gc.ShouldEqual, utils.ErrNoMigrationPath)
+	})
+
+	t.Run("should reject config versions newer than the CLI supports", func(t *testing.T) {
+		files := utils.AppFiles{models.AppConfigFileName: []byte(`{"config_version": 29990101}`)}
+		_, err := utils.MigrateAppFiles(files)
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldContainSubstring, "please upgrade the CLI")
+	})
+}
+
+func TestWriteAppFiles(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-migrate")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	u.So(t, ioutil.WriteFile(filepath.Join(dir, "stitch.json"), []byte(`{"config_version": 20180301}`), 0600), gc.ShouldBeNil)
+
+	before, err := utils.ReadAppFiles(dir)
+	u.So(t, err, gc.ShouldBeNil)
+
+	after := utils.AppFiles{models.AppConfigFileName: []byte(`{"config_version": 20200603}`)}
+
+	u.So(t, utils.WriteAppFiles(dir, before, after), gc.ShouldBeNil)
+
+	_, statErr := os.Stat(filepath.Join(dir, "stitch.json"))
+	u.So(t, os.IsNotExist(statErr), gc.ShouldBeTrue)
+
+	data, err := ioutil.ReadFile(filepath.Join(dir, "config.json"))
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, string(data), gc.ShouldEqual, `{"config_version": 20200603}`)
+}
+
+func TestZipToAppFiles(t *testing.T) {
+	for _, tc := range []struct {
+		description string
+		prefix      string
+	}{
+		{description: "should read the files of an app at the root of the zip"},
+		{description: "should read the files of an app wrapped in a single directory", prefix: "my-app/"},
+	} {
+		t.Run(tc.description, func(t *testing.T) {
+			var buf bytes.Buffer
+			zw := zip.NewWriter(&buf)
+			for name, contents := range map[string]string{
+				models.AppConfigFileName:    `{"config_version": 20200603}`,
+				"functions/hello/source.js": "exports = () => 'hello';",
+				"hosting/files/index.html":  "<html></html>",
+				"values/greeting.json":      `{"name": "greeting"}`,
+			} {
+				w, err := zw.Create(tc.prefix + name)
+				u.So(t, err, gc.ShouldBeNil)
+				_, err = w.Write([]byte(contents))
+				u.So(t, err, gc.ShouldBeNil)
+			}
+			u.So(t, zw.Close(), gc.ShouldBeNil)
+
+			files, err := utils.ZipToAppFiles(&buf)
+			u.So(t, err, gc.ShouldBeNil)
+			u.So(t, files.Paths(), gc.ShouldResemble, []string{
+				models.AppConfigFileName,
+				"functions/hello/source.js",
+				"values/greeting.json",
+			})
+		})
+	}
+}
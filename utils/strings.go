@@ -4,6 +4,7 @@ import (
 	"bytes"
 	"crypto/rand"
 	"math/big"
+	"strings"
 )
 
 var (
@@ -101,3 +102,17 @@ func randomAlphaNumericSpecialString(length int, strict bool) string {
 		}
 	}
 }
+
+// StringSliceFlag is a flag.Value collecting the values of a flag that may be provided multiple times
+type StringSliceFlag []string
+
+// Set adds a value of the flag
+func (s *StringSliceFlag) Set(value string) error {
+	*s = append(*s, value)
+	return nil
+}
+
+// String returns the values of the flag separated by commas
+func (s *StringSliceFlag) String() string {
+	return strings.Join(*s, ",")
+}
@@ -202,10 +202,11 @@ func GenerateValidAccessToken() string {
 // MockRealmClient satisfies an api.RealmClient
 type MockRealmClient struct {
 	CreateEmptyAppFn                  func(groupID, appName, locationName, deploymentModelName string) (*models.App, error)
+	DeleteAppFn                       func(groupID, appID string) error
 	FetchAppByGroupIDAndClientAppIDFn func(groupID, clientAppID string) (*models.App, error)
 	FetchAppByClientAppIDFn           func(clientAppID string) (*models.App, error)
 	FetchAppsByGroupIDFn              func(groupID string) ([]*models.App, error)
-	ListAssetsForAppIDFn              func(groupID, appID string) ([]string, []hosting.AssetDescription, error)
+	ListAssetsForAppIDFn              func(groupID, appID string) ([]hosting.AssetMetadata, error)
 	UploadAssetFn                     func(groupID, appID, path, hash string, size int64, body io.Reader, attributes ...hosting.AssetAttribute) error
 	CopyAssetFn                       func(groupID, appID, fromPath, toPath string) error
 	MoveAssetFn                       func(groupID, appID, fromPath, toPath string) error
@@ -310,6 +311,15 @@ func (msc *MockRealmClient) CreateEmptyApp(groupID, appName, locationName, deplo
 	return nil, errors.New("someone should test me")
 }
 
+// DeleteApp deletes an app
+func (msc *MockRealmClient) DeleteApp(groupID, appID string) error {
+	if msc.DeleteAppFn != nil {
+		return msc.DeleteAppFn(groupID, appID)
+	}
+
+	return nil
+}
+
 // Import will push a local Realm app to the server
 func (msc *MockRealmClient) Import(groupID, appID string, appData []byte, strategy string) error {
 	if msc.ImportFn != nil {
@@ -325,7 +335,7 @@ func (msc *MockRealmClient) FetchAppByGroupIDAndClientAppID(groupID, clientAppID
 		return msc.FetchAppByGroupIDAndClientAppIDFn(groupID, clientAppID)
 	}
 
-	return nil, api.ErrAppNotFound{clientAppID}
+	return nil, api.ErrAppNotFound{ClientAppID: clientAppID}
 }
 
 // FetchAppByClientAppID fetches a Realm app given a clientAppID
@@ -334,7 +344,7 @@ func (msc *MockRealmClient) FetchAppByClientAppID(clientAppID string) (*models.A
 		return msc.FetchAppByClientAppIDFn(clientAppID)
 	}
 
-	return nil, api.ErrAppNotFound{clientAppID}
+	return nil, api.ErrAppNotFound{ClientAppID: clientAppID}
 }
 
 // UploadAsset uploads an asset
@@ -384,6 +394,10 @@ func (msc *MockRealmClient) SetAssetAttributes(groupID, appID, path string, attr
 
 // ListAssetsForAppID fetches a Realm app given a clientAppID
 func (msc *MockRealmClient) ListAssetsForAppID(groupID, appID string) ([]hosting.AssetMetadata, error) {
+	if msc.ListAssetsForAppIDFn != nil {
+		return msc.ListAssetsForAppIDFn(groupID, appID)
+	}
+
 	assetMetadata := []hosting.AssetMetadata{
 		{
 			FilePath: "/bar/shouldRemainSame.txt",
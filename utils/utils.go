@@ -46,8 +46,14 @@ var (
 	HostingFilesDirectory = fmt.Sprintf("%s/files", HostingRoot)
 	// HostingAttributes is the file that stores the static hosting asset descriptions struct
 	HostingAttributes = fmt.Sprintf("%s/metadata.json", HostingRoot)
+	// HostingConfigPath is the file that stores the configuration of how the static hosting assets are served
+	HostingConfigPath = fmt.Sprintf("%s/config.json", HostingRoot)
 	// HostingCacheFileName is the file that stores the cached hosting asset data
 	HostingCacheFileName = ".asset-cache.json"
+	// TranspileCacheDirName is the directory that stores the cached results of transpiling dependencies
+	TranspileCacheDirName = "transpile-cache"
+	// HostingImportManifestFileName is the file in the hosting directory that records the failed operations of an import
+	HostingImportManifestFileName = ".import-failures.json"
 
 	errAppNotFound = errors.New("could not find realm app")
 )
@@ -104,7 +110,7 @@ func WriteZipToDir(dest string, zipData io.Reader, overwrite bool) error {
 		}
 	}
 
-	return err
+	return nil
 }
 
 // WriteFileToDir writes the data to dest and creates the necessary directories along the path
@@ -143,6 +149,11 @@ func processFile(path string, zipFile *zip.File) error {
 			return fmt.Errorf("failed to create sub-directory %q: %s", path, err)
 		}
 	} else {
+		// zips are not required to have entries for the directories of their files
+		if err := os.MkdirAll(filepath.Dir(path), os.ModePerm); err != nil {
+			return fmt.Errorf("failed to create sub-directory %q: %s", filepath.Dir(path), err)
+		}
+
 		f, err := os.OpenFile(path, os.O_WRONLY|os.O_CREATE|os.O_TRUNC, zipFile.Mode())
 		if err != nil {
 			return fmt.Errorf("failed to create file %q: %s", path, err)
@@ -162,7 +173,11 @@ func processFile(path string, zipFile *zip.File) error {
 func UnmarshalFromDir(path string) (map[string]interface{}, error) {
 	app := map[string]interface{}{}
 
-	if err := readAndUnmarshalJSONInto(filepath.Join(path, appConfigName+jsonExt), &app); err != nil {
+	if err := readAndUnmarshalJSONInto(appConfigPath(path), &app); err != nil {
+		return app, err
+	}
+
+	if err := mergeHostingConfig(path, app); err != nil {
 		return app, err
 	}
 
@@ -228,6 +243,49 @@ func UnmarshalFromDir(path string) (map[string]interface{}, error) {
 	return app, nil
 }
 
+// UnmarshalFromZip unmarshals a Realm app from exported zip data into a map[string]interface{}
+func UnmarshalFromZip(zipData io.Reader) (map[string]interface{}, error) {
+	dir, err := ioutil.TempDir("", "realm-cli-app")
+	if err != nil {
+		return nil, err
+	}
+	defer os.RemoveAll(dir)
+
+	if err := WriteZipToDir(dir, zipData, true); err != nil {
+		return nil, err
+	}
+
+	return UnmarshalFromDir(extractedAppDirectory(dir))
+}
+
+// extractedAppDirectory returns the app directory within an extracted zip,
+// which is either the zip's root or its single top-level directory
+func extractedAppDirectory(dir string) string {
+	if _, err := os.Stat(appConfigPath(dir)); err == nil {
+		return dir
+	}
+
+	fileInfos, err := ioutil.ReadDir(dir)
+	if err != nil || len(fileInfos) != 1 || !fileInfos[0].IsDir() {
+		return dir
+	}
+
+	return filepath.Join(dir, fileInfos[0].Name())
+}
+
+// appConfigPath returns the path to the top-level config file of the app at path,
+// falling back to the legacy config file if the app has not been migrated
+func appConfigPath(path string) string {
+	configPath := filepath.Join(path, models.AppConfigFileName)
+	if _, err := os.Stat(configPath); os.IsNotExist(err) {
+		legacyPath := filepath.Join(path, models.LegacyAppConfigFileName)
+		if _, legacyErr := os.Stat(legacyPath); legacyErr == nil {
+			return legacyPath
+		}
+	}
+	return configPath
+}
+
 func unmarshalJSONFiles(path string, ignoreDirErr bool) ([]interface{}, error) {
 	fileInfos, err := ioutil.ReadDir(path)
 	if err != nil && !ignoreDirErr {
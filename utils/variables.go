@@ -0,0 +1,267 @@
+package utils
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// VariablesFileName is the file written alongside a templatized export that
+// stores the values the template variables were extracted from
+const VariablesFileName = "variables.json"
+
+var (
+	// matches either an escaped reference ($${VAR}) or a variable reference (${VAR})
+	variableRegex     = regexp.MustCompile(`\$?\$\{([A-Za-z_][A-Za-z0-9_]*)\}`)
+	variableNameRegex = regexp.MustCompile(`^[A-Za-z_][A-Za-z0-9_]*$`)
+	nonVariableChars  = regexp.MustCompile(`[^A-Z0-9_]+`)
+)
+
+// Variables maps template variable names to their values
+type Variables map[string]string
+
+// Lookup returns the value of the named variable, falling back to the environment
+// when the variable has not been explicitly set
+func (v Variables) Lookup(name string) (string, bool) {
+	if value, ok := v[name]; ok {
+		return value, true
+	}
+	return os.LookupEnv(name)
+}
+
+// Set parses a NAME=VALUE pair and adds it to the Variables
+func (v Variables) Set(pair string) error {
+	idx := strings.Index(pair, "=")
+	if idx == -1 {
+		return fmt.Errorf("variable %q must be of the form NAME=VALUE", pair)
+	}
+
+	name := pair[:idx]
+	if !variableNameRegex.MatchString(name) {
+		return fmt.Errorf("invalid variable name %q", name)
+	}
+
+	v[name] = pair[idx+1:]
+	return nil
+}
+
+// String returns the Variables as a sorted list of NAME=VALUE pairs
+func (v Variables) String() string {
+	pairs := make([]string, 0, len(v))
+	for name, value := range v {
+		pairs = append(pairs, name+"="+value)
+	}
+	sort.Strings(pairs)
+	return strings.Join(pairs, ",")
+}
+
+// LoadVariablesFile reads a JSON object of variable names to string values from the file at path
+func LoadVariablesFile(path string) (Variables, error) {
+	data, err := ioutil.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+
+	vars := Variables{}
+	if err := json.Unmarshal(data, &vars); err != nil {
+		return nil, fmt.Errorf("failed to parse %s: %s", path, err)
+	}
+
+	for name := range vars {
+		if !variableNameRegex.MatchString(name) {
+			return nil, fmt.Errorf("invalid variable name %q in %s", name, path)
+		}
+	}
+
+	return vars, nil
+}
+
+// SubstituteVariables replaces every ${VAR} reference in the string values of a loaded app
+// with its value from vars. Function and webhook sources are left untouched since
+// JavaScript template literals share the same syntax. A reference can be escaped as $${VAR}.
+// It returns the number of references that were substituted, or an error listing every
+// unresolved variable if any reference cannot be resolved
+func SubstituteVariables(app map[string]interface{}, vars Variables) (int, error) {
+	s := substitution{vars: vars, unresolved: map[string]struct{}{}}
+
+	for key, value := range app {
+		app[key] = s.value(key, value)
+	}
+
+	if len(s.unresolved) == 0 {
+		return s.count, nil
+	}
+
+	names := make([]string, 0, len(s.unresolved))
+	for name := range s.unresolved {
+		names = append(names, name)
+	}
+	sort.Strings(names)
+
+	return 0, fmt.Errorf("unresolved template variable(s): %s", strings.Join(names, ", "))
+}
+
+type substitution struct {
+	vars       Variables
+	unresolved map[string]struct{}
+	count      int
+}
+
+func (s *substitution) value(key string, value interface{}) interface{} {
+	switch v := value.(type) {
+	case string:
+		if key == sourceName {
+			return v
+		}
+		return variableRegex.ReplaceAllStringFunc(v, func(ref string) string {
+			if strings.HasPrefix(ref, "$$") {
+				return ref[1:]
+			}
+
+			name := ref[2 : len(ref)-1]
+			resolved, ok := s.vars.Lookup(name)
+			if !ok {
+				s.unresolved[name] = struct{}{}
+				return ref
+			}
+			s.count++
+			return resolved
+		})
+	case map[string]interface{}:
+		for k, child := range v {
+			v[k] = s.value(k, child)
+		}
+		return v
+	case []interface{}:
+		for i, child := range v {
+			v[i] = s.value(key, child)
+		}
+		return v
+	default:
+		return v
+	}
+}
+
+// templatizeField describes an environment-specific field of an app config file
+// that should be replaced with a template variable on export
+type templatizeField struct {
+	// glob matching the files, relative to the app directory
+	pattern string
+	// dotted path to the field within the file
+	field string
+	// reports whether the field applies to the given file contents
+	applies func(doc map[string]interface{}) bool
+	// builds the variable name from the file contents
+	variable func(doc map[string]interface{}) string
+}
+
+var templatizeFields = []templatizeField{
+	{
+		pattern:  appConfigName + jsonExt,
+		field:    "hosting.custom_domain",
+		variable: func(doc map[string]interface{}) string { return "HOSTING_CUSTOM_DOMAIN" },
+	},
+	{
+		pattern: filepath.Join(servicesName, "*", configName+jsonExt),
+		field:   "config.clusterName",
+		applies: func(doc map[string]interface{}) bool {
+			return doc["type"] == "mongodb-atlas"
+		},
+		variable: func(doc map[string]interface{}) string {
+			return variableName("CLUSTER_NAME", doc["name"])
+		},
+	},
+	{
+		pattern: filepath.Join(valuesName, "*"+jsonExt),
+		field:   "value",
+		applies: func(doc map[string]interface{}) bool {
+			fromSecret, _ := doc["from_secret"].(bool)
+			return !fromSecret
+		},
+		variable: func(doc map[string]interface{}) string {
+			return variableName("VALUE", doc["name"])
+		},
+	},
+}
+
+// TemplatizeDir rewrites the known environment-specific fields of the app exported
+// at path into template variables and returns the values that were replaced
+func TemplatizeDir(path string) (Variables, error) {
+	vars := Variables{}
+
+	for _, tf := range templatizeFields {
+		matches, err := filepath.Glob(filepath.Join(path, tf.pattern))
+		if err != nil {
+			return nil, err
+		}
+
+		for _, match := range matches {
+			if err := templatizeFile(match, tf, vars); err != nil {
+				return nil, err
+			}
+		}
+	}
+
+	return vars, nil
+}
+
+func templatizeFile(path string, tf templatizeField, vars Variables) error {
+	var doc map[string]interface{}
+	if err := readAndUnmarshalJSONInto(path, &doc); err != nil {
+		return err
+	}
+
+	if doc == nil || (tf.applies != nil && !tf.applies(doc)) {
+		return nil
+	}
+
+	keys := strings.Split(tf.field, ".")
+	parent := doc
+	for _, key := range keys[:len(keys)-1] {
+		child, ok := parent[key].(map[string]interface{})
+		if !ok {
+			return nil
+		}
+		parent = child
+	}
+
+	last := keys[len(keys)-1]
+	value, ok := parent[last].(string)
+	if !ok || value == "" || variableRegex.MatchString(value) {
+		return nil
+	}
+
+	name := tf.variable(doc)
+	if existing, ok := vars[name]; ok && existing != value {
+		return fmt.Errorf("failed to templatize %s: variable %s is already used for another value", path, name)
+	}
+	vars[name] = value
+	parent[last] = fmt.Sprintf("${%s}", name)
+
+	info, err := os.Stat(path)
+	if err != nil {
+		return err
+	}
+
+	data, err := json.MarshalIndent(doc, "", "    ")
+	if err != nil {
+		return err
+	}
+
+	return ioutil.WriteFile(path, data, info.Mode())
+}
+
+// variableName builds an upper-case variable name from a prefix and an entity name
+func variableName(prefix string, name interface{}) string {
+	suffix, _ := name.(string)
+	suffix = strings.Trim(nonVariableChars.ReplaceAllString(strings.ToUpper(suffix), "_"), "_")
+	if suffix == "" {
+		return prefix
+	}
+	return prefix + "_" + suffix
+}
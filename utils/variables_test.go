@@ -0,0 +1,107 @@
+package utils_test
+
+import (
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"testing"
+
+	"github.com/10gen/realm-cli/utils"
+	u "github.com/10gen/realm-cli/utils/test"
+	gc "github.com/smartystreets/goconvey/convey"
+)
+
+func TestSubstituteVariables(t *testing.T) {
+	t.Run("should substitute variables from the provided set", func(t *testing.T) {
+		app, err := utils.UnmarshalFromDir("../testdata/templated_app")
+		u.So(t, err, gc.ShouldBeNil)
+
+		vars, err := utils.LoadVariablesFile("../testdata/templated_app/variables.json")
+		u.So(t, err, gc.ShouldBeNil)
+
+		substituted, err := utils.SubstituteVariables(app, vars)
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, substituted, gc.ShouldEqual, 2)
+
+		svc := app["services"].([]interface{})[0].(map[string]interface{})
+		svcConfig := svc["config"].(map[string]interface{})["config"].(map[string]interface{})
+		u.So(t, svcConfig["clusterName"], gc.ShouldEqual, "Cluster0")
+
+		value := app["values"].([]interface{})[0].(map[string]interface{})
+		u.So(t, value["value"], gc.ShouldEqual, "https://example.com/hooks/${NOT_A_VARIABLE}")
+
+		fn := app["functions"].([]interface{})[0].(map[string]interface{})
+		u.So(t, fn["source"], gc.ShouldContainSubstring, "`Hello, ${name}!`")
+	})
+
+	t.Run("should fall back to environment variables", func(t *testing.T) {
+		os.Setenv("WEBHOOK_HOST", "env.example.com")
+		defer os.Unsetenv("WEBHOOK_HOST")
+
+		app := map[string]interface{}{"value": "${WEBHOOK_HOST}"}
+		_, err := utils.SubstituteVariables(app, utils.Variables{})
+		u.So(t, err, gc.ShouldBeNil)
+		u.So(t, app["value"], gc.ShouldEqual, "env.example.com")
+	})
+
+	t.Run("should report every unresolved variable", func(t *testing.T) {
+		app, err := utils.UnmarshalFromDir("../testdata/templated_app")
+		u.So(t, err, gc.ShouldBeNil)
+
+		_, err = utils.SubstituteVariables(app, utils.Variables{})
+		u.So(t, err, gc.ShouldNotBeNil)
+		u.So(t, err.Error(), gc.ShouldEqual, "unresolved template variable(s): CLUSTER_NAME_MONGODB_ATLAS, WEBHOOK_HOST")
+	})
+}
+
+func TestVariablesSet(t *testing.T) {
+	vars := utils.Variables{}
+	u.So(t, vars.Set("CLUSTER=Cluster0=prod"), gc.ShouldBeNil)
+	u.So(t, vars["CLUSTER"], gc.ShouldEqual, "Cluster0=prod")
+
+	u.So(t, vars.Set("CLUSTER"), gc.ShouldNotBeNil)
+	u.So(t, vars.Set("1CLUSTER=Cluster0"), gc.ShouldNotBeNil)
+}
+
+func TestTemplatizeDir(t *testing.T) {
+	dir, err := ioutil.TempDir("", "realm-cli-templatize")
+	u.So(t, err, gc.ShouldBeNil)
+	defer os.RemoveAll(dir)
+
+	for path, contents := range map[string]string{
+		"config.json":                           `{"name": "app", "hosting": {"enabled": true, "custom_domain": "app.example.com"}}`,
+		"services/mongodb-atlas/config.json":    `{"name": "mongodb-atlas", "type": "mongodb-atlas", "config": {"clusterName": "Cluster0"}}`,
+		"services/twilio/config.json":           `{"name": "twilio", "type": "twilio", "config": {"sid": "abcdefgh"}}`,
+		"values/webhook_url.json":               `{"name": "webhook-url", "value": "https://example.com/hook"}`,
+		"values/api_key.json":                   `{"name": "api_key", "value": "api_key_secret", "from_secret": true}`,
+		"services/mongodb-atlas/rules/foo.json": `{"database": "db"}`,
+	} {
+		fullPath := filepath.Join(dir, path)
+		u.So(t, os.MkdirAll(filepath.Dir(fullPath), os.ModePerm), gc.ShouldBeNil)
+		u.So(t, ioutil.WriteFile(fullPath, []byte(contents), 0600), gc.ShouldBeNil)
+	}
+
+	vars, err := utils.TemplatizeDir(dir)
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, vars, gc.ShouldResemble, utils.Variables{
+		"HOSTING_CUSTOM_DOMAIN":      "app.example.com",
+		"CLUSTER_NAME_MONGODB_ATLAS": "Cluster0",
+		"VALUE_WEBHOOK_URL":          "https://example.com/hook",
+	})
+
+	app, err := utils.UnmarshalFromDir(dir)
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, app["hosting"].(map[string]interface{})["custom_domain"], gc.ShouldEqual, "${HOSTING_CUSTOM_DOMAIN}")
+
+	substituted, err := utils.SubstituteVariables(app, vars)
+	u.So(t, err, gc.ShouldBeNil)
+	u.So(t, substituted, gc.ShouldEqual, 3)
+	u.So(t, app["hosting"].(map[string]interface{})["custom_domain"], gc.ShouldEqual, "app.example.com")
+
+	for _, v := range app["values"].([]interface{}) {
+		value := v.(map[string]interface{})
+		if value["name"] == "api_key" {
+			u.So(t, value["value"], gc.ShouldEqual, "api_key_secret")
+		}
+	}
+}
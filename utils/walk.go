@@ -0,0 +1,95 @@
+package utils
+
+import (
+	"fmt"
+	"io/ioutil"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Walk calls walkFn for rootDirectory and every directory and regular file below it in lexical order,
+// like filepath.Walk, except that symlinks are resolved: a symlinked file is visited with the FileInfo of its
+// target, and a symlinked directory is walked only if followSymlinks is set, failing if it links back to one
+// of the directories containing it
+func Walk(rootDirectory string, followSymlinks bool, walkFn filepath.WalkFunc) error {
+	info, err := os.Stat(rootDirectory)
+	if err != nil {
+		return walkFn(rootDirectory, nil, err)
+	}
+
+	realPath, err := filepath.EvalSymlinks(rootDirectory)
+	if err != nil {
+		return walkFn(rootDirectory, nil, err)
+	}
+
+	err = walkDir(rootDirectory, info, followSymlinks, []string{realPath}, walkFn)
+	if err == filepath.SkipDir {
+		return nil
+	}
+	return err
+}
+
+// walkDir walks the directory at path, whose real path and those of the directories containing it are ancestors
+func walkDir(path string, info os.FileInfo, followSymlinks bool, ancestors []string, walkFn filepath.WalkFunc) error {
+	if err := walkFn(path, info, nil); err != nil {
+		return err
+	}
+
+	infos, err := ioutil.ReadDir(path)
+	if err != nil {
+		return walkFn(path, info, err)
+	}
+
+	for _, childInfo := range infos {
+		childPath := filepath.Join(path, childInfo.Name())
+		childRealPath := filepath.Join(ancestors[len(ancestors)-1], childInfo.Name())
+
+		if childInfo.Mode()&os.ModeSymlink != 0 {
+			target, err := os.Stat(childPath)
+			if err != nil {
+				return fmt.Errorf("failed to resolve symlink %s: %s", childPath, err)
+			}
+
+			if target.IsDir() {
+				if !followSymlinks {
+					continue
+				}
+
+				if childRealPath, err = filepath.EvalSymlinks(childPath); err != nil {
+					return fmt.Errorf("failed to resolve symlink %s: %s", childPath, err)
+				}
+
+				for _, ancestor := range ancestors {
+					if isWithinDir(ancestor, childRealPath) {
+						return fmt.Errorf("symlink %s creates a cycle, as it links to %s which contains it", childPath, childRealPath)
+					}
+				}
+			}
+
+			childInfo = target
+		}
+
+		switch {
+		case childInfo.IsDir():
+			err = walkDir(childPath, childInfo, followSymlinks, append(ancestors[:len(ancestors):len(ancestors)], childRealPath), walkFn)
+			if err == filepath.SkipDir {
+				err = nil
+			}
+		case childInfo.Mode().IsRegular():
+			err = walkFn(childPath, childInfo, nil)
+		}
+
+		if err != nil {
+			return err
+		}
+	}
+
+	return nil
+}
+
+// isWithinDir reports whether path is dir or is inside it
+func isWithinDir(path, dir string) bool {
+	rel, err := filepath.Rel(dir, path)
+	return err == nil && rel != ".." && !strings.HasPrefix(rel, ".."+string(filepath.Separator))
+}